@@ -169,6 +169,38 @@ func (m *MockAutoscaling) TerminateInstanceInAutoScalingGroup(input *autoscaling
 	return nil, fmt.Errorf("Instance not found")
 }
 
+func (m *MockAutoscaling) DetachInstances(input *autoscaling.DetachInstancesInput) (*autoscaling.DetachInstancesOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	klog.V(2).Infof("DetachInstances %v", input)
+
+	g := m.Groups[aws.StringValue(input.AutoScalingGroupName)]
+	if g == nil {
+		return nil, fmt.Errorf("AutoScaling Group not found")
+	}
+
+	for _, instanceID := range input.InstanceIds {
+		found := false
+		for i := range g.Instances {
+			if aws.StringValue(g.Instances[i].InstanceId) == aws.StringValue(instanceID) {
+				g.Instances = append(g.Instances[:i], g.Instances[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("Instance %q not found in AutoScaling Group", aws.StringValue(instanceID))
+		}
+	}
+
+	if aws.BoolValue(input.ShouldDecrementDesiredCapacity) && g.DesiredCapacity != nil {
+		g.DesiredCapacity = aws.Int64(aws.Int64Value(g.DesiredCapacity) - int64(len(input.InstanceIds)))
+	}
+
+	return &autoscaling.DetachInstancesOutput{}, nil
+}
+
 func (m *MockAutoscaling) DescribeAutoScalingGroupsWithContext(aws.Context, *autoscaling.DescribeAutoScalingGroupsInput, ...request.Option) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
 	klog.Fatalf("Not implemented")
 	return nil, nil
@@ -49,3 +49,14 @@ func (m *MockEC2) DescribeInstancesPages(request *ec2.DescribeInstancesInput, ca
 func (m *MockEC2) DescribeInstancesPagesWithContext(aws.Context, *ec2.DescribeInstancesInput, func(*ec2.DescribeInstancesOutput, bool) bool, ...request.Option) error {
 	panic("Not implemented")
 }
+
+func (m *MockEC2) TerminateInstances(request *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
+	klog.Warningf("MockEc2::TerminateInstances is stub-implemented")
+	return &ec2.TerminateInstancesOutput{}, nil
+}
+func (m *MockEC2) TerminateInstancesWithContext(aws.Context, *ec2.TerminateInstancesInput, ...request.Option) (*ec2.TerminateInstancesOutput, error) {
+	panic("Not implemented")
+}
+func (m *MockEC2) TerminateInstancesRequest(*ec2.TerminateInstancesInput) (*request.Request, *ec2.TerminateInstancesOutput) {
+	panic("Not implemented")
+}
@@ -77,6 +77,8 @@ func (m *MockEC2) addTag(resourceId string, tag *ec2.Tag) {
 		resourceType = ec2.ResourceTypeRouteTable
 	} else if strings.HasPrefix(resourceId, "eipalloc-") {
 		resourceType = ResourceTypeAddress
+	} else if strings.HasPrefix(resourceId, "i-") {
+		resourceType = ec2.ResourceTypeInstance
 	} else {
 		klog.Fatalf("Unknown resource-type in create tags: %v", resourceId)
 	}
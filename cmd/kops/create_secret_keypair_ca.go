@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"encoding/pem"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -36,6 +37,10 @@ import (
 var (
 	createSecretCacertLong = templates.LongDesc(i18n.T(`
 	Add a ca certificate and private key.
+
+	When the cluster uses an external CA (externalCA in the cluster spec) the private key is omitted,
+	and certificates are issued by the external CA. The cert file may then hold the CA certificate,
+	typically an intermediate CA, followed by the certificate of the root CA that issued it.
     `))
 
 	createSecretCacertExample = templates.Examples(i18n.T(`
@@ -43,6 +48,11 @@ var (
 	kops create secret keypair ca \
 		--cert ~/ca.pem --key ~/ca-key.pem \
 		--name k8s-cluster.example.com --state s3://example.com
+
+	Add the certificate chain of an external CA.
+	kops create secret keypair ca \
+		--cert ~/intermediate-chain.pem \
+		--name k8s-cluster.example.com --state s3://example.com
 	`))
 
 	createSecretCacertShort = i18n.T(`Add a ca cert and key`)
@@ -90,15 +100,19 @@ func RunCreateSecretCaCert(f *util.Factory, out io.Writer, options *CreateSecret
 		return fmt.Errorf("error cert provided")
 	}
 
-	if options.CaPrivateKeyPath == "" {
-		return fmt.Errorf("error no private key provided")
-	}
-
 	cluster, err := GetCluster(f, options.ClusterName)
 	if err != nil {
 		return fmt.Errorf("error getting cluster: %q: %v", options.ClusterName, err)
 	}
 
+	externalCA := cluster.Spec.ExternalCA != nil
+	if options.CaPrivateKeyPath == "" && !externalCA {
+		return fmt.Errorf("error no private key provided")
+	}
+	if options.CaPrivateKeyPath != "" && externalCA {
+		return fmt.Errorf("cluster uses an external CA; the private key of the CA must not be provided")
+	}
+
 	clientSet, err := f.Clientset()
 	if err != nil {
 		return fmt.Errorf("error getting clientset: %v", err)
@@ -110,33 +124,72 @@ func RunCreateSecretCaCert(f *util.Factory, out io.Writer, options *CreateSecret
 	}
 
 	options.CaCertPath = utils.ExpandPath(options.CaCertPath)
-	options.CaPrivateKeyPath = utils.ExpandPath(options.CaPrivateKeyPath)
 
 	certBytes, err := ioutil.ReadFile(options.CaCertPath)
 	if err != nil {
 		return fmt.Errorf("error reading user provided cert %q: %v", options.CaCertPath, err)
 	}
-	privateKeyBytes, err := ioutil.ReadFile(options.CaPrivateKeyPath)
+	chain, err := parsePEMCertificateChain(certBytes)
 	if err != nil {
-		return fmt.Errorf("error reading user provided private key %q: %v", options.CaPrivateKeyPath, err)
+		return fmt.Errorf("error loading certificate %q: %v", options.CaCertPath, err)
 	}
-
-	privateKey, err := pki.ParsePEMPrivateKey(privateKeyBytes)
-	if err != nil {
-		return fmt.Errorf("error loading private key %q: %v", privateKeyBytes, err)
+	if len(chain) > 2 || (len(chain) == 2 && !externalCA) {
+		return fmt.Errorf("certificate %q must hold the CA certificate, optionally followed by the root CA certificate of an external CA", options.CaCertPath)
 	}
-	cert, err := pki.ParsePEMCertificate(certBytes)
-	if err != nil {
-		return fmt.Errorf("error loading certificate %q: %v", options.CaCertPath, err)
+
+	var privateKey *pki.PrivateKey
+	if !externalCA {
+		options.CaPrivateKeyPath = utils.ExpandPath(options.CaPrivateKeyPath)
+		privateKeyBytes, err := ioutil.ReadFile(options.CaPrivateKeyPath)
+		if err != nil {
+			return fmt.Errorf("error reading user provided private key %q: %v", options.CaPrivateKeyPath, err)
+		}
+
+		privateKey, err = pki.ParsePEMPrivateKey(privateKeyBytes)
+		if err != nil {
+			return fmt.Errorf("error loading private key %q: %v", privateKeyBytes, err)
+		}
 	}
 
-	err = keyStore.StoreKeypair(fi.CertificateId_CA, cert, privateKey)
+	err = keyStore.StoreKeypair(fi.CertificateId_CA, chain[0], privateKey)
 	if err != nil {
 		return fmt.Errorf("error storing user provided keys %q %q: %v", options.CaCertPath, options.CaPrivateKeyPath, err)
 	}
+	if len(chain) == 2 {
+		// The root CA is added as a secondary certificate, so it is never used to issue certificates
+		if err := keyStore.AddCert(fi.CertificateId_CA, chain[1]); err != nil {
+			return fmt.Errorf("error storing root CA certificate from %q: %v", options.CaCertPath, err)
+		}
+	}
 
 	klog.Infof("using user provided cert: %v\n", options.CaCertPath)
-	klog.Infof("using user provided private key: %v\n", options.CaPrivateKeyPath)
+	if !externalCA {
+		klog.Infof("using user provided private key: %v\n", options.CaPrivateKeyPath)
+	}
 
 	return nil
 }
+
+// parsePEMCertificateChain parses all the certificates in PEM encoded data, in order
+func parsePEMCertificateChain(data []byte) ([]*pki.Certificate, error) {
+	var chain []*pki.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := pki.ParsePEMCertificate(pem.EncodeToMemory(block))
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return chain, nil
+}
@@ -103,8 +103,12 @@ func NewCmdGet(f *util.Factory, out io.Writer) *cobra.Command {
 	cmd.PersistentFlags().StringVarP(&options.output, "output", "o", options.output, "output format.  One of: table, yaml, json")
 
 	// create subcommands
+	cmd.AddCommand(NewCmdGetCertificates(f, out, options))
 	cmd.AddCommand(NewCmdGetCluster(f, out, options))
+	cmd.AddCommand(NewCmdGetEtcdBackups(f, out, options))
 	cmd.AddCommand(NewCmdGetInstanceGroups(f, out, options))
+	cmd.AddCommand(NewCmdGetNodeConfig(f, out, options))
+	cmd.AddCommand(NewCmdGetRollingUpdate(f, out, options))
 	cmd.AddCommand(NewCmdGetSecrets(f, out, options))
 
 	return cmd
@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/certificates"
+	"k8s.io/kops/util/pkg/tables"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	getCertificatesLong = templates.LongDesc(i18n.T(`
+	Display the certificates of the keypairs in the keystore, with when they expire.
+
+	Only the certificate in use (the primary certificate) of each keypair is shown, unless --all is set.`))
+
+	getCertificatesExample = templates.Examples(i18n.T(`
+	# Get the certificates of all keypairs
+	kops get certificates --name k8s-cluster.example.com
+
+	# Get the certificates of the kubelet keypair, including those it replaced
+	kops get certificates --name k8s-cluster.example.com kubelet --all
+
+	# Get the certificates as json
+	kops get certificates --name k8s-cluster.example.com -o json`))
+
+	getCertificatesShort = i18n.T(`Get the certificates in the keystore.`)
+)
+
+type GetCertificatesOptions struct {
+	*GetOptions
+	All bool
+}
+
+// certificateItem is the output of kops get certificates for a single certificate
+type certificateItem struct {
+	*certificates.Certificate
+	DaysRemaining int `json:"daysRemaining"`
+}
+
+func NewCmdGetCertificates(f *util.Factory, out io.Writer, getOptions *GetOptions) *cobra.Command {
+	options := GetCertificatesOptions{
+		GetOptions: getOptions,
+	}
+	cmd := &cobra.Command{
+		Use:     "certificates",
+		Aliases: []string{"certificate", "certs", "cert"},
+		Short:   getCertificatesShort,
+		Long:    getCertificatesLong,
+		Example: getCertificatesExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := RunGetCertificates(&options, args, out)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&options.All, "all", options.All, "Also show the certificates that have been replaced")
+	return cmd
+}
+
+func RunGetCertificates(options *GetCertificatesOptions, args []string, out io.Writer) error {
+	cluster, err := rootCommand.Cluster()
+	if err != nil {
+		return err
+	}
+
+	clientset, err := rootCommand.Clientset()
+	if err != nil {
+		return err
+	}
+
+	keyStore, err := clientset.KeyStore(cluster)
+	if err != nil {
+		return err
+	}
+
+	certs, err := certificates.ListCertificates(keyStore)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	names := sets.NewString(args...)
+	items := []*certificateItem{}
+	for _, c := range certs {
+		if len(args) != 0 && !names.Has(c.Keyset) {
+			continue
+		}
+		if !c.Primary && !options.All {
+			continue
+		}
+		items = append(items, &certificateItem{
+			Certificate:   c,
+			DaysRemaining: c.DaysRemaining(now),
+		})
+	}
+
+	switch options.output {
+	case OutputTable:
+		if len(items) == 0 {
+			return fmt.Errorf("No certificates found")
+		}
+		t := &tables.Table{}
+		t.AddColumn("KEYSET", func(i *certificateItem) string {
+			return i.Keyset
+		})
+		t.AddColumn("ID", func(i *certificateItem) string {
+			return i.Id
+		})
+		t.AddColumn("PRIMARY", func(i *certificateItem) string {
+			return strconv.FormatBool(i.Primary)
+		})
+		t.AddColumn("SUBJECT", func(i *certificateItem) string {
+			return i.Subject
+		})
+		t.AddColumn("SANS", func(i *certificateItem) string {
+			return strings.Join(i.AlternateNames, ",")
+		})
+		t.AddColumn("ISSUER", func(i *certificateItem) string {
+			return i.Issuer
+		})
+		t.AddColumn("NOT AFTER", func(i *certificateItem) string {
+			return i.NotAfter.UTC().Format(time.RFC3339)
+		})
+		t.AddColumn("DAYS", func(i *certificateItem) string {
+			return strconv.Itoa(i.DaysRemaining)
+		})
+		columns := []string{"KEYSET", "SUBJECT", "SANS", "ISSUER", "NOT AFTER", "DAYS"}
+		if options.All {
+			columns = []string{"KEYSET", "ID", "PRIMARY", "SUBJECT", "SANS", "ISSUER", "NOT AFTER", "DAYS"}
+		}
+		return t.Render(items, out, columns...)
+
+	case OutputYaml:
+		y, err := yaml.Marshal(items)
+		if err != nil {
+			return fmt.Errorf("unable to marshal YAML: %v", err)
+		}
+		if _, err := out.Write(y); err != nil {
+			return fmt.Errorf("error writing to output: %v", err)
+		}
+		return nil
+
+	case OutputJSON:
+		j, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %v", err)
+		}
+		if _, err := fmt.Fprintf(out, "%s\n", j); err != nil {
+			return fmt.Errorf("error writing to output: %v", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("Unknown output format: %q", options.output)
+	}
+}
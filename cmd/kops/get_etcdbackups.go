@@ -0,0 +1,196 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/etcdbackup"
+	"k8s.io/kops/util/pkg/tables"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	getEtcdBackupsLong = templates.LongDesc(i18n.T(`
+	Display the etcd backups in the backup store of each etcd cluster, oldest first.`))
+
+	getEtcdBackupsExample = templates.Examples(i18n.T(`
+	# Get the backups of all etcd clusters
+	kops get etcd-backups --name k8s-cluster.example.com
+
+	# Get the backups of the main etcd cluster as json
+	kops get etcd-backups --name k8s-cluster.example.com --etcd-cluster main -o json
+
+	# Get a single backup
+	kops get etcd-backups --name k8s-cluster.example.com 2019-06-05T12:00:00Z-000001`))
+
+	getEtcdBackupsShort = i18n.T(`Get the backups of the etcd clusters.`)
+)
+
+type GetEtcdBackupsOptions struct {
+	*GetOptions
+	EtcdClusters []string
+}
+
+// etcdBackupItem is the output of kops get etcd-backups for a single backup
+type etcdBackupItem struct {
+	EtcdCluster string    `json:"etcdCluster"`
+	Name        string    `json:"name"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+func NewCmdGetEtcdBackups(f *util.Factory, out io.Writer, getOptions *GetOptions) *cobra.Command {
+	options := GetEtcdBackupsOptions{
+		GetOptions: getOptions,
+	}
+	cmd := &cobra.Command{
+		Use:     "etcd-backups",
+		Aliases: []string{"etcd-backup"},
+		Short:   getEtcdBackupsShort,
+		Long:    getEtcdBackupsLong,
+		Example: getEtcdBackupsExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := RunGetEtcdBackups(&options, args, out)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&options.EtcdClusters, "etcd-cluster", options.EtcdClusters, "Names of the etcd clusters, e.g. main or events; defaults to all etcd clusters")
+	return cmd
+}
+
+func RunGetEtcdBackups(options *GetEtcdBackupsOptions, args []string, out io.Writer) error {
+	cluster, err := rootCommand.Cluster()
+	if err != nil {
+		return err
+	}
+
+	etcdClusters, err := selectEtcdClusters(cluster, options.EtcdClusters)
+	if err != nil {
+		return err
+	}
+
+	names := sets.NewString(args...)
+	items := []*etcdBackupItem{}
+	for _, etcdCluster := range etcdClusters {
+		store, err := etcdbackup.BackupStore(cluster, etcdCluster)
+		if err != nil {
+			return err
+		}
+
+		backups, err := etcdbackup.ListBackups(store)
+		if err != nil {
+			return err
+		}
+		for _, backup := range backups {
+			if len(args) != 0 && !names.Has(backup.Name) {
+				continue
+			}
+			items = append(items, &etcdBackupItem{
+				EtcdCluster: etcdCluster.Name,
+				Name:        backup.Name,
+				Timestamp:   backup.Timestamp,
+			})
+		}
+	}
+
+	switch options.output {
+	case OutputTable:
+		if len(items) == 0 {
+			return fmt.Errorf("No etcd backups found")
+		}
+		t := &tables.Table{}
+		t.AddColumn("ETCD CLUSTER", func(i *etcdBackupItem) string {
+			return i.EtcdCluster
+		})
+		t.AddColumn("NAME", func(i *etcdBackupItem) string {
+			return i.Name
+		})
+		t.AddColumn("TIMESTAMP", func(i *etcdBackupItem) string {
+			return i.Timestamp.Format(time.RFC3339)
+		})
+		return t.Render(items, out, "ETCD CLUSTER", "NAME", "TIMESTAMP")
+
+	case OutputYaml:
+		y, err := yaml.Marshal(items)
+		if err != nil {
+			return fmt.Errorf("unable to marshal YAML: %v", err)
+		}
+		if _, err := out.Write(y); err != nil {
+			return fmt.Errorf("error writing to output: %v", err)
+		}
+		return nil
+
+	case OutputJSON:
+		j, err := json.MarshalIndent(items, "", "  ")
+		if err != nil {
+			return fmt.Errorf("unable to marshal JSON: %v", err)
+		}
+		if _, err := fmt.Fprintf(out, "%s\n", j); err != nil {
+			return fmt.Errorf("error writing to output: %v", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("Unknown output format: %q", options.output)
+	}
+}
+
+// selectEtcdClusters returns the etcd clusters with the given names, or all etcd clusters if no names are given.
+// Only etcd clusters managed by etcd-manager have backups that kops can work with.
+func selectEtcdClusters(cluster *kops.Cluster, names []string) ([]*kops.EtcdClusterSpec, error) {
+	var selected []*kops.EtcdClusterSpec
+	if len(names) == 0 {
+		selected = cluster.Spec.EtcdClusters
+	} else {
+		for _, name := range names {
+			var found *kops.EtcdClusterSpec
+			for _, etcdCluster := range cluster.Spec.EtcdClusters {
+				if etcdCluster.Name == name {
+					found = etcdCluster
+				}
+			}
+			if found == nil {
+				var valid []string
+				for _, etcdCluster := range cluster.Spec.EtcdClusters {
+					valid = append(valid, etcdCluster.Name)
+				}
+				return nil, fmt.Errorf("etcd cluster %q not found; valid etcd clusters are: %s", name, strings.Join(valid, ", "))
+			}
+			selected = append(selected, found)
+		}
+	}
+
+	for _, etcdCluster := range selected {
+		if etcdCluster.Provider == kops.EtcdProviderTypeLegacy {
+			return nil, fmt.Errorf("etcd cluster %q is not managed by etcd-manager; backups are only supported with etcd-manager", etcdCluster.Name)
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,245 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kops/cmd/kops/util"
+	api "k8s.io/kops/pkg/apis/kops"
+	apimodel "k8s.io/kops/pkg/apis/kops/model"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/upup/pkg/fi/utils"
+	"k8s.io/kops/util/pkg/tables"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	getNodeConfigLong = templates.LongDesc(i18n.T(`
+	Display which in-place configuration is active on each node of a cluster.
+
+	When in-place updates are enabled, changes to the kubelet flags, file assets and hooks
+	are published by kops update cluster and applied by nodeup on the running nodes.
+	Each node reports the hash of the configuration it has applied, which is compared
+	with the hash of the configuration in the state store.`))
+
+	getNodeConfigExample = templates.Examples(i18n.T(`
+	# Get the convergence of the nodes to the in-place configuration
+	kops get node-config --name k8s-cluster.example.com
+
+	# Get the full configuration hashes as yaml
+	kops get node-config --name k8s-cluster.example.com -o yaml
+	`))
+
+	getNodeConfigShort = i18n.T(`Get the in-place configuration applied to each node.`)
+)
+
+const (
+	// NodeConfigUpToDate means the node has applied the configuration in the state store
+	NodeConfigUpToDate = "UpToDate"
+	// NodeConfigPending means the node has not yet applied the configuration in the state store
+	NodeConfigPending = "Pending"
+	// NodeConfigUnknown means the node has not reported a configuration, or is not in a known instance group
+	NodeConfigUnknown = "Unknown"
+)
+
+type GetNodeConfigOptions struct {
+	*GetOptions
+}
+
+// NodeConfigStatus is the in-place configuration status of a node
+type NodeConfigStatus struct {
+	Node          string `json:"node"`
+	InstanceGroup string `json:"instanceGroup,omitempty"`
+	Active        string `json:"active,omitempty"`
+	Expected      string `json:"expected,omitempty"`
+	Status        string `json:"status"`
+}
+
+func NewCmdGetNodeConfig(f *util.Factory, out io.Writer, getOptions *GetOptions) *cobra.Command {
+	options := GetNodeConfigOptions{
+		GetOptions: getOptions,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "node-config",
+		Aliases: []string{"nodeconfig"},
+		Short:   getNodeConfigShort,
+		Long:    getNodeConfigLong,
+		Example: getNodeConfigExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := RunGetNodeConfig(&options, out)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	return cmd
+}
+
+func RunGetNodeConfig(options *GetNodeConfigOptions, out io.Writer) error {
+	clusterName := rootCommand.ClusterName()
+	if clusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+
+	clientset, err := rootCommand.Clientset()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := clientset.GetCluster(clusterName)
+	if err != nil {
+		return fmt.Errorf("error fetching cluster %q: %v", clusterName, err)
+	}
+
+	if cluster == nil {
+		return fmt.Errorf("cluster %q was not found", clusterName)
+	}
+
+	configBase, err := clientset.ConfigBaseFor(cluster)
+	if err != nil {
+		return fmt.Errorf("error building ConfigBase for cluster: %v", err)
+	}
+
+	// The hashes are computed from the configuration read by nodeup, so that they match the hashes reported by the nodes
+	fullCluster := &api.Cluster{}
+	if err := registry.ReadConfigDeprecated(configBase.Join(registry.PathClusterCompleted), fullCluster); err != nil {
+		return fmt.Errorf("error reading full cluster spec for %q: %v", clusterName, err)
+	}
+
+	if !apimodel.InPlaceUpdates(fullCluster) {
+		return fmt.Errorf("in-place updates are not enabled for cluster %q; set spec.nodeReconciliation.inPlaceUpdates and run kops update cluster", clusterName)
+	}
+
+	list, err := clientset.InstanceGroupsFor(cluster).List(metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	expected := make(map[string]string)
+	for i := range list.Items {
+		name := list.Items[i].ObjectMeta.Name
+		ig := &api.InstanceGroup{}
+		err := registry.ReadConfigDeprecated(registry.InstanceGroupCompletedPath(configBase, name), ig)
+		if err != nil && os.IsNotExist(err) {
+			// Like nodeup, fall back to the instance group as edited if it has not been published by kops update cluster
+			err = registry.ReadConfigDeprecated(configBase.Join("instancegroup", name), ig)
+		}
+		if err != nil {
+			return fmt.Errorf("error reading instance group %q: %v", name, err)
+		}
+		hash, err := apimodel.InPlaceConfigHash(fullCluster, ig)
+		if err != nil {
+			return err
+		}
+		expected[name] = hash
+	}
+
+	k8sClient, err := buildKubernetesClient(cluster)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := k8sClient.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("error listing nodes: %v", err)
+	}
+
+	var statuses []*NodeConfigStatus
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		status := &NodeConfigStatus{
+			Node:          node.Name,
+			InstanceGroup: node.Labels[api.NodeLabelInstanceGroup],
+			Active:        node.Annotations[apimodel.NodeConfigHashAnnotation],
+			Expected:      expected[node.Labels[api.NodeLabelInstanceGroup]],
+		}
+		switch {
+		case status.Active == "" || status.Expected == "":
+			status.Status = NodeConfigUnknown
+		case status.Active == status.Expected:
+			status.Status = NodeConfigUpToDate
+		default:
+			status.Status = NodeConfigPending
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Node < statuses[j].Node
+	})
+
+	switch options.output {
+	case OutputTable:
+		return nodeConfigOutputTable(statuses, out)
+	case OutputYaml:
+		b, err := utils.YamlMarshal(statuses)
+		if err != nil {
+			return fmt.Errorf("error marshaling yaml: %v", err)
+		}
+		_, err = out.Write(b)
+		return err
+	case OutputJSON:
+		b, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshaling json: %v", err)
+		}
+		_, err = fmt.Fprintf(out, "%s\n", b)
+		return err
+	default:
+		return fmt.Errorf("Unknown output format: %q", options.output)
+	}
+}
+
+func nodeConfigOutputTable(statuses []*NodeConfigStatus, out io.Writer) error {
+	t := &tables.Table{}
+	t.AddColumn("NODE", func(s *NodeConfigStatus) string {
+		return s.Node
+	})
+	t.AddColumn("INSTANCEGROUP", func(s *NodeConfigStatus) string {
+		return s.InstanceGroup
+	})
+	t.AddColumn("ACTIVE", func(s *NodeConfigStatus) string {
+		return shortConfigHash(s.Active)
+	})
+	t.AddColumn("EXPECTED", func(s *NodeConfigStatus) string {
+		return shortConfigHash(s.Expected)
+	})
+	t.AddColumn("STATUS", func(s *NodeConfigStatus) string {
+		return s.Status
+	})
+	return t.Render(statuses, out, "NODE", "INSTANCEGROUP", "ACTIVE", "EXPECTED", "STATUS")
+}
+
+// shortConfigHash abbreviates a configuration hash for display
+func shortConfigHash(hash string) string {
+	if hash == "" {
+		return "-"
+	}
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
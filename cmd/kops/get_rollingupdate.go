@@ -0,0 +1,161 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/instancegroups"
+	"k8s.io/kops/upup/pkg/fi/utils"
+	"k8s.io/kops/util/pkg/tables"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	getRollingUpdateLong = templates.LongDesc(i18n.T(`
+	Display the progress of the most recent rolling update of a cluster.`))
+
+	getRollingUpdateExample = templates.Examples(i18n.T(`
+	# Get the progress of the most recent rolling update
+	kops get rolling-update --name k8s-cluster.example.com
+
+	# Get the full rolling update status, including the instances replaced
+	kops get rolling-update --name k8s-cluster.example.com -o yaml
+	`))
+
+	getRollingUpdateShort = i18n.T(`Get the progress of a rolling update.`)
+)
+
+type GetRollingUpdateOptions struct {
+	*GetOptions
+}
+
+func NewCmdGetRollingUpdate(f *util.Factory, out io.Writer, getOptions *GetOptions) *cobra.Command {
+	options := GetRollingUpdateOptions{
+		GetOptions: getOptions,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "rolling-update",
+		Aliases: []string{"rollingupdate"},
+		Short:   getRollingUpdateShort,
+		Long:    getRollingUpdateLong,
+		Example: getRollingUpdateExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := RunGetRollingUpdate(&options, out)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	return cmd
+}
+
+func RunGetRollingUpdate(options *GetRollingUpdateOptions, out io.Writer) error {
+	clusterName := rootCommand.ClusterName()
+	if clusterName == "" {
+		return fmt.Errorf("--name is required")
+	}
+
+	clientset, err := rootCommand.Clientset()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := clientset.GetCluster(clusterName)
+	if err != nil {
+		return fmt.Errorf("error fetching cluster %q: %v", clusterName, err)
+	}
+
+	if cluster == nil {
+		return fmt.Errorf("cluster %q was not found", clusterName)
+	}
+
+	configBase, err := clientset.ConfigBaseFor(cluster)
+	if err != nil {
+		return fmt.Errorf("error building ConfigBase for cluster: %v", err)
+	}
+
+	status, err := instancegroups.ReadRollingUpdateStatus(configBase)
+	if err != nil {
+		return err
+	}
+
+	if status == nil {
+		return fmt.Errorf("no rolling update has been recorded for cluster %q", clusterName)
+	}
+
+	switch options.output {
+	case OutputTable:
+		return rollingUpdateOutputTable(status, out)
+	case OutputYaml:
+		b, err := utils.YamlMarshal(status)
+		if err != nil {
+			return fmt.Errorf("error marshaling yaml: %v", err)
+		}
+		_, err = out.Write(b)
+		return err
+	case OutputJSON:
+		b, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshaling json: %v", err)
+		}
+		_, err = fmt.Fprintf(out, "%s\n", b)
+		return err
+	default:
+		return fmt.Errorf("Unknown output format: %q", options.output)
+	}
+}
+
+func rollingUpdateOutputTable(status *instancegroups.RollingUpdateStatus, out io.Writer) error {
+	fmt.Fprintf(out, "Rolling update of %q started %s: %s\n", status.ClusterName, status.StartTime.Local().Format(time.RFC3339), status.Phase)
+	if status.Message != "" {
+		fmt.Fprintf(out, "%s\n", status.Message)
+	}
+	fmt.Fprintf(out, "\n")
+
+	t := &tables.Table{}
+	t.AddColumn("NAME", func(g *instancegroups.RollingUpdateGroupStatus) string {
+		return g.Name
+	})
+	t.AddColumn("ROLE", func(g *instancegroups.RollingUpdateGroupStatus) string {
+		return string(g.Role)
+	})
+	t.AddColumn("STATUS", func(g *instancegroups.RollingUpdateGroupStatus) string {
+		return string(g.Phase)
+	})
+	t.AddColumn("REPLACED", func(g *instancegroups.RollingUpdateGroupStatus) string {
+		return fmt.Sprintf("%d/%d", len(g.InstancesDone), len(g.Instances))
+	})
+	t.AddColumn("VALIDATED", func(g *instancegroups.RollingUpdateGroupStatus) string {
+		if g.LastValidation == nil {
+			return "-"
+		}
+		if g.LastValidation.Succeeded {
+			return "Yes (" + g.LastValidation.Time.Local().Format(time.RFC3339) + ")"
+		}
+		return "No (" + g.LastValidation.Time.Local().Format(time.RFC3339) + ")"
+	})
+	return t.Render(status.Groups, out, "NAME", "ROLE", "STATUS", "REPLACED", "VALIDATED")
+}
@@ -21,12 +21,14 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"k8s.io/klog"
 	"k8s.io/kops/cmd/kops/util"
 	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/sshcredentials"
+	"k8s.io/kops/pkg/statehistory"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/util/pkg/tables"
 	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
@@ -46,14 +48,18 @@ var (
 	kops get secrets kube -oplaintext
 
 	# Get the admin password for a cluster
-	kops get secrets admin -oplaintext`))
+	kops get secrets admin -oplaintext
+
+	# List the prior versions of a keyset, which can be restored with kops rollback secret
+	kops get secrets ca --type keypair --history`))
 
 	getSecretShort = i18n.T(`Get one or many secrets.`)
 )
 
 type GetSecretsOptions struct {
 	*GetOptions
-	Type string
+	Type    string
+	History bool
 }
 
 func NewCmdGetSecrets(f *util.Factory, out io.Writer, getOptions *GetOptions) *cobra.Command {
@@ -75,9 +81,66 @@ func NewCmdGetSecrets(f *util.Factory, out io.Writer, getOptions *GetOptions) *c
 	}
 
 	cmd.Flags().StringVarP(&options.Type, "type", "", "", "Filter by secret type")
+	cmd.Flags().BoolVar(&options.History, "history", false, "List the prior versions of the secret")
 	return cmd
 }
 
+// secretVersion is a prior version of a keyset or secret
+type secretVersion struct {
+	Type    kops.KeysetType
+	Name    string
+	Version *statehistory.Version
+
+	store fi.HasHistory
+}
+
+// listSecretHistory returns the prior versions of the named keyset and / or secret, newest first
+func listSecretHistory(keyStore fi.CAStore, secretStore fi.SecretStore, secretType string, name string) ([]*secretVersion, error) {
+	stores := make(map[kops.KeysetType]interface{})
+	switch strings.ToLower(secretType) {
+	case "":
+		stores[kops.SecretTypeKeypair] = keyStore
+		stores[kops.SecretTypeSecret] = secretStore
+	case "keypair":
+		stores[kops.SecretTypeKeypair] = keyStore
+	case "secret":
+		stores[kops.SecretTypeSecret] = secretStore
+	case "sshpublickey":
+		return nil, fmt.Errorf("prior versions of SSH public keys are not kept")
+	default:
+		return nil, fmt.Errorf("unknown secret type %q", secretType)
+	}
+
+	var versions []*secretVersion
+	for _, t := range []kops.KeysetType{kops.SecretTypeKeypair, kops.SecretTypeSecret} {
+		store, found := stores[t]
+		if !found {
+			continue
+		}
+		h, ok := store.(fi.HasHistory)
+		if !ok {
+			if secretType != "" {
+				return nil, fmt.Errorf("the %s store does not keep prior versions", strings.ToLower(string(t)))
+			}
+			continue
+		}
+
+		l, err := h.History(name)
+		if err != nil {
+			return nil, fmt.Errorf("error listing prior versions of %q: %v", name, err)
+		}
+		for _, v := range l {
+			versions = append(versions, &secretVersion{
+				Type:    t,
+				Name:    name,
+				Version: v,
+				store:   h,
+			})
+		}
+	}
+	return versions, nil
+}
+
 func listSecrets(keyStore fi.CAStore, secretStore fi.SecretStore, sshCredentialStore fi.SSHCredentialStore, secretType string, names []string) ([]*fi.KeystoreItem, error) {
 	var items []*fi.KeystoreItem
 
@@ -202,6 +265,13 @@ func RunGetSecrets(options *GetSecretsOptions, args []string) error {
 		return err
 	}
 
+	if options.History {
+		if len(args) != 1 {
+			return fmt.Errorf("specify the name of a single secret with --history")
+		}
+		return getSecretHistory(options, keyStore, secretStore, args[0])
+	}
+
 	sshCredentialStore, err := clientset.SSHCredentialStore(cluster)
 	if err != nil {
 		return err
@@ -264,3 +334,37 @@ func RunGetSecrets(options *GetSecretsOptions, args []string) error {
 		return fmt.Errorf("Unknown output format: %q", options.output)
 	}
 }
+
+func getSecretHistory(options *GetSecretsOptions, keyStore fi.CAStore, secretStore fi.SecretStore, name string) error {
+	versions, err := listSecretHistory(keyStore, secretStore, options.Type, name)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		return fmt.Errorf("No prior versions found for %q", name)
+	}
+
+	switch options.output {
+	case OutputTable:
+		t := &tables.Table{}
+		t.AddColumn("NAME", func(v *secretVersion) string {
+			return v.Name
+		})
+		t.AddColumn("TYPE", func(v *secretVersion) string {
+			return string(v.Type)
+		})
+		t.AddColumn("VERSION", func(v *secretVersion) string {
+			return v.Version.ID
+		})
+		t.AddColumn("TIMESTAMP", func(v *secretVersion) string {
+			return v.Version.Timestamp.Format(time.RFC3339)
+		})
+		t.AddColumn("AUTHOR", func(v *secretVersion) string {
+			return v.Version.Author
+		})
+		return t.Render(versions, os.Stdout, "TYPE", "NAME", "VERSION", "TIMESTAMP", "AUTHOR")
+
+	default:
+		return fmt.Errorf("output format %q is not (currently) supported with --history", options.output)
+	}
+}
@@ -143,11 +143,28 @@ func runLifecycleTest(h *testutils.IntegrationTestHarness, o *LifecycleTestOptio
 		}
 	}
 
+	planFile := path.Join(h.TempDir, "plan.json")
+	{
+		options := &UpdateClusterOptions{}
+		options.InitDefaults()
+		options.RunTasksOptions.MaxTaskDuration = 10 * time.Second
+		options.OutPlan = planFile
+
+		// We don't test it here, and it adds a dependency on kubectl
+		options.CreateKubecfg = false
+
+		_, err := RunUpdateCluster(factory, o.ClusterName, &stdout, options)
+		if err != nil {
+			t.Fatalf("error running update cluster %q: %v", o.ClusterName, err)
+		}
+	}
+
 	{
 		options := &UpdateClusterOptions{}
 		options.InitDefaults()
 		options.RunTasksOptions.MaxTaskDuration = 10 * time.Second
 		options.Yes = true
+		options.Plan = planFile
 
 		// We don't test it here, and it adds a dependency on kubectl
 		options.CreateKubecfg = false
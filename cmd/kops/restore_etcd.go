@@ -0,0 +1,223 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/etcdbackup"
+	"k8s.io/kops/util/pkg/vfs"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+// restoreLatestBackup is the name that selects the most recent backup of each etcd cluster
+const restoreLatestBackup = "latest"
+
+var (
+	restoreEtcdLong = templates.LongDesc(i18n.T(`
+	Restore the etcd clusters from a backup.
+
+	A restore command is written to the control directory of the backup store of each etcd cluster,
+	where etcd-manager picks it up once etcd is restarted on the masters. A new etcd cluster is then
+	created and the backup restored onto it. The command waits until etcd-manager has taken on the restore.
+
+	The Kubernetes API is unavailable during the restore, and resources created after the backup are lost.
+	Backups of the main and events etcd clusters have different names; use latest to restore the most
+	recent backup of each.`))
+
+	restoreEtcdExample = templates.Examples(i18n.T(`
+	# Show what would be restored from the most recent backups
+	kops restore etcd --name k8s-cluster.example.com --backup latest
+
+	# Restore the main and events etcd clusters from their most recent backups
+	kops restore etcd --name k8s-cluster.example.com --backup latest --yes
+
+	# Restore the main etcd cluster from a particular backup
+	kops restore etcd --name k8s-cluster.example.com --etcd-cluster main \
+	  --backup 2019-06-05T12:00:00Z-000001 --yes`))
+
+	restoreEtcdShort = i18n.T(`Restore the etcd clusters from a backup.`)
+)
+
+type RestoreEtcdOptions struct {
+	Backup       string
+	EtcdClusters []string
+	Wait         time.Duration
+	Yes          bool
+}
+
+func NewCmdRestoreEtcd(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &RestoreEtcdOptions{
+		Wait: 30 * time.Minute,
+	}
+
+	cmd := &cobra.Command{
+		Use:     "etcd",
+		Short:   restoreEtcdShort,
+		Long:    restoreEtcdLong,
+		Example: restoreEtcdExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := rootCommand.ProcessArgs(args)
+			if err != nil {
+				exitWithError(err)
+			}
+
+			err = RunRestoreEtcd(f, os.Stdout, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Backup, "backup", options.Backup, "Name of the backup to restore, or latest for the most recent backup of each etcd cluster")
+	cmd.Flags().StringSliceVar(&options.EtcdClusters, "etcd-cluster", options.EtcdClusters, "Names of the etcd clusters to restore, e.g. main or events; defaults to the etcd clusters that have the backup")
+	cmd.Flags().DurationVar(&options.Wait, "wait", options.Wait, "How long to wait for etcd-manager to take on the restore; set to 0 to return once the restore has been requested")
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Restore the backup; without --yes only the backups that would be restored are shown")
+
+	return cmd
+}
+
+func RunRestoreEtcd(f *util.Factory, out io.Writer, options *RestoreEtcdOptions) error {
+	if options.Backup == "" {
+		return fmt.Errorf("--backup is required; use kops get etcd-backups to list the backups")
+	}
+
+	cluster, err := rootCommand.Cluster()
+	if err != nil {
+		return err
+	}
+
+	etcdClusters, err := selectEtcdClusters(cluster, options.EtcdClusters)
+	if err != nil {
+		return err
+	}
+
+	type restore struct {
+		etcdCluster *kops.EtcdClusterSpec
+		store       vfs.Path
+		backup      *etcdbackup.Backup
+		command     vfs.Path
+	}
+
+	var restores []*restore
+	for _, etcdCluster := range etcdClusters {
+		store, err := etcdbackup.BackupStore(cluster, etcdCluster)
+		if err != nil {
+			return err
+		}
+		backups, err := etcdbackup.ListBackups(store)
+		if err != nil {
+			return err
+		}
+
+		var backup *etcdbackup.Backup
+		if options.Backup == restoreLatestBackup {
+			if len(backups) != 0 {
+				backup = backups[len(backups)-1]
+			}
+		} else {
+			for _, b := range backups {
+				if b.Name == options.Backup {
+					backup = b
+				}
+			}
+		}
+
+		if backup == nil {
+			if len(options.EtcdClusters) == 0 && options.Backup != restoreLatestBackup {
+				// The backup is expected to be in only one of the backup stores
+				continue
+			}
+			return fmt.Errorf("backup %q not found for etcd cluster %q in %s", options.Backup, etcdCluster.Name, store)
+		}
+
+		restores = append(restores, &restore{etcdCluster: etcdCluster, store: store, backup: backup})
+	}
+
+	if len(restores) == 0 {
+		return fmt.Errorf("backup %q not found for any etcd cluster; use kops get etcd-backups to list the backups", options.Backup)
+	}
+
+	for _, r := range restores {
+		fmt.Fprintf(out, "Will restore etcd cluster %q from backup %q, taken at %s\n", r.etcdCluster.Name, r.backup.Name, r.backup.Timestamp.Format(time.RFC3339))
+	}
+
+	if !options.Yes {
+		fmt.Fprintf(out, "\nMust specify --yes to restore\n")
+		return nil
+	}
+
+	for _, r := range restores {
+		spec, err := etcdbackup.ReadClusterSpec(r.store)
+		if err != nil {
+			return err
+		}
+		if spec == nil {
+			spec = &etcdbackup.ClusterSpec{
+				MemberCount: int32(len(r.etcdCluster.Members)),
+				EtcdVersion: r.etcdCluster.Version,
+			}
+		}
+
+		p, err := etcdbackup.AddCommand(r.store, &etcdbackup.Command{
+			RestoreBackup: &etcdbackup.RestoreBackupCommand{
+				ClusterSpec: spec,
+				Backup:      r.backup.Name,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		r.command = p
+		fmt.Fprintf(out, "Requested restore of etcd cluster %q from backup %q\n", r.etcdCluster.Name, r.backup.Name)
+	}
+
+	fmt.Fprintf(out, "\netcd-manager picks up the restore once etcd is restarted on the masters; restart etcd or roll the masters now.\n")
+
+	if options.Wait == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(options.Wait)
+	for _, r := range restores {
+		fmt.Fprintf(out, "Waiting for etcd-manager to restore etcd cluster %q\n", r.etcdCluster.Name)
+
+		done, err := etcdbackup.WaitFor(time.Until(deadline), func() (bool, error) {
+			pending, err := etcdbackup.IsCommandPending(r.command)
+			return !pending, err
+		})
+		if err != nil {
+			return err
+		}
+		if !done {
+			return fmt.Errorf("timed out waiting for etcd-manager to restore etcd cluster %q; the restore command remains in %s", r.etcdCluster.Name, r.command)
+		}
+
+		fmt.Fprintf(out, "etcd-manager has restored etcd cluster %q from backup %q\n", r.etcdCluster.Name, r.backup.Name)
+	}
+
+	fmt.Fprintf(out, "\nThe restore is complete; the API server may be busy for a while as the cluster returns to the state of the backup.\n")
+
+	return nil
+}
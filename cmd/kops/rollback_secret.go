@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	rollbackSecretLong = templates.LongDesc(i18n.T(`
+	Restore a secret or keyset to a prior version.
+
+	The state store keeps the prior versions of each secret and keyset, up to the secretHistoryDepth of the
+	cluster; they are listed with kops get secrets NAME --history. The value replaced by the rollback is itself
+	kept as a new version, so a rollback can be undone.
+
+	Instances read keysets when they start, so after rolling back a keyset run kops update cluster and
+	kops rolling-update cluster for the cluster to use it.`))
+
+	rollbackSecretExample = templates.Examples(i18n.T(`
+	# List the prior versions of the admin secret
+	kops get secrets admin --type secret --history
+
+	# Restore the admin secret to version 3
+	kops rollback secret admin --type secret --to 3`))
+
+	rollbackSecretShort = i18n.T(`Restore a secret or keyset to a prior version.`)
+)
+
+type RollbackSecretOptions struct {
+	ClusterName string
+	Name        string
+	Type        string
+	To          string
+}
+
+func NewCmdRollbackSecret(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &RollbackSecretOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "secret NAME",
+		Aliases: []string{"secrets"},
+		Short:   rollbackSecretShort,
+		Long:    rollbackSecretLong,
+		Example: rollbackSecretExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) != 1 {
+				exitWithError(fmt.Errorf("syntax: NAME --to VERSION"))
+			}
+			options.Name = args[0]
+
+			if err := rootCommand.ProcessArgs(args[1:]); err != nil {
+				exitWithError(err)
+			}
+
+			options.ClusterName = rootCommand.ClusterName()
+
+			err := RunRollbackSecret(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&options.To, "to", options.To, "The version to restore, as listed by kops get secrets NAME --history")
+	cmd.Flags().StringVar(&options.Type, "type", options.Type, "The type of the secret (keypair or secret), when a keyset and a secret have the same name")
+
+	return cmd
+}
+
+func RunRollbackSecret(f *util.Factory, out io.Writer, options *RollbackSecretOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("ClusterName is required")
+	}
+	if options.To == "" {
+		return fmt.Errorf("--to is required")
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := clientset.GetCluster(options.ClusterName)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		return fmt.Errorf("cluster %q not found", options.ClusterName)
+	}
+
+	keyStore, err := clientset.KeyStore(cluster)
+	if err != nil {
+		return err
+	}
+
+	secretStore, err := clientset.SecretStore(cluster)
+	if err != nil {
+		return err
+	}
+
+	versions, err := listSecretHistory(keyStore, secretStore, options.Type, options.Name)
+	if err != nil {
+		return err
+	}
+
+	var matches []*secretVersion
+	for _, v := range versions {
+		if v.Version.ID == options.To {
+			matches = append(matches, v)
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("version %s of %q not found", options.To, options.Name)
+	}
+	if len(matches) > 1 {
+		return fmt.Errorf("found version %s of both a keyset and a secret named %q; specify --type", options.To, options.Name)
+	}
+
+	match := matches[0]
+	if err := match.store.Rollback(match.Name, match.Version.ID); err != nil {
+		return fmt.Errorf("error rolling back %q: %v", match.Name, err)
+	}
+
+	fmt.Fprintf(out, "Restored %s %q to version %s from %s\n", match.Type, match.Name, match.Version.ID, match.Version.Timestamp.Format("2006-01-02 15:04:05"))
+	if match.Type == kops.SecretTypeKeypair {
+		fmt.Fprintf(out, "Run kops update cluster and kops rolling-update cluster for the cluster to use the restored keyset\n")
+	}
+	return nil
+}
@@ -27,6 +27,8 @@ import (
 	"github.com/spf13/cobra"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
@@ -94,6 +96,30 @@ var (
 		  --fail-on-validate-error="false" \
 		  --node-interval 8m \
 		  --instance-group nodes
+
+		# Roll the k8s-cluster.example.com kops cluster,
+		# launching a replacement for each node before draining it.
+		kops rolling-update cluster k8s-cluster.example.com --yes \
+		  --max-surge 1 \
+		  --max-unavailable 0
+
+		# Roll the k8s-cluster.example.com kops cluster,
+		# leaving pods in the monitoring namespace running and force-deleting
+		# pods labelled app=batch, allowing each pod 10m to be evicted.
+		kops rolling-update cluster k8s-cluster.example.com --yes \
+		  --drain-skip-namespace monitoring \
+		  --drain-force-delete-selector app=batch \
+		  --pod-eviction-timeout 10m
+
+		# Continue an interrupted rolling update of the k8s-cluster.example.com kops cluster.
+		kops rolling-update cluster k8s-cluster.example.com --yes --resume
+
+		# Roll the k8s-cluster.example.com kops cluster,
+		# deregistering each instance from a load balancer before it is terminated
+		# and running a smoke test once the cluster has validated.
+		kops rolling-update cluster k8s-cluster.example.com --yes \
+		  --pre-terminate-hook 'lb-deregister "$KOPS_INSTANCE_ID"' \
+		  --post-validate-hook https://smoke.example.com/check
 		`))
 
 	rollingupdateShort = i18n.T(`Rolling update a cluster.`)
@@ -118,6 +144,21 @@ type RollingUpdateOptions struct {
 	// PostDrainDelay is the duration of a pause after a drain operation
 	PostDrainDelay time.Duration
 
+	// PodEvictionTimeout is the maximum time to spend evicting a single pod when draining a node
+	PodEvictionTimeout time.Duration
+
+	// DrainSkipNamespaces are the namespaces whose pods are left running when draining a node
+	DrainSkipNamespaces []string
+
+	// DrainSkipSelectors are label selectors for pods that are left running when draining a node
+	DrainSkipSelectors []string
+
+	// DrainForceDeleteNamespaces are the namespaces whose pods are deleted, ignoring PodDisruptionBudgets, when draining a node
+	DrainForceDeleteNamespaces []string
+
+	// DrainForceDeleteSelectors are label selectors for pods that are deleted, ignoring PodDisruptionBudgets, when draining a node
+	DrainForceDeleteSelectors []string
+
 	// ValidationTimeout is the timeout for validation to succeed after the drain and pause
 	ValidationTimeout time.Duration
 
@@ -142,6 +183,25 @@ type RollingUpdateOptions struct {
 	// InstanceGroupRoles is the list of roles we should rolling-update
 	// if not specified, all instance groups will be updated
 	InstanceGroupRoles []string
+
+	// MaxSurge is the number or percentage of extra instances to launch in each instance group
+	// during the rolling update; if not specified, the cluster and instance group settings are used
+	MaxSurge string
+
+	// MaxUnavailable is the number or percentage of instances in each instance group that can be
+	// unavailable during the rolling update; if not specified, the cluster and instance group settings are used
+	MaxUnavailable string
+
+	// Resume continues the rolling update recorded in the state store, rather than planning a new one
+	Resume bool
+
+	// PreDrainHooks, PostDrainHooks, PreTerminateHooks and PostValidateHooks are run for each instance replaced,
+	// in addition to the hooks configured in the cluster or instance group spec. Each is either an http(s)
+	// webhook URL or a shell command.
+	PreDrainHooks     []string
+	PostDrainHooks    []string
+	PreTerminateHooks []string
+	PostValidateHooks []string
 }
 
 func (o *RollingUpdateOptions) InitDefaults() {
@@ -157,6 +217,7 @@ func (o *RollingUpdateOptions) InitDefaults() {
 	o.Interactive = false
 
 	o.PostDrainDelay = 5 * time.Second
+	o.PodEvictionTimeout = 5 * time.Minute
 	o.ValidationTimeout = 15 * time.Minute
 }
 
@@ -181,9 +242,21 @@ func NewCmdRollingUpdateCluster(f *util.Factory, out io.Writer) *cobra.Command {
 	cmd.Flags().DurationVar(&options.NodeInterval, "node-interval", options.NodeInterval, "Time to wait between restarting nodes")
 	cmd.Flags().DurationVar(&options.BastionInterval, "bastion-interval", options.BastionInterval, "Time to wait between restarting bastions")
 	cmd.Flags().DurationVar(&options.PostDrainDelay, "post-drain-delay", options.PostDrainDelay, "Time to wait after draining each node")
+	cmd.Flags().DurationVar(&options.PodEvictionTimeout, "pod-eviction-timeout", options.PodEvictionTimeout, "Maximum time to spend evicting each pod when draining a node, including retries while a PodDisruptionBudget blocks its eviction")
+	cmd.Flags().StringSliceVar(&options.DrainSkipNamespaces, "drain-skip-namespace", options.DrainSkipNamespaces, "Namespaces whose pods are left running when draining a node")
+	cmd.Flags().StringSliceVar(&options.DrainSkipSelectors, "drain-skip-selector", options.DrainSkipSelectors, "Label selectors for pods that are left running when draining a node")
+	cmd.Flags().StringSliceVar(&options.DrainForceDeleteNamespaces, "drain-force-delete-namespace", options.DrainForceDeleteNamespaces, "Namespaces whose pods are deleted, ignoring PodDisruptionBudgets, when draining a node")
+	cmd.Flags().StringSliceVar(&options.DrainForceDeleteSelectors, "drain-force-delete-selector", options.DrainForceDeleteSelectors, "Label selectors for pods that are deleted, ignoring PodDisruptionBudgets, when draining a node")
 	cmd.Flags().BoolVarP(&options.Interactive, "interactive", "i", options.Interactive, "Prompt to continue after each instance is updated")
 	cmd.Flags().StringSliceVar(&options.InstanceGroups, "instance-group", options.InstanceGroups, "List of instance groups to update (defaults to all if not specified)")
 	cmd.Flags().StringSliceVar(&options.InstanceGroupRoles, "instance-group-roles", options.InstanceGroupRoles, "If specified, only instance groups of the specified role will be updated (e.g. Master,Node,Bastion)")
+	cmd.Flags().StringVar(&options.MaxSurge, "max-surge", options.MaxSurge, "Number or percentage of extra instances to launch in each instance group before draining old ones (overrides the cluster and instance group settings, but at most one master at a time)")
+	cmd.Flags().BoolVar(&options.Resume, "resume", options.Resume, "Continue an interrupted rolling update, replacing only the remaining instances it planned to replace")
+	cmd.Flags().StringVar(&options.MaxUnavailable, "max-unavailable", options.MaxUnavailable, "Number or percentage of instances in each instance group that can be unavailable at once (overrides the cluster and instance group settings)")
+	cmd.Flags().StringArrayVar(&options.PreDrainHooks, "pre-drain-hook", options.PreDrainHooks, "Shell command or http(s) webhook URL to run for each instance before its node is drained")
+	cmd.Flags().StringArrayVar(&options.PostDrainHooks, "post-drain-hook", options.PostDrainHooks, "Shell command or http(s) webhook URL to run for each instance after its node is drained")
+	cmd.Flags().StringArrayVar(&options.PreTerminateHooks, "pre-terminate-hook", options.PreTerminateHooks, "Shell command or http(s) webhook URL to run for each instance before it is terminated")
+	cmd.Flags().StringArrayVar(&options.PostValidateHooks, "post-validate-hook", options.PostValidateHooks, "Shell command or http(s) webhook URL to run for each instance replaced, once the cluster has validated")
 
 	if featureflag.DrainAndValidateRollingUpdate.Enabled() {
 		cmd.Flags().BoolVar(&options.FailOnDrainError, "fail-on-drain-error", true, "The rolling-update will fail if draining a node fails.")
@@ -218,6 +291,44 @@ func NewCmdRollingUpdateCluster(f *util.Factory, out io.Writer) *cobra.Command {
 
 func RunRollingUpdateCluster(f *util.Factory, out io.Writer, options *RollingUpdateOptions) error {
 
+	maxSurge, err := parseIntOrPercentFlag("max-surge", options.MaxSurge)
+	if err != nil {
+		return err
+	}
+
+	maxUnavailable, err := parseIntOrPercentFlag("max-unavailable", options.MaxUnavailable)
+	if err != nil {
+		return err
+	}
+
+	drainSkipSelectors, err := parseSelectorFlags("drain-skip-selector", options.DrainSkipSelectors)
+	if err != nil {
+		return err
+	}
+
+	drainForceDeleteSelectors, err := parseSelectorFlags("drain-force-delete-selector", options.DrainForceDeleteSelectors)
+	if err != nil {
+		return err
+	}
+
+	var hooks []instancegroups.InstanceHook
+	for _, h := range []struct {
+		flag   string
+		event  api.RollingUpdateHookEvent
+		values []string
+	}{
+		{"pre-drain-hook", api.RollingUpdateHookPreDrain, options.PreDrainHooks},
+		{"post-drain-hook", api.RollingUpdateHookPostDrain, options.PostDrainHooks},
+		{"pre-terminate-hook", api.RollingUpdateHookPreTerminate, options.PreTerminateHooks},
+		{"post-validate-hook", api.RollingUpdateHookPostValidate, options.PostValidateHooks},
+	} {
+		parsed, err := parseHookFlags(h.flag, h.event, h.values)
+		if err != nil {
+			return err
+		}
+		hooks = append(hooks, parsed...)
+	}
+
 	clientset, err := f.Clientset()
 	if err != nil {
 		return err
@@ -228,6 +339,35 @@ func RunRollingUpdateCluster(f *util.Factory, out io.Writer, options *RollingUpd
 		return err
 	}
 
+	configBase, err := clientset.ConfigBaseFor(cluster)
+	if err != nil {
+		return fmt.Errorf("error building ConfigBase for cluster: %v", err)
+	}
+
+	var resumeStatus *instancegroups.RollingUpdateStatus
+	if options.Resume {
+		if len(options.InstanceGroups) != 0 || len(options.InstanceGroupRoles) != 0 {
+			return fmt.Errorf("--instance-group and --instance-group-roles cannot be used with --resume, as the instance groups are taken from the rolling update being resumed")
+		}
+
+		resumeStatus, err = instancegroups.ReadRollingUpdateStatus(configBase)
+		if err != nil {
+			return err
+		}
+		if resumeStatus == nil {
+			return fmt.Errorf("no rolling update has been recorded for cluster %q", cluster.ObjectMeta.Name)
+		}
+		if resumeStatus.Phase == instancegroups.RollingUpdatePhaseCompleted {
+			return fmt.Errorf("the rolling update of cluster %q started at %s has already completed", cluster.ObjectMeta.Name, resumeStatus.StartTime.Format(time.RFC3339))
+		}
+
+		for _, g := range resumeStatus.Groups {
+			if g.Phase != instancegroups.RollingUpdatePhaseCompleted {
+				options.InstanceGroups = append(options.InstanceGroups, g.Name)
+			}
+		}
+	}
+
 	contextName := cluster.ObjectMeta.Name
 	clientGetter := genericclioptions.NewConfigFlags()
 	clientGetter.Context = &contextName
@@ -377,6 +517,11 @@ func RunRollingUpdateCluster(f *util.Factory, out io.Writer, options *RollingUpd
 		}
 	}
 
+	if resumeStatus != nil {
+		// The instances remaining in the plan may already be up to date if the rolling update was forced
+		needUpdate = true
+	}
+
 	if !needUpdate && !options.Force {
 		fmt.Printf("\nNo rolling-update required.\n")
 		return nil
@@ -390,6 +535,20 @@ func RunRollingUpdateCluster(f *util.Factory, out io.Writer, options *RollingUpd
 	if featureflag.DrainAndValidateRollingUpdate.Enabled() {
 		klog.V(2).Infof("Rolling update with drain and validate enabled.")
 	}
+
+	status := resumeStatus
+	if status == nil {
+		status = instancegroups.NewRollingUpdateStatus(options.ClusterName, groups, options.Force)
+	} else {
+		klog.Infof("Resuming the rolling update started at %s", status.StartTime.Format(time.RFC3339))
+		status.Phase = instancegroups.RollingUpdatePhaseInProgress
+		status.Message = ""
+	}
+	progress := instancegroups.NewProgress(cluster, configBase, status)
+	if err := progress.Save(); err != nil {
+		return err
+	}
+
 	d := &instancegroups.RollingUpdateCluster{
 		MasterInterval:    options.MasterInterval,
 		NodeInterval:      options.NodeInterval,
@@ -398,13 +557,78 @@ func RunRollingUpdateCluster(f *util.Factory, out io.Writer, options *RollingUpd
 		Force:             options.Force,
 		Cloud:             cloud,
 		K8sClient:         k8sClient,
-		ClientGetter:      clientGetter,
 		FailOnDrainError:  options.FailOnDrainError,
 		FailOnValidate:    options.FailOnValidate,
 		CloudOnly:         options.CloudOnly,
 		ClusterName:       options.ClusterName,
 		PostDrainDelay:    options.PostDrainDelay,
 		ValidationTimeout: options.ValidationTimeout,
+		MaxSurge:          maxSurge,
+		MaxUnavailable:    maxUnavailable,
+		Hooks:             hooks,
+		Progress:          progress,
+		DrainOptions: instancegroups.DrainOptions{
+			PodEvictionTimeout:    options.PodEvictionTimeout,
+			SkipNamespaces:        options.DrainSkipNamespaces,
+			SkipSelectors:         drainSkipSelectors,
+			ForceDeleteNamespaces: options.DrainForceDeleteNamespaces,
+			ForceDeleteSelectors:  drainForceDeleteSelectors,
+		},
 	}
 	return d.RollingUpdate(groups, cluster, list)
 }
+
+// parseIntOrPercentFlag parses a flag holding either a non-negative integer or a percentage, returning nil if it is not set
+func parseIntOrPercentFlag(name string, value string) (*intstr.IntOrString, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed := intstr.Parse(value)
+	v, err := intstr.GetValueFromIntOrPercent(&parsed, 100, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q for --%s: %v", value, name, err)
+	}
+	if v < 0 {
+		return nil, fmt.Errorf("invalid value %q for --%s: cannot be negative", value, name)
+	}
+
+	return &parsed, nil
+}
+
+// parseSelectorFlags parses the label selectors given to a flag
+func parseSelectorFlags(name string, values []string) ([]labels.Selector, error) {
+	var selectors []labels.Selector
+	for _, value := range values {
+		selector, err := labels.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for --%s: %v", value, name, err)
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, nil
+}
+
+// parseHookFlags builds the hooks given to a flag: values starting with http:// or https:// are called as
+// webhooks, anything else is run as a shell command
+func parseHookFlags(name string, event api.RollingUpdateHookEvent, values []string) ([]instancegroups.InstanceHook, error) {
+	var hooks []instancegroups.InstanceHook
+	for _, value := range values {
+		spec := &api.RollingUpdateHook{
+			Name:   fmt.Sprintf("--%s %s", name, value),
+			Events: []api.RollingUpdateHookEvent{event},
+		}
+		if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+			spec.Webhook = value
+		} else {
+			spec.Exec = []string{"/bin/sh", "-c", value}
+		}
+
+		hook, err := instancegroups.NewInstanceHook(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for --%s: %v", value, name, err)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
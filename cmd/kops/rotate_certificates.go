@@ -0,0 +1,188 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/certificates"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	rotateCertificatesLong = templates.LongDesc(i18n.T(`
+	Rotate the certificates in the keystore, and replace the instances of the cluster so they use the new certificates.
+
+	A replacement certificate is issued for every keypair that was issued by a CA in the keystore. The replacements
+	keep the subject, alternate names and private key of the certificates they replace. The cluster is then updated,
+	which also exports a kubecfg with the new certificates, and all instances are replaced as
+	kops rolling-update cluster --force --yes does.
+
+	With --ca the cluster CA is also replaced, with a new private key, and every keypair is issued by the new CA.
+	Instances that still trust the old CA cannot reach those that trust the new one, so all instances are replaced
+	without draining or validating in between (as --cloudonly does): the instances of each node and bastion group
+	at once, and the masters one after another with no wait in between. The cluster is unavailable until they
+	have all been replaced. Service account tokens hold the CA certificate, so delete the service account token
+	secrets afterwards for them to be reissued.
+
+	Without --yes the certificates that would be rotated are only shown.`))
+
+	rotateCertificatesExample = templates.Examples(i18n.T(`
+	# Show the certificates that would be rotated
+	kops rotate certificates --name k8s-cluster.example.com
+
+	# Rotate the certificates
+	kops rotate certificates --name k8s-cluster.example.com --yes
+
+	# Rotate the cluster CA and all certificates
+	kops rotate certificates --name k8s-cluster.example.com --ca --yes`))
+
+	rotateCertificatesShort = i18n.T(`Rotate the certificates of a cluster.`)
+)
+
+type RotateCertificatesOptions struct {
+	ClusterName string
+
+	// CA also rotates the cluster CA
+	CA bool
+
+	Yes bool
+}
+
+func NewCmdRotateCertificates(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &RotateCertificatesOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "certificates",
+		Aliases: []string{"certificate", "certs"},
+		Short:   rotateCertificatesShort,
+		Long:    rotateCertificatesLong,
+		Example: rotateCertificatesExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := rootCommand.ProcessArgs(args); err != nil {
+				exitWithError(err)
+			}
+
+			options.ClusterName = rootCommand.ClusterName()
+
+			err := RunRotateCertificates(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&options.CA, "ca", options.CA, "Also rotate the cluster CA; the cluster is unavailable while its instances are replaced")
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Rotate the certificates; without --yes the certificates that would be rotated are only shown")
+
+	return cmd
+}
+
+func RunRotateCertificates(f *util.Factory, out io.Writer, options *RotateCertificatesOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("ClusterName is required")
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := clientset.GetCluster(options.ClusterName)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		return fmt.Errorf("cluster %q not found", options.ClusterName)
+	}
+
+	keyStore, err := clientset.KeyStore(cluster)
+	if err != nil {
+		return err
+	}
+
+	plan, err := certificates.PlanRotation(keyStore, options.CA)
+	if err != nil {
+		return err
+	}
+	if len(plan.CAs) == 0 && len(plan.Keypairs) == 0 {
+		return fmt.Errorf("no certificates to rotate")
+	}
+
+	if len(plan.CAs) != 0 {
+		fmt.Fprintf(out, "Will rotate CAs: %s\n", strings.Join(plan.CAs, ", "))
+	}
+	fmt.Fprintf(out, "Will rotate keypairs: %s\n", strings.Join(plan.Keypairs, ", "))
+	if options.CA {
+		fmt.Fprintf(out, "Will then update the cluster and replace all instances without draining or validating: the instances of each\n")
+		fmt.Fprintf(out, "node and bastion group at once, and the masters one after another\n")
+	} else {
+		fmt.Fprintf(out, "Will then update the cluster and replace all instances\n")
+	}
+
+	if !options.Yes {
+		fmt.Fprintf(out, "\nMust specify --yes to rotate\n")
+		return nil
+	}
+
+	fmt.Fprintf(out, "\nRotating certificates\n")
+	if err := certificates.Rotate(keyStore, plan); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Updating cluster\n")
+	updateOptions := &UpdateClusterOptions{}
+	updateOptions.InitDefaults()
+	updateOptions.Yes = true
+	updateOptions.CreateKubecfg = true
+	if _, err := RunUpdateCluster(f, cluster.ObjectMeta.Name, out, updateOptions); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Replacing instances\n")
+	rollingUpdateOptions := &RollingUpdateOptions{}
+	rollingUpdateOptions.InitDefaults()
+	rollingUpdateOptions.ClusterName = cluster.ObjectMeta.Name
+	rollingUpdateOptions.Yes = true
+	rollingUpdateOptions.Force = true
+	retry := "kops rolling-update cluster " + cluster.ObjectMeta.Name + " --force --yes"
+	if options.CA {
+		// The cluster is broken until every instance trusts the new CA, so replace them as quickly as possible
+		rollingUpdateOptions.CloudOnly = true
+		rollingUpdateOptions.FailOnValidate = false
+		rollingUpdateOptions.MasterInterval = time.Second
+		rollingUpdateOptions.NodeInterval = time.Second
+		rollingUpdateOptions.BastionInterval = time.Second
+		rollingUpdateOptions.MaxUnavailable = "100%"
+		retry = "kops rolling-update cluster " + cluster.ObjectMeta.Name + " --cloudonly --force --master-interval 1s --node-interval 1s --bastion-interval 1s --max-unavailable 100% --yes"
+	}
+	if err := RunRollingUpdateCluster(f, out, rollingUpdateOptions); err != nil {
+		return fmt.Errorf("error replacing instances: %v\nThe certificates have been rotated; finish replacing the instances with:\n  %s", err, retry)
+	}
+
+	fmt.Fprintf(out, "\nCertificates rotated\n")
+	if options.CA {
+		fmt.Fprintf(out, "Delete the service account token secrets (of type kubernetes.io/service-account-token) for them to be reissued with the new CA.\n")
+	}
+	return nil
+}
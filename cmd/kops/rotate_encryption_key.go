@@ -0,0 +1,234 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/pkg/encryptionconfig"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	rotateEncryptionKeyLong = templates.LongDesc(i18n.T(`
+	Rotate the key with which kube-apiserver encrypts secrets at rest.
+
+	A new key is generated and rotated into the encryption config created with kops create secret encryptionconfig,
+	in the first aescbc, aesgcm or secretbox provider for each set of resources. The rotation takes these steps:
+
+	* the new key is added as the last key, and the masters are replaced, so every kube-apiserver can decrypt with it
+	* the new key is made the first key, and the masters are replaced, so every kube-apiserver encrypts with it
+	* every secret is rewritten, so that it is encrypted with the new key
+	* the old keys are removed, and the masters are replaced
+
+	Progress is recorded in the state store after each step. If the rotation is interrupted, run the command again
+	to resume it from the step that did not complete.
+
+	Without --yes the steps that would be taken are only shown.`))
+
+	rotateEncryptionKeyExample = templates.Examples(i18n.T(`
+	# Show the steps of the rotation
+	kops rotate encryption-key --name k8s-cluster.example.com
+
+	# Rotate the key, or resume an interrupted rotation
+	kops rotate encryption-key --name k8s-cluster.example.com --yes`))
+
+	rotateEncryptionKeyShort = i18n.T(`Rotate the encryption-at-rest key of a cluster.`)
+)
+
+type RotateEncryptionKeyOptions struct {
+	ClusterName string
+	Yes         bool
+}
+
+func NewCmdRotateEncryptionKey(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &RotateEncryptionKeyOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "encryption-key",
+		Short:   rotateEncryptionKeyShort,
+		Long:    rotateEncryptionKeyLong,
+		Example: rotateEncryptionKeyExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := rootCommand.ProcessArgs(args); err != nil {
+				exitWithError(err)
+			}
+
+			options.ClusterName = rootCommand.ClusterName()
+
+			err := RunRotateEncryptionKey(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Rotate the key; without --yes the steps that would be taken are only shown")
+
+	return cmd
+}
+
+func RunRotateEncryptionKey(f *util.Factory, out io.Writer, options *RotateEncryptionKeyOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("ClusterName is required")
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := clientset.GetCluster(options.ClusterName)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		return fmt.Errorf("cluster %q not found", options.ClusterName)
+	}
+	if !fi.BoolValue(cluster.Spec.EncryptionConfig) {
+		return fmt.Errorf("encryptionConfig is not enabled for cluster %q", options.ClusterName)
+	}
+
+	secretStore, err := clientset.SecretStore(cluster)
+	if err != nil {
+		return err
+	}
+	secret, err := secretStore.FindSecret(encryptionconfig.SecretName)
+	if err != nil {
+		return fmt.Errorf("error reading encryption config: %v", err)
+	}
+	if secret == nil {
+		return fmt.Errorf("encryption config not found; create it with kops create secret encryptionconfig")
+	}
+	config, err := encryptionconfig.Parse(secret.Data)
+	if err != nil {
+		return err
+	}
+
+	configBase, err := registry.ConfigBase(cluster)
+	if err != nil {
+		return err
+	}
+	status, err := encryptionconfig.ReadRotationStatus(configBase)
+	if err != nil {
+		return err
+	}
+	resume := status != nil && status.Step != encryptionconfig.RotationStepCompleted
+	if resume {
+		fmt.Fprintf(out, "Resuming the rotation to key %q started at %s\n", status.NewKey, status.StartTime.Format("2006-01-02 15:04:05"))
+	} else {
+		status, err = encryptionconfig.NewRotationStatus(config)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Will rotate keys %s to new key %q\n", strings.Join(status.OldKeys, ", "), status.NewKey)
+	}
+
+	steps := status.RemainingSteps()
+	for _, step := range steps {
+		fmt.Fprintf(out, "  %s\n", describeRotationStep(step, status))
+	}
+
+	if !options.Yes {
+		fmt.Fprintf(out, "\nMust specify --yes to rotate\n")
+		return nil
+	}
+
+	if !resume {
+		// Record the plan before changing anything, so that an interrupted rotation resumes with the same key
+		if err := encryptionconfig.WriteRotationStatus(cluster, configBase, status); err != nil {
+			return err
+		}
+	}
+
+	k8sClient, err := buildKubernetesClient(cluster)
+	if err != nil {
+		return err
+	}
+
+	for i, step := range steps {
+		fmt.Fprintf(out, "\n%s\n", describeRotationStep(step, status))
+
+		if step == encryptionconfig.RotationStepRewriteSecrets {
+			count, err := encryptionconfig.RewriteSecrets(k8sClient)
+			if err != nil {
+				return fmt.Errorf("%v\nRun kops rotate encryption-key --yes to resume the rotation", err)
+			}
+			fmt.Fprintf(out, "Rewrote %d secrets\n", count)
+		} else {
+			if err := encryptionconfig.ApplyStep(config, status, step); err != nil {
+				return err
+			}
+			data, err := config.Marshal()
+			if err != nil {
+				return err
+			}
+			if _, err := secretStore.ReplaceSecret(encryptionconfig.SecretName, &fi.Secret{Data: data}); err != nil {
+				return fmt.Errorf("error writing encryption config: %v", err)
+			}
+
+			if err := rollMastersForEncryptionConfig(f, out, cluster); err != nil {
+				return fmt.Errorf("error replacing masters: %v\nRun kops rotate encryption-key --yes to resume the rotation", err)
+			}
+		}
+
+		status.Step = encryptionconfig.RotationStepCompleted
+		if i+1 < len(steps) {
+			status.Step = steps[i+1]
+		}
+		if err := encryptionconfig.WriteRotationStatus(cluster, configBase, status); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(out, "\nEncryption key rotated to %q\n", status.NewKey)
+	return nil
+}
+
+// rollMastersForEncryptionConfig replaces the masters, so that kube-apiserver reads the changed encryption config
+func rollMastersForEncryptionConfig(f *util.Factory, out io.Writer, cluster *api.Cluster) error {
+	rollingUpdateOptions := &RollingUpdateOptions{}
+	rollingUpdateOptions.InitDefaults()
+	rollingUpdateOptions.ClusterName = cluster.ObjectMeta.Name
+	rollingUpdateOptions.Yes = true
+	rollingUpdateOptions.Force = true
+	rollingUpdateOptions.InstanceGroupRoles = []string{string(api.InstanceGroupRoleMaster)}
+	return RunRollingUpdateCluster(f, out, rollingUpdateOptions)
+}
+
+func describeRotationStep(step encryptionconfig.RotationStep, status *encryptionconfig.RotationStatus) string {
+	switch step {
+	case encryptionconfig.RotationStepAddKey:
+		return fmt.Sprintf("Adding key %q to the encryption config and replacing the masters", status.NewKey)
+	case encryptionconfig.RotationStepPromoteKey:
+		return fmt.Sprintf("Making key %q the encrypting key and replacing the masters", status.NewKey)
+	case encryptionconfig.RotationStepRewriteSecrets:
+		return fmt.Sprintf("Rewriting all secrets with key %q", status.NewKey)
+	case encryptionconfig.RotationStepRemoveOldKeys:
+		return fmt.Sprintf("Removing keys %s from the encryption config and replacing the masters", strings.Join(status.OldKeys, ", "))
+	default:
+		return string(step)
+	}
+}
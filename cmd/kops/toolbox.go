@@ -47,6 +47,10 @@ func NewCmdToolbox(f *util.Factory, out io.Writer) *cobra.Command {
 
 	cmd.AddCommand(NewCmdToolboxConvertImported(f, out))
 	cmd.AddCommand(NewCmdToolboxDump(f, out))
+	cmd.AddCommand(NewCmdToolboxEncryptState(f, out))
+	cmd.AddCommand(NewCmdToolboxFsck(f, out))
+	cmd.AddCommand(NewCmdToolboxMigrateEtcd(f, out))
+	cmd.AddCommand(NewCmdToolboxMigrateState(f, out))
 	cmd.AddCommand(NewCmdToolboxBundle(f, out))
 	cmd.AddCommand(NewCmdToolboxTemplate(f, out))
 
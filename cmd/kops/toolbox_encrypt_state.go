@@ -0,0 +1,166 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/stateencryption"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/util/pkg/vfs"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	toolboxEncryptStateLong = templates.LongDesc(i18n.T(`
+	Re-encrypt the secrets and private keys in the state store, as stateEncryption in the cluster spec requires.
+
+	Secrets and private keys are encrypted as they are written once stateEncryption is set, but those already
+	in the state store stay as they are until they are rewritten. This command rewrites them all: plaintext objects
+	and objects encrypted with another key are encrypted with the configured key, or decrypted if stateEncryption
+	is not set.
+
+	Objects encrypted with a KMS key can be read whatever stateEncryption is set to, as long as the key is still
+	accessible. Objects encrypted with a key file can only be read with that file, so when changing or removing
+	stateEncryption.keyFile, pass the previous key file with --previous-key-file.
+
+	Without --yes the objects that would be rewritten are only shown.`))
+
+	toolboxEncryptStateExample = templates.Examples(i18n.T(`
+	# Show the objects that would be re-encrypted
+	kops toolbox encrypt-state --name k8s-cluster.example.com
+
+	# Encrypt the existing secrets and private keys after setting stateEncryption
+	kops toolbox encrypt-state --name k8s-cluster.example.com --yes
+
+	# Re-encrypt after changing stateEncryption.keyFile
+	kops toolbox encrypt-state --name k8s-cluster.example.com --previous-key-file ~/old-state.key --yes
+	`))
+
+	toolboxEncryptStateShort = i18n.T(`Re-encrypt the secrets and private keys in the state store.`)
+)
+
+type ToolboxEncryptStateOptions struct {
+	ClusterName string
+
+	// PreviousKeyFile is the key file that objects were encrypted with before stateEncryption was changed
+	PreviousKeyFile string
+
+	Yes bool
+}
+
+func NewCmdToolboxEncryptState(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &ToolboxEncryptStateOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "encrypt-state",
+		Short:   toolboxEncryptStateShort,
+		Long:    toolboxEncryptStateLong,
+		Example: toolboxEncryptStateExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := rootCommand.ProcessArgs(args); err != nil {
+				exitWithError(err)
+			}
+
+			options.ClusterName = rootCommand.ClusterName()
+
+			err := RunToolboxEncryptState(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&options.PreviousKeyFile, "previous-key-file", options.PreviousKeyFile, "Key file that objects were encrypted with before stateEncryption.keyFile was changed or removed")
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Rewrite the objects; without --yes the objects that would be rewritten are only shown")
+
+	return cmd
+}
+
+func RunToolboxEncryptState(f *util.Factory, out io.Writer, options *ToolboxEncryptStateOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("ClusterName is required")
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := clientset.GetCluster(options.ClusterName)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		return fmt.Errorf("cluster %q not found", options.ClusterName)
+	}
+
+	keyStore, err := clientset.KeyStore(cluster)
+	if err != nil {
+		return err
+	}
+	secretStore, err := clientset.SecretStore(cluster)
+	if err != nil {
+		return err
+	}
+
+	// Only the private keys are encrypted; the certificates are public
+	var trees []vfs.Path
+	for _, store := range []interface{}{keyStore, secretStore} {
+		vfsStore, ok := store.(fi.HasVFSPath)
+		if !ok {
+			return fmt.Errorf("state store of cluster %q is not held in a VFS path, so cannot be encrypted", options.ClusterName)
+		}
+		trees = append(trees, vfsStore.VFSPath())
+	}
+	// Prior versions of keysets include their private keys, so are encrypted too
+	trees = append(trees, trees[0].Join(".history"))
+	trees[0] = trees[0].Join("private")
+
+	if cluster.Spec.StateEncryption == nil {
+		fmt.Fprintf(out, "stateEncryption is not set; objects will be decrypted\n")
+	}
+
+	total := 0
+	for _, tree := range trees {
+		rewritten, err := stateencryption.ReencryptTree(cluster, tree, options.PreviousKeyFile, !options.Yes)
+		for _, p := range rewritten {
+			if options.Yes {
+				fmt.Fprintf(out, "Rewrote %s\n", p)
+			} else {
+				fmt.Fprintf(out, "Will rewrite %s\n", p)
+			}
+		}
+		total += len(rewritten)
+		if err != nil {
+			return err
+		}
+	}
+
+	if total == 0 {
+		fmt.Fprintf(out, "All objects are already stored as required\n")
+		return nil
+	}
+	if !options.Yes {
+		fmt.Fprintf(out, "\nMust specify --yes to rewrite %d objects\n", total)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/statecheck"
+	"k8s.io/kops/util/pkg/tables"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	toolboxFsckLong = templates.LongDesc(i18n.T(`
+	Check the state store of a cluster for problems.
+
+	Every object is read and validated, and references between objects are checked: the subnets of instance
+	groups, the instance groups of etcd members, and the certificates of private keys. Files kops does not
+	know about are also reported, as they prevent the cluster being deleted.
+
+	With --repair, problems that can be repaired are: objects that cannot be read are moved to the
+	quarantine directory of the cluster, and private keys without a certificate are removed from their
+	keyset. Other problems must be fixed by hand, for example with kops edit.`))
+
+	toolboxFsckExample = templates.Examples(i18n.T(`
+	# Check the state store of a cluster
+	kops toolbox fsck --name k8s-cluster.example.com
+
+	# Check the state store and repair the problems that can be repaired
+	kops toolbox fsck --name k8s-cluster.example.com --repair
+	`))
+
+	toolboxFsckShort = i18n.T(`Check and repair the state store of a cluster.`)
+)
+
+type ToolboxFsckOptions struct {
+	ClusterName string
+
+	// Repair repairs the problems that can be repaired, rather than only reporting them
+	Repair bool
+}
+
+func NewCmdToolboxFsck(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &ToolboxFsckOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "fsck",
+		Short:   toolboxFsckShort,
+		Long:    toolboxFsckLong,
+		Example: toolboxFsckExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := rootCommand.ProcessArgs(args); err != nil {
+				exitWithError(err)
+			}
+
+			options.ClusterName = rootCommand.ClusterName()
+
+			err := RunToolboxFsck(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&options.Repair, "repair", options.Repair, "Repair the problems that can be repaired; without --repair problems are only reported")
+
+	return cmd
+}
+
+func RunToolboxFsck(f *util.Factory, out io.Writer, options *ToolboxFsckOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("ClusterName is required")
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	// The cluster spec is not read here, as it is one of the objects being checked
+	cluster := &kops.Cluster{}
+	cluster.ObjectMeta.Name = options.ClusterName
+	configBase, err := clientset.ConfigBaseFor(cluster)
+	if err != nil {
+		return err
+	}
+
+	checker := &statecheck.Checker{
+		Clientset:     clientset,
+		ClusterName:   options.ClusterName,
+		ConfigBase:    configBase,
+		QuarantineDir: "quarantine/" + time.Now().UTC().Format("20060102150405"),
+	}
+
+	problems, err := checker.Check()
+	if err != nil {
+		return err
+	}
+	if len(problems) == 0 {
+		fmt.Fprintf(out, "No problems found in the state store of %s\n", options.ClusterName)
+		return nil
+	}
+
+	t := &tables.Table{}
+	t.AddColumn("PATH", func(p *statecheck.Problem) string {
+		return p.Path
+	})
+	t.AddColumn("PROBLEM", func(p *statecheck.Problem) string {
+		return p.Message
+	})
+	t.AddColumn("REPAIR", func(p *statecheck.Problem) string {
+		if p.Repair == "" {
+			return "-"
+		}
+		return p.Repair
+	})
+	if err := t.Render(problems, out, "PATH", "PROBLEM", "REPAIR"); err != nil {
+		return err
+	}
+
+	remaining := 0
+	for _, p := range problems {
+		if !options.Repair || !p.CanRepair() {
+			remaining++
+			continue
+		}
+		if err := checker.Repair(p); err != nil {
+			return fmt.Errorf("error repairing %s: %v", p.Path, err)
+		}
+		fmt.Fprintf(out, "Repaired %s: %s\n", p.Path, p.Repair)
+	}
+
+	if remaining != 0 {
+		if !options.Repair {
+			fmt.Fprintf(out, "\nMust specify --repair to repair the problems that can be repaired\n")
+		}
+		return fmt.Errorf("found %d problems in the state store", remaining)
+	}
+	return nil
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog"
+	"k8s.io/kops/cmd/kops/util"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/assets"
+	"k8s.io/kops/pkg/commands"
+	"k8s.io/kops/pkg/etcdbackup"
+	"k8s.io/kops/pkg/validation"
+	"k8s.io/kops/upup/pkg/fi/cloudup"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	toolboxMigrateEtcdLong = templates.LongDesc(i18n.T(`
+	Migrate the etcd clusters managed by protokube (the Legacy etcd provider) to etcd-manager.
+
+	The migration runs these steps, stopping at the first that fails:
+
+	1. Validate the cluster, so the migration starts from a healthy cluster.
+	2. Snapshot the volumes of every legacy etcd member (AWS only; otherwise use --skip-snapshot once you have your own backup).
+	3. Set the etcd clusters to the Manager provider, keeping the etcd version in use.
+	4. Apply the change to the cloud resources, as kops update cluster --yes does.
+	5. Replace all masters at once, without draining or validating in between, as etcd-manager
+	   adopts the existing data only when no member is still run by protokube.
+	6. Wait for the cluster to validate and for etcd-manager to be running for every member.
+
+	Without --yes the steps are only shown. The Kubernetes API is unavailable while the masters are replaced.`))
+
+	toolboxMigrateEtcdExample = templates.Examples(i18n.T(`
+	# Show the migration steps for a cluster
+	kops toolbox migrate-etcd --name k8s-cluster.example.com
+
+	# Migrate the cluster to etcd-manager
+	kops toolbox migrate-etcd --name k8s-cluster.example.com --yes
+	`))
+
+	toolboxMigrateEtcdShort = i18n.T(`Migrate legacy etcd clusters to etcd-manager.`)
+)
+
+type ToolboxMigrateEtcdOptions struct {
+	ClusterName string
+
+	// SkipSnapshot skips the snapshot of the legacy etcd volumes
+	SkipSnapshot bool
+
+	// ValidationTimeout is how long to wait for the cluster to validate, before and after the migration
+	ValidationTimeout time.Duration
+
+	Yes bool
+}
+
+func (o *ToolboxMigrateEtcdOptions) InitDefaults() {
+	o.ValidationTimeout = 15 * time.Minute
+}
+
+func NewCmdToolboxMigrateEtcd(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &ToolboxMigrateEtcdOptions{}
+	options.InitDefaults()
+
+	cmd := &cobra.Command{
+		Use:     "migrate-etcd",
+		Short:   toolboxMigrateEtcdShort,
+		Long:    toolboxMigrateEtcdLong,
+		Example: toolboxMigrateEtcdExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := rootCommand.ProcessArgs(args); err != nil {
+				exitWithError(err)
+			}
+
+			options.ClusterName = rootCommand.ClusterName()
+
+			err := RunToolboxMigrateEtcd(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&options.SkipSnapshot, "skip-snapshot", options.SkipSnapshot, "Do not snapshot the volumes of the legacy etcd members")
+	cmd.Flags().DurationVar(&options.ValidationTimeout, "validation-timeout", options.ValidationTimeout, "Maximum time to wait for the cluster to validate, before and after the migration")
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Perform the migration; without --yes the steps are only shown")
+
+	return cmd
+}
+
+func RunToolboxMigrateEtcd(f *util.Factory, out io.Writer, options *ToolboxMigrateEtcdOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("ClusterName is required")
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := clientset.GetCluster(options.ClusterName)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		return fmt.Errorf("cluster %q not found", options.ClusterName)
+	}
+
+	instanceGroups, err := commands.ReadAllInstanceGroups(clientset, cluster)
+	if err != nil {
+		return err
+	}
+
+	fullCluster, err := cloudup.PopulateClusterSpec(clientset, cluster, assets.NewAssetBuilder(cluster, ""))
+	if err != nil {
+		return err
+	}
+
+	legacy := commands.LegacyEtcdClusters(fullCluster)
+	if len(legacy) == 0 {
+		return fmt.Errorf("all etcd clusters of %q are already managed by etcd-manager", cluster.ObjectMeta.Name)
+	}
+
+	fmt.Fprintf(out, "Will migrate etcd clusters %s of %q to etcd-manager:\n", strings.Join(legacy, ", "), cluster.ObjectMeta.Name)
+	fmt.Fprintf(out, "  1. validate the cluster\n")
+	if options.SkipSnapshot {
+		fmt.Fprintf(out, "  2. (skipped) snapshot the etcd volumes\n")
+	} else {
+		fmt.Fprintf(out, "  2. snapshot the etcd volumes\n")
+	}
+	fmt.Fprintf(out, "  3. set the etcd provider to Manager\n")
+	fmt.Fprintf(out, "  4. update the cluster\n")
+	fmt.Fprintf(out, "  5. replace all masters at once\n")
+	fmt.Fprintf(out, "  6. wait for the cluster to validate and for etcd-manager to run on every master\n")
+
+	if !options.Yes {
+		fmt.Fprintf(out, "\nMust specify --yes to migrate\n")
+		return nil
+	}
+
+	k8sClient, err := buildKubernetesClient(cluster)
+	if err != nil {
+		return err
+	}
+
+	validate := func() error {
+		list, err := clientset.InstanceGroupsFor(cluster).List(metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("cannot get InstanceGroups for %q: %v", cluster.ObjectMeta.Name, err)
+		}
+		result, err := validateClusterUntil(func() (*validation.ValidationCluster, error) {
+			return validation.ValidateCluster(cluster, list, k8sClient)
+		}, options.ValidationTimeout)
+		if err != nil {
+			return fmt.Errorf("unable to validate cluster: %v", err)
+		}
+		if len(result.Failures) != 0 {
+			var messages []string
+			for _, failure := range result.Failures {
+				messages = append(messages, failure.Message)
+			}
+			return fmt.Errorf("cluster did not validate:\n  %s", strings.Join(messages, "\n  "))
+		}
+		return nil
+	}
+
+	fmt.Fprintf(out, "\nValidating cluster\n")
+	if err := validate(); err != nil {
+		return err
+	}
+
+	if !options.SkipSnapshot {
+		fmt.Fprintf(out, "Snapshotting etcd volumes\n")
+		cloud, err := cloudup.BuildCloud(cluster)
+		if err != nil {
+			return err
+		}
+		status, err := (&commands.CloudDiscoveryStatusStore{}).FindClusterStatus(cluster)
+		if err != nil {
+			return err
+		}
+		snapshotIDs, err := commands.SnapshotEtcdVolumes(cloud, cluster, status, legacy)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Created snapshots %s\n", strings.Join(snapshotIDs, ", "))
+	}
+
+	fmt.Fprintf(out, "Setting the etcd provider to Manager\n")
+	if err := commands.MigrateEtcdClustersToManager(cluster, fullCluster, legacy); err != nil {
+		return err
+	}
+	if err := commands.UpdateCluster(clientset, cluster, instanceGroups); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Updating cluster\n")
+	updateOptions := &UpdateClusterOptions{}
+	updateOptions.InitDefaults()
+	updateOptions.Yes = true
+	updateOptions.CreateKubecfg = false
+	if _, err := RunUpdateCluster(f, cluster.ObjectMeta.Name, out, updateOptions); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Replacing masters\n")
+	rollingUpdateOptions := &RollingUpdateOptions{}
+	rollingUpdateOptions.InitDefaults()
+	rollingUpdateOptions.ClusterName = cluster.ObjectMeta.Name
+	rollingUpdateOptions.Yes = true
+	rollingUpdateOptions.Force = true
+	rollingUpdateOptions.CloudOnly = true
+	rollingUpdateOptions.FailOnValidate = false
+	rollingUpdateOptions.MasterInterval = time.Second
+	rollingUpdateOptions.InstanceGroupRoles = []string{string(api.InstanceGroupRoleMaster)}
+	if err := RunRollingUpdateCluster(f, out, rollingUpdateOptions); err != nil {
+		return fmt.Errorf("error replacing masters: %v\nThe cluster spec has been migrated; finish replacing the masters with:\n"+
+			"  kops rolling-update cluster %s --cloudonly --force --instance-group-roles Master --master-interval 1s --yes", err, cluster.ObjectMeta.Name)
+	}
+
+	fmt.Fprintf(out, "Waiting for the cluster to validate\n")
+	if err := validate(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Checking etcd-manager\n")
+	if err := waitForEtcdManager(k8sClient, cluster, fullCluster, legacy, options.ValidationTimeout); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nMigration of etcd clusters %s to etcd-manager complete\n", strings.Join(legacy, ", "))
+	return nil
+}
+
+// waitForEtcdManager waits until etcd-manager is ready on a master for every member of the named etcd clusters,
+// and has recorded the etcd cluster in its backup store
+func waitForEtcdManager(k8sClient kubernetes.Interface, cluster *api.Cluster, fullCluster *api.Cluster, names []string, timeout time.Duration) error {
+	migrated := sets.NewString(names...)
+	var problems []string
+	err := wait.PollImmediate(10*time.Second, timeout, func() (bool, error) {
+		problems = nil
+		for _, etcdCluster := range fullCluster.Spec.EtcdClusters {
+			if !migrated.Has(etcdCluster.Name) {
+				continue
+			}
+
+			selector := "k8s-app=etcd-manager-" + etcdCluster.Name
+			pods, err := k8sClient.CoreV1().Pods("kube-system").List(metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				klog.Warningf("error listing etcd-manager pods: %v", err)
+				problems = append(problems, fmt.Sprintf("unable to list etcd-manager pods for etcd cluster %q", etcdCluster.Name))
+				continue
+			}
+			ready := 0
+			for i := range pods.Items {
+				if isPodReady(&pods.Items[i]) {
+					ready++
+				}
+			}
+			if ready < len(etcdCluster.Members) {
+				problems = append(problems, fmt.Sprintf("etcd cluster %q has %d of %d etcd-manager pods ready", etcdCluster.Name, ready, len(etcdCluster.Members)))
+				continue
+			}
+
+			store, err := etcdbackup.BackupStore(cluster, etcdCluster)
+			if err != nil {
+				return false, err
+			}
+			spec, err := etcdbackup.ReadClusterSpec(store)
+			if err != nil {
+				return false, err
+			}
+			if spec == nil {
+				problems = append(problems, fmt.Sprintf("etcd-manager has not yet recorded etcd cluster %q in %s", etcdCluster.Name, store))
+			}
+		}
+
+		for _, problem := range problems {
+			klog.Infof("%s, will retry", problem)
+		}
+		return len(problems) == 0, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out waiting for etcd-manager:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return err
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// buildKubernetesClient returns a client for the cluster, using the kubecfg context named after the cluster
+func buildKubernetesClient(cluster *api.Cluster) (kubernetes.Interface, error) {
+	contextName := cluster.ObjectMeta.Name
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(),
+		&clientcmd.ConfigOverrides{CurrentContext: contextName}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("Cannot load kubecfg settings for %q: %v", contextName, err)
+	}
+
+	k8sClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot build kubernetes api client for %q: %v", contextName, err)
+	}
+	return k8sClient, nil
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kops/cmd/kops/util"
+	"k8s.io/kops/pkg/client/simple/vfsclientset"
+	"k8s.io/kops/pkg/statemigration"
+	"k8s.io/kops/util/pkg/tables"
+	"k8s.io/kops/util/pkg/vfs"
+	"k8s.io/kubernetes/pkg/kubectl/util/i18n"
+	"k8s.io/kubernetes/pkg/kubectl/util/templates"
+)
+
+var (
+	toolboxMigrateStateLong = templates.LongDesc(i18n.T(`
+	Move the state of a cluster to another state store, which can use a different backend.
+
+	Every file of the cluster is copied, with the ACLs the cluster needs in the new state store, and each copy
+	is verified. The paths in the cluster spec that are in the state of the cluster (configBase, configStore,
+	keyStore, secretStore and the etcd backup stores) are then rewritten to the new state store. Paths outside
+	the state of the cluster are not changed, and what they refer to is not copied.
+
+	The old state is not removed. Afterwards, use the new state store, run kops update cluster and replace
+	every instance, so that the instances read their configuration from the new state store.
+
+	Without --yes the paths that would be rewritten are only shown. The copy can be run again if it is
+	interrupted; files that have already been copied are skipped.`))
+
+	toolboxMigrateStateExample = templates.Examples(i18n.T(`
+	# Show what would be migrated
+	kops toolbox migrate-state --name k8s-cluster.example.com --to gs://my-state-store
+
+	# Move the state of the cluster from S3 to GCS
+	kops toolbox migrate-state --name k8s-cluster.example.com --to gs://my-state-store --yes
+	export KOPS_STATE_STORE=gs://my-state-store
+	kops update cluster --name k8s-cluster.example.com --yes
+	kops rolling-update cluster --name k8s-cluster.example.com --force --yes
+	`))
+
+	toolboxMigrateStateShort = i18n.T(`Move the state of a cluster to another state store.`)
+)
+
+type ToolboxMigrateStateOptions struct {
+	ClusterName string
+
+	// To is the state store to move the state of the cluster to
+	To string
+
+	Yes bool
+}
+
+func NewCmdToolboxMigrateState(f *util.Factory, out io.Writer) *cobra.Command {
+	options := &ToolboxMigrateStateOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "migrate-state",
+		Short:   toolboxMigrateStateShort,
+		Long:    toolboxMigrateStateLong,
+		Example: toolboxMigrateStateExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := rootCommand.ProcessArgs(args); err != nil {
+				exitWithError(err)
+			}
+
+			options.ClusterName = rootCommand.ClusterName()
+
+			err := RunToolboxMigrateState(f, out, options)
+			if err != nil {
+				exitWithError(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&options.To, "to", options.To, "State store to move the state of the cluster to, e.g. gs://my-state-store")
+	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Migrate the state; without --yes the paths that would be rewritten are only shown")
+
+	return cmd
+}
+
+func RunToolboxMigrateState(f *util.Factory, out io.Writer, options *ToolboxMigrateStateOptions) error {
+	if options.ClusterName == "" {
+		return fmt.Errorf("ClusterName is required")
+	}
+	if options.To == "" {
+		return fmt.Errorf("--to is required")
+	}
+
+	clientset, err := f.Clientset()
+	if err != nil {
+		return err
+	}
+
+	cluster, err := clientset.GetCluster(options.ClusterName)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		return fmt.Errorf("cluster %q not found", options.ClusterName)
+	}
+
+	from, err := clientset.ConfigBaseFor(cluster)
+	if err != nil {
+		return err
+	}
+	toBase, err := vfs.Context.BuildVfsPath(options.To)
+	if err != nil {
+		return fmt.Errorf("error parsing --to %q: %v", options.To, err)
+	}
+	to := toBase.Join(cluster.ObjectMeta.Name)
+	if to.Path() == from.Path() {
+		return fmt.Errorf("the state of cluster %q is already in %s", options.ClusterName, options.To)
+	}
+
+	migrated := cluster.DeepCopy()
+	changes := statemigration.RewritePaths(&migrated.Spec, from.Path(), to.Path())
+	if migrated.Spec.ConfigBase == "" {
+		migrated.Spec.ConfigBase = to.Path()
+	}
+
+	t := &tables.Table{}
+	t.AddColumn("FIELD", func(c *statemigration.PathChange) string {
+		return c.Field
+	})
+	t.AddColumn("OLD", func(c *statemigration.PathChange) string {
+		return c.Old
+	})
+	t.AddColumn("NEW", func(c *statemigration.PathChange) string {
+		if c.New == "" {
+			return "(not migrated)"
+		}
+		return c.New
+	})
+	if err := t.Render(changes, out, "FIELD", "OLD", "NEW"); err != nil {
+		return err
+	}
+
+	if !options.Yes {
+		fmt.Fprintf(out, "\nThe state of the cluster would be copied from %s to %s\n", from, to)
+		fmt.Fprintf(out, "\nMust specify --yes to migrate\n")
+		return nil
+	}
+
+	copied, err := statemigration.CopyState(migrated, from, to)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\nCopied %d files from %s to %s\n", len(copied), from, to)
+
+	// The copied config and completed spec still hold the old paths
+	newClientset := vfsclientset.NewVFSClientset(toBase, true)
+	if _, err := newClientset.UpdateCluster(migrated, nil); err != nil {
+		return fmt.Errorf("error updating cluster spec in %s: %v", options.To, err)
+	}
+	if err := statemigration.RewriteCompletedSpec(migrated, to, from.Path(), to.Path()); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nThe state of cluster %q has been copied to %s.\n", options.ClusterName, options.To)
+	fmt.Fprintf(out, "The old state has not been removed. To complete the migration:\n")
+	fmt.Fprintf(out, " * set KOPS_STATE_STORE=%s\n", options.To)
+	fmt.Fprintf(out, " * kops update cluster --name %s --yes\n", options.ClusterName)
+	fmt.Fprintf(out, " * kops rolling-update cluster --name %s --force --yes\n", options.ClusterName)
+	fmt.Fprintf(out, "Once every instance has been replaced, the old state can be removed.\n")
+
+	return nil
+}
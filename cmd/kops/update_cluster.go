@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -52,6 +53,13 @@ var (
 	updateClusterExample = templates.Examples(i18n.T(`
 	# After cluster has been edited or upgraded, configure it with:
 	kops update cluster k8s-cluster.example.com --yes --state=s3://kops-state-1234 --yes
+
+	# Print the changes that would be made as JSON, for review or policy checks
+	kops update cluster k8s-cluster.example.com --state=s3://kops-state-1234 -o json
+
+	# Save the changes for review, and later apply them only if they are still what would be done
+	kops update cluster k8s-cluster.example.com --state=s3://kops-state-1234 --out-plan plan.json
+	kops update cluster k8s-cluster.example.com --state=s3://kops-state-1234 --plan plan.json --yes
 	`))
 
 	updateClusterShort = i18n.T("Update a cluster.")
@@ -66,6 +74,14 @@ type UpdateClusterOptions struct {
 	RunTasksOptions fi.RunTasksOptions
 	CreateKubecfg   bool
 
+	// Output is the format of the dry-run report: text or json
+	Output string
+
+	// OutPlan is a file to which the plan found by a dry run is written
+	OutPlan string
+	// Plan is a file containing a saved plan; the update is refused unless the changes to be made match it
+	Plan string
+
 	Phase string
 
 	// LifecycleOverrides is a slice of taskName=lifecycle name values.  This slice is used
@@ -80,6 +96,7 @@ func (o *UpdateClusterOptions) InitDefaults() {
 	o.SSHPublicKey = ""
 	o.OutDir = ""
 	o.CreateKubecfg = true
+	o.Output = OutputTable
 	o.RunTasksOptions.InitDefaults()
 }
 
@@ -107,11 +124,14 @@ func NewCmdUpdateCluster(f *util.Factory, out io.Writer) *cobra.Command {
 	}
 
 	cmd.Flags().BoolVarP(&options.Yes, "yes", "y", options.Yes, "Create cloud resources, without --yes update is in dry run mode")
-	cmd.Flags().StringVar(&options.Target, "target", options.Target, "Target - direct, terraform, cloudformation")
+	cmd.Flags().StringVar(&options.Target, "target", options.Target, "Target - direct, terraform, cloudformation, resourcegraph")
 	cmd.Flags().StringVar(&options.Models, "model", options.Models, "Models to apply (separate multiple models with commas)")
 	cmd.Flags().StringVar(&options.SSHPublicKey, "ssh-public-key", options.SSHPublicKey, "SSH public key to use (deprecated: use kops create secret instead)")
 	cmd.Flags().StringVar(&options.OutDir, "out", options.OutDir, "Path to write any local output")
 	cmd.Flags().BoolVar(&options.CreateKubecfg, "create-kube-config", options.CreateKubecfg, "Will control automatically creating the kube config file on your local filesystem")
+	cmd.Flags().StringVarP(&options.Output, "output", "o", options.Output, "Output format of the changes found by a dry run. One of: table, json")
+	cmd.Flags().StringVar(&options.OutPlan, "out-plan", options.OutPlan, "File to write the changes found by a dry run to, for use with --plan")
+	cmd.Flags().StringVar(&options.Plan, "plan", options.Plan, "File containing a plan saved with --out-plan; the update is refused if the changes to be made no longer match it")
 	cmd.Flags().StringVar(&options.Phase, "phase", options.Phase, "Subset of tasks to run: "+strings.Join(cloudup.Phases.List(), ", "))
 	cmd.Flags().StringSliceVar(&options.LifecycleOverrides, "lifecycle-overrides", options.LifecycleOverrides, "comma separated list of phase overrides, example: SecurityGroups=Ignore,InternetGateway=ExistsAndWarnIfChanges")
 
@@ -144,11 +164,35 @@ func RunUpdateCluster(f *util.Factory, clusterName string, out io.Writer, c *Upd
 		targetName = cloudup.TargetDryRun
 	}
 
+	switch c.Output {
+	case "", OutputTable:
+	case OutputJSON:
+		if !isDryrun {
+			return results, fmt.Errorf("--output=%s is only supported for dry runs", c.Output)
+		}
+	default:
+		return results, fmt.Errorf("unknown output format: %q", c.Output)
+	}
+
+	if c.OutPlan != "" && !isDryrun {
+		return results, fmt.Errorf("--out-plan is only supported for dry runs")
+	}
+	if c.Plan != "" {
+		if c.OutPlan != "" {
+			return results, fmt.Errorf("cannot specify both --plan and --out-plan")
+		}
+		if c.Target != cloudup.TargetDirect {
+			return results, fmt.Errorf("--plan is only supported with --target=%s", cloudup.TargetDirect)
+		}
+	}
+
 	if c.OutDir == "" {
 		if c.Target == cloudup.TargetTerraform {
 			c.OutDir = "out/terraform"
 		} else if c.Target == cloudup.TargetCloudformation {
 			c.OutDir = "out/cloudformation"
+		} else if c.Target == cloudup.TargetResourceGraph {
+			c.OutDir = "out/resourcegraph"
 		} else {
 			c.OutDir = "out"
 		}
@@ -253,6 +297,19 @@ func RunUpdateCluster(f *util.Factory, clusterName string, out io.Writer, c *Upd
 		TargetName:         targetName,
 		LifecycleOverrides: lifecycleOverrideMap,
 	}
+	if c.Output == OutputJSON {
+		applyCmd.DryRunOutput = ioutil.Discard
+	}
+
+	if c.Plan != "" {
+		if err := verifyUpdatePlan(f, clusterName, c.Plan, applyCmd); err != nil {
+			return results, err
+		}
+		if !c.Yes {
+			fmt.Fprintf(out, "The changes to be made match the plan in %s; specify --yes to apply them\n", c.Plan)
+			return results, nil
+		}
+	}
 
 	if err := applyCmd.Run(); err != nil {
 		return results, err
@@ -263,6 +320,25 @@ func RunUpdateCluster(f *util.Factory, clusterName string, out io.Writer, c *Upd
 
 	if isDryrun {
 		target := applyCmd.Target.(*fi.DryRunTarget)
+		if c.OutPlan != "" {
+			if err := writeUpdatePlan(c.OutPlan, target, applyCmd.TaskMap); err != nil {
+				return results, err
+			}
+		}
+		if c.Output == OutputJSON {
+			plan, err := target.Plan(applyCmd.TaskMap)
+			if err != nil {
+				return results, err
+			}
+			b, err := json.MarshalIndent(plan, "", "  ")
+			if err != nil {
+				return results, fmt.Errorf("error marshaling plan: %v", err)
+			}
+			if _, err := out.Write(append(b, '\n')); err != nil {
+				return results, fmt.Errorf("error writing to output: %v", err)
+			}
+			return results, nil
+		}
 		if target.HasChanges() {
 			fmt.Fprintf(out, "Must specify --yes to apply changes\n")
 		} else {
@@ -327,6 +403,9 @@ func RunUpdateCluster(f *util.Factory, clusterName string, out io.Writer, c *Upd
 				fmt.Fprintf(sb, "   aws cloudformation create-stack --capabilities CAPABILITY_NAMED_IAM --stack-name %s --template-body file://%s\n", cfName, cfPath)
 				fmt.Fprintf(sb, "\n")
 			}
+		} else if c.Target == cloudup.TargetResourceGraph {
+			fmt.Fprintf(sb, "\n")
+			fmt.Fprintf(sb, "Resource graph output has been placed into %s\n", c.OutDir)
 		} else if firstRun {
 			fmt.Fprintf(sb, "\n")
 			fmt.Fprintf(sb, "Cluster is starting.  It should be ready in a few minutes.\n")
@@ -374,6 +453,61 @@ func RunUpdateCluster(f *util.Factory, clusterName string, out io.Writer, c *Upd
 	return results, nil
 }
 
+// writeUpdatePlan saves the changes found by a dry run to a file, for later use with --plan
+func writeUpdatePlan(p string, target *fi.DryRunTarget, taskMap map[string]fi.Task) error {
+	plan, err := target.Plan(taskMap)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling plan: %v", err)
+	}
+	if err := ioutil.WriteFile(p, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing plan to %q: %v", p, err)
+	}
+	klog.Infof("Plan written to %s", p)
+	return nil
+}
+
+// verifyUpdatePlan computes the changes that applyCmd would make, using a dry run,
+// and returns an error if they differ from those in the plan saved in file p
+func verifyUpdatePlan(f *util.Factory, clusterName string, p string, applyCmd *cloudup.ApplyClusterCmd) error {
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return fmt.Errorf("error reading plan %q: %v", p, err)
+	}
+	saved := &fi.Plan{}
+	if err := json.Unmarshal(b, saved); err != nil {
+		return fmt.Errorf("error parsing plan %q: %v", p, err)
+	}
+
+	// ApplyClusterCmd completes the cluster spec in place, so the dry run uses its own copy
+	cluster, err := GetCluster(f, clusterName)
+	if err != nil {
+		return err
+	}
+
+	dryRunCmd := *applyCmd
+	dryRunCmd.Cluster = cluster
+	dryRunCmd.InstanceGroups = nil
+	dryRunCmd.DryRun = true
+	dryRunCmd.TargetName = cloudup.TargetDryRun
+	dryRunCmd.DryRunOutput = ioutil.Discard
+	if err := dryRunCmd.Run(); err != nil {
+		return err
+	}
+
+	live, err := dryRunCmd.Target.(*fi.DryRunTarget).Plan(dryRunCmd.TaskMap)
+	if err != nil {
+		return err
+	}
+	if diffs := saved.Diff(live); len(diffs) != 0 {
+		return fmt.Errorf("the changes to be made no longer match the plan in %s; review them and save a new plan with --out-plan:\n  %s", p, strings.Join(diffs, "\n  "))
+	}
+	return nil
+}
+
 func parseLifecycle(lifecycle string) (fi.Lifecycle, error) {
 	if v, ok := fi.LifecycleNameMap[lifecycle]; ok {
 		return v, nil
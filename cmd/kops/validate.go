@@ -33,13 +33,19 @@ var (
 	2. All k8s nodes are running and have "Ready" status.
 	3. Component status returns healthy for all components.
 	4. All pods in the kube-system namespace are running and healthy.
+	5. Any additional checks configured in the validation section of the cluster spec pass.
 	`))
 
 	validateExample = templates.Examples(i18n.T(`
 	# Validate a cluster.
 	# This command uses the currently selected kops cluster as
 	# set by the kubectl config.
-	kops validate cluster`))
+	kops validate cluster
+
+	# Wait up to 10 minutes for the cluster to validate, printing the result as JSON.
+	# The exit code is 0 if the cluster is valid, 2 if it is not,
+	# and 3 if it could not be reached to validate it.
+	kops validate cluster --wait 10m -o json`))
 
 	validateShort = i18n.T(`Validate a kops cluster.`)
 )
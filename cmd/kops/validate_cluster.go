@@ -23,13 +23,12 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/klog"
 	"k8s.io/kops/cmd/kops/util"
 	api "k8s.io/kops/pkg/apis/kops"
@@ -44,8 +43,28 @@ func init() {
 	}
 }
 
+const (
+	// validateExitInvalid is the exit code when the cluster was reached but did not validate
+	validateExitInvalid = 2
+	// validateExitUnreachable is the exit code when the cluster could not be reached to validate it
+	validateExitUnreachable = 3
+)
+
+// validatePollInterval is how often we retry validation when waiting for the cluster to validate
+var validatePollInterval = 10 * time.Second
+
+// clusterUnreachableError is returned when validation could not be performed against the cluster
+type clusterUnreachableError struct {
+	err error
+}
+
+func (e *clusterUnreachableError) Error() string {
+	return fmt.Sprintf("unexpected error during validation: %v", e.err)
+}
+
 type ValidateClusterOptions struct {
 	output string
+	wait   time.Duration
 }
 
 func (o *ValidateClusterOptions) InitDefaults() {
@@ -64,17 +83,22 @@ func NewCmdValidateCluster(f *util.Factory, out io.Writer) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			result, err := RunValidateCluster(f, cmd, args, os.Stdout, options)
 			if err != nil {
+				if _, ok := err.(*clusterUnreachableError); ok {
+					fmt.Fprintf(os.Stderr, "\n%v\n", err)
+					os.Exit(validateExitUnreachable)
+				}
 				exitWithError(err)
 			}
 			// We want the validate command to exit non-zero if validation found a problem,
 			// even if we didn't really hit an error during validation.
 			if len(result.Failures) != 0 {
-				os.Exit(2)
+				os.Exit(validateExitInvalid)
 			}
 		},
 	}
 
 	cmd.Flags().StringVarP(&options.output, "output", "o", options.output, "Output format. One of json|yaml|table.")
+	cmd.Flags().DurationVar(&options.wait, "wait", options.wait, "If set, wait up to this long for the cluster to validate, retrying validation until it succeeds")
 
 	return cmd
 }
@@ -115,22 +139,25 @@ func RunValidateCluster(f *util.Factory, cmd *cobra.Command, args []string, out
 	}
 
 	// TODO: Refactor into util.Factory
-	contextName := cluster.ObjectMeta.Name
-	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		clientcmd.NewDefaultClientConfigLoadingRules(),
-		&clientcmd.ConfigOverrides{CurrentContext: contextName}).ClientConfig()
+	k8sClient, err := buildKubernetesClient(cluster)
 	if err != nil {
-		return nil, fmt.Errorf("Cannot load kubecfg settings for %q: %v", contextName, err)
+		// Without a kubeconfig or client the cluster cannot be reached
+		return nil, &clusterUnreachableError{err: err}
 	}
 
-	k8sClient, err := kubernetes.NewForConfig(config)
+	result, err := validateClusterUntil(func() (*validation.ValidationCluster, error) {
+		return validation.ValidateCluster(cluster, list, k8sClient)
+	}, options.wait)
 	if err != nil {
-		return nil, fmt.Errorf("Cannot build kubernetes api client for %q: %v", contextName, err)
+		return nil, &clusterUnreachableError{err: err}
 	}
 
-	result, err := validation.ValidateCluster(cluster, list, k8sClient)
+	keyStore, err := clientSet.KeyStore(cluster)
 	if err != nil {
-		return nil, fmt.Errorf("unexpected error during validation: %v", err)
+		return nil, err
+	}
+	if err := result.ValidateCertificateExpiry(cluster, keyStore); err != nil {
+		return nil, fmt.Errorf("error checking certificate expiry: %v", err)
 	}
 
 	switch options.output {
@@ -164,6 +191,34 @@ func RunValidateCluster(f *util.Factory, cmd *cobra.Command, args []string, out
 	return result, nil
 }
 
+// validateClusterUntil runs validate until the cluster validates or wait has elapsed, returning the result
+// of the last attempt. If the last attempt could not validate the cluster at all, its error is returned.
+func validateClusterUntil(validate func() (*validation.ValidationCluster, error), wait time.Duration) (*validation.ValidationCluster, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		result, err := validate()
+		if err == nil && len(result.Failures) == 0 {
+			return result, nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return result, err
+		}
+
+		if err != nil {
+			klog.Infof("Unable to validate cluster, will retry: %v", err)
+		} else {
+			klog.Infof("Cluster did not pass validation (%d failures), will retry", len(result.Failures))
+		}
+
+		if remaining > validatePollInterval {
+			remaining = validatePollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
 func validateClusterOutputTable(result *validation.ValidationCluster, cluster *api.Cluster, instanceGroups []api.InstanceGroup, out io.Writer) error {
 	t := &tables.Table{}
 	t.AddColumn("NAME", func(c api.InstanceGroup) string {
@@ -229,6 +284,24 @@ func validateClusterOutputTable(result *validation.ValidationCluster, cluster *a
 		}
 	}
 
+	if len(result.Warnings) != 0 {
+		warningsTable := &tables.Table{}
+		warningsTable.AddColumn("KIND", func(e *validation.ValidationError) string {
+			return e.Kind
+		})
+		warningsTable.AddColumn("NAME", func(e *validation.ValidationError) string {
+			return e.Name
+		})
+		warningsTable.AddColumn("MESSAGE", func(e *validation.ValidationError) string {
+			return e.Message
+		})
+
+		fmt.Fprintln(out, "\nVALIDATION WARNINGS")
+		if err := warningsTable.Render(result.Warnings, out, "KIND", "NAME", "MESSAGE"); err != nil {
+			return fmt.Errorf("error rendering warnings table: %v", err)
+		}
+	}
+
 	if len(result.Failures) == 0 {
 		fmt.Fprintf(out, "\nYour cluster %s is ready\n", cluster.Name)
 	} else {
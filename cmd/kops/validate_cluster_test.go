@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s.io/kops/pkg/validation"
+)
+
+func TestValidateClusterUntil(t *testing.T) {
+	validatePollInterval = time.Millisecond
+
+	invalid := &validation.ValidationCluster{
+		Failures: []*validation.ValidationError{{Kind: "Node", Name: "node-1", Message: "node is not ready"}},
+	}
+	valid := &validation.ValidationCluster{}
+	unreachable := fmt.Errorf("connection refused")
+
+	grid := []struct {
+		description string
+		wait        time.Duration
+		attempts    []error
+		results     []*validation.ValidationCluster
+		expectCalls int
+		expectValid bool
+		expectError bool
+	}{
+		{
+			description: "valid",
+			results:     []*validation.ValidationCluster{valid},
+			attempts:    []error{nil},
+			expectCalls: 1,
+			expectValid: true,
+		},
+		{
+			description: "invalid without waiting",
+			results:     []*validation.ValidationCluster{invalid, valid},
+			attempts:    []error{nil, nil},
+			expectCalls: 1,
+		},
+		{
+			description: "unreachable without waiting",
+			results:     []*validation.ValidationCluster{nil, valid},
+			attempts:    []error{unreachable, nil},
+			expectCalls: 1,
+			expectError: true,
+		},
+		{
+			description: "waits until valid",
+			wait:        time.Minute,
+			results:     []*validation.ValidationCluster{nil, invalid, valid},
+			attempts:    []error{unreachable, nil, nil},
+			expectCalls: 3,
+			expectValid: true,
+		},
+	}
+
+	for _, g := range grid {
+		calls := 0
+		result, err := validateClusterUntil(func() (*validation.ValidationCluster, error) {
+			i := calls
+			calls++
+			return g.results[i], g.attempts[i]
+		}, g.wait)
+
+		if calls != g.expectCalls {
+			t.Errorf("%s: expected %d validation attempts, got %d", g.description, g.expectCalls, calls)
+		}
+		if g.expectError {
+			if err == nil {
+				t.Errorf("%s: expected an error", g.description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", g.description, err)
+			continue
+		}
+		if valid := len(result.Failures) == 0; valid != g.expectValid {
+			t.Errorf("%s: expected valid=%v, got %v", g.description, g.expectValid, valid)
+		}
+	}
+}
+
+func TestValidateClusterUntilTimesOut(t *testing.T) {
+	validatePollInterval = time.Millisecond
+
+	invalid := &validation.ValidationCluster{
+		Failures: []*validation.ValidationError{{Kind: "Node", Name: "node-1", Message: "node is not ready"}},
+	}
+
+	start := time.Now()
+	result, err := validateClusterUntil(func() (*validation.ValidationCluster, error) {
+		return invalid, nil
+	}, 20*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Failures) != 1 {
+		t.Errorf("expected the failures of the last attempt to be returned, got %v", result.Failures)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("expected to wait for 20ms, returned after %v", elapsed)
+	}
+}
@@ -32,6 +32,9 @@ import (
 const (
 	retryInterval = 30 * time.Second
 	procSelfExe   = "/proc/self/exe"
+
+	// exitCodeDriftFound is the exit code of a check which found that the node has drifted
+	exitCodeDriftFound = 2
 )
 
 func main() {
@@ -57,6 +60,11 @@ func main() {
 	target := "direct"
 	flag.StringVar(&target, "target", target, "Target - direct, cloudinit")
 
+	mode := nodeup.ModeApply
+	flag.StringVar(&mode, "mode", mode, "Mode - apply to configure the node, check to report drift from the configuration, reconcile to report drift and re-apply the tasks that are safe to re-apply, update to apply a changed in-place configuration")
+	var flagMetricsFile string
+	flag.StringVar(&flagMetricsFile, "metrics-file", "", "when checking for drift, write it to this file in the Prometheus text format")
+
 	installSystemdUnit := false
 	flag.BoolVar(&installSystemdUnit, "install-systemd-unit", installSystemdUnit, "If true, will install a systemd unit instead of running directly")
 
@@ -123,6 +131,8 @@ func main() {
 				Target:         target,
 				CacheDir:       flagCacheDir,
 				FSRoot:         flagRootFS,
+				MetricsFile:    flagMetricsFile,
+				Mode:           mode,
 				ModelDir:       models.NewAssetPath("nodeup"),
 			}
 			err = cmd.Run(os.Stdout)
@@ -130,6 +140,11 @@ func main() {
 				fmt.Printf("success")
 				os.Exit(0)
 			}
+			if err == nodeup.ErrDriftFound {
+				klog.Errorf("%v", err)
+				klog.Flush()
+				os.Exit(exitCodeDriftFound)
+			}
 		}
 
 		if retries == 0 {
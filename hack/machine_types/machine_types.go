@@ -195,6 +195,10 @@ func run() error {
 					machine.GPU = true
 				}
 
+				if strings.Contains(attributes["physicalProcessor"], "Graviton") {
+					machine.Arm64 = true
+				}
+
 				if attributes["ecu"] == "Variable" {
 					machine.Burstable = true
 					machine.ECU = t2CreditsPerHour[machine.Name] // This is actually credits * ECUs, but we'll add that later
@@ -296,6 +300,10 @@ func run() error {
 					output = output + "GPU: true,\n"
 				}
 
+				if m.Arm64 {
+					output = output + "Arm64: true,\n"
+				}
+
 				output = output + "},\n"
 			}
 		}
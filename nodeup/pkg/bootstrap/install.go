@@ -110,6 +110,32 @@ func (i *Installation) buildSystemdJob() *nodetasks.Service {
 	manifest.Set("Unit", "Description", "Run kops bootstrap (nodeup)")
 	manifest.Set("Unit", "Documentation", "https://github.com/kubernetes/kops")
 
+	if environment := NodeupEnvironment(); environment != "" {
+		manifest.Set("Service", "Environment", environment)
+	}
+
+	manifest.Set("Service", "EnvironmentFile", "/etc/environment")
+	manifest.Set("Service", "ExecStart", command)
+	manifest.Set("Service", "Type", "oneshot")
+
+	manifest.Set("Install", "WantedBy", "multi-user.target")
+
+	manifestString := manifest.Render()
+	klog.V(8).Infof("Built service manifest %q\n%s", serviceName, manifestString)
+
+	service := &nodetasks.Service{
+		Name:       serviceName,
+		Definition: fi.String(manifestString),
+	}
+
+	service.InitDefaults()
+
+	return service
+}
+
+// NodeupEnvironment returns the systemd Environment of a unit that runs nodeup, passing on the variables in the
+// current environment that nodeup needs, such as the credentials of the state store
+func NodeupEnvironment() string {
 	var buffer bytes.Buffer
 
 	if os.Getenv("AWS_REGION") != "" {
@@ -180,25 +206,5 @@ func (i *Installation) buildSystemdJob() *nodetasks.Service {
 		buffer.WriteString("\" ")
 	}
 
-	if buffer.String() != "" {
-		manifest.Set("Service", "Environment", buffer.String())
-	}
-
-	manifest.Set("Service", "EnvironmentFile", "/etc/environment")
-	manifest.Set("Service", "ExecStart", command)
-	manifest.Set("Service", "Type", "oneshot")
-
-	manifest.Set("Install", "WantedBy", "multi-user.target")
-
-	manifestString := manifest.Render()
-	klog.V(8).Infof("Built service manifest %q\n%s", serviceName, manifestString)
-
-	service := &nodetasks.Service{
-		Name:       serviceName,
-		Definition: fi.String(manifestString),
-	}
-
-	service.InitDefaults()
-
-	return service
+	return buffer.String()
 }
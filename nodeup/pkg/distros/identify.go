@@ -40,6 +40,10 @@ func FindDistribution(rootfs string) (Distribution, error) {
 				klog.Warningf("bionic is not fully supported nor tested for Kops and Kubernetes")
 				klog.Warningf("this should only be used for testing purposes.")
 				return DistributionBionic, nil
+			} else if line == "DISTRIB_CODENAME=focal" {
+				klog.Warningf("focal is not fully supported nor tested for Kops and Kubernetes")
+				klog.Warningf("this should only be used for testing purposes.")
+				return DistributionFocal, nil
 			}
 		}
 	} else if !os.IsNotExist(err) {
@@ -107,7 +111,7 @@ func FindDistribution(rootfs string) (Distribution, error) {
 				return DistributionContainerOS, nil
 			}
 			if strings.HasPrefix(line, "PRETTY_NAME=\"Amazon Linux 2") {
-				return DistributionCentos7, nil
+				return DistributionAmazonLinux2, nil
 			}
 		}
 		klog.Warningf("unhandled /etc/os-release info %q", string(osRelease))
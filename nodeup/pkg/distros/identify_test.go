@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package distros
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestFindDistribution(t *testing.T) {
+	grid := []struct {
+		files    map[string]string
+		expected Distribution
+	}{
+		{
+			files: map[string]string{
+				"etc/lsb-release":    "DISTRIB_ID=Ubuntu\nDISTRIB_RELEASE=18.04\nDISTRIB_CODENAME=bionic\n",
+				"etc/debian_version": "buster/sid\n",
+			},
+			expected: DistributionBionic,
+		},
+		{
+			files: map[string]string{
+				"etc/lsb-release":    "DISTRIB_ID=Ubuntu\nDISTRIB_RELEASE=20.04\nDISTRIB_CODENAME=focal\n",
+				"etc/debian_version": "bullseye/sid\n",
+			},
+			expected: DistributionFocal,
+		},
+		{
+			files: map[string]string{
+				"etc/os-release": "NAME=\"Amazon Linux\"\nVERSION=\"2\"\nID=\"amzn\"\nPRETTY_NAME=\"Amazon Linux 2\"\n",
+			},
+			expected: DistributionAmazonLinux2,
+		},
+		{
+			files: map[string]string{
+				"etc/redhat-release": "CentOS Linux release 7.6.1810 (Core)\n",
+			},
+			expected: DistributionCentos7,
+		},
+	}
+
+	for _, g := range grid {
+		rootfs, err := ioutil.TempDir("", "rootfs")
+		if err != nil {
+			t.Fatalf("error creating temp dir: %v", err)
+		}
+		defer os.RemoveAll(rootfs)
+
+		for f, contents := range g.files {
+			p := path.Join(rootfs, f)
+			if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+				t.Fatalf("error creating directory for %q: %v", p, err)
+			}
+			if err := ioutil.WriteFile(p, []byte(contents), 0644); err != nil {
+				t.Fatalf("error writing %q: %v", p, err)
+			}
+		}
+
+		actual, err := FindDistribution(rootfs)
+		if err != nil {
+			t.Errorf("unexpected error identifying %q: %v", g.expected, err)
+			continue
+		}
+		if actual != g.expected {
+			t.Errorf("unexpected distribution: expected %q, got %q", g.expected, actual)
+		}
+	}
+}
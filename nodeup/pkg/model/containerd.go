@@ -0,0 +1,237 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/klog"
+	"k8s.io/kops/nodeup/pkg/distros"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/systemd"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/nodeup/nodetasks"
+)
+
+const (
+	// containerdSocket is the path of the CRI socket of containerd
+	containerdSocket = "/run/containerd/containerd.sock"
+
+	// containerdConfigFile is the path of the containerd configuration
+	containerdConfigFile = "/etc/containerd/config.toml"
+)
+
+// ContainerdBuilder installs containerd, when it is the container runtime of the instance group
+type ContainerdBuilder struct {
+	*NodeupModelContext
+}
+
+var _ fi.ModelBuilder = &ContainerdBuilder{}
+
+// containerdBinaries are the binaries installed from the containerd asset, and where they are installed
+var containerdBinaries = []struct {
+	Name     string
+	Dir      string
+	Optional bool
+}{
+	{Name: "containerd", Dir: "/usr/local/bin"},
+	{Name: "containerd-shim", Dir: "/usr/local/bin"},
+	{Name: "ctr", Dir: "/usr/local/bin"},
+	{Name: "crictl", Dir: "/usr/local/bin", Optional: true},
+	{Name: "runc", Dir: "/usr/local/sbin"},
+}
+
+// Build is responsible for installing and configuring containerd
+func (b *ContainerdBuilder) Build(c *fi.ModelBuilderContext) error {
+	if b.ContainerRuntime() != kops.ContainerRuntimeContainerd {
+		return nil
+	}
+
+	switch b.Distribution {
+	case distros.DistributionCoreOS, distros.DistributionFlatcar, distros.DistributionContainerOS:
+		return fmt.Errorf("containerd is not supported on distribution %q", b.Distribution)
+	}
+
+	for _, binary := range containerdBinaries {
+		asset, err := b.Assets.Find(binary.Name, "")
+		if err != nil {
+			return fmt.Errorf("error trying to locate asset %q: %v", binary.Name, err)
+		}
+		if asset == nil {
+			if binary.Optional {
+				klog.Warningf("unable to locate asset %q; won't install it", binary.Name)
+				continue
+			}
+			return fmt.Errorf("unable to locate asset %q", binary.Name)
+		}
+
+		c.AddTask(&nodetasks.File{
+			Path:     filepath.Join(binary.Dir, binary.Name),
+			Contents: asset,
+			Type:     nodetasks.FileType_File,
+			Mode:     s("0755"),
+		})
+	}
+
+	config := b.buildContainerdConfig()
+	if b.Cluster.Spec.Containerd != nil && b.Cluster.Spec.Containerd.ConfigOverride != nil {
+		config = *b.Cluster.Spec.Containerd.ConfigOverride
+	}
+	c.AddTask(&nodetasks.File{
+		Path:     containerdConfigFile,
+		Contents: fi.NewStringResource(config),
+		Type:     nodetasks.FileType_File,
+	})
+
+	c.AddTask(&nodetasks.File{
+		Path:     "/etc/crictl.yaml",
+		Contents: fi.NewStringResource("runtime-endpoint: unix://" + containerdSocket + "\n"),
+		Type:     nodetasks.FileType_File,
+	})
+
+	c.AddTask(b.buildSystemdService())
+
+	return nil
+}
+
+// buildContainerdConfig returns the config.toml for containerd
+func (b *ContainerdBuilder) buildContainerdConfig() string {
+	var lines []string
+	lines = append(lines, "root = \"/var/lib/containerd\"")
+	lines = append(lines, "state = \"/run/containerd\"")
+	lines = append(lines, "oom_score = -999")
+	lines = append(lines, "")
+	lines = append(lines, "[grpc]")
+	lines = append(lines, fmt.Sprintf("  address = %q", containerdSocket))
+	lines = append(lines, "")
+	lines = append(lines, "[plugins.cri]")
+	if image := b.sandboxImage(); image != "" {
+		lines = append(lines, fmt.Sprintf("  sandbox_image = %q", image))
+	}
+	lines = append(lines, "")
+	lines = append(lines, "[plugins.cri.containerd]")
+	lines = append(lines, "  snapshotter = \"overlayfs\"")
+	lines = append(lines, "")
+	lines = append(lines, "[plugins.cri.cni]")
+	lines = append(lines, fmt.Sprintf("  bin_dir = %q", b.CNIBinDir()))
+	lines = append(lines, fmt.Sprintf("  conf_dir = %q", b.CNIConfDir()))
+
+	mirrors := b.registryMirrors()
+	var registries []string
+	for registry := range mirrors {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+	for _, registry := range registries {
+		var endpoints []string
+		for _, endpoint := range mirrors[registry] {
+			endpoints = append(endpoints, fmt.Sprintf("%q", endpoint))
+		}
+		lines = append(lines, "")
+		lines = append(lines, fmt.Sprintf("[plugins.cri.registry.mirrors.%q]", registry))
+		lines = append(lines, fmt.Sprintf("  endpoint = [%s]", strings.Join(endpoints, ", ")))
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// sandboxImage returns the pause image of the kubelet, or "" to use the containerd default
+func (b *ContainerdBuilder) sandboxImage() string {
+	kubelet := b.Cluster.Spec.Kubelet
+	if b.IsMaster && b.Cluster.Spec.MasterKubelet != nil {
+		kubelet = b.Cluster.Spec.MasterKubelet
+	}
+	if kubelet == nil {
+		return ""
+	}
+	return kubelet.PodInfraContainerImage
+}
+
+// registryMirrors returns the mirrors of each registry, from the container registry or proxy of the cluster assets
+func (b *ContainerdBuilder) registryMirrors() map[string][]string {
+	mirrors := make(map[string][]string)
+
+	assets := b.Cluster.Spec.Assets
+	if assets == nil {
+		return mirrors
+	}
+
+	if assets.ContainerProxy != nil && *assets.ContainerProxy != "" {
+		// The proxy serves the images of docker hub and of the other registries under the same paths
+		endpoint := registryEndpoint(*assets.ContainerProxy)
+		mirrors["docker.io"] = []string{endpoint}
+		mirrors["k8s.gcr.io"] = []string{endpoint}
+	}
+
+	if assets.ContainerRegistry != nil && *assets.ContainerRegistry != "" {
+		// The images of k8s.gcr.io are copied to the root of the registry
+		mirrors["k8s.gcr.io"] = []string{registryEndpoint(*assets.ContainerRegistry)}
+	}
+
+	return mirrors
+}
+
+// registryEndpoint returns the endpoint url of a registry, which is https unless a scheme is specified
+func registryEndpoint(registry string) string {
+	registry = strings.TrimRight(registry, "/")
+	if strings.Contains(registry, "://") {
+		return registry
+	}
+	return "https://" + registry
+}
+
+// buildSystemdService returns the systemd unit of containerd
+func (b *ContainerdBuilder) buildSystemdService() *nodetasks.Service {
+	manifest := &systemd.Manifest{}
+	manifest.Set("Unit", "Description", "containerd container runtime")
+	manifest.Set("Unit", "Documentation", "https://containerd.io")
+	manifest.Set("Unit", "After", "network.target")
+
+	command := "/usr/local/bin/containerd --config " + containerdConfigFile
+	if b.Cluster.Spec.Containerd != nil && b.Cluster.Spec.Containerd.LogLevel != nil {
+		command += " --log-level " + *b.Cluster.Spec.Containerd.LogLevel
+	}
+
+	manifest.Set("Service", "ExecStartPre", "-/sbin/modprobe overlay")
+	manifest.Set("Service", "ExecStart", command)
+	manifest.Set("Service", "Restart", "always")
+	manifest.Set("Service", "RestartSec", "5")
+	manifest.Set("Service", "Delegate", "yes")
+	manifest.Set("Service", "KillMode", "process")
+	manifest.Set("Service", "OOMScoreAdjust", "-999")
+	manifest.Set("Service", "LimitNOFILE", "1048576")
+	manifest.Set("Service", "LimitNPROC", "infinity")
+	manifest.Set("Service", "LimitCORE", "infinity")
+	manifest.Set("Service", "TasksMax", "infinity")
+
+	manifest.Set("Install", "WantedBy", "multi-user.target")
+
+	manifestString := manifest.Render()
+	klog.V(8).Infof("Built service manifest %q\n%s", "containerd", manifestString)
+
+	service := &nodetasks.Service{
+		Name:       "containerd.service",
+		Definition: s(manifestString),
+	}
+
+	service.InitDefaults()
+
+	return service
+}
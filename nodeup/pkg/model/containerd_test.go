@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+func TestContainerdBuilder_Config(t *testing.T) {
+	grid := []struct {
+		Assets   *kops.Assets
+		Expected []string
+	}{
+		{
+			Assets: nil,
+			Expected: []string{
+				"sandbox_image = \"k8s.gcr.io/pause-amd64:3.0\"",
+				"bin_dir = \"/opt/cni/bin/\"",
+			},
+		},
+		{
+			Assets: &kops.Assets{ContainerProxy: fi.String("proxy.example.com/")},
+			Expected: []string{
+				"[plugins.cri.registry.mirrors.\"docker.io\"]\n  endpoint = [\"https://proxy.example.com\"]",
+				"[plugins.cri.registry.mirrors.\"k8s.gcr.io\"]\n  endpoint = [\"https://proxy.example.com\"]",
+			},
+		},
+		{
+			Assets: &kops.Assets{ContainerRegistry: fi.String("http://registry.example.com:5000")},
+			Expected: []string{
+				"[plugins.cri.registry.mirrors.\"k8s.gcr.io\"]\n  endpoint = [\"http://registry.example.com:5000\"]",
+			},
+		},
+	}
+
+	for _, g := range grid {
+		b := &ContainerdBuilder{
+			NodeupModelContext: &NodeupModelContext{
+				Cluster: &kops.Cluster{
+					Spec: kops.ClusterSpec{
+						Assets:  g.Assets,
+						Kubelet: &kops.KubeletConfigSpec{PodInfraContainerImage: "k8s.gcr.io/pause-amd64:3.0"},
+					},
+				},
+			},
+		}
+
+		config := b.buildContainerdConfig()
+		for _, expected := range g.Expected {
+			if !strings.Contains(config, expected) {
+				t.Errorf("expected %q in config:\n%s", expected, config)
+			}
+		}
+		if g.Assets == nil && strings.Contains(config, "mirrors") {
+			t.Errorf("unexpected registry mirrors in config:\n%s", config)
+		}
+	}
+}
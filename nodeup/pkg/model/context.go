@@ -24,6 +24,7 @@ import (
 
 	"k8s.io/kops/nodeup/pkg/distros"
 	"k8s.io/kops/pkg/apis/kops"
+	apimodel "k8s.io/kops/pkg/apis/kops/model"
 	"k8s.io/kops/pkg/apis/kops/util"
 	"k8s.io/kops/pkg/apis/nodeup"
 	"k8s.io/kops/pkg/kubeconfig"
@@ -48,6 +49,9 @@ type NodeupModelContext struct {
 	NodeupConfig  *nodeup.Config
 	SecretStore   fi.SecretStore
 
+	// FSRoot is the root of the host filesystem, under which the files already on the host are read
+	FSRoot string
+
 	// IsMaster is true if the InstanceGroup has a role of master (populated by Init)
 	IsMaster bool
 
@@ -349,6 +353,11 @@ func (c *NodeupModelContext) UsesCNI() bool {
 	return true
 }
 
+// ContainerRuntime returns the container runtime of the instance group
+func (c *NodeupModelContext) ContainerRuntime() string {
+	return apimodel.ContainerRuntime(c.Cluster, c.InstanceGroup)
+}
+
 // UseNodeAuthorization checks if have a node authorization policy
 func (c *NodeupModelContext) UseNodeAuthorization() bool {
 	return c.Cluster.Spec.NodeAuthorization != nil
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -86,6 +87,9 @@ type dockerVersion struct {
 // We don't change this with each version of kops, we expect newer versions of kops to populate the field.
 const DefaultDockerVersion = "1.12.3"
 
+// dockerdPath is where the image must install dockerd on architectures we have no docker package for
+const dockerdPath = "/usr/bin/dockerd"
+
 var dockerVersions = []dockerVersion{
 	// 1.11.2 - Jessie
 	{
@@ -111,11 +115,11 @@ var dockerVersions = []dockerVersion{
 		Dependencies:  []string{"bridge-utils", "libapparmor1", "libltdl7", "perl"},
 	},
 
-	// 1.11.2 - Centos / Rhel7 (two packages)
+	// 1.11.2 - Centos / Rhel7 / Amazon Linux 2 (two packages)
 	{
 		DockerVersion: "1.11.2",
 		Name:          "docker-engine",
-		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7},
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "1.11.2",
 		Source:        "https://yum.dockerproject.org/repo/main/centos/7/Packages/docker-engine-1.11.2-1.el7.centos.x86_64.rpm",
@@ -154,11 +158,11 @@ var dockerVersions = []dockerVersion{
 		Dependencies:  []string{"bridge-utils", "libapparmor1", "libltdl7", "perl"},
 	},
 
-	// 1.12.1 - Centos / Rhel7 (two packages)
+	// 1.12.1 - Centos / Rhel7 / Amazon Linux 2 (two packages)
 	{
 		DockerVersion: "1.12.1",
 		Name:          "docker-engine",
-		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7},
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "1.12.1",
 		Source:        "https://yum.dockerproject.org/repo/main/centos/7/Packages/docker-engine-1.12.1-1.el7.centos.x86_64.rpm",
@@ -213,11 +217,11 @@ var dockerVersions = []dockerVersion{
 		Dependencies:  []string{"bridge-utils", "libapparmor1", "libltdl7", "perl"},
 	},
 
-	// 1.12.3 - Centos / Rhel7 (two packages)
+	// 1.12.3 - Centos / Rhel7 / Amazon Linux 2 (two packages)
 	{
 		DockerVersion: "1.12.3",
 		Name:          "docker-engine",
-		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7},
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "1.12.3",
 		Source:        "https://yum.dockerproject.org/repo/main/centos/7/Packages/docker-engine-1.12.3-1.el7.centos.x86_64.rpm",
@@ -287,11 +291,11 @@ var dockerVersions = []dockerVersion{
 		// Depends: iptables, init-system-helpers (>= 1.18~), lsb-base (>= 4.1+Debian11ubuntu7), libapparmor1 (>= 2.6~devel), libc6 (>= 2.17), libdevmapper1.02.1 (>= 2:1.02.97), libltdl7 (>= 2.4.6), libseccomp2 (>= 2.1.0), libsystemd0
 	},
 
-	// 1.12.6 - Centos / Rhel7 (two packages)
+	// 1.12.6 - Centos / Rhel7 / Amazon Linux 2 (two packages)
 	{
 		DockerVersion: "1.12.6",
 		Name:          "docker-engine",
-		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7},
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "1.12.6",
 		Source:        "https://yum.dockerproject.org/repo/main/centos/7/Packages/docker-engine-1.12.6-1.el7.centos.x86_64.rpm",
@@ -361,11 +365,11 @@ var dockerVersions = []dockerVersion{
 		// Depends: iptables, init-system-helpers (>= 1.18~), lsb-base (>= 4.1+Debian11ubuntu7), libapparmor1 (>= 2.6~devel), libc6 (>= 2.17), libdevmapper1.02.1 (>= 2:1.02.97), libltdl7 (>= 2.4.6), libseccomp2 (>= 2.1.0), libsystemd0
 	},
 
-	// 1.13.1 - Centos / Rhel7 (two packages)
+	// 1.13.1 - Centos / Rhel7 / Amazon Linux 2 (two packages)
 	{
 		DockerVersion: "1.13.1",
 		Name:          "docker-engine",
-		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7},
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "1.13.1",
 		Source:        "https://yum.dockerproject.org/repo/main/centos/7/Packages/docker-engine-1.13.1-1.el7.centos.x86_64.rpm",
@@ -434,11 +438,11 @@ var dockerVersions = []dockerVersion{
 		MarkImmutable: []string{"/usr/bin/docker-runc"},
 	},
 
-	// 17.03.2 - Ubuntu Bionic via binary download (no packages available)
+	// 17.03.2 - Ubuntu Bionic / Focal via binary download (no packages available)
 	{
 		DockerVersion: "17.03.2",
 		PlainBinary:   true,
-		Distros:       []distros.Distribution{distros.DistributionBionic},
+		Distros:       []distros.Distribution{distros.DistributionBionic, distros.DistributionFocal},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Source:        "http://download.docker.com/linux/static/stable/x86_64/docker-17.03.2-ce.tgz",
 		Hash:          "141716ae046016a1792ce232a0f4c8eed7fe37d1",
@@ -446,11 +450,11 @@ var dockerVersions = []dockerVersion{
 		MarkImmutable: []string{"/usr/bin/docker-runc"},
 	},
 
-	// 17.03.2 - Centos / Rhel7 (two packages)
+	// 17.03.2 - Centos / Rhel7 / Amazon Linux 2 (two packages)
 	{
 		DockerVersion: "17.03.2",
 		Name:          "docker-ce",
-		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7},
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "17.03.2.ce",
 		Source:        "https://download.docker.com/linux/centos/7/x86_64/stable/Packages/docker-ce-17.03.2.ce-1.el7.centos.x86_64.rpm",
@@ -545,11 +549,11 @@ var dockerVersions = []dockerVersion{
 		//Recommends: aufs-tools, ca-certificates, cgroupfs-mount | cgroup-lite, git, pigz, xz-utils, apparmor
 	},
 
-	// 17.09.0 - Centos / Rhel7 (two packages)
+	// 17.09.0 - Centos / Rhel7 / Amazon Linux 2 (two packages)
 	{
 		DockerVersion: "17.09.0",
 		Name:          "docker-ce",
-		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7},
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "17.09.0.ce",
 		Source:        "https://download.docker.com/linux/centos/7/x86_64/stable/Packages/docker-ce-17.09.0.ce-1.el7.centos.x86_64.rpm",
@@ -564,11 +568,11 @@ var dockerVersions = []dockerVersion{
 		Dependencies: []string{"libtool-ltdl", "libseccomp", "libcgroup", "policycoreutils-python"},
 	},
 
-	// 18.03.1 - Bionic
+	// 18.03.1 - Bionic / Focal
 	{
 		DockerVersion: "18.03.1",
 		Name:          "docker-ce",
-		Distros:       []distros.Distribution{distros.DistributionBionic},
+		Distros:       []distros.Distribution{distros.DistributionBionic, distros.DistributionFocal},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "18.03.1~ce~3-0~ubuntu",
 		Source:        "https://download.docker.com/linux/ubuntu/dists/bionic/pool/stable/amd64/docker-ce_18.03.1~ce~3-0~ubuntu_amd64.deb",
@@ -578,11 +582,11 @@ var dockerVersions = []dockerVersion{
 		//Recommends: aufs-tools, ca-certificates, cgroupfs-mount | cgroup-lite, git, xz-utils, apparmor
 	},
 
-	// 18.06.2 - Bionic
+	// 18.06.2 - Bionic / Focal
 	{
 		DockerVersion: "18.06.2",
 		Name:          "docker-ce",
-		Distros:       []distros.Distribution{distros.DistributionBionic},
+		Distros:       []distros.Distribution{distros.DistributionBionic, distros.DistributionFocal},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "18.06.2~ce~3-0~ubuntu",
 		Source:        "https://download.docker.com/linux/ubuntu/dists/bionic/pool/stable/amd64/docker-ce_18.06.2~ce~3-0~ubuntu_amd64.deb",
@@ -642,11 +646,11 @@ var dockerVersions = []dockerVersion{
 		Dependencies:  []string{"bridge-utils", "libapparmor1", "libltdl7", "perl"},
 	},
 
-	// 18.06.1 - CentOS / Rhel7 (two packages)
+	// 18.06.1 - CentOS / Rhel7 / Amazon Linux 2 (two packages)
 	{
 		DockerVersion: "18.06.1",
 		Name:          "docker-ce",
-		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7},
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "18.06.1.ce",
 		Source:        "https://download.docker.com/linux/centos/7/x86_64/stable/Packages/docker-ce-18.06.1.ce-3.el7.x86_64.rpm",
@@ -685,11 +689,11 @@ var dockerVersions = []dockerVersion{
 		Dependencies: []string{"bridge-utils", "libapparmor1", "libltdl7"},
 	},
 
-	// 18.06.2 - CentOS / Rhel7 (two packages)
+	// 18.06.2 - CentOS / Rhel7 / Amazon Linux 2 (two packages)
 	{
 		DockerVersion: "18.06.2",
 		Name:          "container-selinux",
-		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7},
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "2.68",
 		Source:        "http://mirror.centos.org/centos/7/extras/x86_64/Packages/container-selinux-2.68-1.el7.noarch.rpm",
@@ -699,7 +703,7 @@ var dockerVersions = []dockerVersion{
 	{
 		DockerVersion: "18.06.2",
 		Name:          "docker-ce",
-		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7},
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "18.06.2.ce",
 		Source:        "https://download.docker.com/linux/centos/7/x86_64/stable/Packages/docker-ce-18.06.2.ce-3.el7.x86_64.rpm",
@@ -709,11 +713,11 @@ var dockerVersions = []dockerVersion{
 
 	// 18.06.3 (contains fix for CVE-2019-5736)
 
-	// 18.06.3 - Bionic
+	// 18.06.3 - Bionic / Focal
 	{
 		DockerVersion: "18.06.3",
 		Name:          "docker-ce",
-		Distros:       []distros.Distribution{distros.DistributionBionic},
+		Distros:       []distros.Distribution{distros.DistributionBionic, distros.DistributionFocal},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "18.06.3~ce~3-0~ubuntu",
 		Source:        "https://download.docker.com/linux/ubuntu/dists/bionic/pool/stable/amd64/docker-ce_18.06.3~ce~3-0~ubuntu_amd64.deb",
@@ -748,11 +752,11 @@ var dockerVersions = []dockerVersion{
 		Dependencies:  []string{"bridge-utils", "libapparmor1", "libltdl7", "perl"},
 	},
 
-	// 18.06.3 - CentOS / Rhel7 (two packages)
+	// 18.06.3 - CentOS / Rhel7 / Amazon Linux 2 (two packages)
 	{
 		DockerVersion: "18.06.3",
 		Name:          "docker-ce",
-		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7},
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
 		Architectures: []Architecture{ArchitectureAmd64},
 		Version:       "18.06.3.ce",
 		Source:        "https://download.docker.com/linux/centos/7/x86_64/stable/Packages/docker-ce-18.06.3.ce-3.el7.x86_64.rpm",
@@ -767,6 +771,26 @@ var dockerVersions = []dockerVersion{
 		Dependencies: []string{"libtool-ltdl", "libseccomp", "libcgroup", "policycoreutils-python"},
 	},
 
+	// 18.06.3 - arm64 via binary download (no packages available)
+	{
+		DockerVersion: "18.06.3",
+		PlainBinary:   true,
+		Distros:       []distros.Distribution{distros.DistributionXenial, distros.DistributionBionic, distros.DistributionFocal, distros.DistributionDebian9, distros.DistributionDebian10},
+		Architectures: []Architecture{ArchitectureArm64},
+		Source:        "https://download.docker.com/linux/static/stable/aarch64/docker-18.06.3-ce.tgz",
+		// TODO: Pin the hash with VERIFY_HASHES=1 go test ./nodeup/pkg/model
+		Dependencies: []string{"bridge-utils", "iptables", "libapparmor1", "libltdl7", "perl"},
+	},
+	{
+		DockerVersion: "18.06.3",
+		PlainBinary:   true,
+		Distros:       []distros.Distribution{distros.DistributionRhel7, distros.DistributionCentos7, distros.DistributionAmazonLinux2},
+		Architectures: []Architecture{ArchitectureArm64},
+		Source:        "https://download.docker.com/linux/static/stable/aarch64/docker-18.06.3-ce.tgz",
+		// TODO: Pin the hash with VERIFY_HASHES=1 go test ./nodeup/pkg/model
+		Dependencies: []string{"libtool-ltdl", "libseccomp", "libcgroup"},
+	},
+
 	// TIP: When adding the next version, copy the previous
 	// version, string replace the version, run `VERIFY_HASHES=1
 	// go test ./nodeup/pkg/model` (you might want to temporarily
@@ -815,6 +839,10 @@ func (b *DockerBuilder) dockerVersion() string {
 
 // Build is responsible for configuring the docker daemon
 func (b *DockerBuilder) Build(c *fi.ModelBuilderContext) error {
+	if b.ContainerRuntime() != kops.ContainerRuntimeDocker {
+		klog.Infof("Container runtime is %q; won't install Docker", b.ContainerRuntime())
+		return nil
+	}
 
 	// @check: neither coreos or containeros need provision docker.service, just the docker daemon options
 	switch b.Distribution {
@@ -918,7 +946,15 @@ func (b *DockerBuilder) Build(c *fi.ModelBuilderContext) error {
 		}
 
 		if count == 0 {
-			klog.Warningf("Did not find docker package for %s %s %s", b.Distribution, b.Architecture, dockerVersion)
+			// Docker is only packaged for amd64 (and arm), so on other architectures it must otherwise be part of the image
+			if b.Architecture != ArchitectureAmd64 && b.Architecture != ArchitectureArm {
+				if _, err := os.Stat(filepath.Join(b.FSRoot, dockerdPath)); err != nil {
+					return fmt.Errorf("no docker package for %s %s %s, and docker is not installed in the image (%s: %v)", b.Distribution, b.Architecture, dockerVersion, dockerdPath, err)
+				}
+				klog.Infof("Did not find docker package for %s %s %s; using the docker installed in the image", b.Distribution, b.Architecture, dockerVersion)
+			} else {
+				klog.Warningf("Did not find docker package for %s %s %s", b.Distribution, b.Architecture, dockerVersion)
+			}
 		}
 	}
 
@@ -20,16 +20,20 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"k8s.io/kops/nodeup/pkg/distros"
 	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/flagbuilder"
 	"k8s.io/kops/pkg/testutils"
 	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/nodeup/nodetasks"
 )
 
 func TestDockerPackageNames(t *testing.T) {
@@ -115,15 +119,23 @@ func verifyPackageHash(t *testing.T, u string, hash string) {
 }
 
 func TestDockerBuilder_Simple(t *testing.T) {
-	runDockerBuilderTest(t, "simple")
+	runDockerBuilderTest(t, "simple", distros.DistributionXenial)
 }
 
 func TestDockerBuilder_1_12_1(t *testing.T) {
-	runDockerBuilderTest(t, "docker_1.12.1")
+	runDockerBuilderTest(t, "docker_1.12.1", distros.DistributionXenial)
 }
 
 func TestDockerBuilder_LogFlags(t *testing.T) {
-	runDockerBuilderTest(t, "logflags")
+	runDockerBuilderTest(t, "logflags", distros.DistributionXenial)
+}
+
+func TestDockerBuilder_AmazonLinux2(t *testing.T) {
+	runDockerBuilderTest(t, "amazonlinux2", distros.DistributionAmazonLinux2)
+}
+
+func TestDockerBuilder_Focal(t *testing.T) {
+	runDockerBuilderTest(t, "focal", distros.DistributionFocal)
 }
 
 func TestDockerBuilder_BuildFlags(t *testing.T) {
@@ -183,7 +195,7 @@ func TestDockerBuilder_BuildFlags(t *testing.T) {
 	}
 }
 
-func runDockerBuilderTest(t *testing.T, key string) {
+func runDockerBuilderTest(t *testing.T, key string, distribution distros.Distribution) {
 	basedir := path.Join("tests/dockerbuilder/", key)
 
 	nodeUpModelContext, err := BuildNodeupModelContext(basedir)
@@ -191,6 +203,7 @@ func runDockerBuilderTest(t *testing.T, key string) {
 		t.Fatalf("error parsing cluster yaml %q: %v", basedir, err)
 		return
 	}
+	nodeUpModelContext.Distribution = distribution
 
 	context := &fi.ModelBuilderContext{
 		Tasks: make(map[string]fi.Task),
@@ -206,3 +219,62 @@ func runDockerBuilderTest(t *testing.T, key string) {
 
 	testutils.ValidateTasks(t, basedir, context)
 }
+
+func TestDockerBuilder_Arm64(t *testing.T) {
+	fsRoot, err := ioutil.TempDir("", "dockerbuilder")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(fsRoot)
+
+	build := func(dockerVersion string) (*fi.ModelBuilderContext, error) {
+		nodeUpModelContext, err := BuildNodeupModelContext("tests/dockerbuilder/simple")
+		if err != nil {
+			t.Fatalf("error parsing cluster yaml: %v", err)
+		}
+		nodeUpModelContext.Distribution = distros.DistributionXenial
+		nodeUpModelContext.Architecture = ArchitectureArm64
+		nodeUpModelContext.FSRoot = fsRoot
+		nodeUpModelContext.Cluster.Spec.Docker = &kops.DockerConfig{Version: fi.String(dockerVersion)}
+
+		context := &fi.ModelBuilderContext{
+			Tasks: make(map[string]fi.Task),
+		}
+		builder := DockerBuilder{NodeupModelContext: nodeUpModelContext}
+		return context, builder.Build(context)
+	}
+
+	// Docker publishes arm64 static binaries, which we install like those for amd64
+	context, err := build("18.06.3")
+	if err != nil {
+		t.Fatalf("unexpected error building docker 18.06.3 on arm64: %v", err)
+	}
+	archive, ok := context.Tasks["Archive/docker"].(*nodetasks.Archive)
+	if !ok {
+		t.Fatalf("expected docker 18.06.3 to be installed from an archive on arm64, got tasks %v", context.Tasks)
+	}
+	if !strings.Contains(archive.Source, "/aarch64/") {
+		t.Errorf("expected the arm64 archive of docker, got %q", archive.Source)
+	}
+
+	// There is no arm64 build of older versions, so the image must include docker
+	if _, err := build("1.12.3"); err == nil {
+		t.Errorf("expected an error when docker is not installed in an arm64 image")
+	}
+
+	if err := os.MkdirAll(filepath.Join(fsRoot, filepath.Dir(dockerdPath)), 0755); err != nil {
+		t.Fatalf("error creating directory: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(fsRoot, dockerdPath), nil, 0755); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	context, err = build("1.12.3")
+	if err != nil {
+		t.Fatalf("unexpected error when docker is installed in an arm64 image: %v", err)
+	}
+	for name := range context.Tasks {
+		if strings.HasPrefix(name, "Package/") || strings.HasPrefix(name, "Archive/") {
+			t.Errorf("unexpected docker package task %q on arm64", name)
+		}
+	}
+}
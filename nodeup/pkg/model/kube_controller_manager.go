@@ -104,6 +104,10 @@ func (b *KubeControllerManagerBuilder) Build(c *fi.ModelBuilderContext) error {
 
 // useCertificateSigner checks to see if we need to use the certificate signer for the controller manager
 func (b *KubeControllerManagerBuilder) useCertificateSigner() bool {
+	// The private key of an external CA is not available to sign with
+	if b.Cluster.Spec.ExternalCA != nil {
+		return false
+	}
 	// For now, we enable this on 1.6 and later
 	return b.IsKubernetesGTE("1.6")
 }
@@ -108,6 +108,8 @@ func (b *KubectlBuilder) findKubeconfigUser() (*fi.User, *fi.Group, error) {
 		users = []string{"admin", "root"}
 	case distros.DistributionCentos7:
 		users = []string{"centos"}
+	case distros.DistributionAmazonLinux2:
+		users = []string{"ec2-user"}
 	default:
 		klog.Warningf("Unknown distro; won't write kubeconfig to homedir %s", b.Distribution)
 		return nil, nil, nil
@@ -259,7 +259,11 @@ func (b *KubeletBuilder) buildSystemdService() *nodetasks.Service {
 	manifest := &systemd.Manifest{}
 	manifest.Set("Unit", "Description", "Kubernetes Kubelet Server")
 	manifest.Set("Unit", "Documentation", "https://github.com/kubernetes/kubernetes")
-	manifest.Set("Unit", "After", "docker.service")
+	if b.ContainerRuntime() == kops.ContainerRuntimeContainerd {
+		manifest.Set("Unit", "After", "containerd.service")
+	} else {
+		manifest.Set("Unit", "After", "docker.service")
+	}
 
 	if b.Distribution == distros.DistributionCoreOS {
 		// We add /opt/kubernetes/bin for our utilities (socat, conntrack)
@@ -552,6 +556,21 @@ func (b *KubeletBuilder) buildKubeletConfigSpec() (*kops.KubeletConfigSpec, erro
 		reflectutils.JsonMergeStruct(c, b.InstanceGroup.Spec.Kubelet)
 	}
 
+	// The default pause image is built for a single architecture
+	if b.Architecture == ArchitectureArm64 {
+		c.PodInfraContainerImage = strings.Replace(c.PodInfraContainerImage, "/pause-amd64:", "/pause-arm64:", 1)
+	}
+
+	// Point the kubelet at the CRI socket of containerd
+	if b.ContainerRuntime() == kops.ContainerRuntimeContainerd {
+		if c.ContainerRuntime == "" {
+			c.ContainerRuntime = "remote"
+		}
+		if c.ContainerRuntimeEndpoint == "" {
+			c.ContainerRuntimeEndpoint = "unix://" + containerdSocket
+		}
+	}
+
 	if b.InstanceGroup.Spec.Role == kops.InstanceGroupRoleMaster {
 		if c.NodeLabels == nil {
 			c.NodeLabels = make(map[string]string)
@@ -609,12 +628,17 @@ func (b *KubeletBuilder) buildMasterKubeletKubeconfig() (*nodetasks.File, error)
 		return nil, fmt.Errorf("unable to find CA certificate %q in keystore", fi.CertificateId_CA)
 	}
 
-	caKey, err := b.KeyStore.FindPrivateKey(fi.CertificateId_CA)
-	if err != nil {
-		return nil, fmt.Errorf("error fetching CA certificate from keystore: %v", err)
-	}
-	if caKey == nil {
-		return nil, fmt.Errorf("unable to find CA key %q in keystore", fi.CertificateId_CA)
+	externalCA := fi.UsesExternalCA(b.Cluster, fi.CertificateId_CA)
+
+	var caKey *pki.PrivateKey
+	if !externalCA {
+		caKey, err = b.KeyStore.FindPrivateKey(fi.CertificateId_CA)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching CA certificate from keystore: %v", err)
+		}
+		if caKey == nil {
+			return nil, fmt.Errorf("unable to find CA key %q in keystore", fi.CertificateId_CA)
+		}
 	}
 
 	privateKey, err := pki.GeneratePrivateKey()
@@ -648,7 +672,12 @@ func (b *KubeletBuilder) buildMasterKubeletKubeconfig() (*nodetasks.File, error)
 	t := time.Now().UnixNano()
 	template.SerialNumber = pki.BuildPKISerial(t)
 
-	certificate, err := pki.SignNewCertificate(privateKey, template, caCert.Certificate, caKey)
+	var certificate *pki.Certificate
+	if externalCA {
+		certificate, err = fi.IssueExternalCert(b.Cluster, caCert, privateKey, template)
+	} else {
+		certificate, err = pki.SignNewCertificate(privateKey, template, caCert.Certificate, caKey)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error signing certificate for master kubelet: %v", err)
 	}
@@ -671,10 +700,13 @@ func (b *KubeletBuilder) buildMasterKubeletKubeconfig() (*nodetasks.File, error)
 		return nil, err
 	}
 
+	// The certificate is signed afresh on every run, so we keep an existing
+	// kubeconfig rather than rewriting it (and reporting it as drift) each time.
 	return &nodetasks.File{
-		Path:     b.KubeletKubeConfig(),
-		Contents: fi.NewStringResource(content),
-		Type:     nodetasks.FileType_File,
-		Mode:     s("600"),
+		Path:        b.KubeletKubeConfig(),
+		Contents:    fi.NewStringResource(content),
+		Type:        nodetasks.FileType_File,
+		Mode:        s("600"),
+		IfNotExists: true,
 	}, nil
 }
@@ -17,11 +17,16 @@ limitations under the License.
 package model
 
 import (
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
+	"math/big"
 	"testing"
 
 	"k8s.io/kops/nodeup/pkg/distros"
 	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/client/clientset_generated/clientset/fake"
+	"k8s.io/kops/pkg/pki"
 	"k8s.io/kops/pkg/testutils"
 	"k8s.io/kops/upup/pkg/fi"
 )
@@ -60,6 +65,39 @@ func Test_InstanceGroupKubeletMerge(t *testing.T) {
 	}
 }
 
+func Test_KubeletPauseImageArchitecture(t *testing.T) {
+	for arch, expected := range map[Architecture]string{
+		ArchitectureAmd64: "k8s.gcr.io/pause-amd64:3.0",
+		ArchitectureArm64: "k8s.gcr.io/pause-arm64:3.0",
+	} {
+		cluster := &kops.Cluster{}
+		cluster.Spec.Kubelet = &kops.KubeletConfigSpec{PodInfraContainerImage: "k8s.gcr.io/pause-amd64:3.0"}
+		cluster.Spec.KubernetesVersion = "1.15.0"
+
+		instanceGroup := &kops.InstanceGroup{}
+		instanceGroup.Spec.Role = kops.InstanceGroupRoleNode
+
+		b := &KubeletBuilder{
+			&NodeupModelContext{
+				Architecture:  arch,
+				Cluster:       cluster,
+				InstanceGroup: instanceGroup,
+			},
+		}
+		if err := b.Init(); err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := b.buildKubeletConfigSpec()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.PodInfraContainerImage != expected {
+			t.Errorf("expected pause image %q for %s, got %q", expected, arch, c.PodInfraContainerImage)
+		}
+	}
+}
+
 func TestTaintsAppliedAfter160(t *testing.T) {
 	tests := []struct {
 		version           string
@@ -133,6 +171,70 @@ func TestTaintsAppliedAfter160(t *testing.T) {
 	}
 }
 
+func Test_MasterKubeletKubeconfigIfNotExists(t *testing.T) {
+	cluster := &kops.Cluster{}
+	cluster.Spec.KubernetesVersion = "1.15.0"
+	cluster.Spec.MasterKubelet = &kops.KubeletConfigSpec{HostnameOverride: "master-1"}
+	cluster.Spec.Kubelet = &kops.KubeletConfigSpec{}
+
+	instanceGroup := &kops.InstanceGroup{}
+	instanceGroup.Spec.Role = kops.InstanceGroupRoleMaster
+
+	keystore := fi.NewClientsetCAStore(cluster, fake.NewSimpleClientset().Kops(), "default")
+	privateKey, err := pki.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating private key: %v", err)
+	}
+	caCert, err := pki.SignNewCertificate(privateKey, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "kubernetes"},
+		SerialNumber:          big.NewInt(1),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("error signing CA certificate: %v", err)
+	}
+	if err := keystore.StoreKeypair(fi.CertificateId_CA, caCert, privateKey); err != nil {
+		t.Fatalf("error storing CA keypair: %v", err)
+	}
+
+	b := &KubeletBuilder{
+		&NodeupModelContext{
+			Cluster:       cluster,
+			InstanceGroup: instanceGroup,
+			KeyStore:      keystore,
+		},
+	}
+	if err := b.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The kubelet certificate is signed afresh each time, so the file must
+	// not be rewritten (or reported as drift) once it exists.
+	var contents []string
+	for i := 0; i < 2; i++ {
+		file, err := b.buildMasterKubeletKubeconfig()
+		if err != nil {
+			t.Fatalf("error building master kubelet kubeconfig: %v", err)
+		}
+		if file.Path != b.KubeletKubeConfig() {
+			t.Errorf("unexpected path %q", file.Path)
+		}
+		if !file.IfNotExists {
+			t.Errorf("expected master kubelet kubeconfig to be IfNotExists")
+		}
+		data, err := fi.ResourceAsString(file.Contents)
+		if err != nil {
+			t.Fatalf("error reading kubeconfig: %v", err)
+		}
+		contents = append(contents, data)
+	}
+	if contents[0] == contents[1] {
+		t.Errorf("expected each build to sign a new certificate")
+	}
+}
+
 func stringSlicesEqual(exp, other []string) bool {
 	if exp == nil && other != nil {
 		return false
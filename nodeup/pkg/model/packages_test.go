@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"path"
+	"testing"
+
+	"k8s.io/kops/nodeup/pkg/distros"
+	"k8s.io/kops/pkg/testutils"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+func TestPackagesBuilder_AmazonLinux2(t *testing.T) {
+	runPackagesBuilderTest(t, "amazonlinux2", distros.DistributionAmazonLinux2)
+}
+
+func TestPackagesBuilder_Focal(t *testing.T) {
+	runPackagesBuilderTest(t, "focal", distros.DistributionFocal)
+}
+
+func runPackagesBuilderTest(t *testing.T, key string, distribution distros.Distribution) {
+	basedir := path.Join("tests/packagesbuilder/", key)
+
+	nodeUpModelContext, err := BuildNodeupModelContext(basedir)
+	if err != nil {
+		t.Fatalf("error parsing cluster yaml %q: %v", basedir, err)
+		return
+	}
+	nodeUpModelContext.Distribution = distribution
+
+	context := &fi.ModelBuilderContext{
+		Tasks: make(map[string]fi.Task),
+	}
+
+	builder := PackagesBuilder{NodeupModelContext: nodeUpModelContext}
+
+	err = builder.Build(context)
+	if err != nil {
+		t.Fatalf("error from PackagesBuilder Build: %v", err)
+		return
+	}
+
+	testutils.ValidateTasks(t, basedir, context)
+}
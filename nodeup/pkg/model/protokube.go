@@ -28,6 +28,7 @@ import (
 	"k8s.io/kops/pkg/apis/kops/util"
 	"k8s.io/kops/pkg/assets"
 	"k8s.io/kops/pkg/dns"
+	"k8s.io/kops/pkg/etcdbackup"
 	"k8s.io/kops/pkg/flagbuilder"
 	"k8s.io/kops/pkg/systemd"
 	"k8s.io/kops/upup/pkg/fi"
@@ -135,6 +136,10 @@ func (t *ProtokubeBuilder) buildSystemdService() (*nodetasks.Service, error) {
 		"/usr/bin/protokube",
 	}...)
 
+	if t.ContainerRuntime() == kops.ContainerRuntimeContainerd {
+		dockerArgs = t.protokubeContainerdArgs()
+	}
+
 	protokubeCommand := strings.Join(dockerArgs, " ") + " " + protokubeFlagsArgs
 
 	manifest := &systemd.Manifest{}
@@ -162,6 +167,47 @@ func (t *ProtokubeBuilder) buildSystemdService() (*nodetasks.Service, error) {
 	return service, nil
 }
 
+// protokubeContainerdArgs returns the command to run protokube with containerd, which is the equivalent of the docker command
+func (t *ProtokubeBuilder) protokubeContainerdArgs() []string {
+	args := []string{
+		"/usr/local/bin/ctr", "--namespace", "k8s.io", "run", "--rm",
+		"--mount", "type=bind,src=/,dst=/rootfs,options=rbind:rw",
+		"--mount", "type=bind,src=/var/run/dbus,dst=/var/run/dbus,options=rbind:rw",
+		"--mount", "type=bind,src=/run/systemd,dst=/run/systemd,options=rbind:rw",
+	}
+
+	if t.IsMaster {
+		args = append(args, []string{
+			"--mount", "type=bind,src=" + t.KubectlPath() + ",dst=/opt/kops/bin,options=rbind:ro",
+			"--env", "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin:/opt/kops/bin",
+		}...)
+	}
+
+	args = append(args, []string{
+		"--net-host",
+		"--privileged",
+		"--env", "KUBECONFIG=/rootfs/var/lib/kops/kubeconfig",
+		t.ProtokubeEnvironmentVariables(),
+		qualifiedImageName(t.ProtokubeImageName()),
+		"protokube",
+		"/usr/bin/protokube",
+	}...)
+
+	return args
+}
+
+// qualifiedImageName returns the fully qualified name of an image, as containerd does not default to docker hub
+func qualifiedImageName(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return image
+	}
+	if len(parts) == 1 {
+		return "docker.io/library/" + image
+	}
+	return "docker.io/" + image
+}
+
 // ProtokubeImageName returns the docker image for protokube
 func (t *ProtokubeBuilder) ProtokubeImageName() string {
 	name := ""
@@ -190,6 +236,9 @@ func (t *ProtokubeBuilder) ProtokubeImagePullCommand() string {
 		return "/bin/true"
 	}
 
+	if t.ContainerRuntime() == kops.ContainerRuntimeContainerd {
+		return "/usr/local/bin/ctr --namespace k8s.io images pull " + qualifiedImageName(sources[0])
+	}
 	return "/usr/bin/docker pull " + sources[0]
 }
 
@@ -206,6 +255,7 @@ type ProtokubeFlags struct {
 	DNSServer                 *string  `json:"dns-server,omitempty" flag:"dns-server"`
 	EtcdBackupImage           string   `json:"etcd-backup-image,omitempty" flag:"etcd-backup-image"`
 	EtcdBackupStore           string   `json:"etcd-backup-store,omitempty" flag:"etcd-backup-store"`
+	EtcdBackupRetention       []string `json:"etcd-backup-retention,omitempty" flag:"etcd-backup-retention,repeat"`
 	EtcdImage                 *string  `json:"etcd-image,omitempty" flag:"etcd-image"`
 	EtcdLeaderElectionTimeout *string  `json:"etcd-election-timeout,omitempty" flag:"etcd-election-timeout"`
 	EtcdHearbeatInterval      *string  `json:"etcd-heartbeat-interval,omitempty" flag:"etcd-heartbeat-interval"`
@@ -391,12 +441,47 @@ func (t *ProtokubeBuilder) ProtokubeFlags(k8sVersion semver.Version) (*Protokube
 		}
 
 		f.RemoveDNSNames = strings.Join(names, ",")
+
+		// etcd-manager takes the backups, but it is protokube on the masters that prunes them
+		if t.IsMaster {
+			f.EtcdBackupRetention = t.etcdBackupRetention()
+		}
 	}
 
 	return f, nil
 }
 
-// ProtokubeEnvironmentVariables generates the environments variables for docker
+// etcdBackupRetention returns the retention policies of the etcd backup stores this master prunes.  Only the
+// master in the instance group of the first etcd member (by name) prunes a backup store, so that the masters
+// don't all delete the same backups at the same time.
+func (t *ProtokubeBuilder) etcdBackupRetention() []string {
+	if t.InstanceGroup == nil {
+		return nil
+	}
+
+	var retention []string
+	for _, c := range t.Cluster.Spec.EtcdClusters {
+		if c.Backups == nil || c.Backups.Retention == nil || c.Backups.BackupStore == "" {
+			continue
+		}
+
+		var pruner *kops.EtcdMemberSpec
+		for _, m := range c.Members {
+			if pruner == nil || m.Name < pruner.Name {
+				pruner = m
+			}
+		}
+		if pruner == nil || fi.StringValue(pruner.InstanceGroup) != t.InstanceGroup.ObjectMeta.Name {
+			continue
+		}
+
+		policy := etcdbackup.RetentionPolicyFromSpec(c.Backups.Retention)
+		retention = append(retention, policy.String()+"@"+c.Backups.BackupStore)
+	}
+	return retention
+}
+
+// ProtokubeEnvironmentVariables generates the environments variables for the container runtime
 func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 	var buffer bytes.Buffer
 
@@ -405,7 +490,7 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 	// Passin gossip dns connection limit
 	if os.Getenv("GOSSIP_DNS_CONN_LIMIT") != "" {
 		buffer.WriteString(" ")
-		buffer.WriteString("-e 'GOSSIP_DNS_CONN_LIMIT=")
+		buffer.WriteString("--env 'GOSSIP_DNS_CONN_LIMIT=")
 		buffer.WriteString(os.Getenv("GOSSIP_DNS_CONN_LIMIT"))
 		buffer.WriteString("'")
 		buffer.WriteString(" ")
@@ -414,7 +499,7 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 	// Pass in required credentials when using user-defined s3 endpoint
 	if os.Getenv("AWS_REGION") != "" {
 		buffer.WriteString(" ")
-		buffer.WriteString("-e 'AWS_REGION=")
+		buffer.WriteString("--env 'AWS_REGION=")
 		buffer.WriteString(os.Getenv("AWS_REGION"))
 		buffer.WriteString("'")
 		buffer.WriteString(" ")
@@ -422,19 +507,19 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 
 	if os.Getenv("S3_ENDPOINT") != "" {
 		buffer.WriteString(" ")
-		buffer.WriteString("-e S3_ENDPOINT=")
+		buffer.WriteString("--env S3_ENDPOINT=")
 		buffer.WriteString("'")
 		buffer.WriteString(os.Getenv("S3_ENDPOINT"))
 		buffer.WriteString("'")
-		buffer.WriteString(" -e S3_REGION=")
+		buffer.WriteString(" --env S3_REGION=")
 		buffer.WriteString("'")
 		buffer.WriteString(os.Getenv("S3_REGION"))
 		buffer.WriteString("'")
-		buffer.WriteString(" -e S3_ACCESS_KEY_ID=")
+		buffer.WriteString(" --env S3_ACCESS_KEY_ID=")
 		buffer.WriteString("'")
 		buffer.WriteString(os.Getenv("S3_ACCESS_KEY_ID"))
 		buffer.WriteString("'")
-		buffer.WriteString(" -e S3_SECRET_ACCESS_KEY=")
+		buffer.WriteString(" --env S3_SECRET_ACCESS_KEY=")
 		buffer.WriteString("'")
 		buffer.WriteString(os.Getenv("S3_SECRET_ACCESS_KEY"))
 		buffer.WriteString("'")
@@ -451,7 +536,7 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 			"OS_AUTH_URL",
 			"OS_REGION_NAME",
 		} {
-			buffer.WriteString(" -e '")
+			buffer.WriteString(" --env '")
 			buffer.WriteString(envVar)
 			buffer.WriteString("=")
 			buffer.WriteString(os.Getenv(envVar))
@@ -461,7 +546,7 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 
 	if kops.CloudProviderID(t.Cluster.Spec.CloudProvider) == kops.CloudProviderDO && os.Getenv("DIGITALOCEAN_ACCESS_TOKEN") != "" {
 		buffer.WriteString(" ")
-		buffer.WriteString("-e 'DIGITALOCEAN_ACCESS_TOKEN=")
+		buffer.WriteString("--env 'DIGITALOCEAN_ACCESS_TOKEN=")
 		buffer.WriteString(os.Getenv("DIGITALOCEAN_ACCESS_TOKEN"))
 		buffer.WriteString("'")
 		buffer.WriteString(" ")
@@ -469,7 +554,7 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 
 	if os.Getenv("OSS_REGION") != "" {
 		buffer.WriteString(" ")
-		buffer.WriteString("-e 'OSS_REGION=")
+		buffer.WriteString("--env 'OSS_REGION=")
 		buffer.WriteString(os.Getenv("OSS_REGION"))
 		buffer.WriteString("'")
 		buffer.WriteString(" ")
@@ -477,10 +562,10 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 
 	if os.Getenv("ALIYUN_ACCESS_KEY_ID") != "" {
 		buffer.WriteString(" ")
-		buffer.WriteString("-e 'ALIYUN_ACCESS_KEY_ID=")
+		buffer.WriteString("--env 'ALIYUN_ACCESS_KEY_ID=")
 		buffer.WriteString(os.Getenv("ALIYUN_ACCESS_KEY_ID"))
 		buffer.WriteString("'")
-		buffer.WriteString(" -e 'ALIYUN_ACCESS_KEY_SECRET=")
+		buffer.WriteString(" --env 'ALIYUN_ACCESS_KEY_SECRET=")
 		buffer.WriteString(os.Getenv("ALIYUN_ACCESS_KEY_SECRET"))
 		buffer.WriteString("'")
 		buffer.WriteString(" ")
@@ -493,7 +578,7 @@ func (t *ProtokubeBuilder) ProtokubeEnvironmentVariables() string {
 
 func (t *ProtokubeBuilder) writeProxyEnvVars(buffer *bytes.Buffer) {
 	for _, envVar := range proxy.GetProxyEnvVars(t.Cluster.Spec.EgressProxy) {
-		buffer.WriteString(" -e ")
+		buffer.WriteString(" --env ")
 		buffer.WriteString(envVar.Name)
 		buffer.WriteString("=")
 		buffer.WriteString(envVar.Value)
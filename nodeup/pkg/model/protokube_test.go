@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+func TestProtokubeBuilder_etcdBackupRetention(t *testing.T) {
+	cluster := &kops.Cluster{}
+	cluster.Spec.EtcdClusters = []*kops.EtcdClusterSpec{
+		{
+			Name: "main",
+			Members: []*kops.EtcdMemberSpec{
+				{Name: "b", InstanceGroup: fi.String("master-b")},
+				{Name: "a", InstanceGroup: fi.String("master-a")},
+				{Name: "c", InstanceGroup: fi.String("master-c")},
+			},
+			Backups: &kops.EtcdBackupSpec{
+				BackupStore: "s3://bucket/backups/etcd/main",
+				Retention:   &kops.EtcdBackupRetentionSpec{Hourly: fi.Int32(24), Daily: fi.Int32(7)},
+			},
+		},
+		{
+			Name: "events",
+			Members: []*kops.EtcdMemberSpec{
+				{Name: "a", InstanceGroup: fi.String("master-a")},
+			},
+			Backups: &kops.EtcdBackupSpec{
+				BackupStore: "s3://bucket/backups/etcd/events",
+			},
+		},
+	}
+
+	grid := []struct {
+		instanceGroup string
+		expected      []string
+	}{
+		{
+			instanceGroup: "master-a",
+			expected:      []string{"hourly=24,daily=7,weekly=0@s3://bucket/backups/etcd/main"},
+		},
+		{
+			instanceGroup: "master-b",
+		},
+		{
+			instanceGroup: "master-c",
+		},
+	}
+
+	for _, g := range grid {
+		t.Run(g.instanceGroup, func(t *testing.T) {
+			ig := &kops.InstanceGroup{}
+			ig.ObjectMeta.Name = g.instanceGroup
+			ig.Spec.Role = kops.InstanceGroupRoleMaster
+
+			b := &ProtokubeBuilder{
+				NodeupModelContext: &NodeupModelContext{Cluster: cluster, InstanceGroup: ig},
+			}
+			actual := b.etcdBackupRetention()
+			if !reflect.DeepEqual(actual, g.expected) {
+				t.Errorf("unexpected retention: expected %v, actual %v", g.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+	"k8s.io/kops/nodeup/pkg/bootstrap"
+	"k8s.io/kops/pkg/systemd"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/nodeup/nodetasks"
+)
+
+const (
+	// nodeReconciliationServiceName is the unit that runs nodeup to check the node for drift
+	nodeReconciliationServiceName = "kops-reconciliation.service"
+	// nodeUpdateServiceName is the unit that runs nodeup to apply a changed in-place configuration
+	nodeUpdateServiceName = "kops-update.service"
+	// defaultNodeReconciliationInterval is the time between checks, if not set in the cluster spec
+	defaultNodeReconciliationInterval = time.Hour
+	// defaultUpdateCheckInterval is the time between checks for a changed in-place configuration, if not set in the cluster spec
+	defaultUpdateCheckInterval = time.Minute
+)
+
+// NodeReconciliationBuilder installs a systemd timer that periodically runs nodeup to check the node for drift from
+// its configuration, and optionally to re-apply it. If in-place updates are enabled, it also installs a timer that
+// runs nodeup to apply changes to the configuration published in the state store.
+type NodeReconciliationBuilder struct {
+	*NodeupModelContext
+
+	// Nodeup is the path of the nodeup binary
+	Nodeup string
+	// ConfigLocation is the location of the nodeup configuration
+	ConfigLocation string
+}
+
+var _ fi.ModelBuilder = &NodeReconciliationBuilder{}
+
+// Build is responsible for adding the reconciliation and update services and timers
+func (b *NodeReconciliationBuilder) Build(c *fi.ModelBuilderContext) error {
+	spec := b.Cluster.Spec.NodeReconciliation
+	if spec == nil {
+		return nil
+	}
+
+	{
+		interval := defaultNodeReconciliationInterval
+		if spec.Interval != nil {
+			interval = spec.Interval.Duration
+		}
+
+		mode := "check"
+		if fi.BoolValue(spec.Reconcile) {
+			mode = "reconcile"
+		}
+		command := []string{b.Nodeup, "--conf=" + b.ConfigLocation, "--mode=" + mode, "--retries=0"}
+		if spec.MetricsFile != "" {
+			command = append(command, "--metrics-file="+spec.MetricsFile)
+		}
+
+		c.AddTask(b.buildSystemdService(nodeReconciliationServiceName, "Check the node for drift from its kops configuration (nodeup)", command))
+		c.AddTask(b.buildSystemdTimer(nodeReconciliationServiceName, "Periodic check of the node for drift from its kops configuration", interval))
+	}
+
+	if fi.BoolValue(spec.InPlaceUpdates) {
+		interval := defaultUpdateCheckInterval
+		if spec.UpdateCheckInterval != nil {
+			interval = spec.UpdateCheckInterval.Duration
+		}
+
+		command := []string{b.Nodeup, "--conf=" + b.ConfigLocation, "--mode=update", "--retries=0"}
+
+		c.AddTask(b.buildSystemdService(nodeUpdateServiceName, "Apply changes to the kops configuration in place (nodeup)", command))
+		c.AddTask(b.buildSystemdTimer(nodeUpdateServiceName, "Periodic check for changes to the kops configuration", interval))
+	}
+
+	return nil
+}
+
+// buildSystemdService builds a unit run by a timer. It is not started by nodeup, so that it never runs
+// while nodeup is configuring the node.
+func (b *NodeReconciliationBuilder) buildSystemdService(name string, description string, command []string) *nodetasks.Service {
+	manifest := &systemd.Manifest{}
+	manifest.Set("Unit", "Description", description)
+	manifest.Set("Unit", "Documentation", "https://github.com/kubernetes/kops")
+	manifest.Set("Unit", "After", "kops-configuration.service")
+
+	if environment := bootstrap.NodeupEnvironment(); environment != "" {
+		manifest.Set("Service", "Environment", environment)
+	}
+
+	manifest.Set("Service", "EnvironmentFile", "/etc/environment")
+	manifest.Set("Service", "ExecStart", strings.Join(command, " "))
+	manifest.Set("Service", "Type", "oneshot")
+
+	manifestString := manifest.Render()
+	klog.V(8).Infof("Built service manifest %q\n%s", name, manifestString)
+
+	service := &nodetasks.Service{
+		Name:       name,
+		Definition: s(manifestString),
+		Running:    fi.Bool(false),
+	}
+
+	service.InitDefaults()
+
+	return service
+}
+
+// buildSystemdTimer builds the timer that runs the service at the interval
+func (b *NodeReconciliationBuilder) buildSystemdTimer(serviceName string, description string, interval time.Duration) *nodetasks.Service {
+	unit := &systemd.Manifest{}
+	unit.Set("Unit", "Description", description)
+	unit.Set("Timer", "OnActiveSec", fmt.Sprintf("%ds", int64(interval.Seconds())))
+	unit.Set("Timer", "OnUnitActiveSec", fmt.Sprintf("%ds", int64(interval.Seconds())))
+
+	service := &nodetasks.Service{
+		Name:       strings.TrimSuffix(serviceName, ".service") + ".timer",
+		Definition: s(unit.Render()),
+	}
+
+	service.InitDefaults()
+
+	return service
+}
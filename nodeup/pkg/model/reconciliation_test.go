@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/nodeup/nodetasks"
+)
+
+func TestNodeReconciliationBuilder(t *testing.T) {
+	grid := []struct {
+		Spec     *kops.NodeReconciliationSpec
+		Expected map[string][]string
+	}{
+		{
+			Spec: nil,
+		},
+		{
+			Spec: &kops.NodeReconciliationSpec{},
+			Expected: map[string][]string{
+				"kops-reconciliation.service": {
+					"ExecStart=/var/cache/kubernetes-install/nodeup --conf=/var/cache/kubernetes-install/kube_env.yaml --mode=check --retries=0\n",
+					"Type=oneshot\n",
+				},
+				"kops-reconciliation.timer": {
+					"OnActiveSec=3600s\n",
+					"OnUnitActiveSec=3600s\n",
+				},
+			},
+		},
+		{
+			Spec: &kops.NodeReconciliationSpec{
+				Interval:    &metav1.Duration{Duration: 15 * time.Minute},
+				Reconcile:   fi.Bool(true),
+				MetricsFile: "/var/lib/node_exporter/textfile/kops_nodeup.prom",
+			},
+			Expected: map[string][]string{
+				"kops-reconciliation.service": {
+					"ExecStart=/var/cache/kubernetes-install/nodeup --conf=/var/cache/kubernetes-install/kube_env.yaml --mode=reconcile --retries=0 --metrics-file=/var/lib/node_exporter/textfile/kops_nodeup.prom\n",
+				},
+				"kops-reconciliation.timer": {
+					"OnActiveSec=900s\n",
+					"OnUnitActiveSec=900s\n",
+				},
+			},
+		},
+		{
+			Spec: &kops.NodeReconciliationSpec{
+				InPlaceUpdates: fi.Bool(true),
+			},
+			Expected: map[string][]string{
+				"kops-reconciliation.service": {
+					"--mode=check",
+				},
+				"kops-reconciliation.timer": {
+					"OnActiveSec=3600s\n",
+				},
+				"kops-update.service": {
+					"ExecStart=/var/cache/kubernetes-install/nodeup --conf=/var/cache/kubernetes-install/kube_env.yaml --mode=update --retries=0\n",
+					"Type=oneshot\n",
+				},
+				"kops-update.timer": {
+					"OnActiveSec=60s\n",
+					"OnUnitActiveSec=60s\n",
+				},
+			},
+		},
+		{
+			Spec: &kops.NodeReconciliationSpec{
+				InPlaceUpdates:      fi.Bool(true),
+				UpdateCheckInterval: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+			Expected: map[string][]string{
+				"kops-reconciliation.service": {},
+				"kops-reconciliation.timer":   {},
+				"kops-update.service":         {},
+				"kops-update.timer": {
+					"OnActiveSec=300s\n",
+					"OnUnitActiveSec=300s\n",
+				},
+			},
+		},
+	}
+
+	for _, g := range grid {
+		b := &NodeReconciliationBuilder{
+			NodeupModelContext: &NodeupModelContext{
+				Cluster: &kops.Cluster{
+					Spec: kops.ClusterSpec{NodeReconciliation: g.Spec},
+				},
+			},
+			Nodeup:         "/var/cache/kubernetes-install/nodeup",
+			ConfigLocation: "/var/cache/kubernetes-install/kube_env.yaml",
+		}
+
+		c := &fi.ModelBuilderContext{
+			Tasks: make(map[string]fi.Task),
+		}
+		if err := b.Build(c); err != nil {
+			t.Fatalf("unexpected error from Build(): %v", err)
+		}
+
+		services := make(map[string]*nodetasks.Service)
+		for _, task := range c.Tasks {
+			if service, ok := task.(*nodetasks.Service); ok {
+				services[service.Name] = service
+			}
+		}
+		if len(services) != len(c.Tasks) || len(services) != len(g.Expected) {
+			t.Errorf("expected %d services, got tasks %v", len(g.Expected), c.Tasks)
+			continue
+		}
+
+		for name, expected := range g.Expected {
+			service := services[name]
+			if service == nil {
+				t.Errorf("expected service %q", name)
+				continue
+			}
+			definition := fi.StringValue(service.Definition)
+			for _, s := range expected {
+				if !strings.Contains(definition, s) {
+					t.Errorf("expected %q in %s:\n%s", s, name, definition)
+				}
+			}
+		}
+
+		// The services must only be started by their timers, never while nodeup is configuring the node
+		for _, name := range []string{"kops-reconciliation.service", "kops-update.service"} {
+			if service := services[name]; service != nil && fi.BoolValue(service.Running) {
+				t.Errorf("%s should not be started by nodeup", name)
+			}
+		}
+	}
+}
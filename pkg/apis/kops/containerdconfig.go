@@ -0,0 +1,34 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kops
+
+const (
+	// ContainerRuntimeDocker is the docker container runtime, which is the default
+	ContainerRuntimeDocker = "docker"
+	// ContainerRuntimeContainerd is the containerd container runtime
+	ContainerRuntimeContainerd = "containerd"
+)
+
+// ContainerdConfig is the configuration for containerd
+type ContainerdConfig struct {
+	// Version is the version of containerd to install
+	Version *string `json:"version,omitempty"`
+	// LogLevel is the logging level of containerd: trace, debug, info, warn, error, fatal or panic
+	LogLevel *string `json:"logLevel,omitempty"`
+	// ConfigOverride replaces the config.toml generated by kops
+	ConfigOverride *string `json:"configOverride,omitempty"`
+}
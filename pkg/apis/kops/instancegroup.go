@@ -133,6 +133,8 @@ type InstanceGroupSpec struct {
 	Tenancy string `json:"tenancy,omitempty"`
 	// Kubelet overrides kubelet config from the ClusterSpec
 	Kubelet *KubeletConfigSpec `json:"kubelet,omitempty"`
+	// ContainerRuntime overrides the container runtime of the cluster for this instance group: docker or containerd
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
 	// Taints indicates the kubernetes taints for nodes in this group
 	Taints []string `json:"taints,omitempty"`
 	// MixedInstancesPolicy defined a optional backing of an AWS ASG by a EC2 Fleet (AWS Only)
@@ -151,6 +153,8 @@ type InstanceGroupSpec struct {
 	SecurityGroupOverride *string `json:"securityGroupOverride,omitempty"`
 	// InstanceProtection makes new instances in an autoscaling group protected from scale in
 	InstanceProtection *bool `json:"instanceProtection,omitempty"`
+	// RollingUpdate defines the rolling-update behavior, overriding the cluster-wide settings
+	RollingUpdate *RollingUpdate `json:"rollingUpdate,omitempty"`
 }
 
 const (
@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+// NodeConfigHashAnnotation is the annotation on which nodeup reports the hash of the in-place configuration applied to the node
+const NodeConfigHashAnnotation = "kops.k8s.io/nodeup-config-hash"
+
+// InPlaceUpdates returns true if changes to the kubelet flags, file assets and hooks are applied by nodeup to the
+// running nodes of the cluster, rather than by replacing the instances
+func InPlaceUpdates(c *kops.Cluster) bool {
+	r := c.Spec.NodeReconciliation
+	return r != nil && r.InPlaceUpdates != nil && *r.InPlaceUpdates
+}
+
+// inPlaceConfig holds the settings of an instance group that can be updated in place
+type inPlaceConfig struct {
+	Kubelet       *kops.KubeletConfigSpec `json:"kubelet,omitempty"`
+	MasterKubelet *kops.KubeletConfigSpec `json:"masterKubelet,omitempty"`
+	Hooks         []kops.HookSpec         `json:"hooks,omitempty"`
+	FileAssets    []kops.FileAssetSpec    `json:"fileAssets,omitempty"`
+	IGKubelet     *kops.KubeletConfigSpec `json:"igKubelet,omitempty"`
+	IGHooks       []kops.HookSpec         `json:"igHooks,omitempty"`
+	IGFileAssets  []kops.FileAssetSpec    `json:"igFileAssets,omitempty"`
+}
+
+// InPlaceConfigHash returns a hash of the settings of the instance group that can be updated in place:
+// the kubelet flags, and the file assets and hooks for its role. Nodes report the hash of the settings they have applied,
+// so comparing it with the hash computed from the state store shows whether they have converged.
+func InPlaceConfigHash(c *kops.Cluster, ig *kops.InstanceGroup) (string, error) {
+	role := ig.Spec.Role
+
+	config := &inPlaceConfig{
+		Kubelet:      c.Spec.Kubelet,
+		Hooks:        RelevantHooks(c.Spec.Hooks, role),
+		FileAssets:   RelevantFileAssets(c.Spec.FileAssets, role),
+		IGKubelet:    ig.Spec.Kubelet,
+		IGHooks:      RelevantHooks(ig.Spec.Hooks, role),
+		IGFileAssets: RelevantFileAssets(ig.Spec.FileAssets, role),
+	}
+	if role == kops.InstanceGroupRoleMaster {
+		config.MasterKubelet = c.Spec.MasterKubelet
+	}
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("error serializing configuration of instance group %q: %v", ig.ObjectMeta.Name, err)
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// RelevantHooks returns the hooks that apply to instances with the role
+func RelevantHooks(hooks []kops.HookSpec, role kops.InstanceGroupRole) []kops.HookSpec {
+	var relevant []kops.HookSpec
+	for _, hook := range hooks {
+		if len(hook.Roles) == 0 || hasRole(hook.Roles, role) {
+			relevant = append(relevant, hook)
+		}
+	}
+	return relevant
+}
+
+// RelevantFileAssets returns the file assets that apply to instances with the role
+func RelevantFileAssets(fileAssets []kops.FileAssetSpec, role kops.InstanceGroupRole) []kops.FileAssetSpec {
+	var relevant []kops.FileAssetSpec
+	for _, fileAsset := range fileAssets {
+		if len(fileAsset.Roles) == 0 || hasRole(fileAsset.Roles, role) {
+			relevant = append(relevant, fileAsset)
+		}
+	}
+	return relevant
+}
+
+func hasRole(roles []kops.InstanceGroupRole, role kops.InstanceGroupRole) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+func TestInPlaceConfigHash(t *testing.T) {
+	cluster := func() *kops.Cluster {
+		return &kops.Cluster{
+			Spec: kops.ClusterSpec{
+				Kubelet:       &kops.KubeletConfigSpec{MaxPods: int32Ptr(100)},
+				MasterKubelet: &kops.KubeletConfigSpec{MaxPods: int32Ptr(50)},
+				Hooks: []kops.HookSpec{
+					{Name: "all.service"},
+					{Name: "masters.service", Roles: []kops.InstanceGroupRole{kops.InstanceGroupRoleMaster}},
+				},
+				FileAssets: []kops.FileAssetSpec{
+					{Name: "masters", Path: "/etc/masters", Roles: []kops.InstanceGroupRole{kops.InstanceGroupRoleMaster}},
+				},
+			},
+		}
+	}
+	node := &kops.InstanceGroup{Spec: kops.InstanceGroupSpec{Role: kops.InstanceGroupRoleNode}}
+	master := &kops.InstanceGroup{Spec: kops.InstanceGroupSpec{Role: kops.InstanceGroupRoleMaster}}
+
+	hash := func(c *kops.Cluster, ig *kops.InstanceGroup) string {
+		h, err := InPlaceConfigHash(c, ig)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return h
+	}
+
+	nodeHash := hash(cluster(), node)
+	masterHash := hash(cluster(), master)
+	if nodeHash == masterHash {
+		t.Errorf("expected different hashes for nodes and masters")
+	}
+	if h := hash(cluster(), node); h != nodeHash {
+		t.Errorf("expected hash to be stable, got %q and %q", nodeHash, h)
+	}
+
+	grid := []struct {
+		Description   string
+		Mutate        func(c *kops.Cluster)
+		NodeChanged   bool
+		MasterChanged bool
+	}{
+		{
+			Description:   "kubelet",
+			Mutate:        func(c *kops.Cluster) { c.Spec.Kubelet.MaxPods = int32Ptr(110) },
+			NodeChanged:   true,
+			MasterChanged: true,
+		},
+		{
+			Description:   "master kubelet",
+			Mutate:        func(c *kops.Cluster) { c.Spec.MasterKubelet.MaxPods = int32Ptr(60) },
+			MasterChanged: true,
+		},
+		{
+			Description:   "master hook",
+			Mutate:        func(c *kops.Cluster) { c.Spec.Hooks[1].Manifest = "changed" },
+			MasterChanged: true,
+		},
+		{
+			Description:   "master file asset",
+			Mutate:        func(c *kops.Cluster) { c.Spec.FileAssets[0].Content = "changed" },
+			MasterChanged: true,
+		},
+		{
+			Description:   "hook for all roles",
+			Mutate:        func(c *kops.Cluster) { c.Spec.Hooks[0].Manifest = "changed" },
+			NodeChanged:   true,
+			MasterChanged: true,
+		},
+		{
+			Description: "setting that cannot be updated in place",
+			Mutate:      func(c *kops.Cluster) { c.Spec.KubernetesVersion = "1.16.0" },
+		},
+	}
+
+	for _, g := range grid {
+		c := cluster()
+		g.Mutate(c)
+		if changed := hash(c, node) != nodeHash; changed != g.NodeChanged {
+			t.Errorf("%s: expected node hash changed=%v, got %v", g.Description, g.NodeChanged, changed)
+		}
+		if changed := hash(c, master) != masterHash; changed != g.MasterChanged {
+			t.Errorf("%s: expected master hash changed=%v, got %v", g.Description, g.MasterChanged, changed)
+		}
+	}
+}
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
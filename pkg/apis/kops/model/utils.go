@@ -48,3 +48,27 @@ func FindZonesForInstanceGroup(c *kops.Cluster, ig *kops.InstanceGroup) ([]strin
 	}
 	return zones.List(), nil
 }
+
+// ContainerRuntime returns the container runtime for an instance group, which is the runtime declared in the InstanceGroup, or the cluster runtime, defaulting to docker
+func ContainerRuntime(c *kops.Cluster, ig *kops.InstanceGroup) string {
+	if ig != nil && ig.Spec.ContainerRuntime != "" {
+		return ig.Spec.ContainerRuntime
+	}
+	if c.Spec.ContainerRuntime != "" {
+		return c.Spec.ContainerRuntime
+	}
+	return kops.ContainerRuntimeDocker
+}
+
+// UsesContainerd returns true if the cluster, or any of the instance groups, use the containerd container runtime
+func UsesContainerd(c *kops.Cluster, instanceGroups []*kops.InstanceGroup) bool {
+	if ContainerRuntime(c, nil) == kops.ContainerRuntimeContainerd {
+		return true
+	}
+	for _, ig := range instanceGroups {
+		if ContainerRuntime(c, ig) == kops.ContainerRuntimeContainerd {
+			return true
+		}
+	}
+	return false
+}
@@ -169,3 +169,30 @@ func Test_FindZonesForInstanceGroup(t *testing.T) {
 		}
 	}
 }
+
+func Test_ContainerRuntime(t *testing.T) {
+	grid := []struct {
+		cluster  string
+		ig       string
+		expected string
+	}{
+		{cluster: "", ig: "", expected: "docker"},
+		{cluster: "containerd", ig: "", expected: "containerd"},
+		{cluster: "", ig: "containerd", expected: "containerd"},
+		{cluster: "containerd", ig: "docker", expected: "docker"},
+	}
+	for _, g := range grid {
+		cluster := &kops.Cluster{Spec: kops.ClusterSpec{ContainerRuntime: g.cluster}}
+		ig := &kops.InstanceGroup{Spec: kops.InstanceGroupSpec{ContainerRuntime: g.ig}}
+
+		actual := ContainerRuntime(cluster, ig)
+		if actual != g.expected {
+			t.Errorf("unexpected runtime for cluster %q and instance group %q: expected %q, got %q", g.cluster, g.ig, g.expected, actual)
+		}
+
+		usesContainerd := UsesContainerd(cluster, []*kops.InstanceGroup{ig})
+		if usesContainerd != (g.expected == "containerd" || g.cluster == "containerd") {
+			t.Errorf("unexpected UsesContainerd for cluster %q and instance group %q: %v", g.cluster, g.ig, usesContainerd)
+		}
+	}
+}
@@ -29,8 +29,15 @@ const (
 	PathCluster = "config"
 	// Path for completed cluster spec in the state store
 	PathClusterCompleted = "cluster.spec"
+	// Path for the instance groups as published by kops update cluster, which is where nodeup reads them
+	PathInstanceGroupsCompleted = "instancegroup.spec"
 )
 
+// InstanceGroupCompletedPath returns the path of the named instance group as published by kops update cluster
+func InstanceGroupCompletedPath(configBase vfs.Path, name string) vfs.Path {
+	return configBase.Join(PathInstanceGroupsCompleted, name)
+}
+
 func ConfigBase(c *api.Cluster) (vfs.Path, error) {
 	if c.Spec.ConfigBase == "" {
 		return nil, field.Required(field.NewPath("Spec", "ConfigBase"), "")
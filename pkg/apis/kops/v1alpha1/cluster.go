@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // +genclient
@@ -168,6 +169,112 @@ type ClusterSpec struct {
 	DisableSubnetTags bool `json:"DisableSubnetTags,omitempty"`
 	// Target allows for us to nest extra config for targets such as terraform
 	Target *TargetSpec `json:"target,omitempty"`
+	// RollingUpdate defines the default rolling-update settings for instance groups
+	RollingUpdate *RollingUpdate `json:"rollingUpdate,omitempty"`
+	// Validation configures the checks made when validating the cluster, in addition to the built-in checks
+	Validation *ClusterValidationSpec `json:"validation,omitempty"`
+	// ExternalCA, if set, issues the certificates of the cluster CA through an external signer, so that the private key
+	// of the CA need not be held in the state store
+	ExternalCA *ExternalCASpec `json:"externalCA,omitempty"`
+	// StateEncryption, if set, encrypts the secrets and private keys written to the state store
+	StateEncryption *StateEncryptionSpec `json:"stateEncryption,omitempty"`
+	// SecretHistoryDepth is the number of prior versions of each secret and keyset kept in the state store,
+	// from which they can be rolled back (defaults to 10; 0 keeps no history)
+	SecretHistoryDepth *int32 `json:"secretHistoryDepth,omitempty"`
+	// ContainerRuntime is the container runtime of the instances: docker (the default) or containerd.
+	// It can be overridden for an instance group.
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
+	// Containerd is the configuration of containerd, used when the container runtime is containerd
+	Containerd *ContainerdConfig `json:"containerd,omitempty"`
+	// NodeReconciliation, if set, periodically checks the nodes for drift from their configuration
+	NodeReconciliation *NodeReconciliationSpec `json:"nodeReconciliation,omitempty"`
+}
+
+// ExternalCASpec configures the external signer that issues the certificates of the cluster CA.
+// The certificate of the CA (typically an intermediate CA), but not its private key, is imported into the keystore
+// with kops create secret keypair ca. Exactly one of the fields must be set.
+type ExternalCASpec struct {
+	// Command is run to issue each certificate. The signing request is written to its standard input as JSON, and the
+	// PEM encoded certificate is read from its standard output. The command must also be present on the masters.
+	Command []string `json:"command,omitempty"`
+	// URL is that of a signing service, to which each signing request is POSTed as JSON, and which responds with the
+	// PEM encoded certificate. It must also be reachable from the masters.
+	URL string `json:"url,omitempty"`
+	// KeyFile is the path to the private key of the CA on the machine running kops, with which kops signs the
+	// certificates itself; it is intended for testing
+	KeyFile string `json:"keyFile,omitempty"`
+}
+
+// StateEncryptionSpec configures the envelope encryption of the secrets and private keys in the state store.
+// Each object is encrypted with a data key, which is itself encrypted (wrapped) by the key provider.
+// Exactly one of the fields must be set.
+type StateEncryptionSpec struct {
+	// AWSKMSKeyARN is the ARN of the AWS KMS key that wraps the data keys, e.g. arn:aws:kms:us-east-1:123456789012:key/<id>
+	AWSKMSKeyARN string `json:"awsKMSKeyARN,omitempty"`
+	// GCPKMSKeyName is the resource name of the GCP KMS key that wraps the data keys,
+	// i.e. projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>
+	GCPKMSKeyName string `json:"gcpKMSKeyName,omitempty"`
+	// KeyFile is the path to a file holding a base64 encoded 256 bit key that wraps the data keys.
+	// It must be present on every machine that reads the state store, so it is intended for testing.
+	KeyFile string `json:"keyFile,omitempty"`
+}
+
+// NodeReconciliationSpec configures a systemd timer on each node which runs nodeup to check the node for drift
+// from its configuration, such as edited or deleted files
+type NodeReconciliationSpec struct {
+	// Interval is the time between checks. Defaults to 1h.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// Reconcile, if true, re-applies the drifted files, services, users and groups rather than only reporting the drift.
+	// Running services that depend on a restored file are restarted.
+	Reconcile *bool `json:"reconcile,omitempty"`
+	// MetricsFile, if set, is where the drift is written in the Prometheus text format,
+	// e.g. a file in the directory read by the node_exporter textfile collector
+	MetricsFile string `json:"metricsFile,omitempty"`
+	// InPlaceUpdates, if true, applies changes to the kubelet flags, file assets and hooks to the running nodes,
+	// rather than requiring the instances to be replaced. Each node reports the hash of the settings it has applied.
+	InPlaceUpdates *bool `json:"inPlaceUpdates,omitempty"`
+	// UpdateCheckInterval is the time between checks of the state store for changes to apply in place. Defaults to 1m.
+	UpdateCheckInterval *metav1.Duration `json:"updateCheckInterval,omitempty"`
+}
+
+// ClusterValidationSpec configures the checks made when validating the cluster
+type ClusterValidationSpec struct {
+	// PodNamespaces are namespaces, in addition to kube-system, whose pods must all be running and ready
+	PodNamespaces []string `json:"podNamespaces,omitempty"`
+	// IgnorePodNamespaces are namespaces whose pods are not checked; this may include kube-system
+	IgnorePodNamespaces []string `json:"ignorePodNamespaces,omitempty"`
+	// DaemonSets must be fully rolled out, with an updated and available pod on every node they are scheduled to
+	DaemonSets []ValidationResource `json:"daemonSets,omitempty"`
+	// Deployments must have all of their replicas updated and available
+	Deployments []ValidationResource `json:"deployments,omitempty"`
+	// FailNodeConditions are node conditions, such as MemoryPressure, that fail validation when true on any node
+	FailNodeConditions []string `json:"failNodeConditions,omitempty"`
+	// DNS, if set, checks that names resolve from within the cluster
+	DNS *ValidationDNSSpec `json:"dns,omitempty"`
+	// CertificateExpiryThreshold is how long before a certificate in the keystore expires that validation warns about it.
+	// Defaults to 30 days (720h); set to 0 to disable the warning.
+	CertificateExpiryThreshold *metav1.Duration `json:"certificateExpiryThreshold,omitempty"`
+}
+
+// ValidationResource identifies a namespaced resource checked during validation
+type ValidationResource struct {
+	// Namespace is the namespace of the resource
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the resource
+	Name string `json:"name,omitempty"`
+}
+
+// ValidationDNSSpec configures the check that the cluster DNS is available: the kube-dns service must have ready
+// endpoints and the CoreDNS or kube-dns deployment must be available. With ResolveNames, the names are also resolved
+// from within the cluster.
+type ValidationDNSSpec struct {
+	// ResolveNames resolves the names with a pod which is created in kube-system for each validation, including those
+	// made during rolling updates, which adds the time to schedule and run the pod to every validation
+	ResolveNames *bool `json:"resolveNames,omitempty"`
+	// Names are the names that must resolve, if ResolveNames is set. Defaults to kubernetes.default.
+	Names []string `json:"names,omitempty"`
+	// Image is the image of the pod that resolves the names, which must provide nslookup. Defaults to busybox:1.28.
+	Image string `json:"image,omitempty"`
 }
 
 // NodeAuthorizationSpec is used to node authorization
@@ -426,6 +533,24 @@ type EtcdBackupSpec struct {
 	BackupStore string `json:"backupStore,omitempty"`
 	// Image is the etcd backup manager image to use.  Setting this will create a sidecar container in the etcd pod with the specified image.
 	Image string `json:"image,omitempty"`
+	// Interval is how often etcd-manager takes a backup; if not set the etcd-manager default is used.
+	// Requires Manager.Image to be set, as the default etcd-manager image does not support it.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// Retention describes which backups are kept; other backups are pruned from the BackupStore.
+	// If not set, backups are never pruned.
+	Retention *EtcdBackupRetentionSpec `json:"retention,omitempty"`
+}
+
+// EtcdBackupRetentionSpec describes which etcd backups are kept: the most recent backup in each of
+// the last Hourly hours, Daily days and Weekly weeks in which a backup was taken.
+// The most recent backup is always kept.
+type EtcdBackupRetentionSpec struct {
+	// Hourly is the number of hourly backups to keep
+	Hourly *int32 `json:"hourly,omitempty"`
+	// Daily is the number of daily backups to keep
+	Daily *int32 `json:"daily,omitempty"`
+	// Weekly is the number of weekly backups to keep
+	Weekly *int32 `json:"weekly,omitempty"`
 }
 
 // EtcdManagerSpec describes how we configure the etcd manager
@@ -501,3 +626,51 @@ type TerraformSpec struct {
 func (t *TerraformSpec) IsEmpty() bool {
 	return t.ProviderExtraConfig == nil
 }
+
+// RollingUpdate defines the rolling update behavior for an instance group
+type RollingUpdate struct {
+	// MaxUnavailable is the maximum number of nodes that can be unavailable during the update.
+	// The value can be an absolute number (for example 5) or a percentage of desired
+	// nodes (for example 10%).
+	// The absolute number is calculated from a percentage by rounding down.
+	// A value of 0 for both this and MaxSurge disables rolling updates.
+	// Defaults to 1 if MaxSurge is 0, otherwise defaults to 0.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+	// MaxSurge is the maximum number of extra nodes that can be created
+	// during the update.
+	// The value can be an absolute number (for example 5) or a percentage of
+	// desired nodes (for example 10%).
+	// The absolute number is calculated from a percentage by rounding up.
+	// Cannot be set on instance groups with role "Master"; a default set in the cluster spec
+	// is ignored for them, as masters are always replaced one at a time.
+	// Surging is only supported on AWS and GCE.
+	// Defaults to 0.
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+	// Hooks are run at points in the replacement of each instance, for example to deregister
+	// the instance from external systems before it is terminated.
+	// The hooks of an instance group replace those of the cluster.
+	// +optional
+	Hooks []RollingUpdateHook `json:"hooks,omitempty"`
+}
+
+// RollingUpdateHookEvent is a point in the replacement of an instance at which hooks are run
+type RollingUpdateHookEvent string
+
+// RollingUpdateHook is an action run during the replacement of each instance.
+// Exactly one of Exec and Webhook must be set.
+type RollingUpdateHook struct {
+	// Name identifies the hook in logs and errors
+	Name string `json:"name,omitempty"`
+	// Events are the points at which the hook is run: PreDrain, PostDrain, PreTerminate or PostValidate
+	Events []RollingUpdateHookEvent `json:"events,omitempty"`
+	// Exec is a command, run on the machine running kops, with the details of the instance in its environment
+	Exec []string `json:"exec,omitempty"`
+	// Webhook is a URL to which the details of the instance are POSTed as JSON
+	Webhook string `json:"webhook,omitempty"`
+	// Timeout is the maximum time the hook may run for. Defaults to 5 minutes.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// IgnoreFailure, if true, logs a failure of the hook rather than stopping the rolling update
+	IgnoreFailure bool `json:"ignoreFailure,omitempty"`
+}
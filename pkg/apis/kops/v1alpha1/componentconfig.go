@@ -177,6 +177,10 @@ type KubeletConfigSpec struct {
 	StreamingConnectionIdleTimeout *metav1.Duration `json:"streamingConnectionIdleTimeout,omitempty" flag:"streaming-connection-idle-timeout"`
 	// DockerDisableSharedPID uses a shared PID namespace for containers in a pod.
 	DockerDisableSharedPID *bool `json:"dockerDisableSharedPID,omitempty" flag:"docker-disable-shared-pid"`
+	// ContainerRuntime is the container runtime to use: docker, or remote for a CRI runtime such as containerd
+	ContainerRuntime string `json:"containerRuntime,omitempty" flag:"container-runtime"`
+	// ContainerRuntimeEndpoint is the endpoint of the remote runtime service, e.g. unix:///run/containerd/containerd.sock
+	ContainerRuntimeEndpoint string `json:"containerRuntimeEndpoint,omitempty" flag:"container-runtime-endpoint"`
 	// RootDir is the directory path for managing kubelet files (volume mounts,etc)
 	RootDir string `json:"rootDir,omitempty" flag:"root-dir"`
 	// AuthenticationTokenWebhook uses the TokenReview API to determine authentication for bearer tokens.
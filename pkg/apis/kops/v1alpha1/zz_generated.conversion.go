@@ -213,6 +213,26 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*ClusterValidationSpec)(nil), (*kops.ClusterValidationSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec(a.(*ClusterValidationSpec), b.(*kops.ClusterValidationSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*kops.ClusterValidationSpec)(nil), (*ClusterValidationSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec(a.(*kops.ClusterValidationSpec), b.(*ClusterValidationSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*ContainerdConfig)(nil), (*kops.ContainerdConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig(a.(*ContainerdConfig), b.(*kops.ContainerdConfig), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*kops.ContainerdConfig)(nil), (*ContainerdConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig(a.(*kops.ContainerdConfig), b.(*ContainerdConfig), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*DNSAccessSpec)(nil), (*kops.DNSAccessSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_DNSAccessSpec_To_kops_DNSAccessSpec(a.(*DNSAccessSpec), b.(*kops.DNSAccessSpec), scope)
 	}); err != nil {
@@ -253,6 +273,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*EtcdBackupRetentionSpec)(nil), (*kops.EtcdBackupRetentionSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_EtcdBackupRetentionSpec_To_kops_EtcdBackupRetentionSpec(a.(*EtcdBackupRetentionSpec), b.(*kops.EtcdBackupRetentionSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*kops.EtcdBackupRetentionSpec)(nil), (*EtcdBackupRetentionSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_kops_EtcdBackupRetentionSpec_To_v1alpha1_EtcdBackupRetentionSpec(a.(*kops.EtcdBackupRetentionSpec), b.(*EtcdBackupRetentionSpec), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*EtcdBackupSpec)(nil), (*kops.EtcdBackupSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_EtcdBackupSpec_To_kops_EtcdBackupSpec(a.(*EtcdBackupSpec), b.(*kops.EtcdBackupSpec), scope)
 	}); err != nil {
@@ -303,6 +333,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*ExternalCASpec)(nil), (*kops.ExternalCASpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_ExternalCASpec_To_kops_ExternalCASpec(a.(*ExternalCASpec), b.(*kops.ExternalCASpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*kops.ExternalCASpec)(nil), (*ExternalCASpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_kops_ExternalCASpec_To_v1alpha1_ExternalCASpec(a.(*kops.ExternalCASpec), b.(*ExternalCASpec), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*ExternalDNSConfig)(nil), (*kops.ExternalDNSConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_ExternalDNSConfig_To_kops_ExternalDNSConfig(a.(*ExternalDNSConfig), b.(*kops.ExternalDNSConfig), scope)
 	}); err != nil {
@@ -593,6 +633,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*NodeReconciliationSpec)(nil), (*kops.NodeReconciliationSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_NodeReconciliationSpec_To_kops_NodeReconciliationSpec(a.(*NodeReconciliationSpec), b.(*kops.NodeReconciliationSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*kops.NodeReconciliationSpec)(nil), (*NodeReconciliationSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_kops_NodeReconciliationSpec_To_v1alpha1_NodeReconciliationSpec(a.(*kops.NodeReconciliationSpec), b.(*NodeReconciliationSpec), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*OpenstackBlockStorageConfig)(nil), (*kops.OpenstackBlockStorageConfig)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_OpenstackBlockStorageConfig_To_kops_OpenstackBlockStorageConfig(a.(*OpenstackBlockStorageConfig), b.(*kops.OpenstackBlockStorageConfig), scope)
 	}); err != nil {
@@ -653,6 +703,26 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*RollingUpdate)(nil), (*kops.RollingUpdate)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_RollingUpdate_To_kops_RollingUpdate(a.(*RollingUpdate), b.(*kops.RollingUpdate), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*kops.RollingUpdate)(nil), (*RollingUpdate)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_kops_RollingUpdate_To_v1alpha1_RollingUpdate(a.(*kops.RollingUpdate), b.(*RollingUpdate), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*RollingUpdateHook)(nil), (*kops.RollingUpdateHook)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_RollingUpdateHook_To_kops_RollingUpdateHook(a.(*RollingUpdateHook), b.(*kops.RollingUpdateHook), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*kops.RollingUpdateHook)(nil), (*RollingUpdateHook)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_kops_RollingUpdateHook_To_v1alpha1_RollingUpdateHook(a.(*kops.RollingUpdateHook), b.(*RollingUpdateHook), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*RomanaNetworkingSpec)(nil), (*kops.RomanaNetworkingSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_RomanaNetworkingSpec_To_kops_RomanaNetworkingSpec(a.(*RomanaNetworkingSpec), b.(*kops.RomanaNetworkingSpec), scope)
 	}); err != nil {
@@ -693,6 +763,16 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*StateEncryptionSpec)(nil), (*kops.StateEncryptionSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_StateEncryptionSpec_To_kops_StateEncryptionSpec(a.(*StateEncryptionSpec), b.(*kops.StateEncryptionSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*kops.StateEncryptionSpec)(nil), (*StateEncryptionSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_kops_StateEncryptionSpec_To_v1alpha1_StateEncryptionSpec(a.(*kops.StateEncryptionSpec), b.(*StateEncryptionSpec), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*TargetSpec)(nil), (*kops.TargetSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_TargetSpec_To_kops_TargetSpec(a.(*TargetSpec), b.(*kops.TargetSpec), scope)
 	}); err != nil {
@@ -723,6 +803,26 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddGeneratedConversionFunc((*ValidationDNSSpec)(nil), (*kops.ValidationDNSSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_ValidationDNSSpec_To_kops_ValidationDNSSpec(a.(*ValidationDNSSpec), b.(*kops.ValidationDNSSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*kops.ValidationDNSSpec)(nil), (*ValidationDNSSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_kops_ValidationDNSSpec_To_v1alpha1_ValidationDNSSpec(a.(*kops.ValidationDNSSpec), b.(*ValidationDNSSpec), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*ValidationResource)(nil), (*kops.ValidationResource)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_ValidationResource_To_kops_ValidationResource(a.(*ValidationResource), b.(*kops.ValidationResource), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*kops.ValidationResource)(nil), (*ValidationResource)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_kops_ValidationResource_To_v1alpha1_ValidationResource(a.(*kops.ValidationResource), b.(*ValidationResource), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*VolumeMountSpec)(nil), (*kops.VolumeMountSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_VolumeMountSpec_To_kops_VolumeMountSpec(a.(*VolumeMountSpec), b.(*kops.VolumeMountSpec), scope)
 	}); err != nil {
@@ -1753,6 +1853,62 @@ func autoConvert_v1alpha1_ClusterSpec_To_kops_ClusterSpec(in *ClusterSpec, out *
 	} else {
 		out.Target = nil
 	}
+	if in.RollingUpdate != nil {
+		in, out := &in.RollingUpdate, &out.RollingUpdate
+		*out = new(kops.RollingUpdate)
+		if err := Convert_v1alpha1_RollingUpdate_To_kops_RollingUpdate(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RollingUpdate = nil
+	}
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(kops.ClusterValidationSpec)
+		if err := Convert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Validation = nil
+	}
+	if in.ExternalCA != nil {
+		in, out := &in.ExternalCA, &out.ExternalCA
+		*out = new(kops.ExternalCASpec)
+		if err := Convert_v1alpha1_ExternalCASpec_To_kops_ExternalCASpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.ExternalCA = nil
+	}
+	if in.StateEncryption != nil {
+		in, out := &in.StateEncryption, &out.StateEncryption
+		*out = new(kops.StateEncryptionSpec)
+		if err := Convert_v1alpha1_StateEncryptionSpec_To_kops_StateEncryptionSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.StateEncryption = nil
+	}
+	out.SecretHistoryDepth = in.SecretHistoryDepth
+	out.ContainerRuntime = in.ContainerRuntime
+	if in.Containerd != nil {
+		in, out := &in.Containerd, &out.Containerd
+		*out = new(kops.ContainerdConfig)
+		if err := Convert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Containerd = nil
+	}
+	if in.NodeReconciliation != nil {
+		in, out := &in.NodeReconciliation, &out.NodeReconciliation
+		*out = new(kops.NodeReconciliationSpec)
+		if err := Convert_v1alpha1_NodeReconciliationSpec_To_kops_NodeReconciliationSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.NodeReconciliation = nil
+	}
 	return nil
 }
 
@@ -2020,9 +2176,177 @@ func autoConvert_kops_ClusterSpec_To_v1alpha1_ClusterSpec(in *kops.ClusterSpec,
 	} else {
 		out.Target = nil
 	}
+	if in.RollingUpdate != nil {
+		in, out := &in.RollingUpdate, &out.RollingUpdate
+		*out = new(RollingUpdate)
+		if err := Convert_kops_RollingUpdate_To_v1alpha1_RollingUpdate(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RollingUpdate = nil
+	}
+	if in.Validation != nil {
+		in, out := &in.Validation, &out.Validation
+		*out = new(ClusterValidationSpec)
+		if err := Convert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Validation = nil
+	}
+	if in.ExternalCA != nil {
+		in, out := &in.ExternalCA, &out.ExternalCA
+		*out = new(ExternalCASpec)
+		if err := Convert_kops_ExternalCASpec_To_v1alpha1_ExternalCASpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.ExternalCA = nil
+	}
+	if in.StateEncryption != nil {
+		in, out := &in.StateEncryption, &out.StateEncryption
+		*out = new(StateEncryptionSpec)
+		if err := Convert_kops_StateEncryptionSpec_To_v1alpha1_StateEncryptionSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.StateEncryption = nil
+	}
+	out.SecretHistoryDepth = in.SecretHistoryDepth
+	out.ContainerRuntime = in.ContainerRuntime
+	if in.Containerd != nil {
+		in, out := &in.Containerd, &out.Containerd
+		*out = new(ContainerdConfig)
+		if err := Convert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Containerd = nil
+	}
+	if in.NodeReconciliation != nil {
+		in, out := &in.NodeReconciliation, &out.NodeReconciliation
+		*out = new(NodeReconciliationSpec)
+		if err := Convert_kops_NodeReconciliationSpec_To_v1alpha1_NodeReconciliationSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.NodeReconciliation = nil
+	}
+	return nil
+}
+
+func autoConvert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec(in *ClusterValidationSpec, out *kops.ClusterValidationSpec, s conversion.Scope) error {
+	out.PodNamespaces = in.PodNamespaces
+	out.IgnorePodNamespaces = in.IgnorePodNamespaces
+	if in.DaemonSets != nil {
+		in, out := &in.DaemonSets, &out.DaemonSets
+		*out = make([]kops.ValidationResource, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_ValidationResource_To_kops_ValidationResource(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.DaemonSets = nil
+	}
+	if in.Deployments != nil {
+		in, out := &in.Deployments, &out.Deployments
+		*out = make([]kops.ValidationResource, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_ValidationResource_To_kops_ValidationResource(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Deployments = nil
+	}
+	out.FailNodeConditions = in.FailNodeConditions
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(kops.ValidationDNSSpec)
+		if err := Convert_v1alpha1_ValidationDNSSpec_To_kops_ValidationDNSSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.DNS = nil
+	}
+	out.CertificateExpiryThreshold = in.CertificateExpiryThreshold
+	return nil
+}
+
+// Convert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec is an autogenerated conversion function.
+func Convert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec(in *ClusterValidationSpec, out *kops.ClusterValidationSpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_ClusterValidationSpec_To_kops_ClusterValidationSpec(in, out, s)
+}
+
+func autoConvert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec(in *kops.ClusterValidationSpec, out *ClusterValidationSpec, s conversion.Scope) error {
+	out.PodNamespaces = in.PodNamespaces
+	out.IgnorePodNamespaces = in.IgnorePodNamespaces
+	if in.DaemonSets != nil {
+		in, out := &in.DaemonSets, &out.DaemonSets
+		*out = make([]ValidationResource, len(*in))
+		for i := range *in {
+			if err := Convert_kops_ValidationResource_To_v1alpha1_ValidationResource(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.DaemonSets = nil
+	}
+	if in.Deployments != nil {
+		in, out := &in.Deployments, &out.Deployments
+		*out = make([]ValidationResource, len(*in))
+		for i := range *in {
+			if err := Convert_kops_ValidationResource_To_v1alpha1_ValidationResource(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Deployments = nil
+	}
+	out.FailNodeConditions = in.FailNodeConditions
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(ValidationDNSSpec)
+		if err := Convert_kops_ValidationDNSSpec_To_v1alpha1_ValidationDNSSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.DNS = nil
+	}
+	out.CertificateExpiryThreshold = in.CertificateExpiryThreshold
+	return nil
+}
+
+// Convert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec is an autogenerated conversion function.
+func Convert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec(in *kops.ClusterValidationSpec, out *ClusterValidationSpec, s conversion.Scope) error {
+	return autoConvert_kops_ClusterValidationSpec_To_v1alpha1_ClusterValidationSpec(in, out, s)
+}
+
+func autoConvert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig(in *ContainerdConfig, out *kops.ContainerdConfig, s conversion.Scope) error {
+	out.Version = in.Version
+	out.LogLevel = in.LogLevel
+	out.ConfigOverride = in.ConfigOverride
+	return nil
+}
+
+// Convert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig is an autogenerated conversion function.
+func Convert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig(in *ContainerdConfig, out *kops.ContainerdConfig, s conversion.Scope) error {
+	return autoConvert_v1alpha1_ContainerdConfig_To_kops_ContainerdConfig(in, out, s)
+}
+
+func autoConvert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig(in *kops.ContainerdConfig, out *ContainerdConfig, s conversion.Scope) error {
+	out.Version = in.Version
+	out.LogLevel = in.LogLevel
+	out.ConfigOverride = in.ConfigOverride
 	return nil
 }
 
+// Convert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig is an autogenerated conversion function.
+func Convert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig(in *kops.ContainerdConfig, out *ContainerdConfig, s conversion.Scope) error {
+	return autoConvert_kops_ContainerdConfig_To_v1alpha1_ContainerdConfig(in, out, s)
+}
+
 func autoConvert_v1alpha1_DNSAccessSpec_To_kops_DNSAccessSpec(in *DNSAccessSpec, out *kops.DNSAccessSpec, s conversion.Scope) error {
 	return nil
 }
@@ -2151,9 +2475,43 @@ func Convert_kops_EgressProxySpec_To_v1alpha1_EgressProxySpec(in *kops.EgressPro
 	return autoConvert_kops_EgressProxySpec_To_v1alpha1_EgressProxySpec(in, out, s)
 }
 
+func autoConvert_v1alpha1_EtcdBackupRetentionSpec_To_kops_EtcdBackupRetentionSpec(in *EtcdBackupRetentionSpec, out *kops.EtcdBackupRetentionSpec, s conversion.Scope) error {
+	out.Hourly = in.Hourly
+	out.Daily = in.Daily
+	out.Weekly = in.Weekly
+	return nil
+}
+
+// Convert_v1alpha1_EtcdBackupRetentionSpec_To_kops_EtcdBackupRetentionSpec is an autogenerated conversion function.
+func Convert_v1alpha1_EtcdBackupRetentionSpec_To_kops_EtcdBackupRetentionSpec(in *EtcdBackupRetentionSpec, out *kops.EtcdBackupRetentionSpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_EtcdBackupRetentionSpec_To_kops_EtcdBackupRetentionSpec(in, out, s)
+}
+
+func autoConvert_kops_EtcdBackupRetentionSpec_To_v1alpha1_EtcdBackupRetentionSpec(in *kops.EtcdBackupRetentionSpec, out *EtcdBackupRetentionSpec, s conversion.Scope) error {
+	out.Hourly = in.Hourly
+	out.Daily = in.Daily
+	out.Weekly = in.Weekly
+	return nil
+}
+
+// Convert_kops_EtcdBackupRetentionSpec_To_v1alpha1_EtcdBackupRetentionSpec is an autogenerated conversion function.
+func Convert_kops_EtcdBackupRetentionSpec_To_v1alpha1_EtcdBackupRetentionSpec(in *kops.EtcdBackupRetentionSpec, out *EtcdBackupRetentionSpec, s conversion.Scope) error {
+	return autoConvert_kops_EtcdBackupRetentionSpec_To_v1alpha1_EtcdBackupRetentionSpec(in, out, s)
+}
+
 func autoConvert_v1alpha1_EtcdBackupSpec_To_kops_EtcdBackupSpec(in *EtcdBackupSpec, out *kops.EtcdBackupSpec, s conversion.Scope) error {
 	out.BackupStore = in.BackupStore
 	out.Image = in.Image
+	out.Interval = in.Interval
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(kops.EtcdBackupRetentionSpec)
+		if err := Convert_v1alpha1_EtcdBackupRetentionSpec_To_kops_EtcdBackupRetentionSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Retention = nil
+	}
 	return nil
 }
 
@@ -2165,6 +2523,16 @@ func Convert_v1alpha1_EtcdBackupSpec_To_kops_EtcdBackupSpec(in *EtcdBackupSpec,
 func autoConvert_kops_EtcdBackupSpec_To_v1alpha1_EtcdBackupSpec(in *kops.EtcdBackupSpec, out *EtcdBackupSpec, s conversion.Scope) error {
 	out.BackupStore = in.BackupStore
 	out.Image = in.Image
+	out.Interval = in.Interval
+	if in.Retention != nil {
+		in, out := &in.Retention, &out.Retention
+		*out = new(EtcdBackupRetentionSpec)
+		if err := Convert_kops_EtcdBackupRetentionSpec_To_v1alpha1_EtcdBackupRetentionSpec(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.Retention = nil
+	}
 	return nil
 }
 
@@ -2337,6 +2705,30 @@ func Convert_kops_ExecContainerAction_To_v1alpha1_ExecContainerAction(in *kops.E
 	return autoConvert_kops_ExecContainerAction_To_v1alpha1_ExecContainerAction(in, out, s)
 }
 
+func autoConvert_v1alpha1_ExternalCASpec_To_kops_ExternalCASpec(in *ExternalCASpec, out *kops.ExternalCASpec, s conversion.Scope) error {
+	out.Command = in.Command
+	out.URL = in.URL
+	out.KeyFile = in.KeyFile
+	return nil
+}
+
+// Convert_v1alpha1_ExternalCASpec_To_kops_ExternalCASpec is an autogenerated conversion function.
+func Convert_v1alpha1_ExternalCASpec_To_kops_ExternalCASpec(in *ExternalCASpec, out *kops.ExternalCASpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_ExternalCASpec_To_kops_ExternalCASpec(in, out, s)
+}
+
+func autoConvert_kops_ExternalCASpec_To_v1alpha1_ExternalCASpec(in *kops.ExternalCASpec, out *ExternalCASpec, s conversion.Scope) error {
+	out.Command = in.Command
+	out.URL = in.URL
+	out.KeyFile = in.KeyFile
+	return nil
+}
+
+// Convert_kops_ExternalCASpec_To_v1alpha1_ExternalCASpec is an autogenerated conversion function.
+func Convert_kops_ExternalCASpec_To_v1alpha1_ExternalCASpec(in *kops.ExternalCASpec, out *ExternalCASpec, s conversion.Scope) error {
+	return autoConvert_kops_ExternalCASpec_To_v1alpha1_ExternalCASpec(in, out, s)
+}
+
 func autoConvert_v1alpha1_ExternalDNSConfig_To_kops_ExternalDNSConfig(in *ExternalDNSConfig, out *kops.ExternalDNSConfig, s conversion.Scope) error {
 	out.Disable = in.Disable
 	out.WatchIngress = in.WatchIngress
@@ -2714,6 +3106,7 @@ func autoConvert_v1alpha1_InstanceGroupSpec_To_kops_InstanceGroupSpec(in *Instan
 	} else {
 		out.Kubelet = nil
 	}
+	out.ContainerRuntime = in.ContainerRuntime
 	out.Taints = in.Taints
 	if in.MixedInstancesPolicy != nil {
 		in, out := &in.MixedInstancesPolicy, &out.MixedInstancesPolicy
@@ -2760,6 +3153,15 @@ func autoConvert_v1alpha1_InstanceGroupSpec_To_kops_InstanceGroupSpec(in *Instan
 	}
 	out.SecurityGroupOverride = in.SecurityGroupOverride
 	out.InstanceProtection = in.InstanceProtection
+	if in.RollingUpdate != nil {
+		in, out := &in.RollingUpdate, &out.RollingUpdate
+		*out = new(kops.RollingUpdate)
+		if err := Convert_v1alpha1_RollingUpdate_To_kops_RollingUpdate(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RollingUpdate = nil
+	}
 	return nil
 }
 
@@ -2836,6 +3238,7 @@ func autoConvert_kops_InstanceGroupSpec_To_v1alpha1_InstanceGroupSpec(in *kops.I
 	} else {
 		out.Kubelet = nil
 	}
+	out.ContainerRuntime = in.ContainerRuntime
 	out.Taints = in.Taints
 	if in.MixedInstancesPolicy != nil {
 		in, out := &in.MixedInstancesPolicy, &out.MixedInstancesPolicy
@@ -2881,6 +3284,15 @@ func autoConvert_kops_InstanceGroupSpec_To_v1alpha1_InstanceGroupSpec(in *kops.I
 	}
 	out.SecurityGroupOverride = in.SecurityGroupOverride
 	out.InstanceProtection = in.InstanceProtection
+	if in.RollingUpdate != nil {
+		in, out := &in.RollingUpdate, &out.RollingUpdate
+		*out = new(RollingUpdate)
+		if err := Convert_kops_RollingUpdate_To_v1alpha1_RollingUpdate(*in, *out, s); err != nil {
+			return err
+		}
+	} else {
+		out.RollingUpdate = nil
+	}
 	return nil
 }
 
@@ -3414,6 +3826,8 @@ func autoConvert_v1alpha1_KubeletConfigSpec_To_kops_KubeletConfigSpec(in *Kubele
 	out.AllowedUnsafeSysctls = in.AllowedUnsafeSysctls
 	out.StreamingConnectionIdleTimeout = in.StreamingConnectionIdleTimeout
 	out.DockerDisableSharedPID = in.DockerDisableSharedPID
+	out.ContainerRuntime = in.ContainerRuntime
+	out.ContainerRuntimeEndpoint = in.ContainerRuntimeEndpoint
 	out.RootDir = in.RootDir
 	out.AuthenticationTokenWebhook = in.AuthenticationTokenWebhook
 	out.AuthenticationTokenWebhookCacheTTL = in.AuthenticationTokenWebhookCacheTTL
@@ -3498,6 +3912,8 @@ func autoConvert_kops_KubeletConfigSpec_To_v1alpha1_KubeletConfigSpec(in *kops.K
 	out.AllowedUnsafeSysctls = in.AllowedUnsafeSysctls
 	out.StreamingConnectionIdleTimeout = in.StreamingConnectionIdleTimeout
 	out.DockerDisableSharedPID = in.DockerDisableSharedPID
+	out.ContainerRuntime = in.ContainerRuntime
+	out.ContainerRuntimeEndpoint = in.ContainerRuntimeEndpoint
 	out.RootDir = in.RootDir
 	out.AuthenticationTokenWebhook = in.AuthenticationTokenWebhook
 	out.AuthenticationTokenWebhookCacheTTL = in.AuthenticationTokenWebhookCacheTTL
@@ -4012,6 +4428,34 @@ func Convert_kops_NodeAuthorizerSpec_To_v1alpha1_NodeAuthorizerSpec(in *kops.Nod
 	return autoConvert_kops_NodeAuthorizerSpec_To_v1alpha1_NodeAuthorizerSpec(in, out, s)
 }
 
+func autoConvert_v1alpha1_NodeReconciliationSpec_To_kops_NodeReconciliationSpec(in *NodeReconciliationSpec, out *kops.NodeReconciliationSpec, s conversion.Scope) error {
+	out.Interval = in.Interval
+	out.Reconcile = in.Reconcile
+	out.MetricsFile = in.MetricsFile
+	out.InPlaceUpdates = in.InPlaceUpdates
+	out.UpdateCheckInterval = in.UpdateCheckInterval
+	return nil
+}
+
+// Convert_v1alpha1_NodeReconciliationSpec_To_kops_NodeReconciliationSpec is an autogenerated conversion function.
+func Convert_v1alpha1_NodeReconciliationSpec_To_kops_NodeReconciliationSpec(in *NodeReconciliationSpec, out *kops.NodeReconciliationSpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_NodeReconciliationSpec_To_kops_NodeReconciliationSpec(in, out, s)
+}
+
+func autoConvert_kops_NodeReconciliationSpec_To_v1alpha1_NodeReconciliationSpec(in *kops.NodeReconciliationSpec, out *NodeReconciliationSpec, s conversion.Scope) error {
+	out.Interval = in.Interval
+	out.Reconcile = in.Reconcile
+	out.MetricsFile = in.MetricsFile
+	out.InPlaceUpdates = in.InPlaceUpdates
+	out.UpdateCheckInterval = in.UpdateCheckInterval
+	return nil
+}
+
+// Convert_kops_NodeReconciliationSpec_To_v1alpha1_NodeReconciliationSpec is an autogenerated conversion function.
+func Convert_kops_NodeReconciliationSpec_To_v1alpha1_NodeReconciliationSpec(in *kops.NodeReconciliationSpec, out *NodeReconciliationSpec, s conversion.Scope) error {
+	return autoConvert_kops_NodeReconciliationSpec_To_v1alpha1_NodeReconciliationSpec(in, out, s)
+}
+
 func autoConvert_v1alpha1_OpenstackBlockStorageConfig_To_kops_OpenstackBlockStorageConfig(in *OpenstackBlockStorageConfig, out *kops.OpenstackBlockStorageConfig, s conversion.Scope) error {
 	out.Version = in.Version
 	out.IgnoreAZ = in.IgnoreAZ
@@ -4226,6 +4670,96 @@ func Convert_kops_RBACAuthorizationSpec_To_v1alpha1_RBACAuthorizationSpec(in *ko
 	return autoConvert_kops_RBACAuthorizationSpec_To_v1alpha1_RBACAuthorizationSpec(in, out, s)
 }
 
+func autoConvert_v1alpha1_RollingUpdate_To_kops_RollingUpdate(in *RollingUpdate, out *kops.RollingUpdate, s conversion.Scope) error {
+	out.MaxUnavailable = in.MaxUnavailable
+	out.MaxSurge = in.MaxSurge
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = make([]kops.RollingUpdateHook, len(*in))
+		for i := range *in {
+			if err := Convert_v1alpha1_RollingUpdateHook_To_kops_RollingUpdateHook(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Hooks = nil
+	}
+	return nil
+}
+
+// Convert_v1alpha1_RollingUpdate_To_kops_RollingUpdate is an autogenerated conversion function.
+func Convert_v1alpha1_RollingUpdate_To_kops_RollingUpdate(in *RollingUpdate, out *kops.RollingUpdate, s conversion.Scope) error {
+	return autoConvert_v1alpha1_RollingUpdate_To_kops_RollingUpdate(in, out, s)
+}
+
+func autoConvert_kops_RollingUpdate_To_v1alpha1_RollingUpdate(in *kops.RollingUpdate, out *RollingUpdate, s conversion.Scope) error {
+	out.MaxUnavailable = in.MaxUnavailable
+	out.MaxSurge = in.MaxSurge
+	if in.Hooks != nil {
+		in, out := &in.Hooks, &out.Hooks
+		*out = make([]RollingUpdateHook, len(*in))
+		for i := range *in {
+			if err := Convert_kops_RollingUpdateHook_To_v1alpha1_RollingUpdateHook(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Hooks = nil
+	}
+	return nil
+}
+
+// Convert_kops_RollingUpdate_To_v1alpha1_RollingUpdate is an autogenerated conversion function.
+func Convert_kops_RollingUpdate_To_v1alpha1_RollingUpdate(in *kops.RollingUpdate, out *RollingUpdate, s conversion.Scope) error {
+	return autoConvert_kops_RollingUpdate_To_v1alpha1_RollingUpdate(in, out, s)
+}
+
+func autoConvert_v1alpha1_RollingUpdateHook_To_kops_RollingUpdateHook(in *RollingUpdateHook, out *kops.RollingUpdateHook, s conversion.Scope) error {
+	out.Name = in.Name
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]kops.RollingUpdateHookEvent, len(*in))
+		for i := range *in {
+			(*out)[i] = kops.RollingUpdateHookEvent((*in)[i])
+		}
+	} else {
+		out.Events = nil
+	}
+	out.Exec = in.Exec
+	out.Webhook = in.Webhook
+	out.Timeout = in.Timeout
+	out.IgnoreFailure = in.IgnoreFailure
+	return nil
+}
+
+// Convert_v1alpha1_RollingUpdateHook_To_kops_RollingUpdateHook is an autogenerated conversion function.
+func Convert_v1alpha1_RollingUpdateHook_To_kops_RollingUpdateHook(in *RollingUpdateHook, out *kops.RollingUpdateHook, s conversion.Scope) error {
+	return autoConvert_v1alpha1_RollingUpdateHook_To_kops_RollingUpdateHook(in, out, s)
+}
+
+func autoConvert_kops_RollingUpdateHook_To_v1alpha1_RollingUpdateHook(in *kops.RollingUpdateHook, out *RollingUpdateHook, s conversion.Scope) error {
+	out.Name = in.Name
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]RollingUpdateHookEvent, len(*in))
+		for i := range *in {
+			(*out)[i] = RollingUpdateHookEvent((*in)[i])
+		}
+	} else {
+		out.Events = nil
+	}
+	out.Exec = in.Exec
+	out.Webhook = in.Webhook
+	out.Timeout = in.Timeout
+	out.IgnoreFailure = in.IgnoreFailure
+	return nil
+}
+
+// Convert_kops_RollingUpdateHook_To_v1alpha1_RollingUpdateHook is an autogenerated conversion function.
+func Convert_kops_RollingUpdateHook_To_v1alpha1_RollingUpdateHook(in *kops.RollingUpdateHook, out *RollingUpdateHook, s conversion.Scope) error {
+	return autoConvert_kops_RollingUpdateHook_To_v1alpha1_RollingUpdateHook(in, out, s)
+}
+
 func autoConvert_v1alpha1_RomanaNetworkingSpec_To_kops_RomanaNetworkingSpec(in *RomanaNetworkingSpec, out *kops.RomanaNetworkingSpec, s conversion.Scope) error {
 	out.DaemonServiceIP = in.DaemonServiceIP
 	out.EtcdServiceIP = in.EtcdServiceIP
@@ -4336,6 +4870,30 @@ func Convert_kops_SSHCredentialSpec_To_v1alpha1_SSHCredentialSpec(in *kops.SSHCr
 	return autoConvert_kops_SSHCredentialSpec_To_v1alpha1_SSHCredentialSpec(in, out, s)
 }
 
+func autoConvert_v1alpha1_StateEncryptionSpec_To_kops_StateEncryptionSpec(in *StateEncryptionSpec, out *kops.StateEncryptionSpec, s conversion.Scope) error {
+	out.AWSKMSKeyARN = in.AWSKMSKeyARN
+	out.GCPKMSKeyName = in.GCPKMSKeyName
+	out.KeyFile = in.KeyFile
+	return nil
+}
+
+// Convert_v1alpha1_StateEncryptionSpec_To_kops_StateEncryptionSpec is an autogenerated conversion function.
+func Convert_v1alpha1_StateEncryptionSpec_To_kops_StateEncryptionSpec(in *StateEncryptionSpec, out *kops.StateEncryptionSpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_StateEncryptionSpec_To_kops_StateEncryptionSpec(in, out, s)
+}
+
+func autoConvert_kops_StateEncryptionSpec_To_v1alpha1_StateEncryptionSpec(in *kops.StateEncryptionSpec, out *StateEncryptionSpec, s conversion.Scope) error {
+	out.AWSKMSKeyARN = in.AWSKMSKeyARN
+	out.GCPKMSKeyName = in.GCPKMSKeyName
+	out.KeyFile = in.KeyFile
+	return nil
+}
+
+// Convert_kops_StateEncryptionSpec_To_v1alpha1_StateEncryptionSpec is an autogenerated conversion function.
+func Convert_kops_StateEncryptionSpec_To_v1alpha1_StateEncryptionSpec(in *kops.StateEncryptionSpec, out *StateEncryptionSpec, s conversion.Scope) error {
+	return autoConvert_kops_StateEncryptionSpec_To_v1alpha1_StateEncryptionSpec(in, out, s)
+}
+
 func autoConvert_v1alpha1_TargetSpec_To_kops_TargetSpec(in *TargetSpec, out *kops.TargetSpec, s conversion.Scope) error {
 	if in.Terraform != nil {
 		in, out := &in.Terraform, &out.Terraform
@@ -4416,6 +4974,52 @@ func Convert_kops_UserData_To_v1alpha1_UserData(in *kops.UserData, out *UserData
 	return autoConvert_kops_UserData_To_v1alpha1_UserData(in, out, s)
 }
 
+func autoConvert_v1alpha1_ValidationDNSSpec_To_kops_ValidationDNSSpec(in *ValidationDNSSpec, out *kops.ValidationDNSSpec, s conversion.Scope) error {
+	out.ResolveNames = in.ResolveNames
+	out.Names = in.Names
+	out.Image = in.Image
+	return nil
+}
+
+// Convert_v1alpha1_ValidationDNSSpec_To_kops_ValidationDNSSpec is an autogenerated conversion function.
+func Convert_v1alpha1_ValidationDNSSpec_To_kops_ValidationDNSSpec(in *ValidationDNSSpec, out *kops.ValidationDNSSpec, s conversion.Scope) error {
+	return autoConvert_v1alpha1_ValidationDNSSpec_To_kops_ValidationDNSSpec(in, out, s)
+}
+
+func autoConvert_kops_ValidationDNSSpec_To_v1alpha1_ValidationDNSSpec(in *kops.ValidationDNSSpec, out *ValidationDNSSpec, s conversion.Scope) error {
+	out.ResolveNames = in.ResolveNames
+	out.Names = in.Names
+	out.Image = in.Image
+	return nil
+}
+
+// Convert_kops_ValidationDNSSpec_To_v1alpha1_ValidationDNSSpec is an autogenerated conversion function.
+func Convert_kops_ValidationDNSSpec_To_v1alpha1_ValidationDNSSpec(in *kops.ValidationDNSSpec, out *ValidationDNSSpec, s conversion.Scope) error {
+	return autoConvert_kops_ValidationDNSSpec_To_v1alpha1_ValidationDNSSpec(in, out, s)
+}
+
+func autoConvert_v1alpha1_ValidationResource_To_kops_ValidationResource(in *ValidationResource, out *kops.ValidationResource, s conversion.Scope) error {
+	out.Namespace = in.Namespace
+	out.Name = in.Name
+	return nil
+}
+
+// Convert_v1alpha1_ValidationResource_To_kops_ValidationResource is an autogenerated conversion function.
+func Convert_v1alpha1_ValidationResource_To_kops_ValidationResource(in *ValidationResource, out *kops.ValidationResource, s conversion.Scope) error {
+	return autoConvert_v1alpha1_ValidationResource_To_kops_ValidationResource(in, out, s)
+}
+
+func autoConvert_kops_ValidationResource_To_v1alpha1_ValidationResource(in *kops.ValidationResource, out *ValidationResource, s conversion.Scope) error {
+	out.Namespace = in.Namespace
+	out.Name = in.Name
+	return nil
+}
+
+// Convert_kops_ValidationResource_To_v1alpha1_ValidationResource is an autogenerated conversion function.
+func Convert_kops_ValidationResource_To_v1alpha1_ValidationResource(in *kops.ValidationResource, out *ValidationResource, s conversion.Scope) error {
+	return autoConvert_kops_ValidationResource_To_v1alpha1_ValidationResource(in, out, s)
+}
+
 func autoConvert_v1alpha1_VolumeMountSpec_To_kops_VolumeMountSpec(in *VolumeMountSpec, out *kops.VolumeMountSpec, s conversion.Scope) error {
 	out.Device = in.Device
 	out.Filesystem = in.Filesystem
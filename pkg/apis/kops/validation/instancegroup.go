@@ -133,6 +133,21 @@ func ValidateInstanceGroup(g *kops.InstanceGroup) error {
 		return err
 	}
 
+	if g.Spec.RollingUpdate != nil {
+		if errs := validateRollingUpdate(g.Spec.RollingUpdate, field.NewPath("rollingUpdate"), g.IsMaster()); len(errs) > 0 {
+			return errs.ToAggregate()
+		}
+	}
+
+	if errs := validateContainerRuntime(g.Spec.ContainerRuntime, field.NewPath("containerRuntime")); len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+	if g.Spec.ContainerRuntime == kops.ContainerRuntimeContainerd {
+		if errs := validateContainerdHooks(g.Spec.Hooks, field.NewPath("hooks")); len(errs) > 0 {
+			return errs.ToAggregate()
+		}
+	}
+
 	return nil
 }
 
@@ -246,6 +261,15 @@ func CrossValidateInstanceGroup(g *kops.InstanceGroup, cluster *kops.Cluster, st
 		}
 	}
 
+	if g.Spec.RollingUpdate != nil {
+		allErrs = append(allErrs, validateRollingUpdateSurge(g.Spec.RollingUpdate, fieldPath.Child("Spec", "RollingUpdate"), cluster.Spec.CloudProvider)...)
+	}
+
+	// The options of the cluster that only apply to docker cannot be used by an instance group using containerd
+	if g.Spec.ContainerRuntime == kops.ContainerRuntimeContainerd && cluster.Spec.ContainerRuntime != kops.ContainerRuntimeContainerd {
+		allErrs = append(allErrs, validateContainerdClusterSpec(&cluster.Spec, field.NewPath("Spec"))...)
+	}
+
 	if len(allErrs) != 0 {
 		return allErrs[0]
 	}
@@ -151,7 +151,7 @@ func ValidateCluster(c *kops.Cluster, strict bool) *field.Error {
 	if strict && c.Spec.KubeProxy == nil {
 		return field.Required(fieldSpec.Child("KubeProxy"), "KubeProxy not configured")
 	}
-	if strict && c.Spec.Docker == nil {
+	if strict && c.Spec.Docker == nil && c.Spec.ContainerRuntime != kops.ContainerRuntimeContainerd {
 		return field.Required(fieldSpec.Child("Docker"), "Docker not configured")
 	}
 
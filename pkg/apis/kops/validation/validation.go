@@ -19,11 +19,16 @@ package validation
 import (
 	"fmt"
 	"net"
+	"net/url"
+	"path"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/blang/semver"
 
 	"k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	utilnet "k8s.io/apimachinery/pkg/util/net"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
@@ -116,6 +121,306 @@ func validateClusterSpec(spec *kops.ClusterSpec, fieldPath *field.Path) field.Er
 		}
 	}
 
+	if spec.RollingUpdate != nil {
+		allErrs = append(allErrs, validateRollingUpdate(spec.RollingUpdate, fieldPath.Child("rollingUpdate"), false)...)
+		allErrs = append(allErrs, validateRollingUpdateSurge(spec.RollingUpdate, fieldPath.Child("rollingUpdate"), spec.CloudProvider)...)
+	}
+
+	if spec.Validation != nil {
+		allErrs = append(allErrs, validateClusterValidation(spec.Validation, fieldPath.Child("validation"))...)
+	}
+
+	if spec.ExternalCA != nil {
+		allErrs = append(allErrs, validateExternalCA(spec.ExternalCA, fieldPath.Child("externalCA"))...)
+	}
+
+	if spec.StateEncryption != nil {
+		allErrs = append(allErrs, validateStateEncryption(spec.StateEncryption, fieldPath.Child("stateEncryption"))...)
+	}
+
+	if spec.SecretHistoryDepth != nil && *spec.SecretHistoryDepth < 0 {
+		allErrs = append(allErrs, field.Invalid(fieldPath.Child("secretHistoryDepth"), *spec.SecretHistoryDepth, "must not be negative"))
+	}
+
+	allErrs = append(allErrs, validateContainerRuntime(spec.ContainerRuntime, fieldPath.Child("containerRuntime"))...)
+	if spec.ContainerRuntime == kops.ContainerRuntimeContainerd {
+		allErrs = append(allErrs, validateContainerdClusterSpec(spec, fieldPath)...)
+	}
+
+	if spec.NodeReconciliation != nil {
+		allErrs = append(allErrs, validateNodeReconciliation(spec.NodeReconciliation, fieldPath.Child("nodeReconciliation"))...)
+	}
+
+	return allErrs
+}
+
+func validateNodeReconciliation(spec *kops.NodeReconciliationSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if spec.Interval != nil && spec.Interval.Duration < time.Minute {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("interval"), spec.Interval.Duration.String(), "interval must be at least 1m"))
+	}
+	if spec.UpdateCheckInterval != nil && spec.UpdateCheckInterval.Duration < 30*time.Second {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("updateCheckInterval"), spec.UpdateCheckInterval.Duration.String(), "interval must be at least 30s"))
+	}
+	if spec.MetricsFile != "" && !path.IsAbs(spec.MetricsFile) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("metricsFile"), spec.MetricsFile, "must be an absolute path"))
+	}
+
+	return allErrs
+}
+
+func validateContainerRuntime(runtime string, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	switch runtime {
+	case "", kops.ContainerRuntimeDocker, kops.ContainerRuntimeContainerd:
+		// OK
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath, runtime, []string{kops.ContainerRuntimeDocker, kops.ContainerRuntimeContainerd}))
+	}
+
+	return allErrs
+}
+
+// validateContainerdClusterSpec rejects the options of the cluster spec that only apply to docker, for when the
+// cluster or an instance group uses containerd
+func validateContainerdClusterSpec(spec *kops.ClusterSpec, fieldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if spec.ContainerRuntime == kops.ContainerRuntimeContainerd && spec.Docker != nil {
+		allErrs = append(allErrs, field.Forbidden(fieldPath.Child("docker"), "docker options cannot be used with containerd"))
+	}
+	if spec.Kubelet != nil && spec.Kubelet.DockerDisableSharedPID != nil {
+		allErrs = append(allErrs, field.Forbidden(fieldPath.Child("kubelet", "dockerDisableSharedPID"), "dockerDisableSharedPID cannot be used with containerd"))
+	}
+	if spec.MasterKubelet != nil && spec.MasterKubelet.DockerDisableSharedPID != nil {
+		allErrs = append(allErrs, field.Forbidden(fieldPath.Child("masterKubelet", "dockerDisableSharedPID"), "dockerDisableSharedPID cannot be used with containerd"))
+	}
+	allErrs = append(allErrs, validateContainerdHooks(spec.Hooks, fieldPath.Child("hooks"))...)
+
+	// kubenet is implemented by the docker shim of the kubelet; containerd only supports CNI
+	if n := spec.Networking; n != nil && (n.Classic != nil || n.Kubenet != nil || n.External != nil || n.Kopeio != nil) {
+		allErrs = append(allErrs, field.Forbidden(fieldPath.Child("networking"), "containerd requires a CNI networking provider"))
+	}
+
+	return allErrs
+}
+
+// validateContainerdHooks rejects hooks that run in a docker container
+func validateContainerdHooks(hooks []kops.HookSpec, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i := range hooks {
+		if !hooks[i].Disabled && hooks[i].ExecContainer != nil {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Index(i).Child("execContainer"), "execContainer hooks run in docker, and cannot be used with containerd"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateExternalCA(spec *kops.ExternalCASpec, fldpath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	var set []string
+	if len(spec.Command) != 0 {
+		set = append(set, "command")
+		if spec.Command[0] == "" {
+			allErrs = append(allErrs, field.Required(fldpath.Child("command").Index(0), ""))
+		}
+	}
+	if spec.URL != "" {
+		set = append(set, "url")
+		u, err := url.Parse(spec.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("url"), spec.URL, "must be an http or https URL"))
+		}
+	}
+	if spec.KeyFile != "" {
+		set = append(set, "keyFile")
+	}
+
+	if len(set) == 0 {
+		allErrs = append(allErrs, field.Required(fldpath, "one of command, url or keyFile must be set"))
+	} else if len(set) > 1 {
+		allErrs = append(allErrs, field.Forbidden(fldpath, fmt.Sprintf("only one of command, url or keyFile may be set, not %s", strings.Join(set, ", "))))
+	}
+
+	return allErrs
+}
+
+var gcpKMSKeyNameRegexp = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+$`)
+
+func validateStateEncryption(spec *kops.StateEncryptionSpec, fldpath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	var set []string
+	if spec.AWSKMSKeyARN != "" {
+		set = append(set, "awsKMSKeyARN")
+		// The region is taken from the ARN, so that nodes can decrypt without further configuration
+		tokens := strings.SplitN(spec.AWSKMSKeyARN, ":", 6)
+		if len(tokens) != 6 || tokens[0] != "arn" || tokens[2] != "kms" || tokens[3] == "" || tokens[5] == "" {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("awsKMSKeyARN"), spec.AWSKMSKeyARN, "must be the ARN of a KMS key or alias, e.g. arn:aws:kms:us-east-1:123456789012:key/<id>"))
+		}
+	}
+	if spec.GCPKMSKeyName != "" {
+		set = append(set, "gcpKMSKeyName")
+		if !gcpKMSKeyNameRegexp.MatchString(spec.GCPKMSKeyName) {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("gcpKMSKeyName"), spec.GCPKMSKeyName, "must be of the form projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>"))
+		}
+	}
+	if spec.KeyFile != "" {
+		set = append(set, "keyFile")
+	}
+
+	if len(set) == 0 {
+		allErrs = append(allErrs, field.Required(fldpath, "one of awsKMSKeyARN, gcpKMSKeyName or keyFile must be set"))
+	} else if len(set) > 1 {
+		allErrs = append(allErrs, field.Forbidden(fldpath, fmt.Sprintf("only one of awsKMSKeyARN, gcpKMSKeyName or keyFile may be set, not %s", strings.Join(set, ", "))))
+	}
+
+	return allErrs
+}
+
+func validateClusterValidation(spec *kops.ClusterValidationSpec, fldpath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for i, namespace := range spec.PodNamespaces {
+		if namespace == "" {
+			allErrs = append(allErrs, field.Required(fldpath.Child("podNamespaces").Index(i), ""))
+		}
+	}
+	for i, namespace := range spec.IgnorePodNamespaces {
+		if namespace == "" {
+			allErrs = append(allErrs, field.Required(fldpath.Child("ignorePodNamespaces").Index(i), ""))
+		}
+	}
+	for i := range spec.DaemonSets {
+		allErrs = append(allErrs, validateValidationResource(&spec.DaemonSets[i], fldpath.Child("daemonSets").Index(i))...)
+	}
+	for i := range spec.Deployments {
+		allErrs = append(allErrs, validateValidationResource(&spec.Deployments[i], fldpath.Child("deployments").Index(i))...)
+	}
+	for i, condition := range spec.FailNodeConditions {
+		if condition == "" {
+			allErrs = append(allErrs, field.Required(fldpath.Child("failNodeConditions").Index(i), ""))
+		} else if condition == "Ready" {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("failNodeConditions").Index(i), condition, "Nodes are always required to be Ready"))
+		}
+	}
+	if spec.DNS != nil {
+		for i, name := range spec.DNS.Names {
+			if name == "" {
+				allErrs = append(allErrs, field.Required(fldpath.Child("dns", "names").Index(i), ""))
+			}
+		}
+	}
+	if spec.CertificateExpiryThreshold != nil && spec.CertificateExpiryThreshold.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(fldpath.Child("certificateExpiryThreshold"), spec.CertificateExpiryThreshold.Duration.String(), "must not be negative"))
+	}
+
+	return allErrs
+}
+
+func validateValidationResource(resource *kops.ValidationResource, fldpath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if resource.Namespace == "" {
+		allErrs = append(allErrs, field.Required(fldpath.Child("namespace"), ""))
+	}
+	if resource.Name == "" {
+		allErrs = append(allErrs, field.Required(fldpath.Child("name"), ""))
+	}
+	return allErrs
+}
+
+func validateRollingUpdate(rollingUpdate *kops.RollingUpdate, fldpath *field.Path, onMasterInstanceGroup bool) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if rollingUpdate.MaxUnavailable != nil {
+		unavailable, err := intstr.GetValueFromIntOrPercent(rollingUpdate.MaxUnavailable, 1, false)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("maxUnavailable"), rollingUpdate.MaxUnavailable,
+				fmt.Sprintf("Unable to parse: %v", err)))
+		}
+		if unavailable < 0 {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("maxUnavailable"), rollingUpdate.MaxUnavailable, "Cannot be negative"))
+		}
+	}
+	if rollingUpdate.MaxSurge != nil {
+		surge, err := intstr.GetValueFromIntOrPercent(rollingUpdate.MaxSurge, 1000, true)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("maxSurge"), rollingUpdate.MaxSurge,
+				fmt.Sprintf("Unable to parse: %v", err)))
+		}
+		if onMasterInstanceGroup && surge != 0 {
+			allErrs = append(allErrs, field.Forbidden(fldpath.Child("maxSurge"), "Cannot surge instance groups with role \"Master\""))
+		} else if surge < 0 {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("maxSurge"), rollingUpdate.MaxSurge, "Cannot be negative"))
+		}
+	}
+	for i := range rollingUpdate.Hooks {
+		allErrs = append(allErrs, validateRollingUpdateHook(&rollingUpdate.Hooks[i], fldpath.Child("hooks").Index(i))...)
+	}
+
+	return allErrs
+}
+
+// SurgeSupported returns true if instances can be detached from their instance groups on the cloud provider,
+// which is needed to surge a rolling update
+func SurgeSupported(cloudProvider string) bool {
+	switch kops.CloudProviderID(cloudProvider) {
+	case kops.CloudProviderAWS, kops.CloudProviderGCE:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateRollingUpdateSurge rejects surging on cloud providers that cannot detach instances,
+// so that a rolling update fails up front rather than after instances have been cordoned
+func validateRollingUpdateSurge(rollingUpdate *kops.RollingUpdate, fldpath *field.Path, cloudProvider string) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if rollingUpdate.MaxSurge != nil && !SurgeSupported(cloudProvider) {
+		surge, err := intstr.GetValueFromIntOrPercent(rollingUpdate.MaxSurge, 1000, true)
+		if err == nil && surge > 0 {
+			allErrs = append(allErrs, field.Forbidden(fldpath.Child("maxSurge"), fmt.Sprintf("Surging is not supported on cloud provider %q", cloudProvider)))
+		}
+	}
+	return allErrs
+}
+
+func validateRollingUpdateHook(hook *kops.RollingUpdateHook, fldpath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if len(hook.Events) == 0 {
+		allErrs = append(allErrs, field.Required(fldpath.Child("events"), "At least one event must be specified"))
+	}
+	var validEvents []string
+	for _, event := range kops.RollingUpdateHookEvents {
+		validEvents = append(validEvents, string(event))
+	}
+	for i := range hook.Events {
+		event := string(hook.Events[i])
+		allErrs = append(allErrs, IsValidValue(fldpath.Child("events").Index(i), &event, validEvents)...)
+	}
+
+	if len(hook.Exec) == 0 && hook.Webhook == "" {
+		allErrs = append(allErrs, field.Required(fldpath, "One of exec or webhook must be specified"))
+	} else if len(hook.Exec) != 0 && hook.Webhook != "" {
+		allErrs = append(allErrs, field.Forbidden(fldpath.Child("webhook"), "Only one of exec or webhook may be specified"))
+	}
+
+	if hook.Webhook != "" {
+		u, err := url.Parse(hook.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			allErrs = append(allErrs, field.Invalid(fldpath.Child("webhook"), hook.Webhook, "Must be an http or https URL"))
+		}
+	}
+
+	if hook.Timeout != nil && hook.Timeout.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldpath.Child("timeout"), hook.Timeout, "Must be positive"))
+	}
+
 	return allErrs
 }
 
@@ -345,6 +650,41 @@ func validateEtcdClusterSpec(spec *kops.EtcdClusterSpec, fieldPath *field.Path)
 		errs = append(errs, field.Invalid(fieldPath.Child("provider"), spec.Provider, "Provider must be Manager or Legacy"))
 	}
 
+	if spec.Backups != nil {
+		errs = append(errs, validateEtcdBackupSpec(spec.Backups, fieldPath.Child("backups"))...)
+
+		// The default etcd-manager image exits on an unknown --backup-interval flag
+		if spec.Backups.Interval != nil && (spec.Manager == nil || spec.Manager.Image == "") {
+			errs = append(errs, field.Forbidden(fieldPath.Child("backups", "interval"), "the default etcd-manager image does not support a backup interval; set manager.image to an etcd-manager image that supports --backup-interval"))
+		}
+	}
+
+	return errs
+}
+
+func validateEtcdBackupSpec(spec *kops.EtcdBackupSpec, fieldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if spec.Interval != nil && spec.Interval.Duration < time.Minute {
+		errs = append(errs, field.Invalid(fieldPath.Child("interval"), spec.Interval.Duration.String(), "interval must be at least 1m"))
+	}
+
+	if spec.Retention != nil {
+		counts := []struct {
+			name  string
+			value *int32
+		}{
+			{"hourly", spec.Retention.Hourly},
+			{"daily", spec.Retention.Daily},
+			{"weekly", spec.Retention.Weekly},
+		}
+		for _, count := range counts {
+			if count.value != nil && *count.value < 0 {
+				errs = append(errs, field.Invalid(fieldPath.Child("retention", count.name), *count.value, "must not be negative"))
+			}
+		}
+	}
+
 	return errs
 }
 
@@ -18,11 +18,15 @@ package validation
 
 import (
 	"testing"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
 )
 
 func Test_Validate_DNS(t *testing.T) {
@@ -335,3 +339,528 @@ func Test_Validate_Calico(t *testing.T) {
 		testErrors(t, g.Input, errs, g.ExpectedErrors)
 	}
 }
+
+func Test_Validate_RollingUpdate(t *testing.T) {
+	grid := []struct {
+		Input          kops.RollingUpdate
+		OnMasterIG     bool
+		ExpectedErrors []string
+	}{
+		{
+			Input: kops.RollingUpdate{},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxUnavailable: intStr(intstr.FromInt(0)),
+			},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxUnavailable: intStr(intstr.FromString("0%")),
+			},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxUnavailable: intStr(intstr.FromString("nope")),
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.maxUnavailable"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxUnavailable: intStr(intstr.FromInt(-1)),
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.maxUnavailable"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxUnavailable: intStr(intstr.FromString("-1%")),
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.maxUnavailable"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxSurge: intStr(intstr.FromString("20%")),
+			},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxSurge: intStr(intstr.FromString("nope")),
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.maxSurge"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxSurge: intStr(intstr.FromInt(-1)),
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.maxSurge"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxSurge: intStr(intstr.FromInt(0)),
+			},
+			OnMasterIG: true,
+		},
+		{
+			Input: kops.RollingUpdate{
+				MaxSurge: intStr(intstr.FromInt(1)),
+			},
+			OnMasterIG:     true,
+			ExpectedErrors: []string{"Forbidden::TestField.maxSurge"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				Hooks: []kops.RollingUpdateHook{
+					{
+						Events: []kops.RollingUpdateHookEvent{kops.RollingUpdateHookPreTerminate},
+						Exec:   []string{"/usr/local/bin/deregister"},
+					},
+					{
+						Events:  []kops.RollingUpdateHookEvent{kops.RollingUpdateHookPostValidate},
+						Webhook: "https://smoke.example.com/check",
+						Timeout: &metav1.Duration{Duration: time.Minute},
+					},
+				},
+			},
+		},
+		{
+			Input: kops.RollingUpdate{
+				Hooks: []kops.RollingUpdateHook{
+					{
+						Exec: []string{"/usr/local/bin/deregister"},
+					},
+				},
+			},
+			ExpectedErrors: []string{"Required value::TestField.hooks[0].events"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				Hooks: []kops.RollingUpdateHook{
+					{
+						Events: []kops.RollingUpdateHookEvent{"PostTerminate"},
+						Exec:   []string{"/usr/local/bin/deregister"},
+					},
+				},
+			},
+			ExpectedErrors: []string{"Unsupported value::TestField.hooks[0].events[0]"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				Hooks: []kops.RollingUpdateHook{
+					{
+						Events: []kops.RollingUpdateHookEvent{kops.RollingUpdateHookPreDrain},
+					},
+				},
+			},
+			ExpectedErrors: []string{"Required value::TestField.hooks[0]"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				Hooks: []kops.RollingUpdateHook{
+					{
+						Events:  []kops.RollingUpdateHookEvent{kops.RollingUpdateHookPreDrain},
+						Exec:    []string{"/usr/local/bin/deregister"},
+						Webhook: "https://lb.example.com/deregister",
+					},
+				},
+			},
+			ExpectedErrors: []string{"Forbidden::TestField.hooks[0].webhook"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				Hooks: []kops.RollingUpdateHook{
+					{
+						Events:  []kops.RollingUpdateHookEvent{kops.RollingUpdateHookPreDrain},
+						Webhook: "lb.example.com/deregister",
+					},
+				},
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.hooks[0].webhook"},
+		},
+		{
+			Input: kops.RollingUpdate{
+				Hooks: []kops.RollingUpdateHook{
+					{
+						Events:  []kops.RollingUpdateHookEvent{kops.RollingUpdateHookPreDrain},
+						Webhook: "https://lb.example.com/deregister",
+						Timeout: &metav1.Duration{},
+					},
+				},
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.hooks[0].timeout"},
+		},
+	}
+	for _, g := range grid {
+		errs := validateRollingUpdate(&g.Input, field.NewPath("TestField"), g.OnMasterIG)
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_RollingUpdateSurge(t *testing.T) {
+	grid := []struct {
+		Input          kops.RollingUpdate
+		CloudProvider  string
+		ExpectedErrors []string
+	}{
+		{
+			Input:         kops.RollingUpdate{MaxSurge: intStr(intstr.FromInt(1))},
+			CloudProvider: "aws",
+		},
+		{
+			Input:         kops.RollingUpdate{MaxSurge: intStr(intstr.FromString("20%"))},
+			CloudProvider: "gce",
+		},
+		{
+			Input:         kops.RollingUpdate{MaxSurge: intStr(intstr.FromInt(0))},
+			CloudProvider: "openstack",
+		},
+		{
+			Input:          kops.RollingUpdate{MaxSurge: intStr(intstr.FromInt(1))},
+			CloudProvider:  "openstack",
+			ExpectedErrors: []string{"Forbidden::TestField.maxSurge"},
+		},
+		{
+			Input:          kops.RollingUpdate{MaxSurge: intStr(intstr.FromString("20%"))},
+			CloudProvider:  "digitalocean",
+			ExpectedErrors: []string{"Forbidden::TestField.maxSurge"},
+		},
+	}
+	for _, g := range grid {
+		errs := validateRollingUpdateSurge(&g.Input, field.NewPath("TestField"), g.CloudProvider)
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func intStr(i intstr.IntOrString) *intstr.IntOrString {
+	return &i
+}
+
+func Test_Validate_ExternalCA(t *testing.T) {
+	grid := []struct {
+		Input          kops.ExternalCASpec
+		ExpectedErrors []string
+	}{
+		{
+			Input: kops.ExternalCASpec{Command: []string{"/usr/local/bin/sign", "--profile", "kubernetes"}},
+		},
+		{
+			Input: kops.ExternalCASpec{URL: "https://signer.example.com/sign"},
+		},
+		{
+			Input: kops.ExternalCASpec{KeyFile: "/tmp/ca.key"},
+		},
+		{
+			Input:          kops.ExternalCASpec{},
+			ExpectedErrors: []string{"Required value::TestField"},
+		},
+		{
+			Input:          kops.ExternalCASpec{URL: "signer.example.com"},
+			ExpectedErrors: []string{"Invalid value::TestField.url"},
+		},
+		{
+			Input:          kops.ExternalCASpec{Command: []string{""}},
+			ExpectedErrors: []string{"Required value::TestField.command[0]"},
+		},
+		{
+			Input: kops.ExternalCASpec{
+				URL:     "https://signer.example.com/sign",
+				KeyFile: "/tmp/ca.key",
+			},
+			ExpectedErrors: []string{"Forbidden::TestField"},
+		},
+	}
+	for _, g := range grid {
+		errs := validateExternalCA(&g.Input, field.NewPath("TestField"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_StateEncryption(t *testing.T) {
+	grid := []struct {
+		Input          kops.StateEncryptionSpec
+		ExpectedErrors []string
+	}{
+		{
+			Input: kops.StateEncryptionSpec{AWSKMSKeyARN: "arn:aws:kms:us-east-1:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"},
+		},
+		{
+			Input: kops.StateEncryptionSpec{AWSKMSKeyARN: "arn:aws:kms:us-east-1:123456789012:alias/kops"},
+		},
+		{
+			Input: kops.StateEncryptionSpec{GCPKMSKeyName: "projects/example/locations/global/keyRings/kops/cryptoKeys/state"},
+		},
+		{
+			Input: kops.StateEncryptionSpec{KeyFile: "/tmp/state.key"},
+		},
+		{
+			Input:          kops.StateEncryptionSpec{},
+			ExpectedErrors: []string{"Required value::TestField"},
+		},
+		{
+			Input:          kops.StateEncryptionSpec{AWSKMSKeyARN: "alias/kops"},
+			ExpectedErrors: []string{"Invalid value::TestField.awsKMSKeyARN"},
+		},
+		{
+			Input:          kops.StateEncryptionSpec{GCPKMSKeyName: "projects/example/keyRings/kops"},
+			ExpectedErrors: []string{"Invalid value::TestField.gcpKMSKeyName"},
+		},
+		{
+			Input: kops.StateEncryptionSpec{
+				GCPKMSKeyName: "projects/example/locations/global/keyRings/kops/cryptoKeys/state",
+				KeyFile:       "/tmp/state.key",
+			},
+			ExpectedErrors: []string{"Forbidden::TestField"},
+		},
+	}
+	for _, g := range grid {
+		errs := validateStateEncryption(&g.Input, field.NewPath("TestField"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_ClusterValidation(t *testing.T) {
+	grid := []struct {
+		Input          kops.ClusterValidationSpec
+		ExpectedErrors []string
+	}{
+		{
+			Input: kops.ClusterValidationSpec{
+				PodNamespaces:       []string{"ingress"},
+				IgnorePodNamespaces: []string{"kube-system"},
+				DaemonSets:          []kops.ValidationResource{{Namespace: "kube-system", Name: "calico-node"}},
+				Deployments:         []kops.ValidationResource{{Namespace: "kube-system", Name: "coredns"}},
+				FailNodeConditions:  []string{"MemoryPressure"},
+				DNS:                 &kops.ValidationDNSSpec{},
+
+				CertificateExpiryThreshold: &metav1.Duration{Duration: 14 * 24 * time.Hour},
+			},
+		},
+		{
+			Input: kops.ClusterValidationSpec{
+				DaemonSets: []kops.ValidationResource{{Name: "calico-node"}},
+			},
+			ExpectedErrors: []string{"Required value::TestField.daemonSets[0].namespace"},
+		},
+		{
+			Input: kops.ClusterValidationSpec{
+				Deployments: []kops.ValidationResource{{Namespace: "kube-system"}},
+			},
+			ExpectedErrors: []string{"Required value::TestField.deployments[0].name"},
+		},
+		{
+			Input: kops.ClusterValidationSpec{
+				FailNodeConditions: []string{"Ready"},
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.failNodeConditions[0]"},
+		},
+		{
+			Input: kops.ClusterValidationSpec{
+				DNS: &kops.ValidationDNSSpec{Names: []string{""}},
+			},
+			ExpectedErrors: []string{"Required value::TestField.dns.names[0]"},
+		},
+		{
+			Input: kops.ClusterValidationSpec{
+				CertificateExpiryThreshold: &metav1.Duration{Duration: -time.Hour},
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.certificateExpiryThreshold"},
+		},
+	}
+	for _, g := range grid {
+		errs := validateClusterValidation(&g.Input, field.NewPath("TestField"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_EtcdBackups(t *testing.T) {
+	negative := int32(-1)
+	seven := int32(7)
+	grid := []struct {
+		Input          kops.EtcdBackupSpec
+		ExpectedErrors []string
+	}{
+		{
+			Input: kops.EtcdBackupSpec{
+				Interval:  &metav1.Duration{Duration: time.Hour},
+				Retention: &kops.EtcdBackupRetentionSpec{Hourly: &seven, Daily: &seven, Weekly: &seven},
+			},
+		},
+		{
+			Input: kops.EtcdBackupSpec{
+				Interval: &metav1.Duration{Duration: 10 * time.Second},
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.interval"},
+		},
+		{
+			Input: kops.EtcdBackupSpec{
+				Retention: &kops.EtcdBackupRetentionSpec{Daily: &negative},
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.retention.daily"},
+		},
+	}
+	for _, g := range grid {
+		errs := validateEtcdBackupSpec(&g.Input, field.NewPath("TestField"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_EtcdBackupInterval(t *testing.T) {
+	grid := []struct {
+		Input          kops.EtcdClusterSpec
+		ExpectedErrors []string
+	}{
+		{
+			Input: kops.EtcdClusterSpec{
+				Backups: &kops.EtcdBackupSpec{},
+			},
+		},
+		{
+			Input: kops.EtcdClusterSpec{
+				Backups: &kops.EtcdBackupSpec{Interval: &metav1.Duration{Duration: time.Hour}},
+			},
+			ExpectedErrors: []string{"Forbidden::TestField.backups.interval"},
+		},
+		{
+			Input: kops.EtcdClusterSpec{
+				Backups: &kops.EtcdBackupSpec{Interval: &metav1.Duration{Duration: time.Hour}},
+				Manager: &kops.EtcdManagerSpec{Image: "example.com/etcd-manager:custom"},
+			},
+		},
+	}
+	for _, g := range grid {
+		errs := validateEtcdClusterSpec(&g.Input, field.NewPath("TestField"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_SecretHistoryDepth(t *testing.T) {
+	grid := []struct {
+		Input          *int32
+		ExpectedErrors []string
+	}{
+		{
+			Input: nil,
+		},
+		{
+			Input: fi.Int32(0),
+		},
+		{
+			Input: fi.Int32(25),
+		},
+		{
+			Input:          fi.Int32(-1),
+			ExpectedErrors: []string{"Invalid value::spec.secretHistoryDepth"},
+		},
+	}
+	for _, g := range grid {
+		spec := &kops.ClusterSpec{
+			Subnets:            []kops.ClusterSubnetSpec{{Name: "a"}},
+			SecretHistoryDepth: g.Input,
+		}
+		errs := validateClusterSpec(spec, field.NewPath("spec"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_ContainerRuntime(t *testing.T) {
+	grid := []struct {
+		Input          kops.ClusterSpec
+		ExpectedErrors []string
+	}{
+		{
+			Input: kops.ClusterSpec{},
+		},
+		{
+			Input: kops.ClusterSpec{ContainerRuntime: "docker", Docker: &kops.DockerConfig{}},
+		},
+		{
+			Input: kops.ClusterSpec{ContainerRuntime: "containerd"},
+		},
+		{
+			Input:          kops.ClusterSpec{ContainerRuntime: "rkt"},
+			ExpectedErrors: []string{"Unsupported value::spec.containerRuntime"},
+		},
+		{
+			Input:          kops.ClusterSpec{ContainerRuntime: "containerd", Docker: &kops.DockerConfig{}},
+			ExpectedErrors: []string{"Forbidden::spec.docker"},
+		},
+		{
+			Input: kops.ClusterSpec{
+				ContainerRuntime: "containerd",
+				Kubelet:          &kops.KubeletConfigSpec{DockerDisableSharedPID: fi.Bool(true)},
+			},
+			ExpectedErrors: []string{"Forbidden::spec.kubelet.dockerDisableSharedPID"},
+		},
+		{
+			Input: kops.ClusterSpec{
+				ContainerRuntime: "containerd",
+				Hooks:            []kops.HookSpec{{ExecContainer: &kops.ExecContainerAction{Image: "busybox"}}},
+			},
+			ExpectedErrors: []string{"Forbidden::spec.hooks[0].execContainer"},
+		},
+		{
+			Input: kops.ClusterSpec{
+				ContainerRuntime: "containerd",
+				Networking:       &kops.NetworkingSpec{Kubenet: &kops.KubenetNetworkingSpec{}},
+			},
+			ExpectedErrors: []string{"Forbidden::spec.networking"},
+		},
+		{
+			Input: kops.ClusterSpec{
+				ContainerRuntime: "containerd",
+				Networking:       &kops.NetworkingSpec{Weave: &kops.WeaveNetworkingSpec{}},
+			},
+		},
+	}
+	for _, g := range grid {
+		spec := g.Input
+		spec.Subnets = []kops.ClusterSubnetSpec{{Name: "a"}}
+		errs := validateClusterSpec(&spec, field.NewPath("spec"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
+
+func Test_Validate_NodeReconciliation(t *testing.T) {
+	grid := []struct {
+		Input          kops.NodeReconciliationSpec
+		ExpectedErrors []string
+	}{
+		{
+			Input: kops.NodeReconciliationSpec{},
+		},
+		{
+			Input: kops.NodeReconciliationSpec{
+				Interval:    &metav1.Duration{Duration: 15 * time.Minute},
+				Reconcile:   fi.Bool(true),
+				MetricsFile: "/var/lib/node_exporter/textfile/kops_nodeup.prom",
+			},
+		},
+		{
+			Input: kops.NodeReconciliationSpec{
+				InPlaceUpdates:      fi.Bool(true),
+				UpdateCheckInterval: &metav1.Duration{Duration: 5 * time.Minute},
+			},
+		},
+		{
+			Input: kops.NodeReconciliationSpec{
+				UpdateCheckInterval: &metav1.Duration{Duration: time.Second},
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.updateCheckInterval"},
+		},
+		{
+			Input: kops.NodeReconciliationSpec{
+				Interval: &metav1.Duration{Duration: 10 * time.Second},
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.interval"},
+		},
+		{
+			Input: kops.NodeReconciliationSpec{
+				MetricsFile: "kops_nodeup.prom",
+			},
+			ExpectedErrors: []string{"Invalid value::TestField.metricsFile"},
+		},
+	}
+	for _, g := range grid {
+		errs := validateNodeReconciliation(&g.Input, field.NewPath("TestField"))
+		testErrors(t, g.Input, errs, g.ExpectedErrors)
+	}
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/pki"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+// Certificate describes a certificate in the keystore
+type Certificate struct {
+	// Keyset is the name of the keypair, e.g. kubelet
+	Keyset string `json:"keyset"`
+	// Id is the id of the certificate within the keyset
+	Id string `json:"id"`
+	// Primary is true for the certificate of the keyset that is in use; the others are kept so they remain trusted
+	Primary bool `json:"primary"`
+
+	Subject        string    `json:"subject"`
+	Issuer         string    `json:"issuer"`
+	AlternateNames []string  `json:"alternateNames,omitempty"`
+	IsCA           bool      `json:"isCA,omitempty"`
+	NotBefore      time.Time `json:"notBefore"`
+	NotAfter       time.Time `json:"notAfter"`
+}
+
+// DaysRemaining returns the number of whole days from now until the certificate expires, which is negative once it has expired
+func (c *Certificate) DaysRemaining(now time.Time) int {
+	remaining := c.NotAfter.Sub(now)
+	days := int(remaining / (24 * time.Hour))
+	if remaining < 0 && remaining%(24*time.Hour) != 0 {
+		days--
+	}
+	return days
+}
+
+// ListCertificates returns every certificate of every keypair in the keystore, ordered by keyset and id
+func ListCertificates(keyStore fi.CAStore) ([]*Certificate, error) {
+	keysets, err := keyStore.ListKeysets()
+	if err != nil {
+		return nil, fmt.Errorf("error listing keysets: %v", err)
+	}
+
+	var certificates []*Certificate
+	for _, k := range keysets {
+		if k.Spec.Type != kops.SecretTypeKeypair {
+			continue
+		}
+
+		keyset, err := keyStore.FindCertificateKeyset(k.Name)
+		if err != nil {
+			return nil, err
+		}
+		if keyset == nil {
+			continue
+		}
+
+		primary := fi.FindPrimary(keyset)
+		for i := range keyset.Spec.Keys {
+			item := &keyset.Spec.Keys[i]
+			if len(item.PublicMaterial) == 0 {
+				continue
+			}
+			cert, err := pki.ParsePEMCertificate(item.PublicMaterial)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing certificate %s:%s: %v", k.Name, item.Id, err)
+			}
+
+			c := newCertificate(cert.Certificate)
+			c.Keyset = k.Name
+			c.Id = item.Id
+			c.Primary = primary != nil && primary.Id == item.Id
+			certificates = append(certificates, c)
+		}
+	}
+
+	sort.Slice(certificates, func(i, j int) bool {
+		if certificates[i].Keyset != certificates[j].Keyset {
+			return certificates[i].Keyset < certificates[j].Keyset
+		}
+		return certificates[i].Id < certificates[j].Id
+	})
+
+	return certificates, nil
+}
+
+// Expiring returns the primary certificates that expire within threshold of now, including those that have already expired
+func Expiring(certificates []*Certificate, now time.Time, threshold time.Duration) []*Certificate {
+	var expiring []*Certificate
+	for _, c := range certificates {
+		if !c.Primary {
+			continue
+		}
+		if c.NotAfter.Before(now.Add(threshold)) {
+			expiring = append(expiring, c)
+		}
+	}
+	return expiring
+}
+
+func newCertificate(cert *x509.Certificate) *Certificate {
+	c := &Certificate{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		IsCA:      cert.IsCA,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}
+	c.AlternateNames = append(c.AlternateNames, cert.DNSNames...)
+	c.AlternateNames = append(c.AlternateNames, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		c.AlternateNames = append(c.AlternateNames, ip.String())
+	}
+	return c
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaysRemaining(t *testing.T) {
+	now := time.Date(2019, 6, 5, 12, 0, 0, 0, time.UTC)
+
+	grid := []struct {
+		NotAfter time.Time
+		Expected int
+	}{
+		{NotAfter: now.Add(30 * 24 * time.Hour), Expected: 30},
+		{NotAfter: now.Add(30*24*time.Hour - time.Minute), Expected: 29},
+		{NotAfter: now.Add(time.Hour), Expected: 0},
+		{NotAfter: now, Expected: 0},
+		{NotAfter: now.Add(-time.Hour), Expected: -1},
+		{NotAfter: now.Add(-48 * time.Hour), Expected: -2},
+	}
+	for _, g := range grid {
+		c := &Certificate{NotAfter: g.NotAfter}
+		if actual := c.DaysRemaining(now); actual != g.Expected {
+			t.Errorf("unexpected days remaining until %v: got %d, expected %d", g.NotAfter, actual, g.Expected)
+		}
+	}
+}
+
+func TestExpiring(t *testing.T) {
+	now := time.Date(2019, 6, 5, 12, 0, 0, 0, time.UTC)
+
+	certificates := []*Certificate{
+		{Keyset: "ca", Primary: true, NotAfter: now.Add(10 * 365 * 24 * time.Hour)},
+		{Keyset: "kubelet", Primary: true, NotAfter: now.Add(10 * 24 * time.Hour)},
+		{Keyset: "kubelet", Primary: false, NotAfter: now.Add(-24 * time.Hour)},
+		{Keyset: "master", Primary: true, NotAfter: now.Add(-time.Hour)},
+		{Keyset: "kube-proxy", Primary: true, NotAfter: now.Add(40 * 24 * time.Hour)},
+	}
+
+	var names []string
+	for _, c := range Expiring(certificates, now, 30*24*time.Hour) {
+		names = append(names, c.Keyset)
+	}
+	if len(names) != 2 || names[0] != "kubelet" || names[1] != "master" {
+		t.Errorf("unexpected expiring certificates: %v", names)
+	}
+
+	if expiring := Expiring(certificates, now, 0); len(expiring) != 1 || expiring[0].Keyset != "master" {
+		t.Errorf("expected only the expired certificate with a zero threshold, got %v", expiring)
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/pki"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+// RotationPlan lists the keypairs that Rotate will issue replacement certificates for
+type RotationPlan struct {
+	// CAs are the certificate authorities that get a new private key and certificate
+	CAs []string
+	// Keypairs are the keypairs whose certificates are reissued, keeping their private keys
+	Keypairs []string
+}
+
+// PlanRotation works out which keypairs are rotated. Every keypair issued by a CA in the keystore is rotated;
+// with rotateCA, so is the cluster CA (and so all keypairs it has issued are rotated onto the new CA).
+// Keypairs that were not issued by a CA in the keystore, such as certificates added by the user, are skipped.
+func PlanRotation(keyStore fi.CAStore, rotateCA bool) (*RotationPlan, error) {
+	certificates, err := ListCertificates(keyStore)
+	if err != nil {
+		return nil, err
+	}
+
+	cas := make(map[string]string)
+	for _, c := range certificates {
+		if c.Primary && c.IsCA {
+			cas[c.Subject] = c.Keyset
+		}
+	}
+
+	plan := &RotationPlan{}
+	if rotateCA {
+		found := false
+		for _, keyset := range cas {
+			if keyset == fi.CertificateId_CA {
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("cluster CA %q not found in keystore", fi.CertificateId_CA)
+		}
+		_, caPrivateKey, _, err := keyStore.FindKeypair(fi.CertificateId_CA)
+		if err != nil {
+			return nil, err
+		}
+		if caPrivateKey == nil {
+			return nil, fmt.Errorf("private key of cluster CA %q not found in keystore; an external CA must be rotated outside kops", fi.CertificateId_CA)
+		}
+		plan.CAs = append(plan.CAs, fi.CertificateId_CA)
+	}
+
+	for _, c := range certificates {
+		if !c.Primary || c.IsCA {
+			continue
+		}
+		if _, found := cas[c.Issuer]; !found {
+			klog.Warningf("not rotating keypair %q, which was not issued by a CA in the keystore (issuer %q)", c.Keyset, c.Issuer)
+			continue
+		}
+		plan.Keypairs = append(plan.Keypairs, c.Keyset)
+	}
+
+	return plan, nil
+}
+
+// Rotate issues the replacement certificates in the plan. The replacements keep the subject, alternate names
+// and usages of the certificates they replace, and become the primary certificates of their keysets; the
+// certificates they replace are kept in the keysets. CAs are rotated first, so the keypairs are issued by the new CAs.
+func Rotate(keyStore fi.CAStore, plan *RotationPlan) error {
+	for _, name := range plan.CAs {
+		cert, _, _, err := keyStore.FindKeypair(name)
+		if err != nil {
+			return err
+		}
+		if cert == nil {
+			return fmt.Errorf("certificate %q not found in keystore", name)
+		}
+
+		privateKey, err := pki.GeneratePrivateKey()
+		if err != nil {
+			return err
+		}
+
+		replacement, err := pki.SignNewCertificate(privateKey, buildTemplate(cert.Certificate), nil, nil)
+		if err != nil {
+			return fmt.Errorf("error issuing certificate %q: %v", name, err)
+		}
+		if err := keyStore.StoreKeypair(name, replacement, privateKey); err != nil {
+			return fmt.Errorf("error storing certificate %q: %v", name, err)
+		}
+		klog.Infof("Rotated CA %q", name)
+	}
+
+	// We look up the signers once the CAs have been rotated
+	signers := make(map[string]string)
+	{
+		certificates, err := ListCertificates(keyStore)
+		if err != nil {
+			return err
+		}
+		for _, c := range certificates {
+			if c.Primary && c.IsCA {
+				signers[c.Subject] = c.Keyset
+			}
+		}
+	}
+
+	for _, name := range plan.Keypairs {
+		cert, privateKey, _, err := keyStore.FindKeypair(name)
+		if err != nil {
+			return err
+		}
+		if cert == nil || privateKey == nil {
+			return fmt.Errorf("keypair %q not found in keystore", name)
+		}
+
+		signer := signers[cert.Certificate.Issuer.String()]
+		if signer == "" {
+			return fmt.Errorf("CA that issued keypair %q not found in keystore", name)
+		}
+		caCertificate, caPrivateKey, _, err := keyStore.FindKeypair(signer)
+		if err != nil {
+			return err
+		}
+		if caCertificate == nil {
+			return fmt.Errorf("CA certificate %q not found in keystore", signer)
+		}
+
+		if caPrivateKey == nil {
+			// The CA is external, so the keystore issues the certificate through the external signer
+			if _, err := keyStore.CreateKeypair(signer, name, buildTemplate(cert.Certificate), privateKey); err != nil {
+				return fmt.Errorf("error issuing certificate %q: %v", name, err)
+			}
+		} else {
+			replacement, err := pki.SignNewCertificate(privateKey, buildTemplate(cert.Certificate), caCertificate.Certificate, caPrivateKey)
+			if err != nil {
+				return fmt.Errorf("error issuing certificate %q: %v", name, err)
+			}
+			if err := keyStore.StoreKeypair(name, replacement, privateKey); err != nil {
+				return fmt.Errorf("error storing certificate %q: %v", name, err)
+			}
+		}
+		klog.Infof("Rotated keypair %q, issued by %q", name, signer)
+	}
+
+	return nil
+}
+
+// buildTemplate returns a template for a certificate that replaces cert
+func buildTemplate(cert *x509.Certificate) *x509.Certificate {
+	return &x509.Certificate{
+		// The serial is the id of the certificate in the keyset; the newest is the primary
+		SerialNumber: pki.BuildPKISerial(time.Now().UnixNano()),
+
+		Subject:               cert.Subject,
+		DNSNames:              cert.DNSNames,
+		EmailAddresses:        cert.EmailAddresses,
+		IPAddresses:           cert.IPAddresses,
+		KeyUsage:              cert.KeyUsage,
+		ExtKeyUsage:           cert.ExtKeyUsage,
+		BasicConstraintsValid: cert.BasicConstraintsValid,
+		IsCA:                  cert.IsCA,
+	}
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certificates
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/pki"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+// fakeKeyStore is an in-memory keystore, implementing the methods used for listing and rotating certificates
+type fakeKeyStore struct {
+	fi.CAStore
+
+	keysets map[string][]*fakeKeypair
+
+	// externalCAKey is the private key of an external CA, which is not held in the keysets
+	externalCAKey *pki.PrivateKey
+}
+
+type fakeKeypair struct {
+	cert       *pki.Certificate
+	privateKey *pki.PrivateKey
+}
+
+var _ fi.CAStore = &fakeKeyStore{}
+
+func (s *fakeKeyStore) ListKeysets() ([]*kops.Keyset, error) {
+	var keysets []*kops.Keyset
+	for name := range s.keysets {
+		keyset := &kops.Keyset{}
+		keyset.Name = name
+		keyset.Spec.Type = kops.SecretTypeKeypair
+		keysets = append(keysets, keyset)
+	}
+	return keysets, nil
+}
+
+func (s *fakeKeyStore) FindCertificateKeyset(name string) (*kops.Keyset, error) {
+	keypairs := s.keysets[name]
+	if len(keypairs) == 0 {
+		return nil, nil
+	}
+	keyset := &kops.Keyset{}
+	keyset.Name = name
+	keyset.Spec.Type = kops.SecretTypeKeypair
+	for _, keypair := range keypairs {
+		publicMaterial, err := keypair.cert.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		keyset.Spec.Keys = append(keyset.Spec.Keys, kops.KeysetItem{
+			Id:             keypair.cert.Certificate.SerialNumber.String(),
+			PublicMaterial: publicMaterial,
+		})
+	}
+	return keyset, nil
+}
+
+func (s *fakeKeyStore) FindKeypair(name string) (*pki.Certificate, *pki.PrivateKey, fi.KeysetFormat, error) {
+	var primary *fakeKeypair
+	for _, keypair := range s.keysets[name] {
+		if primary == nil || keypair.cert.Certificate.SerialNumber.Cmp(primary.cert.Certificate.SerialNumber) > 0 {
+			primary = keypair
+		}
+	}
+	if primary == nil {
+		return nil, nil, "", nil
+	}
+	return primary.cert, primary.privateKey, fi.KeysetFormatV1Alpha2, nil
+}
+
+func (s *fakeKeyStore) FindCert(name string) (*pki.Certificate, error) {
+	cert, _, _, err := s.FindKeypair(name)
+	return cert, err
+}
+
+func (s *fakeKeyStore) StoreKeypair(name string, cert *pki.Certificate, privateKey *pki.PrivateKey) error {
+	s.keysets[name] = append(s.keysets[name], &fakeKeypair{cert: cert, privateKey: privateKey})
+	return nil
+}
+
+// CreateKeypair issues the certificate with the private key in externalCAKey, standing in for an external CA
+func (s *fakeKeyStore) CreateKeypair(signer string, name string, template *x509.Certificate, privateKey *pki.PrivateKey) (*pki.Certificate, error) {
+	caCertificate, _, _, err := s.FindKeypair(signer)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := pki.SignNewCertificate(privateKey, template, caCertificate.Certificate, s.externalCAKey)
+	if err != nil {
+		return nil, err
+	}
+	return cert, s.StoreKeypair(name, cert, privateKey)
+}
+
+// buildKeyStore returns a keystore holding a CA, a kubelet keypair issued by the CA and a self-signed certificate
+func buildKeyStore(t *testing.T) fi.CAStore {
+	keyStore := &fakeKeyStore{keysets: make(map[string][]*fakeKeypair)}
+
+	issue := func(name string, template *x509.Certificate, signer string) {
+		privateKey, err := pki.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("error generating private key: %v", err)
+		}
+		template.SerialNumber = big.NewInt(1)
+		var cert *pki.Certificate
+		if signer == "" {
+			cert, err = pki.SignNewCertificate(privateKey, template, nil, nil)
+		} else {
+			caCertificate, caPrivateKey, _, _ := keyStore.FindKeypair(signer)
+			cert, err = pki.SignNewCertificate(privateKey, template, caCertificate.Certificate, caPrivateKey)
+		}
+		if err != nil {
+			t.Fatalf("error signing certificate %q: %v", name, err)
+		}
+		if err := keyStore.StoreKeypair(name, cert, privateKey); err != nil {
+			t.Fatalf("error storing keypair %q: %v", name, err)
+		}
+	}
+
+	issue(fi.CertificateId_CA, fi.BuildCAX509Template(), "")
+	issue("kubelet", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kubelet", Organization: []string{"system:nodes"}},
+		DNSNames:    []string{"kubelet.example.com"},
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, fi.CertificateId_CA)
+	issue("custom", &x509.Certificate{Subject: pkix.Name{CommonName: "custom"}}, "")
+
+	return keyStore
+}
+
+func TestListCertificates(t *testing.T) {
+	keyStore := buildKeyStore(t)
+
+	certificates, err := ListCertificates(keyStore)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var keysets []string
+	for _, c := range certificates {
+		keysets = append(keysets, c.Keyset)
+		if !c.Primary {
+			t.Errorf("expected the only certificate of keyset %q to be primary", c.Keyset)
+		}
+	}
+	if !reflect.DeepEqual(keysets, []string{"ca", "custom", "kubelet"}) {
+		t.Fatalf("unexpected keysets: %v", keysets)
+	}
+
+	kubelet := certificates[2]
+	if kubelet.Subject != "CN=kubelet,O=system:nodes" || kubelet.Issuer != "CN=kubernetes" || kubelet.IsCA {
+		t.Errorf("unexpected kubelet certificate: %+v", kubelet)
+	}
+	if !reflect.DeepEqual(kubelet.AlternateNames, []string{"kubelet.example.com"}) {
+		t.Errorf("unexpected kubelet alternate names: %v", kubelet.AlternateNames)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	for _, rotateCA := range []bool{false, true} {
+		keyStore := buildKeyStore(t)
+
+		oldCA, err := keyStore.FindCert(fi.CertificateId_CA)
+		if err != nil {
+			t.Fatalf("error reading CA: %v", err)
+		}
+		oldKubelet, oldKubeletKey, _, err := keyStore.FindKeypair("kubelet")
+		if err != nil {
+			t.Fatalf("error reading kubelet keypair: %v", err)
+		}
+
+		plan, err := PlanRotation(keyStore, rotateCA)
+		if err != nil {
+			t.Fatalf("unexpected error planning rotation: %v", err)
+		}
+		expected := &RotationPlan{Keypairs: []string{"kubelet"}}
+		if rotateCA {
+			expected.CAs = []string{fi.CertificateId_CA}
+		}
+		if !reflect.DeepEqual(plan, expected) {
+			t.Fatalf("unexpected rotation plan with rotateCA=%v: %+v", rotateCA, plan)
+		}
+
+		if err := Rotate(keyStore, plan); err != nil {
+			t.Fatalf("unexpected error rotating: %v", err)
+		}
+
+		ca, err := keyStore.FindCert(fi.CertificateId_CA)
+		if err != nil {
+			t.Fatalf("error reading CA: %v", err)
+		}
+		if rotateCA == reflect.DeepEqual(ca.Certificate.Raw, oldCA.Certificate.Raw) {
+			t.Errorf("unexpected CA after rotation with rotateCA=%v", rotateCA)
+		}
+
+		kubelet, kubeletKey, _, err := keyStore.FindKeypair("kubelet")
+		if err != nil {
+			t.Fatalf("error reading kubelet keypair: %v", err)
+		}
+		if kubelet.Certificate.SerialNumber.Cmp(oldKubelet.Certificate.SerialNumber) <= 0 {
+			t.Errorf("expected the replacement kubelet certificate to be primary")
+		}
+		if !reflect.DeepEqual(kubelet.Certificate.Subject.String(), oldKubelet.Certificate.Subject.String()) ||
+			!reflect.DeepEqual(kubelet.Certificate.DNSNames, oldKubelet.Certificate.DNSNames) ||
+			!reflect.DeepEqual(kubelet.Certificate.ExtKeyUsage, oldKubelet.Certificate.ExtKeyUsage) {
+			t.Errorf("replacement kubelet certificate does not match the original: %v", kubelet.Certificate.Subject)
+		}
+		if !reflect.DeepEqual(kubeletKey, oldKubeletKey) {
+			t.Errorf("expected the kubelet private key to be kept")
+		}
+		if err := kubelet.Certificate.CheckSignatureFrom(ca.Certificate); err != nil {
+			t.Errorf("replacement kubelet certificate is not signed by the primary CA: %v", err)
+		}
+
+		certificates, err := ListCertificates(keyStore)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectedCount := 4
+		if rotateCA {
+			expectedCount = 5
+		}
+		if len(certificates) != expectedCount {
+			t.Errorf("expected the replaced certificates to be kept, found %d certificates", len(certificates))
+		}
+	}
+}
+
+func TestRotateExternalCA(t *testing.T) {
+	keyStore := buildKeyStore(t).(*fakeKeyStore)
+
+	// Only the certificate of an external CA is held in the keystore
+	ca := keyStore.keysets[fi.CertificateId_CA][0]
+	keyStore.externalCAKey = ca.privateKey
+	ca.privateKey = nil
+
+	if _, err := PlanRotation(keyStore, true); err == nil {
+		t.Errorf("expected an error rotating an external CA")
+	}
+
+	plan, err := PlanRotation(keyStore, false)
+	if err != nil {
+		t.Fatalf("unexpected error planning rotation: %v", err)
+	}
+	if err := Rotate(keyStore, plan); err != nil {
+		t.Fatalf("unexpected error rotating: %v", err)
+	}
+
+	kubelet, _, _, err := keyStore.FindKeypair("kubelet")
+	if err != nil {
+		t.Fatalf("error reading kubelet keypair: %v", err)
+	}
+	if len(keyStore.keysets["kubelet"]) != 2 {
+		t.Errorf("expected the kubelet keypair to be rotated")
+	}
+	if err := kubelet.Certificate.CheckSignatureFrom(ca.cert.Certificate); err != nil {
+		t.Errorf("replacement kubelet certificate is not signed by the external CA: %v", err)
+	}
+}
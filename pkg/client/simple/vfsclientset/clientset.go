@@ -101,6 +101,27 @@ func (c *VFSClientset) SSHCredentialStore(cluster *kops.Cluster) (fi.SSHCredenti
 	return fi.NewVFSSSHCredentialStore(cluster, basedir), nil
 }
 
+// knownStateFiles are the files, relative to the ConfigBase, that kops stores for a cluster
+var knownStateFiles = []string{"config", "cluster.spec"}
+
+// knownStateDirs are the directories, relative to the ConfigBase, under which kops stores files for a cluster
+var knownStateDirs = []string{"addons/", "pki/", "secrets/", "instancegroup/", "instancegroup.spec/", "manifests/", "backups/", "rollingupdate/", "encryptionconfig/", "quarantine/"}
+
+// IsKnownStatePath returns true if the path, relative to the ConfigBase, is one that kops stores for a cluster
+func IsKnownStatePath(relativePath string) bool {
+	for _, f := range knownStateFiles {
+		if relativePath == f {
+			return true
+		}
+	}
+	for _, d := range knownStateDirs {
+		if strings.HasPrefix(relativePath, d) {
+			return true
+		}
+	}
+	return false
+}
+
 func DeleteAllClusterState(basePath vfs.Path) error {
 	paths, err := basePath.ReadTree()
 	if err != nil {
@@ -117,26 +138,8 @@ func DeleteAllClusterState(basePath vfs.Path) error {
 			continue
 		}
 
-		if relativePath == "config" || relativePath == "cluster.spec" {
-			continue
-		}
-		if strings.HasPrefix(relativePath, "addons/") {
-			continue
-		}
-		if strings.HasPrefix(relativePath, "pki/") {
-			continue
-		}
-		if strings.HasPrefix(relativePath, "secrets/") {
-			continue
-		}
-		if strings.HasPrefix(relativePath, "instancegroup/") {
-			continue
-		}
-		if strings.HasPrefix(relativePath, "manifests/") {
-			continue
-		}
 		// TODO: offer an option _not_ to delete backups?
-		if strings.HasPrefix(relativePath, "backups/") {
+		if IsKnownStatePath(relativePath) {
 			continue
 		}
 
@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfsclientset
+
+import (
+	"bytes"
+	"testing"
+
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func TestDeleteAllClusterState(t *testing.T) {
+	grid := []struct {
+		files       []string
+		expectError bool
+	}{
+		{
+			files: []string{"config", "cluster.spec", "instancegroup/nodes", "pki/issued/ca/keyset.yaml"},
+		},
+		{
+			// Written by kops rolling-update cluster
+			files: []string{"config", "rollingupdate/status"},
+		},
+		{
+			// Written by kops rotate encryption-key
+			files: []string{"config", "encryptionconfig/rotation"},
+		},
+		{
+			files:       []string{"config", "unknown/file"},
+			expectError: true,
+		},
+	}
+
+	for _, g := range grid {
+		basePath := vfs.NewMemFSPath(vfs.NewMemFSContext(), "memfs://state/cluster.example.com")
+		for _, f := range g.files {
+			if err := basePath.Join(f).WriteFile(bytes.NewReader([]byte("data")), nil); err != nil {
+				t.Fatalf("error writing %q: %v", f, err)
+			}
+		}
+
+		err := DeleteAllClusterState(basePath)
+		if g.expectError {
+			if err == nil {
+				t.Errorf("expected error deleting %v", g.files)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error deleting %v: %v", g.files, err)
+			continue
+		}
+		for _, f := range g.files {
+			if _, err := basePath.Join(f).ReadFile(); err == nil {
+				t.Errorf("expected %q to be deleted", f)
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+// LegacyEtcdClusters returns the names of the etcd clusters that are managed by protokube rather than etcd-manager.
+// fullCluster must be the populated cluster spec, in which the provider of every etcd cluster is set.
+func LegacyEtcdClusters(fullCluster *kops.Cluster) []string {
+	var names []string
+	for _, etcdCluster := range fullCluster.Spec.EtcdClusters {
+		if etcdCluster.Provider == kops.EtcdProviderTypeLegacy {
+			names = append(names, etcdCluster.Name)
+		}
+	}
+	return names
+}
+
+// MigrateEtcdClustersToManager switches the named etcd clusters in cluster to etcd-manager.
+// The etcd version in use is taken from fullCluster and set explicitly, because the default version
+// for etcd-manager can differ from that of the legacy provider, and the migration must not also upgrade etcd.
+func MigrateEtcdClustersToManager(cluster *kops.Cluster, fullCluster *kops.Cluster, names []string) error {
+	for _, name := range names {
+		var etcdCluster, fullEtcdCluster *kops.EtcdClusterSpec
+		for _, c := range cluster.Spec.EtcdClusters {
+			if c.Name == name {
+				etcdCluster = c
+			}
+		}
+		for _, c := range fullCluster.Spec.EtcdClusters {
+			if c.Name == name {
+				fullEtcdCluster = c
+			}
+		}
+		if etcdCluster == nil || fullEtcdCluster == nil {
+			return fmt.Errorf("etcd cluster %q not found", name)
+		}
+		if fullEtcdCluster.Version == "" {
+			return fmt.Errorf("unable to determine the version of etcd cluster %q", name)
+		}
+
+		etcdCluster.Provider = kops.EtcdProviderTypeManager
+		etcdCluster.Version = fullEtcdCluster.Version
+	}
+	return nil
+}
+
+// SnapshotEtcdVolumes takes a snapshot of the volume of every member of the named etcd clusters,
+// waiting for the snapshots to complete. It returns the ids of the snapshots.
+func SnapshotEtcdVolumes(cloud fi.Cloud, cluster *kops.Cluster, status *kops.ClusterStatus, names []string) ([]string, error) {
+	awsCloud, ok := cloud.(awsup.AWSCloud)
+	if !ok {
+		return nil, fmt.Errorf("snapshots of etcd volumes are not (currently) supported for %T", cloud)
+	}
+
+	var snapshotIDs []string
+	for _, name := range names {
+		var etcdStatus *kops.EtcdClusterStatus
+		for i := range status.EtcdClusters {
+			if status.EtcdClusters[i].Name == name {
+				etcdStatus = &status.EtcdClusters[i]
+			}
+		}
+		if etcdStatus == nil || len(etcdStatus.Members) == 0 {
+			return nil, fmt.Errorf("no volumes found for etcd cluster %q", name)
+		}
+
+		for _, member := range etcdStatus.Members {
+			request := &ec2.CreateSnapshotInput{
+				VolumeId:    aws.String(member.VolumeId),
+				Description: aws.String(fmt.Sprintf("etcd-%s member %s of %s, before migration to etcd-manager", name, member.Name, cluster.ObjectMeta.Name)),
+			}
+			response, err := awsCloud.EC2().CreateSnapshot(request)
+			if err != nil {
+				return nil, fmt.Errorf("error creating snapshot of volume %q: %v", member.VolumeId, err)
+			}
+
+			snapshotID := aws.StringValue(response.SnapshotId)
+			klog.Infof("Created snapshot %s of etcd-%s volume %s", snapshotID, name, member.VolumeId)
+			if err := awsCloud.CreateTags(snapshotID, awsCloud.Tags()); err != nil {
+				return nil, fmt.Errorf("error tagging snapshot %q: %v", snapshotID, err)
+			}
+			snapshotIDs = append(snapshotIDs, snapshotID)
+		}
+	}
+
+	klog.Infof("Waiting for %d etcd volume snapshots to complete", len(snapshotIDs))
+	start := time.Now()
+	err := awsCloud.EC2().WaitUntilSnapshotCompleted(&ec2.DescribeSnapshotsInput{
+		SnapshotIds: aws.StringSlice(snapshotIDs),
+	})
+	if err != nil {
+		return snapshotIDs, fmt.Errorf("error waiting for snapshots %v to complete: %v", snapshotIDs, err)
+	}
+	klog.V(2).Infof("Snapshots completed after %v", time.Since(start))
+
+	return snapshotIDs, nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+func TestMigrateEtcdClustersToManager(t *testing.T) {
+	cluster := &kops.Cluster{
+		Spec: kops.ClusterSpec{
+			EtcdClusters: []*kops.EtcdClusterSpec{
+				{Name: "main"},
+				{Name: "events", Provider: kops.EtcdProviderTypeLegacy},
+			},
+		},
+	}
+	fullCluster := &kops.Cluster{
+		Spec: kops.ClusterSpec{
+			EtcdClusters: []*kops.EtcdClusterSpec{
+				{Name: "main", Provider: kops.EtcdProviderTypeLegacy, Version: "2.2.1"},
+				{Name: "events", Provider: kops.EtcdProviderTypeLegacy, Version: "2.2.1"},
+			},
+		},
+	}
+
+	legacy := LegacyEtcdClusters(fullCluster)
+	if !reflect.DeepEqual(legacy, []string{"main", "events"}) {
+		t.Fatalf("unexpected legacy etcd clusters: %v", legacy)
+	}
+
+	if err := MigrateEtcdClustersToManager(cluster, fullCluster, legacy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []*kops.EtcdClusterSpec{
+		{Name: "main", Provider: kops.EtcdProviderTypeManager, Version: "2.2.1"},
+		{Name: "events", Provider: kops.EtcdProviderTypeManager, Version: "2.2.1"},
+	}
+	if !reflect.DeepEqual(cluster.Spec.EtcdClusters, expected) {
+		t.Errorf("unexpected etcd clusters after migration: %v", cluster.Spec.EtcdClusters)
+	}
+
+	if err := MigrateEtcdClustersToManager(cluster, fullCluster, []string{"other"}); err == nil {
+		t.Errorf("expected an error migrating an unknown etcd cluster")
+	}
+}
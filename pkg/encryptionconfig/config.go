@@ -0,0 +1,220 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryptionconfig
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"k8s.io/kops/upup/pkg/fi/utils"
+)
+
+// SecretName is the name of the secret in the secret store holding the encryption config
+const SecretName = "encryptionconfig"
+
+// keySize is the size of the keys we generate, which is valid for the aescbc, aesgcm and secretbox providers
+const keySize = 32
+
+// EncryptionConfiguration is the configuration of encryption-at-rest read by kube-apiserver.
+// Only the fields kops needs to rotate keys are typed; the kms provider is passed through unchanged.
+type EncryptionConfiguration struct {
+	Kind       string                  `json:"kind"`
+	APIVersion string                  `json:"apiVersion"`
+	Resources  []ResourceConfiguration `json:"resources"`
+}
+
+// ResourceConfiguration is the list of providers that encrypt a set of resources, the first of which encrypts
+type ResourceConfiguration struct {
+	Resources []string                `json:"resources"`
+	Providers []ProviderConfiguration `json:"providers"`
+}
+
+// ProviderConfiguration configures one provider; exactly one field is set
+type ProviderConfiguration struct {
+	AESGCM    *KeysConfiguration     `json:"aesgcm,omitempty"`
+	AESCBC    *KeysConfiguration     `json:"aescbc,omitempty"`
+	Secretbox *KeysConfiguration     `json:"secretbox,omitempty"`
+	Identity  *IdentityConfiguration `json:"identity,omitempty"`
+	KMS       map[string]interface{} `json:"kms,omitempty"`
+}
+
+// KeysConfiguration is the configuration of a provider that encrypts with keys held in the config
+type KeysConfiguration struct {
+	// Keys are the keys of the provider; the first encrypts, and all are tried when decrypting
+	Keys []Key `json:"keys"`
+}
+
+// IdentityConfiguration is the configuration of the identity provider, which does not encrypt
+type IdentityConfiguration struct{}
+
+// Key is a named key
+type Key struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+}
+
+// Parse parses an encryption config
+func Parse(data []byte) (*EncryptionConfiguration, error) {
+	config := &EncryptionConfiguration{}
+	if err := utils.YamlUnmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("error parsing encryption config: %v", err)
+	}
+	return config, nil
+}
+
+// Marshal serializes the encryption config
+func (c *EncryptionConfiguration) Marshal() ([]byte, error) {
+	data, err := utils.YamlMarshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing encryption config: %v", err)
+	}
+	return data, nil
+}
+
+// keyedProviders returns the first provider with keys for each set of resources.
+// Keys are only rotated in that provider: it is the one that encrypts, or the first to be tried.
+func (c *EncryptionConfiguration) keyedProviders() []*KeysConfiguration {
+	var providers []*KeysConfiguration
+	for i := range c.Resources {
+		for _, p := range c.Resources[i].Providers {
+			var keys *KeysConfiguration
+			switch {
+			case p.AESCBC != nil:
+				keys = p.AESCBC
+			case p.AESGCM != nil:
+				keys = p.AESGCM
+			case p.Secretbox != nil:
+				keys = p.Secretbox
+			}
+			if keys != nil {
+				providers = append(providers, keys)
+				break
+			}
+		}
+	}
+	return providers
+}
+
+// KeyNames returns the names of the keys of the providers that keys are rotated in, in the order they are tried
+func (c *EncryptionConfiguration) KeyNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, p := range c.keyedProviders() {
+		for _, k := range p.Keys {
+			if !seen[k.Name] {
+				seen[k.Name] = true
+				names = append(names, k.Name)
+			}
+		}
+	}
+	return names
+}
+
+// HasKey returns true if every provider that keys are rotated in has the named key
+func (c *EncryptionConfiguration) HasKey(name string) bool {
+	providers := c.keyedProviders()
+	if len(providers) == 0 {
+		return false
+	}
+	for _, p := range providers {
+		found := false
+		for _, k := range p.Keys {
+			if k.Name == name {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// AddKey adds the key as the last key of each provider that keys are rotated in, so that it can decrypt but
+// does not yet encrypt
+func (c *EncryptionConfiguration) AddKey(key Key) error {
+	providers := c.keyedProviders()
+	if len(providers) == 0 {
+		return fmt.Errorf("encryption config has no aescbc, aesgcm or secretbox provider whose keys can be rotated")
+	}
+	for _, p := range providers {
+		found := false
+		for _, k := range p.Keys {
+			if k.Name == key.Name {
+				found = true
+			}
+		}
+		if !found {
+			p.Keys = append(p.Keys, key)
+		}
+	}
+	return nil
+}
+
+// PromoteKey makes the named key the first key of each provider that keys are rotated in, so that it encrypts
+func (c *EncryptionConfiguration) PromoteKey(name string) error {
+	if !c.HasKey(name) {
+		return fmt.Errorf("encryption config does not have key %q", name)
+	}
+	for _, p := range c.keyedProviders() {
+		keys := make([]Key, 0, len(p.Keys))
+		for _, k := range p.Keys {
+			if k.Name == name {
+				keys = append([]Key{k}, keys...)
+			} else {
+				keys = append(keys, k)
+			}
+		}
+		p.Keys = keys
+	}
+	return nil
+}
+
+// RemoveKeys removes the named keys from each provider that keys are rotated in
+func (c *EncryptionConfiguration) RemoveKeys(names []string) error {
+	remove := make(map[string]bool)
+	for _, name := range names {
+		remove[name] = true
+	}
+	for _, p := range c.keyedProviders() {
+		var keys []Key
+		for _, k := range p.Keys {
+			if !remove[k.Name] {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) == 0 {
+			return fmt.Errorf("removing keys %v would leave a provider without keys", names)
+		}
+		p.Keys = keys
+	}
+	return nil
+}
+
+// NewKey generates a random key with the given name
+func NewKey(name string) (Key, error) {
+	secret := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return Key{}, fmt.Errorf("error generating key: %v", err)
+	}
+	return Key{
+		Name:   name,
+		Secret: base64.StdEncoding.EncodeToString(secret),
+	}, nil
+}
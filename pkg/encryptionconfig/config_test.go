@@ -0,0 +1,145 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryptionconfig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testConfig = `kind: EncryptionConfiguration
+apiVersion: apiserver.config.k8s.io/v1
+resources:
+  - resources:
+    - secrets
+    providers:
+    - aescbc:
+        keys:
+        - name: key1
+          secret: c2VjcmV0IGlzIHNlY3VyZQ==
+    - identity: {}
+  - resources:
+    - configmaps
+    providers:
+    - kms:
+        name: myKmsPlugin
+        endpoint: unix:///tmp/socketfile.sock
+        cachesize: 100
+    - secretbox:
+        keys:
+        - name: key1
+          secret: YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXoxMjM0NTY=
+`
+
+func keysOf(t *testing.T, data []byte) [][]string {
+	config, err := Parse(data)
+	if err != nil {
+		t.Fatalf("unexpected error parsing config: %v", err)
+	}
+	var names [][]string
+	for _, p := range config.keyedProviders() {
+		var keys []string
+		for _, k := range p.Keys {
+			keys = append(keys, k.Name)
+		}
+		names = append(names, keys)
+	}
+	return names
+}
+
+func TestRotateKeys(t *testing.T) {
+	config, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("unexpected error parsing config: %v", err)
+	}
+	if !reflect.DeepEqual(config.KeyNames(), []string{"key1"}) {
+		t.Fatalf("unexpected key names %v", config.KeyNames())
+	}
+
+	key, err := NewKey("key2")
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+	if err := config.AddKey(key); err != nil {
+		t.Fatalf("unexpected error adding key: %v", err)
+	}
+	// Adding the key again is a no-op
+	if err := config.AddKey(key); err != nil {
+		t.Fatalf("unexpected error adding key: %v", err)
+	}
+	data, err := config.Marshal()
+	if err != nil {
+		t.Fatalf("unexpected error serializing config: %v", err)
+	}
+	if got := keysOf(t, data); !reflect.DeepEqual(got, [][]string{{"key1", "key2"}, {"key1", "key2"}}) {
+		t.Errorf("unexpected keys after adding key: %v", got)
+	}
+	if !strings.Contains(string(data), "myKmsPlugin") || !strings.Contains(string(data), "identity: {}") {
+		t.Errorf("expected other providers to be kept:\n%s", data)
+	}
+
+	if err := config.PromoteKey("key2"); err != nil {
+		t.Fatalf("unexpected error promoting key: %v", err)
+	}
+	data, err = config.Marshal()
+	if err != nil {
+		t.Fatalf("unexpected error serializing config: %v", err)
+	}
+	if got := keysOf(t, data); !reflect.DeepEqual(got, [][]string{{"key2", "key1"}, {"key2", "key1"}}) {
+		t.Errorf("unexpected keys after promoting key: %v", got)
+	}
+
+	if err := config.RemoveKeys([]string{"key1"}); err != nil {
+		t.Fatalf("unexpected error removing keys: %v", err)
+	}
+	data, err = config.Marshal()
+	if err != nil {
+		t.Fatalf("unexpected error serializing config: %v", err)
+	}
+	if got := keysOf(t, data); !reflect.DeepEqual(got, [][]string{{"key2"}, {"key2"}}) {
+		t.Errorf("unexpected keys after removing keys: %v", got)
+	}
+
+	if err := config.RemoveKeys([]string{"key2"}); err == nil {
+		t.Errorf("expected an error removing the only key")
+	}
+	if err := config.PromoteKey("key3"); err == nil {
+		t.Errorf("expected an error promoting a missing key")
+	}
+}
+
+func TestAddKeyWithoutKeyedProvider(t *testing.T) {
+	config, err := Parse([]byte(`kind: EncryptionConfiguration
+apiVersion: apiserver.config.k8s.io/v1
+resources:
+  - resources:
+    - secrets
+    providers:
+    - identity: {}
+`))
+	if err != nil {
+		t.Fatalf("unexpected error parsing config: %v", err)
+	}
+	key, err := NewKey("key1")
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+	if err := config.AddKey(key); err == nil {
+		t.Errorf("expected an error adding a key without a provider that holds keys")
+	}
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryptionconfig
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// PathRotationStatus is the path, relative to the cluster's ConfigBase, where key rotation progress is recorded
+const PathRotationStatus = "encryptionconfig/rotation"
+
+// RotationStep is a step of a key rotation.
+// Each step changes the encryption config and then replaces the masters, except RotationStepRewriteSecrets.
+type RotationStep string
+
+const (
+	// RotationStepAddKey adds the new key as the last key, so every kube-apiserver can decrypt with it
+	RotationStepAddKey RotationStep = "AddKey"
+	// RotationStepPromoteKey makes the new key the first key, so it encrypts
+	RotationStepPromoteKey RotationStep = "PromoteKey"
+	// RotationStepRewriteSecrets rewrites every secret, so they are encrypted with the new key
+	RotationStepRewriteSecrets RotationStep = "RewriteSecrets"
+	// RotationStepRemoveOldKeys removes the keys that were replaced
+	RotationStepRemoveOldKeys RotationStep = "RemoveOldKeys"
+	// RotationStepCompleted is recorded once the rotation has completed
+	RotationStepCompleted RotationStep = "Completed"
+)
+
+// RotationSteps are the steps of a key rotation, in order
+var RotationSteps = []RotationStep{
+	RotationStepAddKey,
+	RotationStepPromoteKey,
+	RotationStepRewriteSecrets,
+	RotationStepRemoveOldKeys,
+}
+
+// RotationStatus is the persisted record of a key rotation, allowing it to be resumed
+type RotationStatus struct {
+	// NewKey is the name of the key being rotated in; the key itself is only held in the encryption config
+	NewKey string `json:"newKey"`
+	// OldKeys are the names of the keys being rotated out
+	OldKeys []string `json:"oldKeys"`
+	// Step is the next step of the rotation
+	Step RotationStep `json:"step"`
+	// StartTime is when the rotation was started
+	StartTime time.Time `json:"startTime"`
+	// UpdateTime is when the status was last recorded
+	UpdateTime time.Time `json:"updateTime"`
+}
+
+// NewRotationStatus plans the rotation of the keys of the encryption config
+func NewRotationStatus(config *EncryptionConfiguration) (*RotationStatus, error) {
+	oldKeys := config.KeyNames()
+	if len(oldKeys) == 0 {
+		return nil, fmt.Errorf("encryption config has no aescbc, aesgcm or secretbox provider whose keys can be rotated")
+	}
+
+	now := time.Now().UTC()
+	return &RotationStatus{
+		NewKey:    "key" + now.Format("20060102150405"),
+		OldKeys:   oldKeys,
+		Step:      RotationStepAddKey,
+		StartTime: now,
+	}, nil
+}
+
+// RemainingSteps returns the steps of the rotation that have not been completed
+func (s *RotationStatus) RemainingSteps() []RotationStep {
+	for i, step := range RotationSteps {
+		if step == s.Step {
+			return RotationSteps[i:]
+		}
+	}
+	return nil
+}
+
+// ReadRotationStatus reads the recorded key rotation status for a cluster, returning nil if there is none
+func ReadRotationStatus(configBase vfs.Path) (*RotationStatus, error) {
+	status := &RotationStatus{}
+	if err := registry.ReadConfigDeprecated(configBase.Join(PathRotationStatus), status); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading key rotation status: %v", err)
+	}
+	return status, nil
+}
+
+// WriteRotationStatus records the key rotation status for a cluster
+func WriteRotationStatus(cluster *api.Cluster, configBase vfs.Path, status *RotationStatus) error {
+	status.UpdateTime = time.Now().UTC()
+	if err := registry.WriteConfigDeprecated(cluster, configBase.Join(PathRotationStatus), status); err != nil {
+		return fmt.Errorf("error recording key rotation status: %v", err)
+	}
+	return nil
+}
+
+// ApplyStep makes the change to the encryption config for a step of the rotation.
+// Steps can be applied again, so that an interrupted rotation can be resumed.
+func ApplyStep(config *EncryptionConfiguration, status *RotationStatus, step RotationStep) error {
+	switch step {
+	case RotationStepAddKey:
+		if config.HasKey(status.NewKey) {
+			return nil
+		}
+		key, err := NewKey(status.NewKey)
+		if err != nil {
+			return err
+		}
+		return config.AddKey(key)
+
+	case RotationStepPromoteKey:
+		return config.PromoteKey(status.NewKey)
+
+	case RotationStepRemoveOldKeys:
+		if !config.HasKey(status.NewKey) {
+			return fmt.Errorf("encryption config does not have key %q", status.NewKey)
+		}
+		return config.RemoveKeys(status.OldKeys)
+
+	default:
+		return fmt.Errorf("step %q does not change the encryption config", step)
+	}
+}
+
+// RewriteSecrets rewrites every secret in the cluster unchanged, so that kube-apiserver encrypts each with
+// the current key. It returns the number of secrets rewritten.
+func RewriteSecrets(k8sClient kubernetes.Interface) (int, error) {
+	secrets, err := k8sClient.CoreV1().Secrets(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("error listing secrets: %v", err)
+	}
+
+	count := 0
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		for attempt := 0; ; attempt++ {
+			_, err := k8sClient.CoreV1().Secrets(secret.Namespace).Update(secret)
+			if err == nil {
+				count++
+				break
+			}
+			if errors.IsNotFound(err) {
+				// Deleted since we listed it; nothing to rewrite
+				break
+			}
+			if !errors.IsConflict(err) || attempt >= 5 {
+				return count, fmt.Errorf("error rewriting secret %s/%s: %v", secret.Namespace, secret.Name, err)
+			}
+
+			klog.V(2).Infof("secret %s/%s changed while rewriting it; retrying", secret.Namespace, secret.Name)
+			secret, err = k8sClient.CoreV1().Secrets(secret.Namespace).Get(secret.Name, metav1.GetOptions{})
+			if err != nil {
+				if errors.IsNotFound(err) {
+					break
+				}
+				return count, fmt.Errorf("error reading secret %s/%s: %v", secrets.Items[i].Namespace, secrets.Items[i].Name, err)
+			}
+		}
+	}
+	return count, nil
+}
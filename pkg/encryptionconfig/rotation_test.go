@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryptionconfig
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func TestRotationSteps(t *testing.T) {
+	config, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("unexpected error parsing config: %v", err)
+	}
+
+	status, err := NewRotationStatus(config)
+	if err != nil {
+		t.Fatalf("unexpected error planning rotation: %v", err)
+	}
+	if !reflect.DeepEqual(status.OldKeys, []string{"key1"}) {
+		t.Errorf("unexpected old keys %v", status.OldKeys)
+	}
+	if !reflect.DeepEqual(status.RemainingSteps(), RotationSteps) {
+		t.Errorf("unexpected remaining steps %v", status.RemainingSteps())
+	}
+
+	// Applying a step twice, as when resuming, has the same result
+	for i := 0; i < 2; i++ {
+		if err := ApplyStep(config, status, RotationStepAddKey); err != nil {
+			t.Fatalf("unexpected error adding key: %v", err)
+		}
+	}
+	secret := config.keyedProviders()[0].Keys[1].Secret
+	if err := ApplyStep(config, status, RotationStepAddKey); err != nil {
+		t.Fatalf("unexpected error adding key: %v", err)
+	}
+	if config.keyedProviders()[0].Keys[1].Secret != secret {
+		t.Errorf("expected the key not to be regenerated when the step is applied again")
+	}
+
+	for _, step := range []RotationStep{RotationStepPromoteKey, RotationStepPromoteKey, RotationStepRemoveOldKeys, RotationStepRemoveOldKeys} {
+		if err := ApplyStep(config, status, step); err != nil {
+			t.Fatalf("unexpected error applying step %s: %v", step, err)
+		}
+	}
+	if !reflect.DeepEqual(config.KeyNames(), []string{status.NewKey}) {
+		t.Errorf("unexpected keys after rotation %v", config.KeyNames())
+	}
+
+	if err := ApplyStep(config, status, RotationStepRewriteSecrets); err == nil {
+		t.Errorf("expected an error applying a step that does not change the encryption config")
+	}
+
+	status.Step = RotationStepRewriteSecrets
+	if !reflect.DeepEqual(status.RemainingSteps(), []RotationStep{RotationStepRewriteSecrets, RotationStepRemoveOldKeys}) {
+		t.Errorf("unexpected remaining steps %v", status.RemainingSteps())
+	}
+	status.Step = RotationStepCompleted
+	if len(status.RemainingSteps()) != 0 {
+		t.Errorf("unexpected remaining steps %v", status.RemainingSteps())
+	}
+}
+
+func TestRotationStatusRoundTrip(t *testing.T) {
+	cluster := &api.Cluster{}
+	configBase := vfs.NewMemFSPath(vfs.NewMemFSContext(), "memfs://state/cluster.example.com")
+
+	status, err := ReadRotationStatus(configBase)
+	if err != nil {
+		t.Fatalf("unexpected error reading status: %v", err)
+	}
+	if status != nil {
+		t.Fatalf("expected no status, got %v", status)
+	}
+
+	written := &RotationStatus{NewKey: "key2", OldKeys: []string{"key1"}, Step: RotationStepRewriteSecrets}
+	if err := WriteRotationStatus(cluster, configBase, written); err != nil {
+		t.Fatalf("unexpected error writing status: %v", err)
+	}
+	status, err = ReadRotationStatus(configBase)
+	if err != nil {
+		t.Fatalf("unexpected error reading status: %v", err)
+	}
+	if status == nil || status.NewKey != "key2" || status.Step != RotationStepRewriteSecrets || !reflect.DeepEqual(status.OldKeys, []string{"key1"}) {
+		t.Errorf("unexpected status %v", status)
+	}
+}
+
+func TestRewriteSecrets(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset(
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "a"}},
+		&v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "b"}},
+	)
+
+	// The first update of each secret conflicts, as if it had changed since it was listed
+	conflicted := make(map[string]bool)
+	k8sClient.PrependReactor("update", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		secret := action.(k8stesting.UpdateAction).GetObject().(*v1.Secret)
+		if !conflicted[secret.Name] {
+			conflicted[secret.Name] = true
+			return true, nil, errors.NewConflict(schema.GroupResource{Resource: "secrets"}, secret.Name, fmt.Errorf("object has been modified"))
+		}
+		return false, nil, nil
+	})
+
+	count, err := RewriteSecrets(k8sClient)
+	if err != nil {
+		t.Fatalf("unexpected error rewriting secrets: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 secrets to be rewritten, got %d", count)
+	}
+
+	updates := 0
+	for _, action := range k8sClient.Actions() {
+		if action.GetVerb() == "update" {
+			updates++
+		}
+	}
+	if updates != 4 {
+		t.Errorf("expected each secret to be updated again after a conflict, got %d updates", updates)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/urls"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// MetaFilename is the name of the file etcd-manager writes into each backup to describe it.
+// It is removed first when a backup is deleted, so a partially deleted backup is never restored.
+const MetaFilename = "_etcd_backup.meta"
+
+// Backup is a backup of an etcd cluster in a backup store
+type Backup struct {
+	// Name is the name of the backup, which is the name of its directory in the backup store
+	Name string
+	// Timestamp is when the backup was taken
+	Timestamp time.Time
+	// Files are the files that make up the backup
+	Files []vfs.Path
+}
+
+// BackupStore returns the backup store of the etcd cluster.
+// Unless set in the spec, it is the same directory of the cluster's state store that etcd-manager defaults to.
+func BackupStore(cluster *kops.Cluster, etcdCluster *kops.EtcdClusterSpec) (vfs.Path, error) {
+	store := ""
+	if etcdCluster.Backups != nil {
+		store = etcdCluster.Backups.BackupStore
+	}
+	if store == "" {
+		if cluster.Spec.ConfigBase == "" {
+			return nil, fmt.Errorf("configBase not set for cluster %q", cluster.ObjectMeta.Name)
+		}
+		store = urls.Join(cluster.Spec.ConfigBase, "backups", "etcd", etcdCluster.Name)
+	}
+
+	p, err := vfs.Context.BuildVfsPath(store)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing backup store %q for etcd cluster %q: %v", store, etcdCluster.Name, err)
+	}
+	return p, nil
+}
+
+// ParseBackupName returns the time at which the backup with the given name was taken.
+// etcd-manager names backups after the time they were taken, with a sequence number suffix, e.g. 2019-06-05T12:00:00Z-000001
+func ParseBackupName(name string) (time.Time, bool) {
+	s := name
+	if i := strings.LastIndex(s, "Z-"); i != -1 {
+		s = s[:i+1]
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.UTC(), true
+}
+
+// ListBackups returns the backups in the backup store, oldest first.
+// Directories in the store that are not named like backups, such as the etcd-manager control directory, are ignored.
+func ListBackups(store vfs.Path) ([]*Backup, error) {
+	files, err := store.ReadTree()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing backup store %q: %v", store, err)
+	}
+
+	prefix := strings.TrimSuffix(store.Path(), "/") + "/"
+	backups := make(map[string]*Backup)
+	for _, f := range files {
+		relativePath := strings.TrimPrefix(f.Path(), prefix)
+		if relativePath == f.Path() {
+			klog.Warningf("ignoring file %q that is not in the backup store %q", f, store)
+			continue
+		}
+
+		name := strings.SplitN(relativePath, "/", 2)[0]
+		backup := backups[name]
+		if backup == nil {
+			timestamp, ok := ParseBackupName(name)
+			if !ok {
+				klog.V(4).Infof("ignoring %q in backup store, which is not a backup", name)
+				continue
+			}
+			backup = &Backup{Name: name, Timestamp: timestamp}
+			backups[name] = backup
+		}
+		backup.Files = append(backup.Files, f)
+	}
+
+	var list []*Backup
+	for _, backup := range backups {
+		list = append(list, backup)
+	}
+	sortBackups(list)
+	return list, nil
+}
+
+// sortBackups orders the backups oldest first
+func sortBackups(backups []*Backup) {
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].Timestamp.Equal(backups[j].Timestamp) {
+			return backups[i].Timestamp.Before(backups[j].Timestamp)
+		}
+		return backups[i].Name < backups[j].Name
+	})
+}
+
+// DeleteBackup removes all the files of the backup, starting with its metadata
+func DeleteBackup(backup *Backup) error {
+	files := append([]vfs.Path{}, backup.Files...)
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Base() == MetaFilename && files[j].Base() != MetaFilename
+	})
+
+	for _, f := range files {
+		if err := f.Remove(); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error deleting %q from backup %q: %v", f, backup.Name, err)
+		}
+	}
+	return nil
+}
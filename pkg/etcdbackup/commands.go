@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+const (
+	// controlDir is the directory in the backup store that etcd-manager watches for commands
+	controlDir = "control"
+	// clusterSpecFilename is the name of the file in the control directory holding the expected etcd cluster spec
+	clusterSpecFilename = "etcd-cluster-spec"
+	// CommandFilename is the name of the file holding each command in the control directory
+	CommandFilename = "_command.json"
+)
+
+// PollInterval is how often we check the backup store when waiting for etcd-manager
+var PollInterval = 10 * time.Second
+
+// Command is a command for etcd-manager, in the form etcd-manager reads from the control directory.
+// etcd-manager removes the command once it has carried it out.
+type Command struct {
+	// Timestamp is when the command was issued, in nanoseconds since the epoch
+	Timestamp int64 `json:"timestamp,string"`
+	// RestoreBackup asks etcd-manager to restore a backup
+	RestoreBackup *RestoreBackupCommand `json:"restoreBackup,omitempty"`
+}
+
+// RestoreBackupCommand asks etcd-manager to restore the etcd cluster from a backup
+type RestoreBackupCommand struct {
+	// ClusterSpec is the spec of the restored etcd cluster
+	ClusterSpec *ClusterSpec `json:"clusterSpec,omitempty"`
+	// Backup is the name of the backup to restore
+	Backup string `json:"backup"`
+}
+
+// ClusterSpec is the spec etcd-manager keeps for an etcd cluster
+type ClusterSpec struct {
+	MemberCount int32  `json:"memberCount,omitempty"`
+	EtcdVersion string `json:"etcdVersion,omitempty"`
+}
+
+// ReadClusterSpec returns the etcd cluster spec that etcd-manager expects, or nil if etcd-manager has not written one
+func ReadClusterSpec(store vfs.Path) (*ClusterSpec, error) {
+	p := store.Join(controlDir, clusterSpecFilename)
+	data, err := p.ReadFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading %q: %v", p, err)
+	}
+
+	spec := &ClusterSpec{}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("error parsing %q: %v", p, err)
+	}
+	return spec, nil
+}
+
+// AddCommand writes the command into the control directory of the backup store, returning the path of the command file
+func AddCommand(store vfs.Path, cmd *Command) (vfs.Path, error) {
+	if cmd.Timestamp == 0 {
+		cmd.Timestamp = time.Now().UnixNano()
+	}
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing etcd-manager command: %v", err)
+	}
+
+	name := time.Unix(0, cmd.Timestamp).UTC().Format(time.RFC3339Nano)
+	p := store.Join(controlDir, name, CommandFilename)
+	if err := p.CreateFile(bytes.NewReader(data), nil); err != nil {
+		return nil, fmt.Errorf("error writing etcd-manager command to %q: %v", p, err)
+	}
+	return p, nil
+}
+
+// IsCommandPending returns true if the command at p has not yet been carried out by etcd-manager
+func IsCommandPending(p vfs.Path) (bool, error) {
+	if _, err := p.ReadFile(); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error reading etcd-manager command %q: %v", p, err)
+	}
+	return true, nil
+}
+
+// WaitFor calls done every PollInterval until it returns true or an error, or until timeout has elapsed.
+// It returns false if the timeout was reached.
+func WaitFor(timeout time.Duration, done func() (bool, error)) (bool, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := done()
+		if err != nil || ok {
+			return ok, err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false, nil
+		}
+		if remaining > PollInterval {
+			remaining = PollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
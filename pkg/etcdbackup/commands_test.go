@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func TestAddCommand(t *testing.T) {
+	store := vfs.NewMemFSPath(vfs.NewMemFSContext(), "backups/etcd/main")
+
+	spec := `{"memberCount":3,"etcdVersion":"3.2.24"}`
+	if err := store.Join("control", "etcd-cluster-spec").WriteFile(bytes.NewReader([]byte(spec)), nil); err != nil {
+		t.Fatalf("error writing cluster spec: %v", err)
+	}
+
+	clusterSpec, err := ReadClusterSpec(store)
+	if err != nil {
+		t.Fatalf("error reading cluster spec: %v", err)
+	}
+	if clusterSpec == nil || clusterSpec.MemberCount != 3 || clusterSpec.EtcdVersion != "3.2.24" {
+		t.Fatalf("unexpected cluster spec: %v", clusterSpec)
+	}
+
+	cmd := &Command{
+		Timestamp: time.Date(2019, 6, 5, 12, 0, 0, 0, time.UTC).UnixNano(),
+		RestoreBackup: &RestoreBackupCommand{
+			ClusterSpec: clusterSpec,
+			Backup:      "2019-06-05T11:00:00Z-000001",
+		},
+	}
+	p, err := AddCommand(store, cmd)
+	if err != nil {
+		t.Fatalf("error adding command: %v", err)
+	}
+	if expected := "memfs://backups/etcd/main/control/2019-06-05T12:00:00Z/_command.json"; p.Path() != expected {
+		t.Errorf("unexpected command path %q, expected %q", p.Path(), expected)
+	}
+
+	data, err := p.ReadFile()
+	if err != nil {
+		t.Fatalf("error reading command: %v", err)
+	}
+	expected := `{"timestamp":"1559736000000000000","restoreBackup":{"clusterSpec":{"memberCount":3,"etcdVersion":"3.2.24"},"backup":"2019-06-05T11:00:00Z-000001"}}`
+	if string(data) != expected {
+		t.Errorf("unexpected command %s, expected %s", data, expected)
+	}
+
+	if pending, err := IsCommandPending(p); err != nil || !pending {
+		t.Errorf("expected command to be pending: %v", err)
+	}
+	if err := p.Remove(); err != nil {
+		t.Fatalf("error removing command: %v", err)
+	}
+	if pending, err := IsCommandPending(p); err != nil || pending {
+		t.Errorf("expected command not to be pending once removed: %v", err)
+	}
+}
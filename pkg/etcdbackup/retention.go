@@ -0,0 +1,158 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// RetentionPolicy decides which backups are kept in a backup store.
+// The most recent backup in each of the last Hourly hours, Daily days and Weekly weeks is kept, as is the most recent backup.
+// Hours, days and weeks are in UTC; weeks are ISO weeks.
+type RetentionPolicy struct {
+	Hourly int
+	Daily  int
+	Weekly int
+}
+
+// RetentionPolicyFromSpec builds the RetentionPolicy for the retention settings in the cluster spec
+func RetentionPolicyFromSpec(spec *kops.EtcdBackupRetentionSpec) RetentionPolicy {
+	policy := RetentionPolicy{}
+	if spec.Hourly != nil {
+		policy.Hourly = int(*spec.Hourly)
+	}
+	if spec.Daily != nil {
+		policy.Daily = int(*spec.Daily)
+	}
+	if spec.Weekly != nil {
+		policy.Weekly = int(*spec.Weekly)
+	}
+	return policy
+}
+
+// String renders the policy in the form accepted by ParseRetentionPolicy, e.g. hourly=24,daily=7,weekly=4
+func (p RetentionPolicy) String() string {
+	return fmt.Sprintf("hourly=%d,daily=%d,weekly=%d", p.Hourly, p.Daily, p.Weekly)
+}
+
+// ParseRetentionPolicy parses a policy of the form hourly=24,daily=7,weekly=4; omitted periods keep no backups
+func ParseRetentionPolicy(s string) (RetentionPolicy, error) {
+	policy := RetentionPolicy{}
+	for _, token := range strings.Split(s, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		kv := strings.SplitN(token, "=", 2)
+		if len(kv) != 2 {
+			return policy, fmt.Errorf("invalid retention %q, expected <period>=<count>", token)
+		}
+		n, err := strconv.Atoi(kv[1])
+		if err != nil || n < 0 {
+			return policy, fmt.Errorf("invalid count in retention %q", token)
+		}
+
+		switch kv[0] {
+		case "hourly":
+			policy.Hourly = n
+		case "daily":
+			policy.Daily = n
+		case "weekly":
+			policy.Weekly = n
+		default:
+			return policy, fmt.Errorf("unknown period in retention %q, expected hourly, daily or weekly", token)
+		}
+	}
+	return policy, nil
+}
+
+// Expired returns the backups that the policy does not keep, oldest first
+func (p RetentionPolicy) Expired(backups []*Backup) []*Backup {
+	if len(backups) == 0 {
+		return nil
+	}
+
+	newestFirst := append([]*Backup{}, backups...)
+	sortBackups(newestFirst)
+	for i, j := 0, len(newestFirst)-1; i < j; i, j = i+1, j-1 {
+		newestFirst[i], newestFirst[j] = newestFirst[j], newestFirst[i]
+	}
+
+	keep := map[*Backup]bool{
+		newestFirst[0]: true,
+	}
+
+	periods := []struct {
+		count  int
+		bucket func(t time.Time) string
+	}{
+		{p.Hourly, func(t time.Time) string { return t.Format("2006-01-02T15") }},
+		{p.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{p.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%d", year, week)
+		}},
+	}
+	for _, period := range periods {
+		seen := make(map[string]bool)
+		for _, backup := range newestFirst {
+			bucket := period.bucket(backup.Timestamp.UTC())
+			if seen[bucket] {
+				continue
+			}
+			if len(seen) >= period.count {
+				break
+			}
+			seen[bucket] = true
+			keep[backup] = true
+		}
+	}
+
+	var expired []*Backup
+	for i := len(newestFirst) - 1; i >= 0; i-- {
+		if !keep[newestFirst[i]] {
+			expired = append(expired, newestFirst[i])
+		}
+	}
+	return expired
+}
+
+// Prune deletes the backups in the backup store that the policy does not keep, returning the deleted backups
+func Prune(store vfs.Path, policy RetentionPolicy) ([]*Backup, error) {
+	backups, err := ListBackups(store)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []*Backup
+	for _, backup := range policy.Expired(backups) {
+		klog.Infof("deleting expired etcd backup %q from %s", backup.Name, store)
+		if err := DeleteBackup(backup); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, backup)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package etcdbackup
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func TestParseBackupName(t *testing.T) {
+	grid := []struct {
+		Name     string
+		Expected string
+	}{
+		{Name: "2019-06-05T12:00:00Z-000001", Expected: "2019-06-05T12:00:00Z"},
+		{Name: "2019-06-05T12:00:00Z", Expected: "2019-06-05T12:00:00Z"},
+		{Name: "control"},
+		{Name: "2019-06-05"},
+	}
+	for _, g := range grid {
+		timestamp, ok := ParseBackupName(g.Name)
+		if g.Expected == "" {
+			if ok {
+				t.Errorf("expected %q not to be a backup name, got %v", g.Name, timestamp)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("expected %q to be a backup name", g.Name)
+			continue
+		}
+		if actual := timestamp.Format("2006-01-02T15:04:05Z07:00"); actual != g.Expected {
+			t.Errorf("unexpected timestamp for %q: %s", g.Name, actual)
+		}
+	}
+}
+
+func TestParseRetentionPolicy(t *testing.T) {
+	grid := []struct {
+		Input    string
+		Expected RetentionPolicy
+		Error    bool
+	}{
+		{Input: "hourly=24,daily=7,weekly=4", Expected: RetentionPolicy{Hourly: 24, Daily: 7, Weekly: 4}},
+		{Input: "daily=7", Expected: RetentionPolicy{Daily: 7}},
+		{Input: "", Expected: RetentionPolicy{}},
+		{Input: "monthly=1", Error: true},
+		{Input: "daily=-1", Error: true},
+		{Input: "daily", Error: true},
+	}
+	for _, g := range grid {
+		actual, err := ParseRetentionPolicy(g.Input)
+		if g.Error {
+			if err == nil {
+				t.Errorf("expected error parsing %q", g.Input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %v", g.Input, err)
+			continue
+		}
+		if actual != g.Expected {
+			t.Errorf("unexpected policy for %q: %v", g.Input, actual)
+		}
+		if roundTrip, err := ParseRetentionPolicy(actual.String()); err != nil || roundTrip != actual {
+			t.Errorf("policy %v did not round trip through %q", actual, actual.String())
+		}
+	}
+}
+
+func TestRetentionPolicyExpired(t *testing.T) {
+	names := []string{
+		"2019-06-01T10:00:00Z-000001", // week 22
+		"2019-06-03T10:00:00Z-000002", // week 23
+		"2019-06-04T10:00:00Z-000003",
+		"2019-06-05T10:00:00Z-000004",
+		"2019-06-05T11:00:00Z-000005",
+		"2019-06-05T11:30:00Z-000006",
+		"2019-06-05T12:00:00Z-000007",
+		"2019-06-05T12:15:00Z-000008",
+	}
+	var backups []*Backup
+	for _, name := range names {
+		timestamp, _ := ParseBackupName(name)
+		backups = append(backups, &Backup{Name: name, Timestamp: timestamp})
+	}
+
+	grid := []struct {
+		Policy   RetentionPolicy
+		Expected []string
+	}{
+		{
+			// The most recent backup is always kept
+			Policy:   RetentionPolicy{},
+			Expected: names[:7],
+		},
+		{
+			Policy: RetentionPolicy{Hourly: 2},
+			// keeps 12:15 and 11:30
+			Expected: []string{names[0], names[1], names[2], names[3], names[4], names[6]},
+		},
+		{
+			Policy: RetentionPolicy{Daily: 2},
+			// keeps 06-05 12:15 and 06-04
+			Expected: []string{names[0], names[1], names[3], names[4], names[5], names[6]},
+		},
+		{
+			Policy: RetentionPolicy{Weekly: 2},
+			// keeps 06-05 12:15 (week 23) and 06-01 (week 22)
+			Expected: names[1:7],
+		},
+		{
+			Policy:   RetentionPolicy{Hourly: 2, Daily: 2, Weekly: 2},
+			Expected: []string{names[1], names[3], names[4], names[6]},
+		},
+		{
+			// Only the most recent backup in each hour is kept
+			Policy:   RetentionPolicy{Hourly: 100, Daily: 100, Weekly: 100},
+			Expected: []string{names[4], names[6]},
+		},
+	}
+
+	for _, g := range grid {
+		var actual []string
+		for _, b := range g.Policy.Expired(backups) {
+			actual = append(actual, b.Name)
+		}
+		if !reflect.DeepEqual(actual, g.Expected) {
+			t.Errorf("unexpected expired backups for %v: %v, expected %v", g.Policy, actual, g.Expected)
+		}
+	}
+}
+
+func TestPrune(t *testing.T) {
+	store := vfs.NewMemFSPath(vfs.NewMemFSContext(), "backups/etcd/main")
+
+	files := []string{
+		"control/etcd-cluster-spec",
+		"2019-06-04T10:00:00Z-000001/" + MetaFilename,
+		"2019-06-04T10:00:00Z-000001/etcd.backup.gz",
+		"2019-06-05T10:00:00Z-000002/" + MetaFilename,
+		"2019-06-05T10:00:00Z-000002/etcd.backup.gz",
+		"2019-06-05T11:00:00Z-000003/" + MetaFilename,
+		"2019-06-05T11:00:00Z-000003/etcd.backup.gz",
+	}
+	for _, f := range files {
+		if err := store.Join(f).WriteFile(bytes.NewReader([]byte(f)), nil); err != nil {
+			t.Fatalf("error writing %s: %v", f, err)
+		}
+	}
+
+	backups, err := ListBackups(store)
+	if err != nil {
+		t.Fatalf("error listing backups: %v", err)
+	}
+	if len(backups) != 3 || backups[0].Name != "2019-06-04T10:00:00Z-000001" || len(backups[0].Files) != 2 {
+		t.Fatalf("unexpected backups: %v", backups)
+	}
+
+	deleted, err := Prune(store, RetentionPolicy{Daily: 1})
+	if err != nil {
+		t.Fatalf("error pruning backups: %v", err)
+	}
+	if len(deleted) != 2 || deleted[0].Name != "2019-06-04T10:00:00Z-000001" || deleted[1].Name != "2019-06-05T10:00:00Z-000002" {
+		t.Fatalf("unexpected deleted backups: %v", deleted)
+	}
+
+	for i, f := range files {
+		_, err := store.Join(f).ReadFile()
+		shouldExist := i == 0 || i >= 5
+		if shouldExist && err != nil {
+			t.Errorf("expected %s to be kept: %v", f, err)
+		}
+		if !shouldExist && !os.IsNotExist(err) {
+			t.Errorf("expected %s to be deleted", f)
+		}
+	}
+}
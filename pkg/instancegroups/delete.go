@@ -18,9 +18,11 @@ package instancegroups
 
 import (
 	"fmt"
+	"os"
 
 	"k8s.io/klog"
 	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
 	"k8s.io/kops/pkg/client/simple"
 	"k8s.io/kops/upup/pkg/fi"
 )
@@ -61,5 +63,15 @@ func (d *DeleteInstanceGroup) DeleteInstanceGroup(group *api.InstanceGroup) erro
 		return err
 	}
 
+	// Remove the copy published for nodeup by kops update cluster
+	configBase, err := d.Clientset.ConfigBaseFor(d.Cluster)
+	if err != nil {
+		return fmt.Errorf("error building ConfigBase for cluster: %v", err)
+	}
+	p := registry.InstanceGroupCompletedPath(configBase, group.ObjectMeta.Name)
+	if err := p.Remove(); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error deleting %q: %v", p, err)
+	}
+
 	return nil
 }
@@ -0,0 +1,307 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+)
+
+const (
+	// defaultPodEvictionTimeout is used when DrainOptions.PodEvictionTimeout is not set
+	defaultPodEvictionTimeout = 5 * time.Minute
+
+	// mirrorPodAnnotation is set by the kubelet on the API representation of static pods
+	mirrorPodAnnotation = "kubernetes.io/config.mirror"
+)
+
+var (
+	// evictionRetryInterval is the initial delay before retrying an eviction blocked by a PodDisruptionBudget
+	evictionRetryInterval = 5 * time.Second
+	// evictionRetryMaxInterval is the maximum delay between retries of a blocked eviction
+	evictionRetryMaxInterval = 1 * time.Minute
+	// podDeletionPollInterval is how often we check whether an evicted pod has terminated
+	podDeletionPollInterval = 2 * time.Second
+)
+
+// DrainOptions controls how nodes are drained during a rolling update
+type DrainOptions struct {
+	// PodEvictionTimeout is the maximum time to spend evicting a single pod and waiting for it to terminate,
+	// including retries while its eviction is blocked by a PodDisruptionBudget
+	PodEvictionTimeout time.Duration
+
+	// SkipNamespaces are the namespaces whose pods are left running on the node
+	SkipNamespaces []string
+	// SkipSelectors select the pods that are left running on the node
+	SkipSelectors []labels.Selector
+
+	// ForceDeleteNamespaces are the namespaces whose pods are deleted rather than evicted, ignoring PodDisruptionBudgets
+	ForceDeleteNamespaces []string
+	// ForceDeleteSelectors select the pods that are deleted rather than evicted, ignoring PodDisruptionBudgets
+	ForceDeleteSelectors []labels.Selector
+}
+
+// BlockedPod describes a pod that could not be removed from a node
+type BlockedPod struct {
+	// Namespace is the namespace of the pod
+	Namespace string `json:"namespace"`
+	// Name is the name of the pod
+	Name string `json:"name"`
+	// Reason describes why the pod could not be removed
+	Reason string `json:"reason"`
+}
+
+// DrainReport describes the outcome of draining a node
+type DrainReport struct {
+	// NodeName is the name of the node that was drained
+	NodeName string
+	// Evicted are the pods that were evicted, as namespace/name
+	Evicted []string
+	// Deleted are the pods that were force-deleted, as namespace/name
+	Deleted []string
+	// Skipped are the pods that were left on the node, as namespace/name
+	Skipped []string
+	// Blocked are the pods that could not be removed from the node
+	Blocked []BlockedPod
+}
+
+// DrainError is returned when some pods could not be removed from a node
+type DrainError struct {
+	Report *DrainReport
+}
+
+func (e *DrainError) Error() string {
+	var reasons []string
+	for _, b := range e.Report.Blocked {
+		reasons = append(reasons, fmt.Sprintf("%s/%s: %s", b.Namespace, b.Name, b.Reason))
+	}
+	return fmt.Sprintf("%d pod(s) could not be removed from node %q: %s", len(e.Report.Blocked), e.Report.NodeName, strings.Join(reasons, "; "))
+}
+
+// podAction is what we do with a pod when draining its node
+type podAction int
+
+const (
+	podActionEvict podAction = iota
+	podActionDelete
+	podActionSkip
+)
+
+func (o *DrainOptions) podAction(pod *corev1.Pod) podAction {
+	if _, found := pod.Annotations[mirrorPodAnnotation]; found {
+		// Static pods can't be removed through the API
+		return podActionSkip
+	}
+	if controller := metav1.GetControllerOf(pod); controller != nil && controller.Kind == "DaemonSet" {
+		// The DaemonSet controller would just recreate it
+		return podActionSkip
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return podActionSkip
+	}
+	if matchesPod(pod, o.SkipNamespaces, o.SkipSelectors) {
+		return podActionSkip
+	}
+	if matchesPod(pod, o.ForceDeleteNamespaces, o.ForceDeleteSelectors) {
+		return podActionDelete
+	}
+	return podActionEvict
+}
+
+func matchesPod(pod *corev1.Pod, namespaces []string, selectors []labels.Selector) bool {
+	for _, namespace := range namespaces {
+		if pod.Namespace == namespace {
+			return true
+		}
+	}
+	for _, selector := range selectors {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+// cordonNode marks a node as unschedulable
+func cordonNode(k8sClient kubernetes.Interface, nodeName string) error {
+	node, err := k8sClient.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting node %q: %v", nodeName, err)
+	}
+	if node.Spec.Unschedulable {
+		return nil
+	}
+
+	node.Spec.Unschedulable = true
+	if _, err := k8sClient.CoreV1().Nodes().Update(node); err != nil {
+		return fmt.Errorf("error cordoning node %q: %v", nodeName, err)
+	}
+	return nil
+}
+
+// drainNode removes the pods from a node: evicting them through the eviction API, so that PodDisruptionBudgets
+// are honoured, or deleting them if they are to be forced off the node.
+// Pods are removed concurrently; a pod that could not be removed is recorded as blocked in the report.
+func drainNode(k8sClient kubernetes.Interface, nodeName string, options *DrainOptions) (*DrainReport, error) {
+	podList, err := k8sClient.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{"spec.nodeName": nodeName}).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods on node %q: %v", nodeName, err)
+	}
+
+	timeout := options.PodEvictionTimeout
+	if timeout == 0 {
+		timeout = defaultPodEvictionTimeout
+	}
+
+	report := &DrainReport{NodeName: nodeName}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Spec.NodeName != "" && pod.Spec.NodeName != nodeName {
+			continue
+		}
+		key := pod.Namespace + "/" + pod.Name
+
+		action := options.podAction(pod)
+		if action == podActionSkip {
+			klog.V(2).Infof("Not removing pod %s from node %q", key, nodeName)
+			report.Skipped = append(report.Skipped, key)
+			continue
+		}
+
+		wg.Add(1)
+		go func(pod *corev1.Pod, action podAction) {
+			defer wg.Done()
+
+			var err error
+			if action == podActionDelete {
+				err = deletePod(k8sClient, pod, timeout)
+			} else {
+				err = evictPod(k8sClient, pod, timeout)
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err != nil {
+				klog.Warningf("Unable to remove pod %s from node %q: %v", key, nodeName, err)
+				report.Blocked = append(report.Blocked, BlockedPod{
+					Namespace: pod.Namespace,
+					Name:      pod.Name,
+					Reason:    err.Error(),
+				})
+			} else if action == podActionDelete {
+				report.Deleted = append(report.Deleted, key)
+			} else {
+				report.Evicted = append(report.Evicted, key)
+			}
+		}(pod, action)
+	}
+	wg.Wait()
+
+	return report, nil
+}
+
+// evictPod evicts a pod and waits for it to terminate, retrying with backoff while the eviction is
+// refused because of a PodDisruptionBudget
+func evictPod(k8sClient kubernetes.Interface, pod *corev1.Pod, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	retryInterval := evictionRetryInterval
+
+	for {
+		eviction := &policy.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+		}
+		err := k8sClient.CoreV1().Pods(pod.Namespace).Evict(eviction)
+		if err == nil {
+			break
+		}
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return fmt.Errorf("error evicting pod: %v", err)
+		}
+
+		if time.Now().Add(retryInterval).After(deadline) {
+			return fmt.Errorf("eviction still refused after %v, most likely because of a PodDisruptionBudget: %v", timeout, err)
+		}
+
+		klog.Infof("Eviction of pod %s/%s was refused (%v), retrying in %v", pod.Namespace, pod.Name, err, retryInterval)
+		time.Sleep(retryInterval)
+
+		retryInterval *= 2
+		if retryInterval > evictionRetryMaxInterval {
+			retryInterval = evictionRetryMaxInterval
+		}
+	}
+
+	return waitForPodDeletion(k8sClient, pod, deadline, timeout)
+}
+
+// deletePod deletes a pod, ignoring any PodDisruptionBudget, and waits for it to terminate
+func deletePod(k8sClient kubernetes.Interface, pod *corev1.Pod, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	err := k8sClient.CoreV1().Pods(pod.Namespace).Delete(pod.Name, &metav1.DeleteOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("error deleting pod: %v", err)
+	}
+
+	return waitForPodDeletion(k8sClient, pod, deadline, timeout)
+}
+
+func waitForPodDeletion(k8sClient kubernetes.Interface, pod *corev1.Pod, deadline time.Time, timeout time.Duration) error {
+	for {
+		p, err := k8sClient.CoreV1().Pods(pod.Namespace).Get(pod.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			klog.Warningf("Error checking whether pod %s/%s has terminated: %v", pod.Namespace, pod.Name, err)
+		} else if p.UID != pod.UID {
+			// The pod was replaced by another with the same name
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("pod was removed but did not terminate within %v", timeout)
+		}
+		time.Sleep(podDeletionPollInterval)
+	}
+}
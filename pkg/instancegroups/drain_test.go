@@ -0,0 +1,212 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policy "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func init() {
+	evictionRetryInterval = 1 * time.Millisecond
+	evictionRetryMaxInterval = 5 * time.Millisecond
+	podDeletionPollInterval = 1 * time.Millisecond
+}
+
+func testPod(namespace, name string, podLabels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			UID:       types.UID("uid-" + name),
+			Labels:    podLabels,
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node-1",
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+		},
+	}
+}
+
+// fakeEvictions makes the fake clientset remove evicted and deleted pods,
+// and refuse the eviction of pods in blocked as a PodDisruptionBudget would
+type fakeEvictions struct {
+	mutex   sync.Mutex
+	removed map[string]bool
+	blocked map[string]bool
+}
+
+func (f *fakeEvictions) install(k8sClient *fake.Clientset) {
+	f.removed = make(map[string]bool)
+
+	k8sClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policy.Eviction)
+		key := eviction.Namespace + "/" + eviction.Name
+		if f.blocked[key] {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		f.removed[key] = true
+		return true, nil, nil
+	})
+
+	k8sClient.PrependReactor("delete", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		deleteAction := action.(k8stesting.DeleteAction)
+
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		f.removed[deleteAction.GetNamespace()+"/"+deleteAction.GetName()] = true
+		return true, nil, nil
+	})
+
+	k8sClient.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		getAction := action.(k8stesting.GetAction)
+
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		if f.removed[getAction.GetNamespace()+"/"+getAction.GetName()] {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, getAction.GetName())
+		}
+		return false, nil, nil
+	})
+}
+
+func TestDrainNode(t *testing.T) {
+	daemonSetPod := testPod("kube-system", "ds-pod", nil)
+	daemonSetPod.OwnerReferences = []metav1.OwnerReference{
+		{Kind: "DaemonSet", Name: "ds", Controller: boolPointer(true)},
+	}
+	mirrorPod := testPod("kube-system", "mirror-pod", nil)
+	mirrorPod.Annotations = map[string]string{mirrorPodAnnotation: "abc"}
+	completedPod := testPod("default", "completed-pod", nil)
+	completedPod.Status.Phase = corev1.PodSucceeded
+
+	k8sClient := fake.NewSimpleClientset(
+		testPod("default", "app", nil),
+		testPod("default", "batch", map[string]string{"app": "batch"}),
+		testPod("default", "keep", map[string]string{"keep": "true"}),
+		testPod("monitoring", "prometheus", nil),
+		testPod("scratch", "tmp", nil),
+		daemonSetPod,
+		mirrorPod,
+		completedPod,
+	)
+	evictions := &fakeEvictions{}
+	evictions.install(k8sClient)
+
+	options := &DrainOptions{
+		PodEvictionTimeout:    time.Second,
+		SkipNamespaces:        []string{"monitoring"},
+		SkipSelectors:         []labels.Selector{labels.SelectorFromSet(labels.Set{"keep": "true"})},
+		ForceDeleteNamespaces: []string{"scratch"},
+		ForceDeleteSelectors:  []labels.Selector{labels.SelectorFromSet(labels.Set{"app": "batch"})},
+	}
+
+	report, err := drainNode(k8sClient, "node-1", options)
+	if err != nil {
+		t.Fatalf("unexpected error draining node: %v", err)
+	}
+
+	sort.Strings(report.Evicted)
+	sort.Strings(report.Deleted)
+	sort.Strings(report.Skipped)
+
+	if expected := []string{"default/app"}; !reflect.DeepEqual(report.Evicted, expected) {
+		t.Errorf("expected evicted %v, got %v", expected, report.Evicted)
+	}
+	if expected := []string{"default/batch", "scratch/tmp"}; !reflect.DeepEqual(report.Deleted, expected) {
+		t.Errorf("expected deleted %v, got %v", expected, report.Deleted)
+	}
+	if expected := []string{"default/completed-pod", "default/keep", "kube-system/ds-pod", "kube-system/mirror-pod", "monitoring/prometheus"}; !reflect.DeepEqual(report.Skipped, expected) {
+		t.Errorf("expected skipped %v, got %v", expected, report.Skipped)
+	}
+	if len(report.Blocked) != 0 {
+		t.Errorf("expected no blocked pods, got %v", report.Blocked)
+	}
+}
+
+func TestDrainNodeBlockedByDisruptionBudget(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset(
+		testPod("default", "app", nil),
+		testPod("default", "protected", nil),
+	)
+	evictions := &fakeEvictions{
+		blocked: map[string]bool{"default/protected": true},
+	}
+	evictions.install(k8sClient)
+
+	report, err := drainNode(k8sClient, "node-1", &DrainOptions{PodEvictionTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error draining node: %v", err)
+	}
+
+	if expected := []string{"default/app"}; !reflect.DeepEqual(report.Evicted, expected) {
+		t.Errorf("expected evicted %v, got %v", expected, report.Evicted)
+	}
+	if len(report.Blocked) != 1 || report.Blocked[0].Namespace != "default" || report.Blocked[0].Name != "protected" {
+		t.Fatalf("expected default/protected to be blocked, got %v", report.Blocked)
+	}
+
+	drainErr := &DrainError{Report: report}
+	if msg := drainErr.Error(); !strings.Contains(msg, "default/protected") {
+		t.Errorf("expected the error to name the blocked pod, was %q", msg)
+	}
+}
+
+func TestCordonNode(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+	})
+
+	if err := cordonNode(k8sClient, "node-1"); err != nil {
+		t.Fatalf("unexpected error cordoning node: %v", err)
+	}
+
+	node, err := k8sClient.CoreV1().Nodes().Get("node-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error getting node: %v", err)
+	}
+	if !node.Spec.Unschedulable {
+		t.Errorf("expected node to be unschedulable")
+	}
+}
+
+func boolPointer(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/cloudinstances"
+)
+
+// defaultHookTimeout is used when a hook does not specify a timeout
+const defaultHookTimeout = 5 * time.Minute
+
+// HookContext describes the instance a hook is being run for
+type HookContext struct {
+	// Event is the point in the replacement of the instance that has been reached
+	Event api.RollingUpdateHookEvent `json:"event"`
+	// ClusterName is the name of the cluster being updated
+	ClusterName string `json:"clusterName"`
+	// InstanceGroup is the name of the instance group of the instance
+	InstanceGroup string `json:"instanceGroup"`
+	// InstanceID is the cloud provider id of the instance.
+	// For PostValidate hooks this is the instance that was replaced.
+	InstanceID string `json:"instanceID"`
+	// NodeName is the name of the kubernetes node of the instance, if it was registered
+	NodeName string `json:"nodeName,omitempty"`
+}
+
+// InstanceHook is run at points in the replacement of each instance during a rolling update:
+// before and after its node is drained, before it is terminated, and once the cluster has
+// validated after it was replaced.
+type InstanceHook interface {
+	// RunHook is called at each event for each instance being replaced.
+	// Returning an error stops the rolling update.
+	RunHook(hookContext *HookContext) error
+}
+
+// NewInstanceHook builds the built-in InstanceHook, running a command or calling a webhook, described by spec
+func NewInstanceHook(spec *api.RollingUpdateHook) (InstanceHook, error) {
+	if len(spec.Exec) == 0 && spec.Webhook == "" {
+		return nil, fmt.Errorf("hook %q must specify one of exec or webhook", spec.Name)
+	}
+	if len(spec.Exec) != 0 && spec.Webhook != "" {
+		return nil, fmt.Errorf("hook %q must not specify both exec and webhook", spec.Name)
+	}
+
+	h := &builtinHook{
+		spec:    *spec,
+		timeout: defaultHookTimeout,
+	}
+	if spec.Timeout != nil {
+		h.timeout = spec.Timeout.Duration
+	}
+	return h, nil
+}
+
+// buildInstanceHooks builds the built-in hooks described by specs
+func buildInstanceHooks(specs []api.RollingUpdateHook) ([]InstanceHook, error) {
+	var hooks []InstanceHook
+	for i := range specs {
+		hook, err := NewInstanceHook(&specs[i])
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+// builtinHook runs a command, or POSTs to a webhook, for the events it is configured for
+type builtinHook struct {
+	spec    api.RollingUpdateHook
+	timeout time.Duration
+}
+
+var _ InstanceHook = &builtinHook{}
+
+func (h *builtinHook) name() string {
+	if h.spec.Name != "" {
+		return h.spec.Name
+	}
+	if h.spec.Webhook != "" {
+		return h.spec.Webhook
+	}
+	return strings.Join(h.spec.Exec, " ")
+}
+
+func (h *builtinHook) handles(event api.RollingUpdateHookEvent) bool {
+	for _, e := range h.spec.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// RunHook implements InstanceHook
+func (h *builtinHook) RunHook(hookContext *HookContext) error {
+	if !h.handles(hookContext.Event) {
+		return nil
+	}
+
+	klog.Infof("Running %s hook %q for instance %q", hookContext.Event, h.name(), hookContext.InstanceID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	var err error
+	if h.spec.Webhook != "" {
+		err = h.callWebhook(ctx, hookContext)
+	} else {
+		err = h.runCommand(ctx, hookContext)
+	}
+	if err != nil {
+		if h.spec.IgnoreFailure {
+			klog.Warningf("Ignoring failure of %s hook %q for instance %q: %v", hookContext.Event, h.name(), hookContext.InstanceID, err)
+			return nil
+		}
+		return fmt.Errorf("%s hook %q failed for instance %q: %v", hookContext.Event, h.name(), hookContext.InstanceID, err)
+	}
+	return nil
+}
+
+// runCommand runs the hook command, passing the details of the instance in its environment
+func (h *builtinHook) runCommand(ctx context.Context, hookContext *HookContext) error {
+	cmd := exec.CommandContext(ctx, h.spec.Exec[0], h.spec.Exec[1:]...)
+	cmd.Env = append(os.Environ(),
+		"KOPS_HOOK_EVENT="+string(hookContext.Event),
+		"KOPS_CLUSTER_NAME="+hookContext.ClusterName,
+		"KOPS_INSTANCE_GROUP="+hookContext.InstanceGroup,
+		"KOPS_INSTANCE_ID="+hookContext.InstanceID,
+		"KOPS_NODE_NAME="+hookContext.NodeName,
+	)
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("command timed out after %v", h.timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("error running command: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	klog.V(2).Infof("Output of hook %q: %s", h.name(), output)
+	return nil
+}
+
+// callWebhook POSTs the details of the instance, as JSON, to the hook URL, expecting a 2xx response
+func (h *builtinHook) callWebhook(ctx context.Context, hookContext *HookContext) error {
+	body, err := json.Marshal(hookContext)
+	if err != nil {
+		return fmt.Errorf("error marshaling webhook request: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.spec.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error calling webhook: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// runHooks runs the hooks of the instance group for an instance that has reached event
+func (r *RollingUpdateInstanceGroup) runHooks(event api.RollingUpdateHookEvent, u *cloudinstances.CloudInstanceGroupMember, rollingUpdateData *RollingUpdateCluster) error {
+	if len(r.hooks) == 0 {
+		return nil
+	}
+
+	hookContext := &HookContext{
+		Event:         event,
+		ClusterName:   rollingUpdateData.ClusterName,
+		InstanceGroup: r.CloudGroup.InstanceGroup.ObjectMeta.Name,
+		InstanceID:    u.ID,
+	}
+	if u.Node != nil {
+		hookContext.NodeName = u.Node.Name
+	}
+
+	for _, hook := range r.hooks {
+		if err := hook.RunHook(hookContext); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	v1 "k8s.io/api/core/v1"
+	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kops/cloudmock/aws/mockautoscaling"
+	kopsapi "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/cloudinstances"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+)
+
+func testHookContext(event kopsapi.RollingUpdateHookEvent) *HookContext {
+	return &HookContext{
+		Event:         event,
+		ClusterName:   "test.k8s.local",
+		InstanceGroup: "nodes",
+		InstanceID:    "i-1234",
+		NodeName:      "node-1",
+	}
+}
+
+func TestExecHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hooks")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	hook, err := NewInstanceHook(&kopsapi.RollingUpdateHook{
+		Events: []kopsapi.RollingUpdateHookEvent{kopsapi.RollingUpdateHookPreTerminate},
+		Exec:   []string{"sh", "-c", "echo $KOPS_HOOK_EVENT $KOPS_CLUSTER_NAME $KOPS_INSTANCE_GROUP $KOPS_INSTANCE_ID $KOPS_NODE_NAME >> " + out},
+	})
+	if err != nil {
+		t.Fatalf("error building hook: %v", err)
+	}
+
+	for _, event := range kopsapi.RollingUpdateHookEvents {
+		if err := hook.RunHook(testHookContext(event)); err != nil {
+			t.Fatalf("unexpected error running hook for %s: %v", event, err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatalf("error reading hook output: %v", err)
+	}
+	if expected := "PreTerminate test.k8s.local nodes i-1234 node-1\n"; string(b) != expected {
+		t.Errorf("expected the hook to run only for PreTerminate with output %q, got %q", expected, string(b))
+	}
+}
+
+func TestExecHookFailure(t *testing.T) {
+	grid := []struct {
+		Spec        kopsapi.RollingUpdateHook
+		ExpectError string
+	}{
+		{
+			Spec: kopsapi.RollingUpdateHook{
+				Name:   "deregister",
+				Events: []kopsapi.RollingUpdateHookEvent{kopsapi.RollingUpdateHookPreDrain},
+				Exec:   []string{"sh", "-c", "echo not registered; exit 1"},
+			},
+			ExpectError: "not registered",
+		},
+		{
+			Spec: kopsapi.RollingUpdateHook{
+				Name:    "slow",
+				Events:  []kopsapi.RollingUpdateHookEvent{kopsapi.RollingUpdateHookPreDrain},
+				Exec:    []string{"sleep", "10"},
+				Timeout: &v1meta.Duration{Duration: 50 * time.Millisecond},
+			},
+			ExpectError: "timed out",
+		},
+		{
+			Spec: kopsapi.RollingUpdateHook{
+				Name:          "optional",
+				Events:        []kopsapi.RollingUpdateHookEvent{kopsapi.RollingUpdateHookPreDrain},
+				Exec:          []string{"false"},
+				IgnoreFailure: true,
+			},
+		},
+	}
+	for _, g := range grid {
+		hook, err := NewInstanceHook(&g.Spec)
+		if err != nil {
+			t.Fatalf("error building hook %q: %v", g.Spec.Name, err)
+		}
+		err = hook.RunHook(testHookContext(kopsapi.RollingUpdateHookPreDrain))
+		if g.ExpectError == "" {
+			if err != nil {
+				t.Errorf("hook %q: unexpected error: %v", g.Spec.Name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), g.ExpectError) {
+			t.Errorf("hook %q: expected error containing %q, got %v", g.Spec.Name, g.ExpectError, err)
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var received []HookContext
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", req.Method)
+		}
+		hookContext := HookContext{}
+		if err := json.NewDecoder(req.Body).Decode(&hookContext); err != nil {
+			t.Errorf("error decoding webhook request: %v", err)
+		}
+		received = append(received, hookContext)
+		if fail {
+			http.Error(w, "smoke test failed", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	hook, err := NewInstanceHook(&kopsapi.RollingUpdateHook{
+		Events:  []kopsapi.RollingUpdateHookEvent{kopsapi.RollingUpdateHookPostValidate},
+		Webhook: server.URL,
+	})
+	if err != nil {
+		t.Fatalf("error building hook: %v", err)
+	}
+
+	if err := hook.RunHook(testHookContext(kopsapi.RollingUpdateHookPostValidate)); err != nil {
+		t.Fatalf("unexpected error running hook: %v", err)
+	}
+	if err := hook.RunHook(testHookContext(kopsapi.RollingUpdateHookPreDrain)); err != nil {
+		t.Fatalf("unexpected error running hook: %v", err)
+	}
+	if expected := []HookContext{*testHookContext(kopsapi.RollingUpdateHookPostValidate)}; !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected webhook to receive %v, got %v", expected, received)
+	}
+
+	fail = true
+	err = hook.RunHook(testHookContext(kopsapi.RollingUpdateHookPostValidate))
+	if err == nil || !strings.Contains(err.Error(), "smoke test failed") {
+		t.Errorf("expected webhook failure to be reported, got %v", err)
+	}
+}
+
+// recordingHook records the events it is run for
+type recordingHook struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (h *recordingHook) RunHook(hookContext *HookContext) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.events = append(h.events, string(hookContext.Event)+" "+hookContext.InstanceID)
+	return nil
+}
+
+func TestRollingUpdateRunsHooks(t *testing.T) {
+	mockcloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	mockcloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+	mockcloud.Autoscaling().CreateAutoScalingGroup(&autoscaling.CreateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String("node-1"),
+		MinSize:              aws.Int64(1),
+		MaxSize:              aws.Int64(5),
+	})
+	mockcloud.Autoscaling().AttachInstances(&autoscaling.AttachInstancesInput{
+		AutoScalingGroupName: aws.String("node-1"),
+		InstanceIds:          aws.StringSlice([]string{"node-1a", "node-1b"}),
+	})
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Name = "test.k8s.local"
+
+	group := &cloudinstances.CloudInstanceGroup{
+		HumanName: "node-1",
+		InstanceGroup: &kopsapi.InstanceGroup{
+			ObjectMeta: v1meta.ObjectMeta{
+				Name: "node-1",
+			},
+			Spec: kopsapi.InstanceGroupSpec{
+				Role: kopsapi.InstanceGroupRoleNode,
+			},
+		},
+	}
+	var nodes []*v1.Node
+	for _, id := range []string{"node-1a", "node-1b"} {
+		node := &v1.Node{ObjectMeta: v1meta.ObjectMeta{Name: id}}
+		nodes = append(nodes, node)
+		group.NeedUpdate = append(group.NeedUpdate, &cloudinstances.CloudInstanceGroupMember{
+			ID:                 id,
+			Node:               node,
+			CloudInstanceGroup: group,
+		})
+	}
+
+	hook := &recordingHook{}
+	c := &RollingUpdateCluster{
+		Cloud:           mockcloud,
+		MasterInterval:  1 * time.Millisecond,
+		NodeInterval:    1 * time.Millisecond,
+		BastionInterval: 1 * time.Millisecond,
+		K8sClient:       fake.NewSimpleClientset(nodes[0], nodes[1]),
+		ClusterName:     cluster.Name,
+		Hooks:           []InstanceHook{hook},
+	}
+
+	groups := map[string]*cloudinstances.CloudInstanceGroup{"node-1": group}
+	if err := c.RollingUpdate(groups, cluster, &kopsapi.InstanceGroupList{}); err != nil {
+		t.Fatalf("Error on rolling update: %v", err)
+	}
+
+	// The cluster does not validate against the fake client, so no PostValidate hooks are run
+	expected := []string{
+		"PreDrain node-1a", "PostDrain node-1a", "PreTerminate node-1a",
+		"PreDrain node-1b", "PostDrain node-1b", "PreTerminate node-1b",
+	}
+	if !reflect.DeepEqual(hook.events, expected) {
+		t.Errorf("expected hook events %v, got %v", expected, hook.events)
+	}
+}
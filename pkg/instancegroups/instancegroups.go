@@ -26,15 +26,12 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/klog"
 	api "k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/cloudinstances"
 	"k8s.io/kops/pkg/featureflag"
 	"k8s.io/kops/pkg/validation"
 	"k8s.io/kops/upup/pkg/fi"
-	cmddrain "k8s.io/kubernetes/pkg/kubectl/cmd/drain"
-	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
 )
 
 // RollingUpdateInstanceGroup is the AWS ASG backing an InstanceGroup.
@@ -44,6 +41,9 @@ type RollingUpdateInstanceGroup struct {
 	// CloudGroup is the kops cloud provider groups
 	CloudGroup *cloudinstances.CloudInstanceGroup
 
+	// hooks are run at points in the replacement of each instance
+	hooks []InstanceHook
+
 	// TODO should remove the need to have rollingupdate struct and add:
 	// TODO - the kubernetes client
 	// TODO - the cluster name
@@ -100,10 +100,6 @@ func promptInteractive(upgradedHostId, upgradedHostName string) (stopPrompting b
 	return stopPrompting, err
 }
 
-// TODO: Temporarily increase size of ASG?
-// TODO: Remove from ASG first so status is immediately updated?
-// TODO: Batch termination, like a rolling-update
-
 // RollingUpdate performs a rolling update on a list of ec2 instances.
 func (r *RollingUpdateInstanceGroup) RollingUpdate(rollingUpdateData *RollingUpdateCluster, cluster *api.Cluster, instanceGroupList *api.InstanceGroupList, isBastion bool, sleepAfterTerminate time.Duration, validationTimeout time.Duration) (err error) {
 
@@ -126,6 +122,21 @@ func (r *RollingUpdateInstanceGroup) RollingUpdate(rollingUpdateData *RollingUpd
 		update = append(update, r.CloudGroup.Ready...)
 	}
 
+	if plan := rollingUpdateData.Progress.plannedGroup(r.CloudGroup.InstanceGroup.ObjectMeta.Name); plan != nil {
+		// Only replace the instances that were planned and are not done yet; this lets an
+		// interrupted rolling update continue without also replacing the instances it launched.
+		var members []*cloudinstances.CloudInstanceGroupMember
+		members = append(members, r.CloudGroup.NeedUpdate...)
+		members = append(members, r.CloudGroup.Ready...)
+
+		update = nil
+		for _, u := range members {
+			if plan.IsPlanned(u.ID) && !plan.IsDone(u.ID) {
+				update = append(update, u)
+			}
+		}
+	}
+
 	if len(update) == 0 {
 		return nil
 	}
@@ -145,81 +156,113 @@ func (r *RollingUpdateInstanceGroup) RollingUpdate(rollingUpdateData *RollingUpd
 		}
 	}
 
-	for _, u := range update {
-		instanceId := u.ID
+	settings := rollingUpdateData.resolveSettings(cluster, r.CloudGroup.InstanceGroup, len(r.CloudGroup.Ready)+len(r.CloudGroup.NeedUpdate))
 
-		nodeName := ""
-		if u.Node != nil {
-			nodeName = u.Node.Name
-		}
+	if r.hooks, err = buildInstanceHooks(settings.Hooks); err != nil {
+		return fmt.Errorf("error building rolling update hooks for InstanceGroup %s: %v", r.CloudGroup.InstanceGroup.ObjectMeta.Name, err)
+	}
+	r.hooks = append(r.hooks, rollingUpdateData.Hooks...)
 
-		if isBastion {
-			// We don't want to validate for bastions - they aren't part of the cluster
-		} else if rollingUpdateData.CloudOnly {
+	maxSurge := settings.MaxSurge.IntValue()
+	maxUnavailable := settings.MaxUnavailable.IntValue()
+	if maxSurge+maxUnavailable == 0 {
+		klog.Infof("Rolling updates for InstanceGroup %s are disabled, as maxSurge and maxUnavailable are both 0", r.CloudGroup.InstanceGroup.ObjectMeta.Name)
+		return nil
+	}
 
-			klog.Warning("Not draining cluster nodes as 'cloudonly' flag is set.")
+	if maxSurge > 0 && (isBastion || rollingUpdateData.CloudOnly || !featureflag.DrainAndValidateRollingUpdate.Enabled()) {
+		// Without validation we have no way of knowing when the replacements are ready,
+		// so there is nothing to be gained from launching them before terminating the old instances.
+		klog.Warningf("Not surging InstanceGroup %s as the cluster is not being validated", r.CloudGroup.InstanceGroup.ObjectMeta.Name)
+		maxUnavailable += maxSurge
+		maxSurge = 0
+	}
 
-		} else if featureflag.DrainAndValidateRollingUpdate.Enabled() {
+	update = prioritizeUpdate(update)
 
-			if u.Node != nil {
-				klog.Infof("Draining the node: %q.", nodeName)
+	// Instances left detached by an earlier, interrupted rolling update already have their replacements
+	// and so use up some of the surge.
+	numSurge := 0
+	for _, u := range update {
+		if u.Detached {
+			numSurge++
+		}
+	}
 
-				if err = r.DrainNode(u, rollingUpdateData); err != nil {
-					if rollingUpdateData.FailOnDrainError {
-						return fmt.Errorf("failed to drain node %q: %v", nodeName, err)
-					} else {
-						klog.Infof("Ignoring error draining node %q: %v", nodeName, err)
-					}
-				}
-			} else {
-				klog.Warningf("Skipping drain of instance %q, because it is not registered in kubernetes", instanceId)
+	for len(update) > 0 {
+		batchSize := maxSurge + maxUnavailable
+		if rollingUpdateData.Interactive || r.CloudGroup.InstanceGroup.IsMaster() {
+			// We prompt after every instance when interactive, and we never replace
+			// more than one master at a time so as to keep etcd quorum
+			batchSize = 1
+		}
+		if numSurge > batchSize {
+			batchSize = numSurge
+		}
+		if batchSize > len(update) {
+			batchSize = len(update)
+		}
+		batch := update[:batchSize]
+		update = update[batchSize:]
+
+		// Detach instances so that their replacements are launched before we drain them
+		detached := false
+		for _, u := range batch {
+			if numSurge >= maxSurge {
+				break
+			}
+			if u.Detached {
+				continue
+			}
+			if err = r.detachInstance(u); err != nil {
+				return err
 			}
+			numSurge++
+			detached = true
 		}
 
-		// We unregister the node before deleting it; if the replacement comes up with the same name it would otherwise still be cordoned
-		// (It often seems like GCE tries to re-use names)
-		if !isBastion && !rollingUpdateData.CloudOnly {
-			if u.Node == nil {
-				klog.Warningf("no kubernetes Node associated with %s, skipping node deletion", instanceId)
-			} else {
-				klog.Infof("deleting node %q from kubernetes", nodeName)
-				if err := r.deleteNode(u.Node, rollingUpdateData); err != nil {
-					return fmt.Errorf("error deleting node %q: %v", nodeName, err)
-				}
+		if detached {
+			klog.Infof("waiting for %v after detaching instances", sleepAfterTerminate)
+			time.Sleep(sleepAfterTerminate)
+
+			if _, err = r.maybeValidate(rollingUpdateData, cluster, instanceGroupList, validationTimeout, "detaching instances"); err != nil {
+				return err
 			}
 		}
 
-		if err = r.DeleteInstance(u); err != nil {
-			klog.Errorf("error deleting instance %q, node %q: %v", instanceId, nodeName, err)
+		if err = r.drainTerminateAndWaitBatch(batch, rollingUpdateData, isBastion, sleepAfterTerminate); err != nil {
 			return err
 		}
-
-		// Wait for the minimum interval
-		klog.Infof("waiting for %v after terminating instance", sleepAfterTerminate)
-		time.Sleep(sleepAfterTerminate)
+		rollingUpdateData.Progress.instancesDone(r.CloudGroup.InstanceGroup.ObjectMeta.Name, batch)
+		for _, u := range batch {
+			if u.Detached {
+				numSurge--
+			}
+		}
 
 		if isBastion {
-			klog.Infof("Deleted a bastion instance, %s, and continuing with rolling-update.", instanceId)
-
-			continue
-		} else if rollingUpdateData.CloudOnly {
-			klog.Warningf("Not validating cluster as cloudonly flag is set.")
-
-		} else if featureflag.DrainAndValidateRollingUpdate.Enabled() {
-			klog.Info("Validating the cluster.")
-
-			if err = r.ValidateClusterWithDuration(rollingUpdateData, cluster, instanceGroupList, validationTimeout); err != nil {
-
-				if rollingUpdateData.FailOnValidate {
-					klog.Errorf("Cluster did not validate within %s", validationTimeout)
-					return fmt.Errorf("error validating cluster after removing a node: %v", err)
+			klog.Infof("Deleted %d bastion instance(s), and continuing with rolling-update.", len(batch))
+		} else {
+			validated, err := r.maybeValidate(rollingUpdateData, cluster, instanceGroupList, validationTimeout, "removing a node")
+			if err != nil {
+				return err
+			}
+			if validated {
+				for _, u := range batch {
+					if err := r.runHooks(api.RollingUpdateHookPostValidate, u, rollingUpdateData); err != nil {
+						return err
+					}
 				}
-
-				klog.Warningf("Cluster validation failed after removing instance, proceeding since fail-on-validate is set to false: %v", err)
 			}
 		}
 
 		if rollingUpdateData.Interactive {
+			u := batch[len(batch)-1]
+			nodeName := ""
+			if u.Node != nil {
+				nodeName = u.Node.Name
+			}
+
 			stopPrompting, err := promptInteractive(u.ID, nodeName)
 			if err != nil {
 				return err
@@ -234,6 +277,139 @@ func (r *RollingUpdateInstanceGroup) RollingUpdate(rollingUpdateData *RollingUpd
 	return nil
 }
 
+// prioritizeUpdate orders the instances to be updated so that detached instances come first,
+// as their replacements have already been launched.
+func prioritizeUpdate(update []*cloudinstances.CloudInstanceGroupMember) []*cloudinstances.CloudInstanceGroupMember {
+	var detached, attached []*cloudinstances.CloudInstanceGroupMember
+	for _, u := range update {
+		if u.Detached {
+			detached = append(detached, u)
+		} else {
+			attached = append(attached, u)
+		}
+	}
+	return append(detached, attached...)
+}
+
+// drainTerminateAndWaitBatch drains and terminates the instances of a batch concurrently,
+// returning once all of them have been processed. The first error encountered is returned.
+func (r *RollingUpdateInstanceGroup) drainTerminateAndWaitBatch(batch []*cloudinstances.CloudInstanceGroupMember, rollingUpdateData *RollingUpdateCluster, isBastion bool, sleepAfterTerminate time.Duration) error {
+	if len(batch) == 1 {
+		return r.drainTerminateAndWait(batch[0], rollingUpdateData, isBastion, sleepAfterTerminate)
+	}
+
+	klog.Infof("Replacing %d instances of group %q concurrently.", len(batch), r.CloudGroup.HumanName)
+
+	errs := make(chan error, len(batch))
+	for _, u := range batch {
+		go func(u *cloudinstances.CloudInstanceGroupMember) {
+			errs <- r.drainTerminateAndWait(u, rollingUpdateData, isBastion, sleepAfterTerminate)
+		}(u)
+	}
+
+	var firstErr error
+	for range batch {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+func (r *RollingUpdateInstanceGroup) drainTerminateAndWait(u *cloudinstances.CloudInstanceGroupMember, rollingUpdateData *RollingUpdateCluster, isBastion bool, sleepAfterTerminate time.Duration) error {
+	instanceId := u.ID
+
+	nodeName := ""
+	if u.Node != nil {
+		nodeName = u.Node.Name
+	}
+
+	if isBastion {
+		// We don't want to validate for bastions - they aren't part of the cluster
+	} else if rollingUpdateData.CloudOnly {
+
+		klog.Warning("Not draining cluster nodes as 'cloudonly' flag is set.")
+
+	} else if featureflag.DrainAndValidateRollingUpdate.Enabled() {
+
+		if u.Node != nil {
+			if err := r.runHooks(api.RollingUpdateHookPreDrain, u, rollingUpdateData); err != nil {
+				return err
+			}
+
+			klog.Infof("Draining the node: %q.", nodeName)
+
+			if err := r.DrainNode(u, rollingUpdateData); err != nil {
+				if rollingUpdateData.FailOnDrainError {
+					return fmt.Errorf("failed to drain node %q: %v", nodeName, err)
+				} else {
+					klog.Infof("Ignoring error draining node %q: %v", nodeName, err)
+				}
+			}
+
+			if err := r.runHooks(api.RollingUpdateHookPostDrain, u, rollingUpdateData); err != nil {
+				return err
+			}
+		} else {
+			klog.Warningf("Skipping drain of instance %q, because it is not registered in kubernetes", instanceId)
+		}
+	}
+
+	// We unregister the node before deleting it; if the replacement comes up with the same name it would otherwise still be cordoned
+	// (It often seems like GCE tries to re-use names)
+	if !isBastion && !rollingUpdateData.CloudOnly {
+		if u.Node == nil {
+			klog.Warningf("no kubernetes Node associated with %s, skipping node deletion", instanceId)
+		} else {
+			klog.Infof("deleting node %q from kubernetes", nodeName)
+			if err := r.deleteNode(u.Node, rollingUpdateData); err != nil {
+				return fmt.Errorf("error deleting node %q: %v", nodeName, err)
+			}
+		}
+	}
+
+	if err := r.runHooks(api.RollingUpdateHookPreTerminate, u, rollingUpdateData); err != nil {
+		return err
+	}
+
+	if err := r.DeleteInstance(u); err != nil {
+		klog.Errorf("error deleting instance %q, node %q: %v", instanceId, nodeName, err)
+		return err
+	}
+
+	// Wait for the minimum interval
+	klog.Infof("waiting for %v after terminating instance", sleepAfterTerminate)
+	time.Sleep(sleepAfterTerminate)
+
+	return nil
+}
+
+// maybeValidate validates the cluster, unless validation is disabled, returning true if the cluster validated
+func (r *RollingUpdateInstanceGroup) maybeValidate(rollingUpdateData *RollingUpdateCluster, cluster *api.Cluster, instanceGroupList *api.InstanceGroupList, validationTimeout time.Duration, operation string) (bool, error) {
+	if rollingUpdateData.CloudOnly {
+		klog.Warningf("Not validating cluster as cloudonly flag is set.")
+
+	} else if featureflag.DrainAndValidateRollingUpdate.Enabled() {
+		klog.Info("Validating the cluster.")
+
+		err := r.ValidateClusterWithDuration(rollingUpdateData, cluster, instanceGroupList, validationTimeout)
+		rollingUpdateData.Progress.validated(r.CloudGroup.InstanceGroup.ObjectMeta.Name, err)
+		if err != nil {
+
+			if rollingUpdateData.FailOnValidate {
+				klog.Errorf("Cluster did not validate within %s", validationTimeout)
+				return false, fmt.Errorf("error validating cluster after %s: %v", operation, err)
+			}
+
+			klog.Warningf("Cluster validation failed after %s, proceeding since fail-on-validate is set to false: %v", operation, err)
+			return false, nil
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
 // ValidateClusterWithDuration runs validation.ValidateCluster until either we get positive result or the timeout expires
 func (r *RollingUpdateInstanceGroup) ValidateClusterWithDuration(rollingUpdateData *RollingUpdateCluster, cluster *api.Cluster, instanceGroupList *api.InstanceGroupList, duration time.Duration) error {
 	// TODO should we expose this to the UI?
@@ -313,45 +489,61 @@ func (r *RollingUpdateInstanceGroup) DeleteInstance(u *cloudinstances.CloudInsta
 
 }
 
+// detachInstance detaches a Cloud Instance from its group, so that the cloud launches a replacement for it.
+func (r *RollingUpdateInstanceGroup) detachInstance(u *cloudinstances.CloudInstanceGroupMember) error {
+	id := u.ID
+	nodeName := ""
+	if u.Node != nil {
+		nodeName = u.Node.Name
+	}
+	if nodeName != "" {
+		klog.Infof("Detaching instance %q, node %q, in group %q.", id, nodeName, r.CloudGroup.HumanName)
+	} else {
+		klog.Infof("Detaching instance %q, in group %q.", id, r.CloudGroup.HumanName)
+	}
+
+	if err := r.Cloud.DetachInstance(u); err != nil {
+		if nodeName != "" {
+			return fmt.Errorf("error detaching instance %q, node %q: %v", id, nodeName, err)
+		} else {
+			return fmt.Errorf("error detaching instance %q: %v", id, err)
+		}
+	}
+
+	u.Detached = true
+
+	return nil
+}
+
 // DrainNode drains a K8s node.
+// Pods are evicted, honouring their PodDisruptionBudgets; if some pods cannot be removed,
+// a *DrainError reporting them is returned.
 func (r *RollingUpdateInstanceGroup) DrainNode(u *cloudinstances.CloudInstanceGroupMember, rollingUpdateData *RollingUpdateCluster) error {
-	if rollingUpdateData.ClientGetter == nil {
-		return fmt.Errorf("ClientGetter not set")
+	if rollingUpdateData.K8sClient == nil {
+		return fmt.Errorf("K8sClient not set")
 	}
 
 	if u.Node.Name == "" {
 		return fmt.Errorf("node name not set")
 	}
-	f := cmdutil.NewFactory(rollingUpdateData.ClientGetter)
 
-	streams := genericclioptions.IOStreams{
-		Out:    os.Stdout,
-		ErrOut: os.Stderr,
+	if err := cordonNode(rollingUpdateData.K8sClient, u.Node.Name); err != nil {
+		return err
 	}
 
-	drain := cmddrain.NewCmdDrain(f, streams)
-	args := []string{u.Node.Name}
-	options := cmddrain.NewDrainOptions(f, streams)
-
-	// Override some options
-	options.IgnoreDaemonsets = true
-	options.Force = true
-	options.DeleteLocalData = true
-	options.GracePeriodSeconds = -1
-
-	err := options.Complete(f, drain, args)
+	report, err := drainNode(rollingUpdateData.K8sClient, u.Node.Name, &rollingUpdateData.DrainOptions)
 	if err != nil {
-		return fmt.Errorf("error setting up drain: %v", err)
+		return fmt.Errorf("error draining node: %v", err)
 	}
 
-	err = options.RunCordonOrUncordon(true)
-	if err != nil {
-		return fmt.Errorf("error cordoning node node: %v", err)
-	}
+	klog.Infof("Drained node %q: %d pod(s) evicted, %d deleted, %d left running", u.Node.Name, len(report.Evicted), len(report.Deleted), len(report.Skipped))
 
-	err = options.RunDrain()
-	if err != nil {
-		return fmt.Errorf("error draining node: %v", err)
+	if len(report.Blocked) != 0 {
+		for _, b := range report.Blocked {
+			klog.Warningf("Pod %s/%s blocked the drain of node %q: %s", b.Namespace, b.Name, u.Node.Name, b.Reason)
+		}
+		rollingUpdateData.Progress.drainBlocked(r.CloudGroup.InstanceGroup.ObjectMeta.Name, report.Blocked)
+		return &DrainError{Report: report}
 	}
 
 	if rollingUpdateData.PostDrainDelay > 0 {
@@ -0,0 +1,310 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/pkg/cloudinstances"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// PathRollingUpdateStatus is the path, relative to the cluster's ConfigBase, where rolling update progress is recorded
+const PathRollingUpdateStatus = "rollingupdate/status"
+
+// RollingUpdatePhase describes the state of a rolling update, or of one of its instance groups
+type RollingUpdatePhase string
+
+const (
+	RollingUpdatePhasePending    RollingUpdatePhase = "Pending"
+	RollingUpdatePhaseInProgress RollingUpdatePhase = "InProgress"
+	RollingUpdatePhaseCompleted  RollingUpdatePhase = "Completed"
+	RollingUpdatePhaseFailed     RollingUpdatePhase = "Failed"
+)
+
+// RollingUpdateStatus is the persisted record of a rolling update, allowing it to be resumed
+type RollingUpdateStatus struct {
+	// ClusterName is the name of the cluster being updated
+	ClusterName string `json:"clusterName"`
+	// Phase is the overall state of the rolling update
+	Phase RollingUpdatePhase `json:"phase"`
+	// Message holds the error that stopped the rolling update, if any
+	Message string `json:"message,omitempty"`
+	// StartTime is when the rolling update was first started
+	StartTime time.Time `json:"startTime"`
+	// UpdateTime is when the status was last recorded
+	UpdateTime time.Time `json:"updateTime"`
+	// Groups is the plan of the rolling update: the instance groups and the instances to be replaced in each
+	Groups []*RollingUpdateGroupStatus `json:"groups,omitempty"`
+}
+
+// RollingUpdateGroupStatus records the progress of the rolling update of a single instance group
+type RollingUpdateGroupStatus struct {
+	// Name is the name of the instance group
+	Name string `json:"name"`
+	// Role is the role of the instance group
+	Role api.InstanceGroupRole `json:"role"`
+	// Phase is the state of the rolling update of this instance group
+	Phase RollingUpdatePhase `json:"phase"`
+	// Instances are the ids of the instances that are to be replaced
+	Instances []string `json:"instances,omitempty"`
+	// InstancesDone are the ids of the instances that have been replaced
+	InstancesDone []string `json:"instancesDone,omitempty"`
+	// LastValidation is the result of the most recent cluster validation made while updating this group
+	LastValidation *RollingUpdateValidationStatus `json:"lastValidation,omitempty"`
+	// DrainBlockedPods are the pods that blocked the most recent failed drain of a node of this group
+	DrainBlockedPods []BlockedPod `json:"drainBlockedPods,omitempty"`
+}
+
+// RollingUpdateValidationStatus is the result of a cluster validation
+type RollingUpdateValidationStatus struct {
+	// Time is when the validation finished
+	Time time.Time `json:"time"`
+	// Succeeded is true if the cluster validated
+	Succeeded bool `json:"succeeded"`
+	// Message describes why the validation failed
+	Message string `json:"message,omitempty"`
+}
+
+// NewRollingUpdateStatus builds the plan for a rolling update of the specified groups
+func NewRollingUpdateStatus(clusterName string, groups map[string]*cloudinstances.CloudInstanceGroup, force bool) *RollingUpdateStatus {
+	now := time.Now().UTC()
+	status := &RollingUpdateStatus{
+		ClusterName: clusterName,
+		Phase:       RollingUpdatePhaseInProgress,
+		StartTime:   now,
+		UpdateTime:  now,
+	}
+
+	for _, group := range groups {
+		g := &RollingUpdateGroupStatus{
+			Name:  group.InstanceGroup.ObjectMeta.Name,
+			Role:  group.InstanceGroup.Spec.Role,
+			Phase: RollingUpdatePhasePending,
+		}
+		for _, u := range group.NeedUpdate {
+			g.Instances = append(g.Instances, u.ID)
+		}
+		if force {
+			for _, u := range group.Ready {
+				g.Instances = append(g.Instances, u.ID)
+			}
+		}
+		if len(g.Instances) == 0 {
+			g.Phase = RollingUpdatePhaseCompleted
+		}
+		status.Groups = append(status.Groups, g)
+	}
+
+	sort.Slice(status.Groups, func(i, j int) bool {
+		return status.Groups[i].Name < status.Groups[j].Name
+	})
+
+	return status
+}
+
+// Group returns the status of the named instance group, or nil if it is not part of the plan
+func (s *RollingUpdateStatus) Group(name string) *RollingUpdateGroupStatus {
+	if s == nil {
+		return nil
+	}
+	for _, g := range s.Groups {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
+
+// IsDone returns true if the instance has already been replaced
+func (g *RollingUpdateGroupStatus) IsDone(id string) bool {
+	for _, done := range g.InstancesDone {
+		if done == id {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPlanned returns true if the instance is to be replaced
+func (g *RollingUpdateGroupStatus) IsPlanned(id string) bool {
+	for _, planned := range g.Instances {
+		if planned == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadRollingUpdateStatus reads the recorded rolling update status for a cluster, returning nil if there is none
+func ReadRollingUpdateStatus(configBase vfs.Path) (*RollingUpdateStatus, error) {
+	status := &RollingUpdateStatus{}
+	if err := registry.ReadConfigDeprecated(configBase.Join(PathRollingUpdateStatus), status); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading rolling update status: %v", err)
+	}
+	return status, nil
+}
+
+// Progress records the progress of a rolling update in the state store.
+// A nil Progress records nothing.
+type Progress struct {
+	cluster    *api.Cluster
+	configBase vfs.Path
+
+	mutex  sync.Mutex
+	status *RollingUpdateStatus
+}
+
+// NewProgress creates a Progress which records status under configBase
+func NewProgress(cluster *api.Cluster, configBase vfs.Path, status *RollingUpdateStatus) *Progress {
+	return &Progress{
+		cluster:    cluster,
+		configBase: configBase,
+		status:     status,
+	}
+}
+
+// Status returns the status being recorded
+func (p *Progress) Status() *RollingUpdateStatus {
+	if p == nil {
+		return nil
+	}
+	return p.status
+}
+
+// Save writes the current status to the state store
+func (p *Progress) Save() error {
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.save()
+}
+
+func (p *Progress) save() error {
+	p.status.UpdateTime = time.Now().UTC()
+	if err := registry.WriteConfigDeprecated(p.cluster, p.configBase.Join(PathRollingUpdateStatus), p.status); err != nil {
+		return fmt.Errorf("error recording rolling update status: %v", err)
+	}
+	return nil
+}
+
+// update applies fn to the status and records the result.
+// Failing to record progress does not fail the rolling update, so errors are only logged.
+func (p *Progress) update(fn func(status *RollingUpdateStatus)) {
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	fn(p.status)
+	if err := p.save(); err != nil {
+		klog.Warningf("%v", err)
+	}
+}
+
+// plannedGroup returns a copy of the plan for the named instance group, or nil if nothing is planned
+func (p *Progress) plannedGroup(name string) *RollingUpdateGroupStatus {
+	if p == nil {
+		return nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	g := p.status.Group(name)
+	if g == nil {
+		return nil
+	}
+	plan := *g
+	plan.Instances = append([]string(nil), g.Instances...)
+	plan.InstancesDone = append([]string(nil), g.InstancesDone...)
+	return &plan
+}
+
+func (p *Progress) groupPhase(name string, phase RollingUpdatePhase) {
+	p.update(func(status *RollingUpdateStatus) {
+		if g := status.Group(name); g != nil {
+			g.Phase = phase
+		}
+	})
+}
+
+func (p *Progress) instancesDone(name string, members []*cloudinstances.CloudInstanceGroupMember) {
+	p.update(func(status *RollingUpdateStatus) {
+		g := status.Group(name)
+		if g == nil {
+			return
+		}
+		for _, u := range members {
+			if !g.IsDone(u.ID) {
+				g.InstancesDone = append(g.InstancesDone, u.ID)
+			}
+		}
+	})
+}
+
+func (p *Progress) validated(name string, err error) {
+	p.update(func(status *RollingUpdateStatus) {
+		g := status.Group(name)
+		if g == nil {
+			return
+		}
+		g.LastValidation = &RollingUpdateValidationStatus{
+			Time:      time.Now().UTC(),
+			Succeeded: err == nil,
+		}
+		if err != nil {
+			g.LastValidation.Message = err.Error()
+		}
+	})
+}
+
+func (p *Progress) drainBlocked(name string, blocked []BlockedPod) {
+	p.update(func(status *RollingUpdateStatus) {
+		if g := status.Group(name); g != nil {
+			g.DrainBlockedPods = blocked
+		}
+	})
+}
+
+func (p *Progress) finished(err error) {
+	p.update(func(status *RollingUpdateStatus) {
+		if err != nil {
+			status.Phase = RollingUpdatePhaseFailed
+			status.Message = err.Error()
+		} else {
+			status.Phase = RollingUpdatePhaseCompleted
+			status.Message = ""
+		}
+	})
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	v1 "k8s.io/api/core/v1"
+	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kops/cloudmock/aws/mockautoscaling"
+	kopsapi "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/cloudinstances"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func buildProgressTestGroups(cloud awsup.AWSCloud) map[string]*cloudinstances.CloudInstanceGroup {
+	groups := make(map[string]*cloudinstances.CloudInstanceGroup)
+	for _, name := range []string{"node-1", "node-2"} {
+		ids := []string{name + "a", name + "b"}
+
+		cloud.Autoscaling().CreateAutoScalingGroup(&autoscaling.CreateAutoScalingGroupInput{
+			AutoScalingGroupName: aws.String(name),
+			MinSize:              aws.Int64(1),
+			MaxSize:              aws.Int64(5),
+		})
+		cloud.Autoscaling().AttachInstances(&autoscaling.AttachInstancesInput{
+			AutoScalingGroupName: aws.String(name),
+			InstanceIds:          aws.StringSlice(ids),
+		})
+
+		group := &cloudinstances.CloudInstanceGroup{
+			HumanName: name,
+			InstanceGroup: &kopsapi.InstanceGroup{
+				ObjectMeta: v1meta.ObjectMeta{
+					Name: name,
+				},
+				Spec: kopsapi.InstanceGroupSpec{
+					Role: kopsapi.InstanceGroupRoleNode,
+				},
+			},
+		}
+		for _, id := range ids {
+			group.NeedUpdate = append(group.NeedUpdate, &cloudinstances.CloudInstanceGroupMember{
+				ID:                 id,
+				Node:               &v1.Node{},
+				CloudInstanceGroup: group,
+			})
+		}
+		groups[name] = group
+	}
+	return groups
+}
+
+func TestRollingUpdateRecordsProgress(t *testing.T) {
+	mockcloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	mockcloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Name = "test.k8s.local"
+
+	groups := buildProgressTestGroups(mockcloud)
+
+	configBase := vfs.NewMemFSPath(vfs.NewMemFSContext(), "state/test.k8s.local")
+	progress := NewProgress(cluster, configBase, NewRollingUpdateStatus(cluster.Name, groups, false))
+	if err := progress.Save(); err != nil {
+		t.Fatalf("error saving progress: %v", err)
+	}
+
+	c := &RollingUpdateCluster{
+		Cloud:           mockcloud,
+		MasterInterval:  1 * time.Millisecond,
+		NodeInterval:    1 * time.Millisecond,
+		BastionInterval: 1 * time.Millisecond,
+		K8sClient:       fake.NewSimpleClientset(),
+		Progress:        progress,
+	}
+
+	if err := c.RollingUpdate(groups, cluster, &kopsapi.InstanceGroupList{}); err != nil {
+		t.Fatalf("Error on rolling update: %v", err)
+	}
+
+	status, err := ReadRollingUpdateStatus(configBase)
+	if err != nil {
+		t.Fatalf("error reading status: %v", err)
+	}
+	if status == nil {
+		t.Fatalf("status was not recorded")
+	}
+	if status.Phase != RollingUpdatePhaseCompleted {
+		t.Errorf("expected rolling update to be completed, was %q", status.Phase)
+	}
+	for _, g := range status.Groups {
+		if g.Phase != RollingUpdatePhaseCompleted {
+			t.Errorf("expected group %s to be completed, was %q", g.Name, g.Phase)
+		}
+		if !reflect.DeepEqual(g.InstancesDone, g.Instances) {
+			t.Errorf("expected group %s to have replaced %v, replaced %v", g.Name, g.Instances, g.InstancesDone)
+		}
+		if g.LastValidation == nil {
+			t.Errorf("expected group %s to record its last validation", g.Name)
+		}
+	}
+}
+
+func TestRollingUpdateResume(t *testing.T) {
+	mockcloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	mockcloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Name = "test.k8s.local"
+
+	groups := buildProgressTestGroups(mockcloud)
+
+	// node-1 was completed and node-2 was interrupted after replacing node-2a
+	status := NewRollingUpdateStatus(cluster.Name, groups, false)
+	status.Phase = RollingUpdatePhaseFailed
+	status.Group("node-1").Phase = RollingUpdatePhaseCompleted
+	status.Group("node-1").InstancesDone = []string{"node-1a", "node-1b"}
+	status.Group("node-2").Phase = RollingUpdatePhaseInProgress
+	status.Group("node-2").InstancesDone = []string{"node-2a"}
+
+	// node-2c was launched since, and must not be replaced
+	groups["node-2"].NeedUpdate = append(groups["node-2"].NeedUpdate, &cloudinstances.CloudInstanceGroupMember{
+		ID:                 "node-2c",
+		Node:               &v1.Node{},
+		CloudInstanceGroup: groups["node-2"],
+	})
+
+	configBase := vfs.NewMemFSPath(vfs.NewMemFSContext(), "state/test.k8s.local")
+	c := &RollingUpdateCluster{
+		Cloud:           mockcloud,
+		MasterInterval:  1 * time.Millisecond,
+		NodeInterval:    1 * time.Millisecond,
+		BastionInterval: 1 * time.Millisecond,
+		K8sClient:       fake.NewSimpleClientset(),
+		Progress:        NewProgress(cluster, configBase, status),
+	}
+
+	if err := c.RollingUpdate(groups, cluster, &kopsapi.InstanceGroupList{}); err != nil {
+		t.Fatalf("Error on rolling update: %v", err)
+	}
+
+	asgGroups, _ := mockcloud.Autoscaling().DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: aws.StringSlice([]string{"node-1", "node-2"}),
+	})
+	remaining := make(map[string][]string)
+	for _, g := range asgGroups.AutoScalingGroups {
+		for _, i := range g.Instances {
+			remaining[aws.StringValue(g.AutoScalingGroupName)] = append(remaining[aws.StringValue(g.AutoScalingGroupName)], aws.StringValue(i.InstanceId))
+		}
+	}
+
+	expected := map[string][]string{
+		// node-1 was skipped, as was node-2a, which was recorded as replaced before the interruption
+		"node-1": {"node-1a", "node-1b"},
+		"node-2": {"node-2a"},
+	}
+	if !reflect.DeepEqual(remaining, expected) {
+		t.Errorf("expected remaining instances %v, got %v", expected, remaining)
+	}
+
+	if status.Phase != RollingUpdatePhaseCompleted {
+		t.Errorf("expected rolling update to be completed, was %q", status.Phase)
+	}
+	if done := status.Group("node-2").InstancesDone; !reflect.DeepEqual(done, []string{"node-2a", "node-2b"}) {
+		t.Errorf("unexpected instances done for node-2: %v", done)
+	}
+}
@@ -21,10 +21,11 @@ import (
 	"sync"
 	"time"
 
-	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog"
 	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/validation"
 	"k8s.io/kops/pkg/cloudinstances"
 	"k8s.io/kops/upup/pkg/fi"
 )
@@ -45,7 +46,6 @@ type RollingUpdateCluster struct {
 	Force bool
 
 	K8sClient        kubernetes.Interface
-	ClientGetter     genericclioptions.RESTClientGetter
 	FailOnDrainError bool
 	FailOnValidate   bool
 	CloudOnly        bool
@@ -54,12 +54,65 @@ type RollingUpdateCluster struct {
 	// PostDrainDelay is the duration we wait after draining each node
 	PostDrainDelay time.Duration
 
+	// DrainOptions controls how pods are removed from nodes
+	DrainOptions DrainOptions
+
 	// ValidationTimeout is the maximum time to wait for the cluster to validate, once we start validation
 	ValidationTimeout time.Duration
+
+	// MaxSurge overrides the maxSurge rolling-update setting of the cluster and its instance groups, if set
+	MaxSurge *intstr.IntOrString
+	// MaxUnavailable overrides the maxUnavailable rolling-update setting of the cluster and its instance groups, if set
+	MaxUnavailable *intstr.IntOrString
+
+	// Hooks are run at points in the replacement of each instance, in addition to the hooks
+	// configured in the rolling update settings of the cluster or instance group
+	Hooks []InstanceHook
+
+	// Progress, if set, records the progress of the rolling update so that it can be resumed.
+	// Only the instances planned in its status are replaced, and groups it records as completed are skipped.
+	Progress *Progress
 }
 
 // RollingUpdate performs a rolling update on a K8s Cluster.
 func (c *RollingUpdateCluster) RollingUpdate(groups map[string]*cloudinstances.CloudInstanceGroup, cluster *api.Cluster, instanceGroups *api.InstanceGroupList) error {
+	err := c.rollingUpdate(groups, cluster, instanceGroups)
+	c.Progress.finished(err)
+	return err
+}
+
+// rollingUpdateGroup performs a rolling update on a single instance group, recording its progress.
+func (c *RollingUpdateCluster) rollingUpdateGroup(group *cloudinstances.CloudInstanceGroup, cluster *api.Cluster, instanceGroups *api.InstanceGroupList, isBastion bool, sleepAfterTerminate time.Duration) error {
+	name := group.InstanceGroup.ObjectMeta.Name
+	if c.Progress != nil {
+		plan := c.Progress.plannedGroup(name)
+		if plan == nil {
+			klog.Infof("Skipping InstanceGroup %q, as it is not part of the rolling update being resumed", name)
+			return nil
+		}
+		if plan.Phase == RollingUpdatePhaseCompleted {
+			klog.Infof("Skipping InstanceGroup %q, as its rolling update has already completed", name)
+			return nil
+		}
+	}
+
+	c.Progress.groupPhase(name, RollingUpdatePhaseInProgress)
+
+	g, err := NewRollingUpdateInstanceGroup(c.Cloud, group)
+	if err == nil {
+		err = g.RollingUpdate(c, cluster, instanceGroups, isBastion, sleepAfterTerminate, c.ValidationTimeout)
+	}
+
+	if err != nil {
+		c.Progress.groupPhase(name, RollingUpdatePhaseFailed)
+	} else {
+		c.Progress.groupPhase(name, RollingUpdatePhaseCompleted)
+	}
+
+	return err
+}
+
+func (c *RollingUpdateCluster) rollingUpdate(groups map[string]*cloudinstances.CloudInstanceGroup, cluster *api.Cluster, instanceGroups *api.InstanceGroupList) error {
 	if len(groups) == 0 {
 		klog.Info("Cloud Instance Group length is zero. Not doing a rolling-update.")
 		return nil
@@ -84,6 +137,16 @@ func (c *RollingUpdateCluster) RollingUpdate(groups map[string]*cloudinstances.C
 		}
 	}
 
+	// Check that surging is supported before any instance is cordoned
+	if !validation.SurgeSupported(string(c.Cloud.ProviderID())) {
+		for _, group := range nodeGroups {
+			settings := c.resolveSettings(cluster, group.InstanceGroup, len(group.Ready)+len(group.NeedUpdate))
+			if settings.MaxSurge.IntValue() > 0 {
+				return fmt.Errorf("instance group %q is configured to surge, which is not supported on cloud provider %q", group.InstanceGroup.ObjectMeta.Name, c.Cloud.ProviderID())
+			}
+		}
+	}
+
 	// Upgrade bastions first; if these go down we can't see anything
 	{
 		var wg sync.WaitGroup
@@ -97,10 +160,7 @@ func (c *RollingUpdateCluster) RollingUpdate(groups map[string]*cloudinstances.C
 
 				defer wg.Done()
 
-				g, err := NewRollingUpdateInstanceGroup(c.Cloud, group)
-				if err == nil {
-					err = g.RollingUpdate(c, cluster, instanceGroups, true, c.BastionInterval, c.ValidationTimeout)
-				}
+				err := c.rollingUpdateGroup(group, cluster, instanceGroups, true, c.BastionInterval)
 
 				resultsMutex.Lock()
 				results[k] = err
@@ -125,10 +185,7 @@ func (c *RollingUpdateCluster) RollingUpdate(groups map[string]*cloudinstances.C
 		// and we don't want to roll all the masters at the same time.  See issue #284
 
 		for _, group := range masterGroups {
-			g, err := NewRollingUpdateInstanceGroup(c.Cloud, group)
-			if err == nil {
-				err = g.RollingUpdate(c, cluster, instanceGroups, false, c.MasterInterval, c.ValidationTimeout)
-			}
+			err := c.rollingUpdateGroup(group, cluster, instanceGroups, false, c.MasterInterval)
 
 			// Do not continue update if master(s) failed, cluster is potentially in an unhealthy state
 			if err != nil {
@@ -159,10 +216,7 @@ func (c *RollingUpdateCluster) RollingUpdate(groups map[string]*cloudinstances.C
 			defer wg.Done()
 
 			for k, group := range nodeGroups {
-				g, err := NewRollingUpdateInstanceGroup(c.Cloud, group)
-				if err == nil {
-					err = g.RollingUpdate(c, cluster, instanceGroups, false, c.NodeInterval, c.ValidationTimeout)
-				}
+				err := c.rollingUpdateGroup(group, cluster, instanceGroups, false, c.NodeInterval)
 
 				resultsMutex.Lock()
 				results[k] = err
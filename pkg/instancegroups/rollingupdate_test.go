@@ -17,6 +17,7 @@ limitations under the License.
 package instancegroups
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -25,8 +26,10 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	v1meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/kops/cloudmock/aws/mockautoscaling"
+	"k8s.io/kops/cloudmock/aws/mockec2"
 	kopsapi "k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/cloudinstances"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
@@ -679,3 +682,269 @@ func TestRollingUpdateUnknownRole(t *testing.T) {
 		}
 	}
 }
+
+func TestRollingUpdateSurge(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset()
+
+	mockcloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	mockcloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+	mockEC2 := &mockec2.MockEC2{}
+	mockcloud.MockEC2 = mockEC2
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Name = "test.k8s.local"
+
+	c := &RollingUpdateCluster{
+		Cloud:           mockcloud,
+		MasterInterval:  1 * time.Millisecond,
+		NodeInterval:    1 * time.Millisecond,
+		BastionInterval: 1 * time.Millisecond,
+		Force:           false,
+		K8sClient:       k8sClient,
+	}
+
+	cloud := c.Cloud.(awsup.AWSCloud)
+	cloud.Autoscaling().CreateAutoScalingGroup(&autoscaling.CreateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String("node-1"),
+		MinSize:              aws.Int64(1),
+		MaxSize:              aws.Int64(5),
+	})
+	cloud.Autoscaling().AttachInstances(&autoscaling.AttachInstancesInput{
+		AutoScalingGroupName: aws.String("node-1"),
+		InstanceIds:          []*string{aws.String("i-0001"), aws.String("i-0002")},
+	})
+
+	maxSurge := intstr.FromInt(1)
+	group := &cloudinstances.CloudInstanceGroup{
+		HumanName: "node-1",
+		InstanceGroup: &kopsapi.InstanceGroup{
+			ObjectMeta: v1meta.ObjectMeta{
+				Name: "node-1",
+			},
+			Spec: kopsapi.InstanceGroupSpec{
+				Role: kopsapi.InstanceGroupRoleNode,
+				RollingUpdate: &kopsapi.RollingUpdate{
+					MaxSurge: &maxSurge,
+				},
+			},
+		},
+	}
+	for _, id := range []string{"i-0001", "i-0002"} {
+		group.NeedUpdate = append(group.NeedUpdate, &cloudinstances.CloudInstanceGroupMember{
+			ID:                 id,
+			Node:               &v1.Node{},
+			CloudInstanceGroup: group,
+		})
+	}
+
+	groups := map[string]*cloudinstances.CloudInstanceGroup{"node-1": group}
+	err := c.RollingUpdate(groups, cluster, &kopsapi.InstanceGroupList{})
+	if err != nil {
+		t.Errorf("Error on rolling update: %v", err)
+	}
+
+	asgGroups, _ := cloud.Autoscaling().DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String("node-1")},
+	})
+	for _, g := range asgGroups.AutoScalingGroups {
+		if len(g.Instances) > 0 {
+			t.Errorf("Not all instances detached from %s", aws.StringValue(g.AutoScalingGroupName))
+		}
+	}
+
+	for _, u := range group.NeedUpdate {
+		if !u.Detached {
+			t.Errorf("Instance %s was not detached", u.ID)
+		}
+
+		tagged := false
+		for _, tag := range mockEC2.Tags {
+			if aws.StringValue(tag.ResourceId) == u.ID && aws.StringValue(tag.Key) == awsup.TagNameDetachedInstance {
+				tagged = true
+				if aws.StringValue(tag.Value) != "node-1" {
+					t.Errorf("Instance %s tagged with unexpected group %q", u.ID, aws.StringValue(tag.Value))
+				}
+			}
+		}
+		if !tagged {
+			t.Errorf("Instance %s was not tagged as detached", u.ID)
+		}
+	}
+}
+
+// noSurgeCloud is a cloud on which instances cannot be detached from their groups
+type noSurgeCloud struct {
+	*awsup.MockAWSCloud
+}
+
+func (c *noSurgeCloud) ProviderID() kopsapi.CloudProviderID {
+	return kopsapi.CloudProviderOpenstack
+}
+
+func TestRollingUpdateSurgeNotSupported(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset()
+
+	mockcloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	mockcloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+	mockcloud.MockEC2 = &mockec2.MockEC2{}
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Name = "test.k8s.local"
+
+	c := &RollingUpdateCluster{
+		Cloud:           &noSurgeCloud{MockAWSCloud: mockcloud},
+		MasterInterval:  1 * time.Millisecond,
+		NodeInterval:    1 * time.Millisecond,
+		BastionInterval: 1 * time.Millisecond,
+		K8sClient:       k8sClient,
+	}
+
+	mockcloud.Autoscaling().CreateAutoScalingGroup(&autoscaling.CreateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String("node-1"),
+		MinSize:              aws.Int64(1),
+		MaxSize:              aws.Int64(5),
+	})
+	mockcloud.Autoscaling().AttachInstances(&autoscaling.AttachInstancesInput{
+		AutoScalingGroupName: aws.String("node-1"),
+		InstanceIds:          []*string{aws.String("i-0001")},
+	})
+
+	maxSurge := intstr.FromInt(1)
+	group := &cloudinstances.CloudInstanceGroup{
+		HumanName: "node-1",
+		InstanceGroup: &kopsapi.InstanceGroup{
+			ObjectMeta: v1meta.ObjectMeta{
+				Name: "node-1",
+			},
+			Spec: kopsapi.InstanceGroupSpec{
+				Role: kopsapi.InstanceGroupRoleNode,
+				RollingUpdate: &kopsapi.RollingUpdate{
+					MaxSurge: &maxSurge,
+				},
+			},
+		},
+	}
+	group.NeedUpdate = append(group.NeedUpdate, &cloudinstances.CloudInstanceGroupMember{
+		ID:                 "i-0001",
+		Node:               &v1.Node{},
+		CloudInstanceGroup: group,
+	})
+
+	groups := map[string]*cloudinstances.CloudInstanceGroup{"node-1": group}
+	err := c.RollingUpdate(groups, cluster, &kopsapi.InstanceGroupList{})
+	if err == nil {
+		t.Fatalf("expected rolling update to fail when surging is not supported")
+	}
+
+	asgGroups, _ := mockcloud.Autoscaling().DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String("node-1")},
+	})
+	for _, g := range asgGroups.AutoScalingGroups {
+		if len(g.Instances) != 1 {
+			t.Errorf("expected the instance of %s to be left alone, got %v", aws.StringValue(g.AutoScalingGroupName), g.Instances)
+		}
+	}
+}
+
+// concurrentTerminateCloud records how many instances are being deleted at the same time
+type concurrentTerminateCloud struct {
+	*awsup.MockAWSCloud
+
+	mutex       sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (c *concurrentTerminateCloud) DeleteInstance(i *cloudinstances.CloudInstanceGroupMember) error {
+	c.mutex.Lock()
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	c.mutex.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	c.mutex.Lock()
+	c.inFlight--
+	c.mutex.Unlock()
+
+	return c.MockAWSCloud.DeleteInstance(i)
+}
+
+func TestRollingUpdateMaxUnavailable(t *testing.T) {
+	k8sClient := fake.NewSimpleClientset()
+
+	mockcloud := awsup.BuildMockAWSCloud("us-east-1", "abc")
+	mockcloud.MockAutoscaling = &mockautoscaling.MockAutoscaling{}
+	cloud := &concurrentTerminateCloud{MockAWSCloud: mockcloud}
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Name = "test.k8s.local"
+
+	c := &RollingUpdateCluster{
+		Cloud:           cloud,
+		MasterInterval:  1 * time.Millisecond,
+		NodeInterval:    1 * time.Millisecond,
+		BastionInterval: 1 * time.Millisecond,
+		Force:           false,
+		K8sClient:       k8sClient,
+	}
+
+	ids := []string{"node-1a", "node-1b", "node-1c", "node-1d", "node-1e"}
+	var instanceIds []*string
+	for _, id := range ids {
+		instanceIds = append(instanceIds, aws.String(id))
+	}
+	mockcloud.Autoscaling().CreateAutoScalingGroup(&autoscaling.CreateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String("node-1"),
+		MinSize:              aws.Int64(1),
+		MaxSize:              aws.Int64(5),
+	})
+	mockcloud.Autoscaling().AttachInstances(&autoscaling.AttachInstancesInput{
+		AutoScalingGroupName: aws.String("node-1"),
+		InstanceIds:          instanceIds,
+	})
+
+	maxUnavailable := intstr.FromString("40%")
+	group := &cloudinstances.CloudInstanceGroup{
+		HumanName: "node-1",
+		InstanceGroup: &kopsapi.InstanceGroup{
+			ObjectMeta: v1meta.ObjectMeta{
+				Name: "node-1",
+			},
+			Spec: kopsapi.InstanceGroupSpec{
+				Role: kopsapi.InstanceGroupRoleNode,
+				RollingUpdate: &kopsapi.RollingUpdate{
+					MaxUnavailable: &maxUnavailable,
+				},
+			},
+		},
+	}
+	for _, id := range ids {
+		group.NeedUpdate = append(group.NeedUpdate, &cloudinstances.CloudInstanceGroupMember{
+			ID:                 id,
+			Node:               &v1.Node{},
+			CloudInstanceGroup: group,
+		})
+	}
+
+	groups := map[string]*cloudinstances.CloudInstanceGroup{"node-1": group}
+	err := c.RollingUpdate(groups, cluster, &kopsapi.InstanceGroupList{})
+	if err != nil {
+		t.Errorf("Error on rolling update: %v", err)
+	}
+
+	asgGroups, _ := mockcloud.Autoscaling().DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{aws.String("node-1")},
+	})
+	for _, g := range asgGroups.AutoScalingGroups {
+		if len(g.Instances) > 0 {
+			t.Errorf("Not all instances terminated in %s", aws.StringValue(g.AutoScalingGroupName))
+		}
+	}
+
+	if cloud.maxInFlight != 2 {
+		t.Errorf("Expected 2 instances to be replaced concurrently, got %d", cloud.maxInFlight)
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"k8s.io/apimachinery/pkg/util/intstr"
+	api "k8s.io/kops/pkg/apis/kops"
+)
+
+// resolveSettings computes the effective rolling-update settings for an instance group.
+// Settings given on the command line take precedence over those of the instance group,
+// which in turn take precedence over those of the cluster, except that the command line
+// never makes more than one master unavailable at once.
+// Percentages are resolved against numInstances, so the returned values are always integers.
+func (c *RollingUpdateCluster) resolveSettings(cluster *api.Cluster, group *api.InstanceGroup, numInstances int) api.RollingUpdate {
+	rollingUpdate := api.RollingUpdate{
+		MaxUnavailable: c.MaxUnavailable,
+		MaxSurge:       c.MaxSurge,
+	}
+
+	for _, defaults := range []*api.RollingUpdate{group.Spec.RollingUpdate, cluster.Spec.RollingUpdate} {
+		if defaults == nil {
+			continue
+		}
+		if rollingUpdate.MaxUnavailable == nil {
+			rollingUpdate.MaxUnavailable = defaults.MaxUnavailable
+		}
+		if rollingUpdate.MaxSurge == nil {
+			rollingUpdate.MaxSurge = defaults.MaxSurge
+		}
+		if len(rollingUpdate.Hooks) == 0 {
+			rollingUpdate.Hooks = defaults.Hooks
+		}
+	}
+
+	if rollingUpdate.MaxSurge == nil {
+		zero := intstr.FromInt(0)
+		rollingUpdate.MaxSurge = &zero
+	}
+
+	if rollingUpdate.MaxSurge.Type == intstr.String {
+		surge, _ := intstr.GetValueFromIntOrPercent(rollingUpdate.MaxSurge, numInstances, true)
+		surgeInt := intstr.FromInt(surge)
+		rollingUpdate.MaxSurge = &surgeInt
+	}
+
+	// Masters register themselves through their local apiserver, which depends on the local etcd
+	// having joined the etcd cluster, so they cannot be surged. We fall back to replacing them one
+	// at a time instead.
+	masterSurgeDisabled := false
+	if group.IsMaster() && rollingUpdate.MaxSurge.IntValue() != 0 {
+		zero := intstr.FromInt(0)
+		rollingUpdate.MaxSurge = &zero
+		masterSurgeDisabled = true
+	}
+
+	if rollingUpdate.MaxUnavailable == nil {
+		unavailable := intstr.FromInt(1)
+		if rollingUpdate.MaxSurge.IntValue() > 0 {
+			unavailable = intstr.FromInt(0)
+		}
+		rollingUpdate.MaxUnavailable = &unavailable
+	}
+
+	if rollingUpdate.MaxUnavailable.Type == intstr.String {
+		unavailable, _ := intstr.GetValueFromIntOrPercent(rollingUpdate.MaxUnavailable, numInstances, false)
+		if unavailable <= 0 {
+			// While we round down, percentages should resolve to a minimum of 1
+			unavailable = 1
+		}
+		unavailableInt := intstr.FromInt(unavailable)
+		rollingUpdate.MaxUnavailable = &unavailableInt
+	}
+
+	if masterSurgeDisabled && rollingUpdate.MaxUnavailable.IntValue() == 0 {
+		one := intstr.FromInt(1)
+		rollingUpdate.MaxUnavailable = &one
+	}
+
+	// The command line applies to every instance group, but terminating several masters of a group
+	// at once would lose etcd quorum, so we cap it to one master at a time.
+	if group.IsMaster() && c.MaxUnavailable != nil && rollingUpdate.MaxUnavailable.IntValue() > 1 {
+		one := intstr.FromInt(1)
+		rollingUpdate.MaxUnavailable = &one
+	}
+
+	return rollingUpdate
+}
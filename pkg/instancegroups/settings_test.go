@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instancegroups
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+func intOrString(s string) *intstr.IntOrString {
+	v := intstr.Parse(s)
+	return &v
+}
+
+func TestResolveSettings(t *testing.T) {
+	grid := []struct {
+		description    string
+		override       *kops.RollingUpdate
+		cluster        *kops.RollingUpdate
+		group          *kops.RollingUpdate
+		role           kops.InstanceGroupRole
+		numInstances   int
+		maxSurge       int
+		maxUnavailable int
+	}{
+		{
+			description:    "defaults",
+			role:           kops.InstanceGroupRoleNode,
+			numInstances:   10,
+			maxSurge:       0,
+			maxUnavailable: 1,
+		},
+		{
+			description:    "surge defaults unavailable to zero",
+			group:          &kops.RollingUpdate{MaxSurge: intOrString("2")},
+			role:           kops.InstanceGroupRoleNode,
+			numInstances:   10,
+			maxSurge:       2,
+			maxUnavailable: 0,
+		},
+		{
+			description:    "group overrides cluster",
+			cluster:        &kops.RollingUpdate{MaxSurge: intOrString("1"), MaxUnavailable: intOrString("3")},
+			group:          &kops.RollingUpdate{MaxSurge: intOrString("2")},
+			role:           kops.InstanceGroupRoleNode,
+			numInstances:   10,
+			maxSurge:       2,
+			maxUnavailable: 3,
+		},
+		{
+			description:    "override takes precedence",
+			override:       &kops.RollingUpdate{MaxUnavailable: intOrString("4")},
+			cluster:        &kops.RollingUpdate{MaxUnavailable: intOrString("3")},
+			group:          &kops.RollingUpdate{MaxUnavailable: intOrString("2")},
+			role:           kops.InstanceGroupRoleNode,
+			numInstances:   10,
+			maxSurge:       0,
+			maxUnavailable: 4,
+		},
+		{
+			description:    "percentages",
+			group:          &kops.RollingUpdate{MaxSurge: intOrString("25%"), MaxUnavailable: intOrString("25%")},
+			role:           kops.InstanceGroupRoleNode,
+			numInstances:   10,
+			maxSurge:       3,
+			maxUnavailable: 2,
+		},
+		{
+			description:    "unavailable percentage is at least one",
+			group:          &kops.RollingUpdate{MaxUnavailable: intOrString("10%")},
+			role:           kops.InstanceGroupRoleNode,
+			numInstances:   3,
+			maxSurge:       0,
+			maxUnavailable: 1,
+		},
+		{
+			description:    "disabled",
+			group:          &kops.RollingUpdate{MaxSurge: intOrString("0"), MaxUnavailable: intOrString("0")},
+			role:           kops.InstanceGroupRoleNode,
+			numInstances:   10,
+			maxSurge:       0,
+			maxUnavailable: 0,
+		},
+		{
+			description:    "masters do not surge",
+			cluster:        &kops.RollingUpdate{MaxSurge: intOrString("1"), MaxUnavailable: intOrString("0")},
+			role:           kops.InstanceGroupRoleMaster,
+			numInstances:   1,
+			maxSurge:       0,
+			maxUnavailable: 1,
+		},
+		{
+			description:    "override replaces masters one at a time",
+			override:       &kops.RollingUpdate{MaxUnavailable: intOrString("100%")},
+			role:           kops.InstanceGroupRoleMaster,
+			numInstances:   3,
+			maxSurge:       0,
+			maxUnavailable: 1,
+		},
+		{
+			description:    "group can replace masters together",
+			group:          &kops.RollingUpdate{MaxUnavailable: intOrString("2")},
+			role:           kops.InstanceGroupRoleMaster,
+			numInstances:   3,
+			maxSurge:       0,
+			maxUnavailable: 2,
+		},
+	}
+
+	for _, g := range grid {
+		c := &RollingUpdateCluster{}
+		if g.override != nil {
+			c.MaxSurge = g.override.MaxSurge
+			c.MaxUnavailable = g.override.MaxUnavailable
+		}
+
+		cluster := &kops.Cluster{}
+		cluster.Spec.RollingUpdate = g.cluster
+
+		ig := &kops.InstanceGroup{}
+		ig.Spec.Role = g.role
+		ig.Spec.RollingUpdate = g.group
+
+		settings := c.resolveSettings(cluster, ig, g.numInstances)
+		if settings.MaxSurge.Type != intstr.Int || settings.MaxSurge.IntValue() != g.maxSurge {
+			t.Errorf("%s: expected maxSurge %d, got %s", g.description, g.maxSurge, settings.MaxSurge.String())
+		}
+		if settings.MaxUnavailable.Type != intstr.Int || settings.MaxUnavailable.IntValue() != g.maxUnavailable {
+			t.Errorf("%s: expected maxUnavailable %d, got %s", g.description, g.maxUnavailable, settings.MaxUnavailable.String())
+		}
+	}
+}
+
+func TestResolveSettingsHooks(t *testing.T) {
+	clusterHooks := []kops.RollingUpdateHook{{Name: "cluster"}}
+	groupHooks := []kops.RollingUpdateHook{{Name: "group"}}
+
+	c := &RollingUpdateCluster{}
+
+	cluster := &kops.Cluster{}
+	cluster.Spec.RollingUpdate = &kops.RollingUpdate{Hooks: clusterHooks}
+
+	ig := &kops.InstanceGroup{}
+	ig.Spec.Role = kops.InstanceGroupRoleNode
+
+	if settings := c.resolveSettings(cluster, ig, 1); len(settings.Hooks) != 1 || settings.Hooks[0].Name != "cluster" {
+		t.Errorf("expected the cluster hooks to be used, got %v", settings.Hooks)
+	}
+
+	ig.Spec.RollingUpdate = &kops.RollingUpdate{Hooks: groupHooks}
+	if settings := c.resolveSettings(cluster, ig, 1); len(settings.Hooks) != 1 || settings.Hooks[0].Name != "group" {
+		t.Errorf("expected the instance group hooks to replace those of the cluster, got %v", settings.Hooks)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/klog"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/util/pkg/architectures"
+)
+
+// InstanceGroupArchitecture returns the CPU architecture of the instances in the instance group,
+// which is determined by the machine type; all the machine types of an instance group must have the same architecture
+func InstanceGroupArchitecture(cluster *kops.Cluster, ig *kops.InstanceGroup) (architectures.Architecture, error) {
+	if kops.CloudProviderID(cluster.Spec.CloudProvider) != kops.CloudProviderAWS {
+		return architectures.ArchitectureAmd64, nil
+	}
+
+	var machineTypes []string
+	for _, machineType := range strings.Split(ig.Spec.MachineType, ",") {
+		machineType = strings.TrimSpace(machineType)
+		if machineType != "" {
+			machineTypes = append(machineTypes, machineType)
+		}
+	}
+	if ig.Spec.MixedInstancesPolicy != nil {
+		machineTypes = append(machineTypes, ig.Spec.MixedInstancesPolicy.Instances...)
+	}
+
+	arch := architectures.Architecture("")
+	for _, machineType := range machineTypes {
+		machineArch := architectures.ArchitectureAmd64
+		info, err := awsup.GetMachineTypeInfo(machineType)
+		if err != nil {
+			klog.Warningf("unknown machine type %q in instance group %q, assuming it is %s", machineType, ig.ObjectMeta.Name, machineArch)
+		} else if info.Arm64 {
+			machineArch = architectures.ArchitectureArm64
+		}
+
+		if arch == "" {
+			arch = machineArch
+		} else if arch != machineArch {
+			return "", fmt.Errorf("instance group %q mixes machine types of different architectures (%s and %s)", ig.ObjectMeta.Name, arch, machineArch)
+		}
+	}
+
+	if arch == "" {
+		arch = architectures.ArchitectureAmd64
+	}
+	return arch, nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package model
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/util/pkg/architectures"
+)
+
+func Test_InstanceGroupArchitecture(t *testing.T) {
+	grid := []struct {
+		CloudProvider string
+		MachineType   string
+		Mixed         []string
+		Expected      architectures.Architecture
+		ExpectError   bool
+	}{
+		{CloudProvider: "aws", MachineType: "m5.large", Expected: architectures.ArchitectureAmd64},
+		{CloudProvider: "aws", MachineType: "a1.large", Expected: architectures.ArchitectureArm64},
+		{CloudProvider: "aws", MachineType: "a1.large,a1.xlarge", Expected: architectures.ArchitectureArm64},
+		{CloudProvider: "aws", MachineType: "a1.large", Mixed: []string{"a1.xlarge"}, Expected: architectures.ArchitectureArm64},
+		{CloudProvider: "aws", MachineType: "", Expected: architectures.ArchitectureAmd64},
+		{CloudProvider: "aws", MachineType: "unknown.large", Expected: architectures.ArchitectureAmd64},
+		{CloudProvider: "aws", MachineType: "a1.large,m5.large", ExpectError: true},
+		{CloudProvider: "aws", MachineType: "m5.large", Mixed: []string{"a1.large"}, ExpectError: true},
+		{CloudProvider: "gce", MachineType: "n1-standard-1", Expected: architectures.ArchitectureAmd64},
+	}
+	for _, g := range grid {
+		cluster := &kops.Cluster{}
+		cluster.Spec.CloudProvider = g.CloudProvider
+
+		ig := &kops.InstanceGroup{
+			ObjectMeta: metav1.ObjectMeta{Name: "nodes"},
+		}
+		ig.Spec.MachineType = g.MachineType
+		if g.Mixed != nil {
+			ig.Spec.MixedInstancesPolicy = &kops.MixedInstancesPolicySpec{Instances: g.Mixed}
+		}
+
+		actual, err := InstanceGroupArchitecture(cluster, ig)
+		if g.ExpectError {
+			if err == nil {
+				t.Errorf("expected error for %q %q %v", g.CloudProvider, g.MachineType, g.Mixed)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q %q %v: %v", g.CloudProvider, g.MachineType, g.Mixed, err)
+			continue
+		}
+		if actual != g.Expected {
+			t.Errorf("unexpected architecture for %q %q %v: expected %q, got %q", g.CloudProvider, g.MachineType, g.Mixed, g.Expected, actual)
+		}
+	}
+}
@@ -30,16 +30,20 @@ import (
 	"k8s.io/klog"
 
 	"k8s.io/kops/pkg/apis/kops"
+	apimodel "k8s.io/kops/pkg/apis/kops/model"
 	"k8s.io/kops/pkg/apis/nodeup"
 	"k8s.io/kops/pkg/model/resources"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/util/pkg/architectures"
 )
 
 // BootstrapScript creates the bootstrap script
 type BootstrapScript struct {
-	NodeUpSource        string
-	NodeUpSourceHash    string
+	// NodeUpSource is the location of nodeup, for each architecture
+	NodeUpSource map[architectures.Architecture]string
+	// NodeUpSourceHash is the hash of nodeup, for each architecture
+	NodeUpSourceHash    map[architectures.Architecture]string
 	NodeUpConfigBuilder func(ig *kops.InstanceGroup) (*nodeup.Config, error)
 }
 
@@ -134,12 +138,20 @@ func (b *BootstrapScript) ResourceNodeUp(ig *kops.InstanceGroup, cluster *kops.C
 		return nil, nil
 	}
 
+	arch, err := InstanceGroupArchitecture(cluster, ig)
+	if err != nil {
+		return nil, err
+	}
+
 	functions := template.FuncMap{
-		"NodeUpSource": func() string {
-			return b.NodeUpSource
+		"NodeUpSource": func() (string, error) {
+			if b.NodeUpSource[arch] == "" {
+				return "", fmt.Errorf("no nodeup source for architecture %s of instance group %q", arch, ig.ObjectMeta.Name)
+			}
+			return b.NodeUpSource[arch], nil
 		},
 		"NodeUpSourceHash": func() string {
-			return b.NodeUpSourceHash
+			return b.NodeUpSourceHash[arch]
 		},
 		"KubeEnv": func() (string, error) {
 			return b.KubeEnv(ig)
@@ -164,11 +176,17 @@ func (b *BootstrapScript) ResourceNodeUp(ig *kops.InstanceGroup, cluster *kops.C
 		"ClusterSpec": func() (string, error) {
 			cs := cluster.Spec
 
+			// When updated in place, the kubelet flags, hooks and file assets are left out so that changing them does not
+			// require the instances to be replaced
+			inPlace := apimodel.InPlaceUpdates(cluster)
+
 			spec := make(map[string]interface{})
 			spec["cloudConfig"] = cs.CloudConfig
 			spec["docker"] = cs.Docker
 			spec["kubeProxy"] = cs.KubeProxy
-			spec["kubelet"] = cs.Kubelet
+			if !inPlace {
+				spec["kubelet"] = cs.Kubelet
+			}
 
 			if cs.NodeAuthorization != nil {
 				spec["nodeAuthorization"] = cs.NodeAuthorization
@@ -185,7 +203,9 @@ func (b *BootstrapScript) ResourceNodeUp(ig *kops.InstanceGroup, cluster *kops.C
 				spec["kubeAPIServer"] = cs.KubeAPIServer
 				spec["kubeControllerManager"] = cs.KubeControllerManager
 				spec["kubeScheduler"] = cs.KubeScheduler
-				spec["masterKubelet"] = cs.MasterKubelet
+				if !inPlace {
+					spec["masterKubelet"] = cs.MasterKubelet
+				}
 
 				for _, etcdCluster := range cs.EtcdClusters {
 					c := kops.EtcdClusterSpec{
@@ -204,20 +224,22 @@ func (b *BootstrapScript) ResourceNodeUp(ig *kops.InstanceGroup, cluster *kops.C
 				}
 			}
 
-			hooks, err := b.getRelevantHooks(cs.Hooks, ig.Spec.Role)
-			if err != nil {
-				return "", err
-			}
-			if len(hooks) > 0 {
-				spec["hooks"] = hooks
-			}
+			if !inPlace {
+				hooks, err := b.getRelevantHooks(cs.Hooks, ig.Spec.Role)
+				if err != nil {
+					return "", err
+				}
+				if len(hooks) > 0 {
+					spec["hooks"] = hooks
+				}
 
-			fileAssets, err := b.getRelevantFileAssets(cs.FileAssets, ig.Spec.Role)
-			if err != nil {
-				return "", err
-			}
-			if len(fileAssets) > 0 {
-				spec["fileAssets"] = fileAssets
+				fileAssets, err := b.getRelevantFileAssets(cs.FileAssets, ig.Spec.Role)
+				if err != nil {
+					return "", err
+				}
+				if len(fileAssets) > 0 {
+					spec["fileAssets"] = fileAssets
+				}
 			}
 
 			content, err := yaml.Marshal(spec)
@@ -229,24 +251,27 @@ func (b *BootstrapScript) ResourceNodeUp(ig *kops.InstanceGroup, cluster *kops.C
 
 		"IGSpec": func() (string, error) {
 			spec := make(map[string]interface{})
-			spec["kubelet"] = ig.Spec.Kubelet
 			spec["nodeLabels"] = ig.Spec.NodeLabels
 			spec["taints"] = ig.Spec.Taints
 
-			hooks, err := b.getRelevantHooks(ig.Spec.Hooks, ig.Spec.Role)
-			if err != nil {
-				return "", err
-			}
-			if len(hooks) > 0 {
-				spec["hooks"] = hooks
-			}
+			if !apimodel.InPlaceUpdates(cluster) {
+				spec["kubelet"] = ig.Spec.Kubelet
 
-			fileAssets, err := b.getRelevantFileAssets(ig.Spec.FileAssets, ig.Spec.Role)
-			if err != nil {
-				return "", err
-			}
-			if len(fileAssets) > 0 {
-				spec["fileAssets"] = fileAssets
+				hooks, err := b.getRelevantHooks(ig.Spec.Hooks, ig.Spec.Role)
+				if err != nil {
+					return "", err
+				}
+				if len(hooks) > 0 {
+					spec["hooks"] = hooks
+				}
+
+				fileAssets, err := b.getRelevantFileAssets(ig.Spec.FileAssets, ig.Spec.Role)
+				if err != nil {
+					return "", err
+				}
+				if len(fileAssets) > 0 {
+					spec["fileAssets"] = fileAssets
+				}
 			}
 
 			content, err := yaml.Marshal(spec)
@@ -273,19 +298,7 @@ func (b *BootstrapScript) ResourceNodeUp(ig *kops.InstanceGroup, cluster *kops.C
 // getRelevantHooks returns a list of hooks to be applied to the instance group,
 // with the Manifest and ExecContainer Commands fingerprinted to reduce size
 func (b *BootstrapScript) getRelevantHooks(allHooks []kops.HookSpec, role kops.InstanceGroupRole) ([]kops.HookSpec, error) {
-	relevantHooks := []kops.HookSpec{}
-	for _, hook := range allHooks {
-		if len(hook.Roles) == 0 {
-			relevantHooks = append(relevantHooks, hook)
-			continue
-		}
-		for _, hookRole := range hook.Roles {
-			if role == hookRole {
-				relevantHooks = append(relevantHooks, hook)
-				break
-			}
-		}
-	}
+	relevantHooks := apimodel.RelevantHooks(allHooks, role)
 
 	hooks := []kops.HookSpec{}
 	if len(relevantHooks) > 0 {
@@ -323,19 +336,7 @@ func (b *BootstrapScript) getRelevantHooks(allHooks []kops.HookSpec, role kops.I
 // getRelevantFileAssets returns a list of file assets to be applied to the
 // instance group, with the Content fingerprinted to reduce size
 func (b *BootstrapScript) getRelevantFileAssets(allFileAssets []kops.FileAssetSpec, role kops.InstanceGroupRole) ([]kops.FileAssetSpec, error) {
-	relevantFileAssets := []kops.FileAssetSpec{}
-	for _, fileAsset := range allFileAssets {
-		if len(fileAsset.Roles) == 0 {
-			relevantFileAssets = append(relevantFileAssets, fileAsset)
-			continue
-		}
-		for _, fileAssetRole := range fileAsset.Roles {
-			if role == fileAssetRole {
-				relevantFileAssets = append(relevantFileAssets, fileAsset)
-				break
-			}
-		}
-	}
+	relevantFileAssets := apimodel.RelevantFileAssets(allFileAssets, role)
 
 	fileAssets := []kops.FileAssetSpec{}
 	if len(relevantFileAssets) > 0 {
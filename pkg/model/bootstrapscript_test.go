@@ -25,6 +25,8 @@ import (
 	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/apis/nodeup"
 	"k8s.io/kops/pkg/diff"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/util/pkg/architectures"
 )
 
 func Test_ProxyFunc(t *testing.T) {
@@ -120,8 +122,12 @@ func TestBootstrapUserData(t *testing.T) {
 		}
 
 		bs := &BootstrapScript{
-			NodeUpSource:        "NUSource",
-			NodeUpSourceHash:    "NUSHash",
+			NodeUpSource: map[architectures.Architecture]string{
+				architectures.ArchitectureAmd64: "NUSource",
+			},
+			NodeUpSourceHash: map[architectures.Architecture]string{
+				architectures.ArchitectureAmd64: "NUSHash",
+			},
 			NodeUpConfigBuilder: renderNodeUpConfig,
 		}
 
@@ -305,3 +311,54 @@ func makeTestInstanceGroup(role kops.InstanceGroupRole, hookSpecRoles []kops.Ins
 		},
 	}
 }
+
+func TestBootstrapUserData_InPlaceUpdates(t *testing.T) {
+	render := func(cluster *kops.Cluster, group *kops.InstanceGroup) string {
+		bs := &BootstrapScript{
+			NodeUpSource: map[architectures.Architecture]string{
+				architectures.ArchitectureAmd64: "NUSource",
+			},
+			NodeUpSourceHash: map[architectures.Architecture]string{
+				architectures.ArchitectureAmd64: "NUSHash",
+			},
+			NodeUpConfigBuilder: func(ig *kops.InstanceGroup) (*nodeup.Config, error) {
+				return &nodeup.Config{}, nil
+			},
+		}
+		res, err := bs.ResourceNodeUp(group, cluster)
+		if err != nil {
+			t.Fatalf("failed to create nodeup resource: %v", err)
+		}
+		actual, err := res.AsString()
+		if err != nil {
+			t.Fatalf("failed to render nodeup resource: %v", err)
+		}
+		return actual
+	}
+
+	for _, inPlace := range []bool{false, true} {
+		for _, role := range []kops.InstanceGroupRole{kops.InstanceGroupRoleMaster, kops.InstanceGroupRoleNode} {
+			roles := []kops.InstanceGroupRole{role}
+
+			cluster := makeTestCluster(roles, roles)
+			group := makeTestInstanceGroup(role, roles, roles)
+			if inPlace {
+				cluster.Spec.NodeReconciliation = &kops.NodeReconciliationSpec{InPlaceUpdates: fi.Bool(true)}
+			}
+			before := render(cluster, group)
+
+			cluster.Spec.Kubelet.LogLevel = fi.Int32(4)
+			cluster.Spec.MasterKubelet.LogLevel = fi.Int32(4)
+			cluster.Spec.Hooks[0].ExecContainer.Image = "busybox:changed"
+			cluster.Spec.FileAssets[0].Content = "changed"
+			group.Spec.Kubelet.LogLevel = fi.Int32(4)
+			group.Spec.Hooks[0].Manifest = "Type=oneshot\nExecStart=/bin/true"
+			group.Spec.FileAssets[0].Content = "changed"
+			after := render(cluster, group)
+
+			if changed := before != after; changed == inPlace {
+				t.Errorf("role %s with in-place updates %v: expected user data changed=%v, got %v", role, inPlace, !inPlace, changed)
+			}
+		}
+	}
+}
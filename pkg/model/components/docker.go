@@ -36,6 +36,11 @@ var _ loader.OptionsBuilder = &DockerOptionsBuilder{}
 func (b *DockerOptionsBuilder) BuildOptions(o interface{}) error {
 	clusterSpec := o.(*kops.ClusterSpec)
 
+	if clusterSpec.ContainerRuntime == kops.ContainerRuntimeContainerd {
+		// Docker options cannot be set when using containerd; instance groups using docker use the defaults of nodeup
+		return nil
+	}
+
 	sv, err := KubernetesVersion(clusterSpec)
 	if err != nil {
 		return fmt.Errorf("unable to determine kubernetes version from %q", clusterSpec.KubernetesVersion)
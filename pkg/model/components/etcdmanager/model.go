@@ -26,6 +26,7 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/yaml"
 	scheme "k8s.io/client-go/kubernetes/scheme"
@@ -256,6 +257,9 @@ func (b *EtcdManagerBuilder) buildPod(etcdCluster *kops.EtcdClusterSpec) (*v1.Po
 		if etcdCluster.Manager != nil && etcdCluster.Manager.Image != "" {
 			klog.Warningf("overloading image in manifest %s with images %s", bundle, etcdCluster.Manager.Image)
 			container.Image = etcdCluster.Manager.Image
+		} else if etcdCluster.Backups != nil && etcdCluster.Backups.Interval != nil {
+			// The embedded etcd-manager exits on an unknown --backup-interval flag, which would take down etcd
+			return nil, fmt.Errorf("backups.interval is not supported by %s; set manager.image to an etcd-manager image that supports --backup-interval", container.Image)
 		}
 	}
 
@@ -275,8 +279,10 @@ func (b *EtcdManagerBuilder) buildPod(etcdCluster *kops.EtcdClusterSpec) (*v1.Po
 	clusterName := "etcd-" + etcdCluster.Name
 	peerPort := 2380
 	backupStore := ""
+	var backupInterval *metav1.Duration
 	if etcdCluster.Backups != nil {
 		backupStore = etcdCluster.Backups.BackupStore
+		backupInterval = etcdCluster.Backups.Interval
 	}
 
 	pod.Name = "etcd-manager-" + etcdCluster.Name
@@ -329,12 +335,13 @@ func (b *EtcdManagerBuilder) buildPod(etcdCluster *kops.EtcdClusterSpec) (*v1.Po
 	logFile := "/var/log/" + name + ".log"
 
 	config := &config{
-		Containerized: true,
-		ClusterName:   clusterName,
-		BackupStore:   backupStore,
-		GrpcPort:      grpcPort,
-		DNSSuffix:     dnsInternalSuffix,
-		EtcdInsecure:  etcdInsecure,
+		Containerized:  true,
+		ClusterName:    clusterName,
+		BackupStore:    backupStore,
+		BackupInterval: backupInterval,
+		GrpcPort:       grpcPort,
+		DNSSuffix:      dnsInternalSuffix,
+		EtcdInsecure:   etcdInsecure,
 	}
 
 	config.LogVerbosity = 6
@@ -482,4 +489,8 @@ type config struct {
 	VolumeTag            []string `flag:"volume-tag,repeat"`
 	VolumeNameTag        string   `flag:"volume-name-tag"`
 	DNSSuffix            string   `flag:"dns-suffix"`
+
+	// BackupInterval is how often etcd-manager takes a backup; etcd-manager's default is used if not set.
+	// The embedded etcd-manager image does not support the flag, so it is only set with a custom image.
+	BackupInterval *metav1.Duration `flag:"backup-interval"`
 }
@@ -18,8 +18,12 @@ package etcdmanager
 
 import (
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/assets"
 	"k8s.io/kops/pkg/model"
 	"k8s.io/kops/pkg/testutils"
@@ -72,3 +76,68 @@ func LoadKopsModelContext(basedir string) (*model.KopsModelContext, error) {
 
 	return kopsContext, nil
 }
+
+// Test_BuildPod_BackupInterval renders the manifest against the embedded etcd-manager image,
+// which does not support --backup-interval
+func Test_BuildPod_BackupInterval(t *testing.T) {
+	grid := []struct {
+		Description string
+		Interval    *metav1.Duration
+		Image       string
+		ExpectFlag  string
+		ExpectError bool
+	}{
+		{
+			Description: "embedded image without interval",
+		},
+		{
+			Description: "embedded image with interval",
+			Interval:    &metav1.Duration{Duration: 30 * time.Minute},
+			ExpectError: true,
+		},
+		{
+			Description: "custom image with interval",
+			Interval:    &metav1.Duration{Duration: 30 * time.Minute},
+			Image:       "example.com/etcd-manager:custom",
+			ExpectFlag:  "--backup-interval=30m0s",
+		},
+	}
+
+	for _, g := range grid {
+		kopsModelContext, err := LoadKopsModelContext("tests/minimal")
+		if err != nil {
+			t.Fatalf("error loading model: %v", err)
+		}
+
+		builder := EtcdManagerBuilder{
+			KopsModelContext: kopsModelContext,
+			AssetBuilder:     assets.NewAssetBuilder(kopsModelContext.Cluster, ""),
+		}
+
+		etcdCluster := kopsModelContext.Cluster.Spec.EtcdClusters[0]
+		etcdCluster.Backups.Interval = g.Interval
+		if g.Image != "" {
+			etcdCluster.Manager = &kops.EtcdManagerSpec{Image: g.Image}
+		}
+
+		pod, err := builder.buildPod(etcdCluster)
+		if g.ExpectError {
+			if err == nil {
+				t.Errorf("%s: expected error", g.Description)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", g.Description, err)
+			continue
+		}
+
+		command := strings.Join(pod.Spec.Containers[0].Command, " ")
+		if g.ExpectFlag != "" && !strings.Contains(command, g.ExpectFlag) {
+			t.Errorf("%s: expected %q in command %q", g.Description, g.ExpectFlag, command)
+		}
+		if g.ExpectFlag == "" && strings.Contains(command, "--backup-interval") {
+			t.Errorf("%s: unexpected --backup-interval in command %q", g.Description, command)
+		}
+	}
+}
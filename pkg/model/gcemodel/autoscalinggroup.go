@@ -109,6 +109,11 @@ func (b *AutoscalingGroupModelBuilder) Build(c *fi.ModelBuilderContext) error {
 				t.Scopes = append(t.Scopes, "storage-rw")
 			}
 
+			// Every instance reads the state store, so must be able to unwrap its data keys
+			if b.Cluster.Spec.StateEncryption != nil && b.Cluster.Spec.StateEncryption.GCPKMSKeyName != "" {
+				t.Scopes = append(t.Scopes, "https://www.googleapis.com/auth/cloudkms")
+			}
+
 			if len(b.SSHPublicKeys) > 0 {
 				var gFmtKeys []string
 				for _, key := range b.SSHPublicKeys {
@@ -0,0 +1,103 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gcemodel
+
+import (
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/model"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/gcetasks"
+)
+
+func TestAutoscalingGroupModelBuilder_StateEncryptionScope(t *testing.T) {
+	const cloudKMSScope = "https://www.googleapis.com/auth/cloudkms"
+
+	grid := []struct {
+		stateEncryption *kops.StateEncryptionSpec
+		expectScope     bool
+	}{
+		{
+			stateEncryption: nil,
+			expectScope:     false,
+		},
+		{
+			stateEncryption: &kops.StateEncryptionSpec{KeyFile: "/etc/kops/state.key"},
+			expectScope:     false,
+		},
+		{
+			stateEncryption: &kops.StateEncryptionSpec{GCPKMSKeyName: "projects/p/locations/global/keyRings/r/cryptoKeys/k"},
+			expectScope:     true,
+		},
+	}
+
+	for _, g := range grid {
+		cluster := &kops.Cluster{}
+		cluster.ObjectMeta.Name = "cluster.example.com"
+		cluster.Spec.CloudProvider = string(kops.CloudProviderGCE)
+		cluster.Spec.Subnets = []kops.ClusterSubnetSpec{{Name: "us-test1", Region: "us-test1"}}
+		cluster.Spec.StateEncryption = g.stateEncryption
+
+		var instanceGroups []*kops.InstanceGroup
+		for _, role := range []kops.InstanceGroupRole{kops.InstanceGroupRoleMaster, kops.InstanceGroupRoleNode} {
+			ig := &kops.InstanceGroup{}
+			ig.ObjectMeta.Name = string(role)
+			ig.Spec.Role = role
+			ig.Spec.Subnets = []string{"us-test1"}
+			ig.Spec.Zones = []string{"us-test1-a"}
+			instanceGroups = append(instanceGroups, ig)
+		}
+
+		b := &AutoscalingGroupModelBuilder{
+			GCEModelContext: &GCEModelContext{
+				KopsModelContext: &model.KopsModelContext{
+					Cluster:        cluster,
+					InstanceGroups: instanceGroups,
+				},
+			},
+			BootstrapScript: &model.BootstrapScript{},
+		}
+
+		c := &fi.ModelBuilderContext{Tasks: make(map[string]fi.Task)}
+		if err := b.Build(c); err != nil {
+			t.Fatalf("error building model: %v", err)
+		}
+
+		templates := 0
+		for _, task := range c.Tasks {
+			template, ok := task.(*gcetasks.InstanceTemplate)
+			if !ok {
+				continue
+			}
+			templates++
+
+			hasScope := false
+			for _, scope := range template.Scopes {
+				if scope == cloudKMSScope {
+					hasScope = true
+				}
+			}
+			if hasScope != g.expectScope {
+				t.Errorf("state encryption %v: instance template %q has cloudkms scope %v, expected %v", g.stateEncryption, fi.StringValue(template.Name), hasScope, g.expectScope)
+			}
+		}
+		if templates != len(instanceGroups) {
+			t.Errorf("expected %d instance templates, got %d", len(instanceGroups), templates)
+		}
+	}
+}
@@ -168,6 +168,10 @@ func (b *PolicyBuilder) BuildAWSPolicyMaster() (*Policy, error) {
 		addKMSIAMPolicies(p, stringorslice.Slice(b.KMSKeys), b.Cluster.Spec.IAM.Legacy)
 	}
 
+	if b.Cluster.Spec.StateEncryption != nil && b.Cluster.Spec.StateEncryption.AWSKMSKeyARN != "" {
+		addStateEncryptionKMSPolicies(p, b.Cluster.Spec.StateEncryption.AWSKMSKeyARN)
+	}
+
 	if b.HostedZoneID != "" {
 		addRoute53Permissions(p, b.HostedZoneID)
 	}
@@ -210,6 +214,10 @@ func (b *PolicyBuilder) BuildAWSPolicyNode() (*Policy, error) {
 		return nil, fmt.Errorf("failed to generate AWS IAM S3 access statements: %v", err)
 	}
 
+	if b.Cluster.Spec.StateEncryption != nil && b.Cluster.Spec.StateEncryption.AWSKMSKeyARN != "" {
+		addStateEncryptionKMSPolicies(p, b.Cluster.Spec.StateEncryption.AWSKMSKeyARN)
+	}
+
 	if b.Cluster.Spec.IAM.Legacy {
 		if b.HostedZoneID != "" {
 			addRoute53Permissions(p, b.HostedZoneID)
@@ -351,6 +359,7 @@ func (b *PolicyBuilder) AddS3Permissions(p *Policy) (*Policy, error) {
 						strings.Join([]string{b.IAMPrefix(), ":s3:::", iamS3Path, "/cluster.spec"}, ""),
 						strings.Join([]string{b.IAMPrefix(), ":s3:::", iamS3Path, "/config"}, ""),
 						strings.Join([]string{b.IAMPrefix(), ":s3:::", iamS3Path, "/instancegroup/*"}, ""),
+						strings.Join([]string{b.IAMPrefix(), ":s3:::", iamS3Path, "/instancegroup.spec/*"}, ""),
 						strings.Join([]string{b.IAMPrefix(), ":s3:::", iamS3Path, "/pki/issued/*"}, ""),
 						strings.Join([]string{b.IAMPrefix(), ":s3:::", iamS3Path, "/pki/private/kube-proxy/*"}, ""),
 						strings.Join([]string{b.IAMPrefix(), ":s3:::", iamS3Path, "/pki/ssh/*"}, ""),
@@ -591,6 +600,15 @@ func addKMSIAMPolicies(p *Policy, resource stringorslice.StringOrSlice, legacyIA
 	})
 }
 
+// addStateEncryptionKMSPolicies allows nodeup to decrypt the secrets and keys in the state store
+func addStateEncryptionKMSPolicies(p *Policy, keyARN string) {
+	p.Statement = append(p.Statement, &Statement{
+		Effect:   StatementEffectAllow,
+		Action:   stringorslice.Of("kms:Decrypt"),
+		Resource: stringorslice.Of(keyARN),
+	})
+}
+
 func addNodeEC2Policies(p *Policy, resource stringorslice.StringOrSlice) {
 	// Protokube makes a DescribeInstances call, DescribeRegions when finding S3 State Bucket
 	p.Statement = append(p.Statement, &Statement{
@@ -43,10 +43,17 @@ func (b *PKIModelBuilder) Build(c *fi.ModelBuilderContext) error {
 	// to the newer keyset.yaml representation.
 	format := string(fi.KeysetFormatV1Alpha2)
 
+	// An external CA must be imported with kops create secret keypair ca; we never create it
+	caLifecycle := b.Lifecycle
+	if b.Cluster.Spec.ExternalCA != nil {
+		existsAndWarnIfChanges := fi.LifecycleExistsAndWarnIfChanges
+		caLifecycle = &existsAndWarnIfChanges
+	}
+
 	// TODO: Only create the CA via this task
 	defaultCA := &fitasks.Keypair{
 		Name:      fi.String(fi.CertificateId_CA),
-		Lifecycle: b.Lifecycle,
+		Lifecycle: caLifecycle,
 		Subject:   "cn=kubernetes",
 		Type:      "ca",
 		Format:    format,
@@ -0,0 +1,213 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/pki"
+)
+
+// Request is what is sent to an external signer to issue a certificate
+type Request struct {
+	// CSR is the PEM encoded certificate signing request, which holds the subject, alternate names and public key
+	CSR string `json:"csr"`
+	// KeyUsage lists the key usages of the certificate, e.g. DigitalSignature or KeyEncipherment
+	KeyUsage []string `json:"keyUsage,omitempty"`
+	// ExtKeyUsage lists the extended key usages of the certificate, e.g. ServerAuth or ClientAuth
+	ExtKeyUsage []string `json:"extKeyUsage,omitempty"`
+}
+
+// Signer issues certificates from a CA whose private key is not held by kops
+type Signer interface {
+	// Sign returns the PEM encoded certificate issued for the request, which may be followed by intermediate certificates
+	Sign(request *Request) ([]byte, error)
+}
+
+// New builds the Signer configured by spec, for the CA with the given certificate
+func New(spec *kops.ExternalCASpec, caCertificate *pki.Certificate) (Signer, error) {
+	switch {
+	case len(spec.Command) != 0:
+		return &ExecSigner{Command: spec.Command}, nil
+	case spec.URL != "":
+		return &HTTPSigner{URL: spec.URL}, nil
+	case spec.KeyFile != "":
+		return &FileSigner{CACertificate: caCertificate, KeyFile: spec.KeyFile}, nil
+	default:
+		return nil, fmt.Errorf("externalCA must set one of command, url or keyFile")
+	}
+}
+
+// Issue issues a certificate for privateKey, as described by template, through the signer.
+// The certificate returned by the signer must be for privateKey, and issued by the CA with caCertificate.
+func Issue(signer Signer, caCertificate *pki.Certificate, template *x509.Certificate, privateKey *pki.PrivateKey) (*pki.Certificate, error) {
+	request, err := BuildRequest(template, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := signer.Sign(request)
+	if err != nil {
+		return nil, fmt.Errorf("error signing certificate for %q: %v", template.Subject.CommonName, err)
+	}
+
+	cert, err := pki.ParsePEMCertificate(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing certificate returned by signer: %v", err)
+	}
+
+	rsaPrivateKey, ok := privateKey.Key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unexpected private key type %T", privateKey.Key)
+	}
+	rsaPublicKey, ok := cert.Certificate.PublicKey.(*rsa.PublicKey)
+	if !ok || rsaPublicKey.N.Cmp(rsaPrivateKey.N) != 0 || rsaPublicKey.E != rsaPrivateKey.E {
+		return nil, fmt.Errorf("certificate returned by signer is not for the requested key")
+	}
+	if err := cert.Certificate.CheckSignatureFrom(caCertificate.Certificate); err != nil {
+		return nil, fmt.Errorf("certificate returned by signer was not issued by CA %q: %v", caCertificate.Subject.String(), err)
+	}
+	cert.PublicKey = cert.Certificate.PublicKey
+
+	return cert, nil
+}
+
+// BuildRequest builds the signing request for a certificate for privateKey, as described by template
+func BuildRequest(template *x509.Certificate, privateKey *pki.PrivateKey) (*Request, error) {
+	csrTemplate := &x509.CertificateRequest{
+		Subject:        template.Subject,
+		DNSNames:       template.DNSNames,
+		EmailAddresses: template.EmailAddresses,
+		IPAddresses:    template.IPAddresses,
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, csrTemplate, privateKey.Key)
+	if err != nil {
+		return nil, fmt.Errorf("error creating certificate signing request: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := pem.Encode(&b, &pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}); err != nil {
+		return nil, fmt.Errorf("error encoding certificate signing request: %v", err)
+	}
+
+	request := &Request{
+		CSR: b.String(),
+	}
+	for _, u := range keyUsages {
+		if template.KeyUsage&u.usage != 0 {
+			request.KeyUsage = append(request.KeyUsage, u.name)
+		}
+	}
+	for _, usage := range template.ExtKeyUsage {
+		name := ""
+		for _, u := range extKeyUsages {
+			if u.usage == usage {
+				name = u.name
+			}
+		}
+		if name == "" {
+			return nil, fmt.Errorf("unsupported extended key usage %v", usage)
+		}
+		request.ExtKeyUsage = append(request.ExtKeyUsage, name)
+	}
+
+	return request, nil
+}
+
+// ParseRequest parses a signing request, returning the CSR and the template for the certificate
+func ParseRequest(request *Request) (*x509.CertificateRequest, *x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(request.CSR))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, nil, fmt.Errorf("csr is not a PEM encoded certificate request")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing certificate request: %v", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, nil, fmt.Errorf("invalid signature on certificate request: %v", err)
+	}
+
+	template := &x509.Certificate{
+		Subject:               csr.Subject,
+		DNSNames:              csr.DNSNames,
+		EmailAddresses:        csr.EmailAddresses,
+		IPAddresses:           csr.IPAddresses,
+		PublicKey:             csr.PublicKey,
+		BasicConstraintsValid: true,
+	}
+	for _, name := range request.KeyUsage {
+		found := false
+		for _, u := range keyUsages {
+			if u.name == name {
+				template.KeyUsage |= u.usage
+				found = true
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("unknown key usage %q", name)
+		}
+	}
+	for _, name := range request.ExtKeyUsage {
+		found := false
+		for _, u := range extKeyUsages {
+			if u.name == name {
+				template.ExtKeyUsage = append(template.ExtKeyUsage, u.usage)
+				found = true
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("unknown extended key usage %q", name)
+		}
+	}
+
+	return csr, template, nil
+}
+
+var keyUsages = []struct {
+	name  string
+	usage x509.KeyUsage
+}{
+	{"DigitalSignature", x509.KeyUsageDigitalSignature},
+	{"ContentCommitment", x509.KeyUsageContentCommitment},
+	{"KeyEncipherment", x509.KeyUsageKeyEncipherment},
+	{"DataEncipherment", x509.KeyUsageDataEncipherment},
+	{"KeyAgreement", x509.KeyUsageKeyAgreement},
+	{"CertSign", x509.KeyUsageCertSign},
+	{"CRLSign", x509.KeyUsageCRLSign},
+	{"EncipherOnly", x509.KeyUsageEncipherOnly},
+	{"DecipherOnly", x509.KeyUsageDecipherOnly},
+}
+
+var extKeyUsages = []struct {
+	name  string
+	usage x509.ExtKeyUsage
+}{
+	{"Any", x509.ExtKeyUsageAny},
+	{"ServerAuth", x509.ExtKeyUsageServerAuth},
+	{"ClientAuth", x509.ExtKeyUsageClientAuth},
+	{"CodeSigning", x509.ExtKeyUsageCodeSigning},
+	{"EmailProtection", x509.ExtKeyUsageEmailProtection},
+	{"TimeStamping", x509.ExtKeyUsageTimeStamping},
+	{"OCSPSigning", x509.ExtKeyUsageOCSPSigning},
+}
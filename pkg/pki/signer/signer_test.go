@@ -0,0 +1,213 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/kops/pkg/pki"
+)
+
+// buildCA creates a CA, writing its private key to a file in dir
+func buildCA(t *testing.T, dir string) (*pki.Certificate, string) {
+	caPrivateKey, err := pki.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating private key: %v", err)
+	}
+	caCertificate, err := pki.SignNewCertificate(caPrivateKey, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "intermediate"},
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("error creating CA: %v", err)
+	}
+
+	keyFile := filepath.Join(dir, "ca.key")
+	keyData, err := caPrivateKey.AsBytes()
+	if err != nil {
+		t.Fatalf("error encoding private key: %v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyData, 0600); err != nil {
+		t.Fatalf("error writing private key: %v", err)
+	}
+	return caCertificate, keyFile
+}
+
+func buildTemplate() *x509.Certificate {
+	return &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "kubernetes-master", Organization: []string{"system:masters"}},
+		DNSNames:    []string{"api.example.com", "kubernetes.default"},
+		IPAddresses: []net.IP{net.ParseIP("100.64.0.1").To4()},
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+}
+
+func checkIssued(t *testing.T, cert *pki.Certificate, template *x509.Certificate) {
+	if cert.Certificate.Subject.String() != template.Subject.String() {
+		t.Errorf("unexpected subject %q", cert.Certificate.Subject.String())
+	}
+	if !reflect.DeepEqual(cert.Certificate.DNSNames, template.DNSNames) {
+		t.Errorf("unexpected DNS names %v", cert.Certificate.DNSNames)
+	}
+	if len(cert.Certificate.IPAddresses) != 1 || !cert.Certificate.IPAddresses[0].Equal(template.IPAddresses[0]) {
+		t.Errorf("unexpected IP addresses %v", cert.Certificate.IPAddresses)
+	}
+	if cert.Certificate.KeyUsage != template.KeyUsage {
+		t.Errorf("unexpected key usage %v", cert.Certificate.KeyUsage)
+	}
+	if !reflect.DeepEqual(cert.Certificate.ExtKeyUsage, template.ExtKeyUsage) {
+		t.Errorf("unexpected extended key usage %v", cert.Certificate.ExtKeyUsage)
+	}
+	if cert.Certificate.IsCA {
+		t.Errorf("issued certificate should not be a CA")
+	}
+}
+
+func TestFileSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signer")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caCertificate, keyFile := buildCA(t, dir)
+	privateKey, err := pki.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating private key: %v", err)
+	}
+
+	template := buildTemplate()
+	cert, err := Issue(&FileSigner{CACertificate: caCertificate, KeyFile: keyFile}, caCertificate, template, privateKey)
+	if err != nil {
+		t.Fatalf("unexpected error issuing certificate: %v", err)
+	}
+	checkIssued(t, cert, template)
+
+	// A certificate from another CA is rejected
+	otherCA, _ := buildCA(t, dir)
+	if _, err := Issue(&FileSigner{CACertificate: caCertificate, KeyFile: keyFile}, otherCA, template, privateKey); err == nil {
+		t.Errorf("expected an error for a certificate not issued by the CA")
+	}
+}
+
+func TestHTTPSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signer")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caCertificate, keyFile := buildCA(t, dir)
+	fileSigner := &FileSigner{CACertificate: caCertificate, KeyFile: keyFile}
+
+	// otherKey, if set, is the key the server issues certificates for, instead of the requested key
+	var otherKey *pki.PrivateKey
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := &Request{}
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if otherKey != nil {
+			other, err := BuildRequest(buildTemplate(), otherKey)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			request.CSR = other.CSR
+		}
+		data, err := fileSigner.Sign(request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	privateKey, err := pki.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating private key: %v", err)
+	}
+
+	template := buildTemplate()
+	cert, err := Issue(&HTTPSigner{URL: server.URL}, caCertificate, template, privateKey)
+	if err != nil {
+		t.Fatalf("unexpected error issuing certificate: %v", err)
+	}
+	checkIssued(t, cert, template)
+
+	otherKey, err = pki.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating private key: %v", err)
+	}
+	if _, err := Issue(&HTTPSigner{URL: server.URL}, caCertificate, template, privateKey); err == nil {
+		t.Errorf("expected an error for a certificate issued for another key")
+	}
+
+	if _, err := Issue(&HTTPSigner{URL: server.URL + "/missing"}, caCertificate, template, privateKey); err == nil {
+		t.Errorf("expected an error when the signer fails")
+	}
+}
+
+func TestRequestRoundTrip(t *testing.T) {
+	privateKey, err := pki.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating private key: %v", err)
+	}
+
+	template := buildTemplate()
+	request, err := BuildRequest(template, privateKey)
+	if err != nil {
+		t.Fatalf("unexpected error building request: %v", err)
+	}
+	if !reflect.DeepEqual(request.KeyUsage, []string{"DigitalSignature", "KeyEncipherment"}) {
+		t.Errorf("unexpected key usage in request: %v", request.KeyUsage)
+	}
+	if !reflect.DeepEqual(request.ExtKeyUsage, []string{"ServerAuth", "ClientAuth"}) {
+		t.Errorf("unexpected extended key usage in request: %v", request.ExtKeyUsage)
+	}
+
+	_, parsed, err := ParseRequest(request)
+	if err != nil {
+		t.Fatalf("unexpected error parsing request: %v", err)
+	}
+	if parsed.KeyUsage != template.KeyUsage || !reflect.DeepEqual(parsed.ExtKeyUsage, template.ExtKeyUsage) {
+		t.Errorf("usages did not round-trip: %v %v", parsed.KeyUsage, parsed.ExtKeyUsage)
+	}
+	if !reflect.DeepEqual(parsed.DNSNames, template.DNSNames) || parsed.Subject.String() != template.Subject.String() {
+		t.Errorf("names did not round-trip: %v %v", parsed.Subject, parsed.DNSNames)
+	}
+
+	request.KeyUsage = []string{"Unknown"}
+	if _, _, err := ParseRequest(request); err == nil {
+		t.Errorf("expected an error for an unknown key usage")
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"k8s.io/kops/pkg/pki"
+)
+
+// ExecSigner issues certificates by running a command, which reads the request as JSON from its standard input
+// and writes the PEM encoded certificate to its standard output
+type ExecSigner struct {
+	Command []string
+}
+
+var _ Signer = &ExecSigner{}
+
+// Sign implements Signer::Sign
+func (s *ExecSigner) Sign(request *Request) ([]byte, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling signing request: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(s.Command[0], s.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error running %s: %v: %s", strings.Join(s.Command, " "), err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// HTTPSigner issues certificates by POSTing the request as JSON to a signing service,
+// which responds with the PEM encoded certificate
+type HTTPSigner struct {
+	URL string
+
+	// Client is the HTTP client used to reach the signing service; a client with a timeout of 30s is used if not set
+	Client *http.Client
+}
+
+var _ Signer = &HTTPSigner{}
+
+// Sign implements Signer::Sign
+func (s *HTTPSigner) Sign(request *Request) ([]byte, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling signing request: %v", err)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	response, err := client.Post(s.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("error sending signing request to %s: %v", s.URL, err)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response from %s: %v", s.URL, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("signing request to %s failed with status %s: %s", s.URL, response.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// FileSigner issues certificates itself, with the private key of the CA read from a local file.
+// It stands in for an external signer when testing.
+type FileSigner struct {
+	CACertificate *pki.Certificate
+	KeyFile       string
+}
+
+var _ Signer = &FileSigner{}
+
+// Sign implements Signer::Sign
+func (s *FileSigner) Sign(request *Request) ([]byte, error) {
+	data, err := ioutil.ReadFile(s.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA private key %q: %v", s.KeyFile, err)
+	}
+	caPrivateKey, err := pki.ParsePEMPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CA private key %q: %v", s.KeyFile, err)
+	}
+	if caPrivateKey == nil {
+		return nil, fmt.Errorf("no private key found in %q", s.KeyFile)
+	}
+
+	_, template, err := ParseRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	template.SerialNumber = pki.BuildPKISerial(time.Now().UnixNano())
+
+	cert, err := pki.SignNewCertificate(nil, template, s.CACertificate.Certificate, caPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return cert.AsBytes()
+}
@@ -193,6 +193,11 @@ func DeleteInstance(cloud Cloud, instance *cloudinstances.CloudInstanceGroupMemb
 	return fmt.Errorf("spotinst: unexpected instance group type, got: %T", group.Raw)
 }
 
+// DetachInstance is not supported by spotinst, as detached instances are always terminated.
+func DetachInstance(cloud Cloud, instance *cloudinstances.CloudInstanceGroupMember) error {
+	return fmt.Errorf("spotinst: detaching instance %q is not supported", instance.ID)
+}
+
 // GetCloudGroups returns a list of InstanceGroups as CloudInstanceGroup objects.
 func GetCloudGroups(cloud Cloud, cluster *kops.Cluster, instanceGroups []*kops.InstanceGroup,
 	warnUnmatched bool, nodes []v1.Node) (map[string]*cloudinstances.CloudInstanceGroup, error) {
@@ -0,0 +1,461 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statecheck
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/acls"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/pkg/apis/kops/v1alpha1"
+	"k8s.io/kops/pkg/apis/kops/validation"
+	"k8s.io/kops/pkg/client/simple"
+	"k8s.io/kops/pkg/client/simple/vfsclientset"
+	"k8s.io/kops/pkg/kopscodecs"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// Problem is an inconsistency found in the state store of a cluster
+type Problem struct {
+	// Path is the path of the entry with the problem, relative to the cluster's ConfigBase
+	Path string
+	// Message describes the problem
+	Message string
+	// Repair describes how the problem is repaired; it is empty if the problem must be fixed by hand
+	Repair string
+
+	repair func() error
+}
+
+// CanRepair returns true if the problem can be repaired automatically
+func (p *Problem) CanRepair() bool {
+	return p.repair != nil
+}
+
+// Checker checks the state store of a cluster for objects that cannot be read, are invalid, or refer to objects
+// that do not exist
+type Checker struct {
+	// Clientset is the clientset for the state store
+	Clientset simple.Clientset
+	// ClusterName is the name of the cluster to check
+	ClusterName string
+	// ConfigBase is the path of the cluster in the state store
+	ConfigBase vfs.Path
+	// QuarantineDir is the directory, relative to ConfigBase, that broken entries are moved to
+	QuarantineDir string
+
+	cluster  *kops.Cluster
+	problems []*Problem
+}
+
+// Check walks the state store of the cluster and returns the problems found
+func (c *Checker) Check() ([]*Problem, error) {
+	c.problems = nil
+	c.cluster = nil
+
+	if err := c.checkUnknownFiles(); err != nil {
+		return nil, err
+	}
+
+	cluster, err := c.checkCluster()
+	if err != nil {
+		return nil, err
+	}
+	if cluster == nil {
+		// Nothing else can be checked without the cluster
+		return c.problems, nil
+	}
+	c.cluster = cluster
+
+	if err := c.checkInstanceGroups(cluster); err != nil {
+		return nil, err
+	}
+	if err := c.checkKeysets(cluster); err != nil {
+		return nil, err
+	}
+	if err := c.checkSecrets(cluster); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(c.problems, func(i, j int) bool {
+		return c.problems[i].Path < c.problems[j].Path
+	})
+	return c.problems, nil
+}
+
+// Repair repairs the problem
+func (c *Checker) Repair(p *Problem) error {
+	if p.repair == nil {
+		return fmt.Errorf("%s cannot be repaired automatically: %s", p.Path, p.Message)
+	}
+	return p.repair()
+}
+
+func (c *Checker) addProblem(path string, format string, args ...interface{}) *Problem {
+	p := &Problem{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	}
+	c.problems = append(c.problems, p)
+	return p
+}
+
+// addQuarantine records a problem that is repaired by moving the entry to the quarantine directory
+func (c *Checker) addQuarantine(path string, format string, args ...interface{}) {
+	p := c.addProblem(path, format, args...)
+	p.Repair = "move to " + c.QuarantineDir + "/" + path
+	p.repair = func() error {
+		return c.quarantine(path)
+	}
+}
+
+// checkUnknownFiles reports files that kops does not store for a cluster
+func (c *Checker) checkUnknownFiles() error {
+	files, err := c.ConfigBase.ReadTree()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error listing %q: %v", c.ConfigBase, err)
+	}
+	for _, f := range files {
+		relativePath, err := vfs.RelativePath(c.ConfigBase, f)
+		if err != nil {
+			return err
+		}
+		if relativePath == "" || vfsclientset.IsKnownStatePath(relativePath) {
+			continue
+		}
+		c.addProblem(relativePath, "unknown file; it will prevent kops delete cluster")
+	}
+	return nil
+}
+
+// checkCluster checks the cluster spec and the completed cluster spec, returning the cluster if it can be read
+func (c *Checker) checkCluster() (*kops.Cluster, error) {
+	o, err := c.readObject(registry.PathCluster, "Cluster")
+	if err != nil {
+		return nil, err
+	}
+	if o == nil {
+		return nil, nil
+	}
+	cluster := o.(*kops.Cluster)
+
+	if cluster.ObjectMeta.Name != "" && cluster.ObjectMeta.Name != c.ClusterName {
+		c.addProblem(registry.PathCluster, "cluster is named %q, but is stored as %q", cluster.ObjectMeta.Name, c.ClusterName)
+	}
+	if cluster.ObjectMeta.Name == "" {
+		cluster.ObjectMeta.Name = c.ClusterName
+	}
+	if cluster.Spec.ConfigBase == "" {
+		cluster.Spec.ConfigBase = c.ConfigBase.Path()
+	}
+	if err := validation.ValidateCluster(cluster, false); err != nil {
+		c.addProblem(registry.PathCluster, "cluster spec is not valid: %v", err)
+	}
+
+	completed, err := c.ConfigBase.Join(registry.PathClusterCompleted).ReadFile()
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error reading %q: %v", registry.PathClusterCompleted, err)
+		}
+		c.addProblem(registry.PathClusterCompleted, "completed cluster spec is missing; run kops update cluster to write it")
+	} else if _, _, err := kopscodecs.Decode(completed, nil); err != nil {
+		c.addQuarantine(registry.PathClusterCompleted, "completed cluster spec cannot be parsed: %v", err)
+	}
+
+	return cluster, nil
+}
+
+// readObject reads and decodes the object at the path, recording a problem and returning nil if that fails
+func (c *Checker) readObject(path string, kind string) (runtime.Object, error) {
+	data, err := c.ConfigBase.Join(path).ReadFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.addProblem(path, "%s is missing", kind)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading %q: %v", path, err)
+	}
+
+	defaultReadVersion := v1alpha1.SchemeGroupVersion.WithKind(kind)
+	o, gvk, err := kopscodecs.Decode(data, &defaultReadVersion)
+	if err != nil {
+		c.addQuarantine(path, "%s cannot be parsed: %v", kind, err)
+		return nil, nil
+	}
+	if gvk == nil || gvk.Kind != kind {
+		c.addQuarantine(path, "expected a %s, found %v", kind, kindOf(gvk))
+		return nil, nil
+	}
+	return o, nil
+}
+
+// checkInstanceGroups checks that the instance groups can be read and are valid, and that the subnets and etcd
+// members refer to ones that exist
+func (c *Checker) checkInstanceGroups(cluster *kops.Cluster) error {
+	files, err := c.ConfigBase.Join("instancegroup").ReadDir()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error listing instance groups: %v", err)
+	}
+
+	subnets := make(map[string]bool)
+	for _, subnet := range cluster.Spec.Subnets {
+		subnets[subnet.Name] = true
+	}
+
+	instanceGroups := make(map[string]*kops.InstanceGroup)
+	for _, f := range files {
+		name := f.Base()
+		path := "instancegroup/" + name
+		if _, err := f.ReadFile(); err != nil {
+			// A directory, or removed since it was listed
+			continue
+		}
+
+		o, err := c.readObject(path, "InstanceGroup")
+		if err != nil {
+			return err
+		}
+		if o == nil {
+			continue
+		}
+		ig := o.(*kops.InstanceGroup)
+
+		if ig.ObjectMeta.Name != name {
+			c.addQuarantine(path, "instance group is named %q, but is stored as %q", ig.ObjectMeta.Name, name)
+			continue
+		}
+		instanceGroups[name] = ig
+
+		if err := validation.ValidateInstanceGroup(ig); err != nil {
+			c.addProblem(path, "instance group is not valid: %v", err)
+		}
+		for _, subnet := range ig.Spec.Subnets {
+			if !subnets[subnet] {
+				c.addProblem(path, "instance group refers to subnet %q, which is not a subnet of the cluster", subnet)
+			}
+		}
+	}
+
+	if len(instanceGroups) == 0 {
+		c.addProblem("instancegroup", "cluster has no instance groups")
+	}
+
+	for _, etcdCluster := range cluster.Spec.EtcdClusters {
+		for _, member := range etcdCluster.Members {
+			igName := fi.StringValue(member.InstanceGroup)
+			ig := instanceGroups[igName]
+			if ig == nil {
+				c.addProblem(registry.PathCluster, "member %q of etcd cluster %q refers to instance group %q, which does not exist", member.Name, etcdCluster.Name, igName)
+				continue
+			}
+			if ig.Spec.Role != kops.InstanceGroupRoleMaster {
+				c.addProblem(registry.PathCluster, "member %q of etcd cluster %q refers to instance group %q, which is not a master", member.Name, etcdCluster.Name, igName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkKeysets checks that the keysets can be read, and that every private key has a certificate
+func (c *Checker) checkKeysets(cluster *kops.Cluster) error {
+	keyStore, err := c.Clientset.KeyStore(cluster)
+	if err != nil {
+		return err
+	}
+
+	certificateNames, err := c.listDir("pki/issued")
+	if err != nil {
+		return err
+	}
+	privateKeyNames, err := c.listDir("pki/private")
+	if err != nil {
+		return err
+	}
+
+	if !certificateNames[fi.CertificateId_CA] && c.exists(registry.PathClusterCompleted) {
+		c.addProblem("pki/issued/"+fi.CertificateId_CA, "the cluster has been created, but the CA keyset is missing")
+	}
+
+	for name := range certificateNames {
+		certificates, err := keyStore.FindCertificateKeyset(name)
+		if err != nil {
+			c.addQuarantine("pki/issued/"+name, "keyset cannot be read: %v", err)
+			delete(certificateNames, name)
+			continue
+		}
+		if certificates == nil || len(certificates.Spec.Keys) == 0 {
+			c.addProblem("pki/issued/"+name, "keyset has no certificates")
+		}
+	}
+
+	for name := range privateKeyNames {
+		if !certificateNames[name] {
+			c.addQuarantine("pki/private/"+name, "private keys of keyset %q have no certificates", name)
+			continue
+		}
+
+		privateKeys, err := keyStore.FindPrivateKeyset(name)
+		if err != nil {
+			c.addQuarantine("pki/private/"+name, "private keys of keyset %q cannot be read: %v", name, err)
+			continue
+		}
+		certificates, err := keyStore.FindCertificateKeyset(name)
+		if err != nil {
+			return err
+		}
+
+		ids := make(map[string]bool)
+		if certificates != nil {
+			for _, key := range certificates.Spec.Keys {
+				ids[key.Id] = true
+			}
+		}
+		for _, key := range privateKeys.Spec.Keys {
+			if ids[key.Id] {
+				continue
+			}
+			keyset := &kops.Keyset{}
+			keyset.Name = name
+			keyset.Spec.Type = kops.SecretTypeKeypair
+			id := key.Id
+
+			p := c.addProblem("pki/private/"+name+"/"+id+".key", "private key %s of keyset %q has no certificate", id, name)
+			p.Repair = "remove the private key from the keyset"
+			if _, ok := keyStore.(fi.HasHistory); ok {
+				p.Repair += "; it is kept in the history of the keyset"
+			}
+			p.repair = func() error {
+				return keyStore.DeleteKeysetItem(keyset, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkSecrets checks that the secrets can be read
+func (c *Checker) checkSecrets(cluster *kops.Cluster) error {
+	secretStore, err := c.Clientset.SecretStore(cluster)
+	if err != nil {
+		return err
+	}
+
+	names, err := secretStore.ListSecrets()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error listing secrets: %v", err)
+	}
+	for _, name := range names {
+		if _, err := secretStore.FindSecret(name); err != nil {
+			c.addQuarantine("secrets/"+name, "secret cannot be read: %v", err)
+		}
+	}
+	return nil
+}
+
+// listDir returns the names of the entries of the directory, relative to the ConfigBase
+func (c *Checker) listDir(path string) (map[string]bool, error) {
+	names := make(map[string]bool)
+	files, err := c.ConfigBase.Join(path).ReadTree()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return names, nil
+		}
+		return nil, fmt.Errorf("error listing %q: %v", path, err)
+	}
+	base := c.ConfigBase.Join(path)
+	for _, f := range files {
+		relativePath, err := vfs.RelativePath(base, f)
+		if err != nil {
+			return nil, err
+		}
+		tokens := strings.Split(relativePath, "/")
+		if len(tokens) < 2 {
+			klog.V(2).Infof("ignoring unexpected file %q", f)
+			continue
+		}
+		names[tokens[0]] = true
+	}
+	return names, nil
+}
+
+func (c *Checker) exists(path string) bool {
+	_, err := c.ConfigBase.Join(path).ReadFile()
+	return err == nil
+}
+
+// quarantine moves the file or directory at the path to the quarantine directory
+func (c *Checker) quarantine(path string) error {
+	src := c.ConfigBase.Join(path)
+
+	// The ACL strategies need a cluster, but the cluster spec may be the entry being quarantined
+	cluster := c.cluster
+	if cluster == nil {
+		cluster = &kops.Cluster{}
+	}
+
+	var files []vfs.Path
+	if _, err := src.ReadFile(); err == nil {
+		files = append(files, src)
+	} else {
+		tree, err := src.ReadTree()
+		if err != nil {
+			return fmt.Errorf("error listing %q: %v", src, err)
+		}
+		files = tree
+	}
+
+	for _, f := range files {
+		relativePath, err := vfs.RelativePath(c.ConfigBase, f)
+		if err != nil {
+			return err
+		}
+		dest := c.ConfigBase.Join(c.QuarantineDir, relativePath)
+		acl, err := acls.GetACL(dest, cluster)
+		if err != nil {
+			return err
+		}
+		if err := vfs.CopyFile(f, dest, acl); err != nil {
+			return err
+		}
+		if err := f.Remove(); err != nil {
+			return fmt.Errorf("error removing %q: %v", f, err)
+		}
+	}
+	return nil
+}
+
+func kindOf(gvk *schema.GroupVersionKind) string {
+	if gvk == nil || gvk.Kind == "" {
+		return "an object without a kind"
+	}
+	return "a " + gvk.Kind
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statecheck
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"k8s.io/kops/pkg/client/simple/vfsclientset"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+const testCluster = `apiVersion: kops.k8s.io/v1alpha2
+kind: Cluster
+metadata:
+  name: minimal.example.com
+spec:
+  cloudProvider: aws
+  configBase: memfs://tests/minimal.example.com
+  etcdClusters:
+  - name: main
+    etcdMembers:
+    - name: a
+      instanceGroup: master-us-test-1a
+  kubernetesVersion: v1.14.0
+  networkCIDR: 172.20.0.0/16
+  nonMasqueradeCIDR: 100.64.0.0/10
+  subnets:
+  - name: us-test-1a
+    cidr: 172.20.32.0/19
+    type: Public
+    zone: us-test-1a
+`
+
+const testNodes = `apiVersion: kops.k8s.io/v1alpha2
+kind: InstanceGroup
+metadata:
+  name: nodes
+spec:
+  role: Node
+  subnets:
+  - us-test-1b
+`
+
+func buildChecker(t *testing.T, files map[string]string) *Checker {
+	vfs.Context.ResetMemfsContext(true)
+
+	basePath, err := vfs.Context.BuildVfsPath("memfs://tests")
+	if err != nil {
+		t.Fatalf("error building base path: %v", err)
+	}
+	configBase := basePath.Join("minimal.example.com")
+	for k, v := range files {
+		if err := configBase.Join(k).WriteFile(bytes.NewReader([]byte(v)), nil); err != nil {
+			t.Fatalf("error writing %s: %v", k, err)
+		}
+	}
+
+	return &Checker{
+		Clientset:     vfsclientset.NewVFSClientset(basePath, true),
+		ClusterName:   "minimal.example.com",
+		ConfigBase:    configBase,
+		QuarantineDir: "quarantine/test",
+	}
+}
+
+// findProblem returns the problem for the path whose message contains the string
+func findProblem(problems []*Problem, path string, message string) *Problem {
+	for _, p := range problems {
+		if p.Path == path && strings.Contains(p.Message, message) {
+			return p
+		}
+	}
+	return nil
+}
+
+func TestCheck(t *testing.T) {
+	c := buildChecker(t, map[string]string{
+		"config":                     testCluster,
+		"instancegroup/nodes":        testNodes,
+		"instancegroup/broken":       "not: [valid",
+		"pki/private/orphan/1.key":   "key",
+		"notes.txt":                  "notes",
+		"rollingupdate/minimal.yaml": "{}",
+	})
+
+	problems, err := c.Check()
+	if err != nil {
+		t.Fatalf("unexpected error checking state: %v", err)
+	}
+
+	grid := []struct {
+		Path      string
+		Message   string
+		CanRepair bool
+	}{
+		{Path: "notes.txt", Message: "unknown file"},
+		{Path: "cluster.spec", Message: "missing"},
+		{Path: "config", Message: `refers to instance group "master-us-test-1a", which does not exist`},
+		{Path: "instancegroup/nodes", Message: `refers to subnet "us-test-1b"`},
+		{Path: "instancegroup/broken", Message: "cannot be parsed", CanRepair: true},
+		{Path: "pki/private/orphan", Message: "have no certificates", CanRepair: true},
+	}
+	for _, g := range grid {
+		p := findProblem(problems, g.Path, g.Message)
+		if p == nil {
+			t.Errorf("expected problem %q for %s, got %v", g.Message, g.Path, problems)
+			continue
+		}
+		if p.CanRepair() != g.CanRepair {
+			t.Errorf("expected CanRepair=%v for %s, got %v", g.CanRepair, g.Path, p.CanRepair())
+		}
+	}
+	if p := findProblem(problems, "rollingupdate/minimal.yaml", ""); p != nil {
+		t.Errorf("unexpected problem for known file: %s", p.Message)
+	}
+	if p := findProblem(problems, "config", "not valid"); p != nil {
+		t.Errorf("unexpected problem for valid cluster spec: %s", p.Message)
+	}
+
+	for _, p := range problems {
+		if !p.CanRepair() {
+			continue
+		}
+		if err := c.Repair(p); err != nil {
+			t.Fatalf("unexpected error repairing %s: %v", p.Path, err)
+		}
+	}
+
+	for k, v := range map[string]string{
+		"instancegroup/broken":     "not: [valid",
+		"pki/private/orphan/1.key": "key",
+	} {
+		if _, err := c.ConfigBase.Join(k).ReadFile(); err == nil {
+			t.Errorf("expected %s to be removed", k)
+		}
+		data, err := c.ConfigBase.Join("quarantine/test", k).ReadFile()
+		if err != nil {
+			t.Errorf("expected %s to be quarantined: %v", k, err)
+		} else if string(data) != v {
+			t.Errorf("unexpected quarantined contents of %s: %q", k, data)
+		}
+	}
+}
+
+func TestCheckBrokenCluster(t *testing.T) {
+	c := buildChecker(t, map[string]string{
+		"config":              "kind: InstanceGroup\n",
+		"instancegroup/nodes": testNodes,
+	})
+
+	problems, err := c.Check()
+	if err != nil {
+		t.Fatalf("unexpected error checking state: %v", err)
+	}
+	if len(problems) != 1 {
+		t.Fatalf("expected only the cluster spec to be checked, got %v", problems)
+	}
+	if problems[0].Path != "config" || !problems[0].CanRepair() {
+		t.Errorf("expected the cluster spec to be quarantined, got %s: %s", problems[0].Path, problems[0].Message)
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stateencryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+// envelopeHeader starts every encrypted object, so that objects written before encryption was enabled,
+// which are plaintext, can still be read
+const envelopeHeader = "#kops-envelope/v1\n"
+
+// dataKeySize is the size of the AES-256 data keys that encrypt objects
+const dataKeySize = 32
+
+// envelope is the serialized form of an encrypted object, which follows envelopeHeader
+type envelope struct {
+	// Provider is the name of the key provider that wrapped the data key
+	Provider string `json:"provider"`
+	// KeyID identifies the key, of the key provider, that wrapped the data key
+	KeyID string `json:"keyID,omitempty"`
+	// WrappedKey is the data key, encrypted by the key provider
+	WrappedKey []byte `json:"wrappedKey"`
+	// Nonce is the AES-GCM nonce
+	Nonce []byte `json:"nonce"`
+	// Ciphertext is the object, encrypted with the data key using AES-GCM
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// ProviderResolver returns the key provider with which to unwrap a data key, given the provider name and key id
+// recorded when the data key was wrapped
+type ProviderResolver func(name string, keyID string) (KeyProvider, error)
+
+type dataKey struct {
+	plaintext []byte
+	wrapped   []byte
+}
+
+var (
+	dataKeysMutex sync.Mutex
+	// sealingKeys holds the data key that encrypts objects, for each key provider and key, so that the
+	// key provider is called once per process rather than for every object
+	sealingKeys = make(map[string]*dataKey)
+	// openedKeys holds the data keys that have been unwrapped, by wrapped key
+	openedKeys = make(map[string][]byte)
+)
+
+// IsEncrypted returns true if data is an encrypted object
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(envelopeHeader))
+}
+
+// Seal encrypts an object for the state store of the cluster; the object is returned unchanged if state
+// encryption is not enabled for the cluster
+func Seal(cluster *kops.Cluster, data []byte) ([]byte, error) {
+	spec := specForCluster(cluster)
+	if spec == nil {
+		return data, nil
+	}
+
+	provider, err := NewKeyProvider(spec)
+	if err != nil {
+		return nil, err
+	}
+	return Encrypt(provider, data)
+}
+
+// Open decrypts an object read from the state store of the cluster; objects that are not encrypted are returned unchanged.
+// The key provider that wrapped the data key is the one recorded in the object, so objects remain readable after
+// the state encryption of the cluster has been changed, except those whose key file is no longer configured.
+func Open(cluster *kops.Cluster, data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+
+	spec := specForCluster(cluster)
+	return Decrypt(func(name string, keyID string) (KeyProvider, error) {
+		return providerFor(spec, name, keyID)
+	}, data)
+}
+
+// Encrypt encrypts data with a data key wrapped by provider
+func Encrypt(provider KeyProvider, data []byte) ([]byte, error) {
+	key, err := sealingKey(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key.plaintext)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	e := &envelope{
+		Provider:   provider.Name(),
+		KeyID:      provider.KeyID(),
+		WrappedKey: key.wrapped,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, data, nil),
+	}
+	return marshalEnvelope(e)
+}
+
+// Decrypt decrypts data, which must be an encrypted object, unwrapping its data key with the provider returned by resolve
+func Decrypt(resolve ProviderResolver, data []byte) ([]byte, error) {
+	e, err := parseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := openedKey(resolve, e)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted object has invalid nonce")
+	}
+	plaintext, err := gcm.Open(nil, e.Nonce, e.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting object: %v", err)
+	}
+	return plaintext, nil
+}
+
+// EncryptedWith returns the name of the key provider and the key id with which an encrypted object was encrypted
+func EncryptedWith(data []byte) (string, string, error) {
+	e, err := parseEnvelope(data)
+	if err != nil {
+		return "", "", err
+	}
+	return e.Provider, e.KeyID, nil
+}
+
+func marshalEnvelope(e *envelope) ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing encrypted object: %v", err)
+	}
+	return append([]byte(envelopeHeader), b...), nil
+}
+
+func parseEnvelope(data []byte) (*envelope, error) {
+	if !IsEncrypted(data) {
+		return nil, fmt.Errorf("object is not encrypted")
+	}
+	e := &envelope{}
+	if err := json.Unmarshal(data[len(envelopeHeader):], e); err != nil {
+		return nil, fmt.Errorf("error parsing encrypted object: %v", err)
+	}
+	return e, nil
+}
+
+func specForCluster(cluster *kops.Cluster) *kops.StateEncryptionSpec {
+	if cluster == nil {
+		return nil
+	}
+	return cluster.Spec.StateEncryption
+}
+
+// sealingKey returns the data key for encrypting objects with provider, generating and wrapping it on first use
+func sealingKey(provider KeyProvider) (*dataKey, error) {
+	dataKeysMutex.Lock()
+	defer dataKeysMutex.Unlock()
+
+	id := provider.Name() + "/" + provider.KeyID()
+	if key := sealingKeys[id]; key != nil {
+		return key, nil
+	}
+
+	plaintext := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		return nil, fmt.Errorf("error generating data key: %v", err)
+	}
+	wrapped, err := provider.WrapKey(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("error wrapping data key with %s key %q: %v", provider.Name(), provider.KeyID(), err)
+	}
+
+	key := &dataKey{plaintext: plaintext, wrapped: wrapped}
+	sealingKeys[id] = key
+	openedKeys[string(wrapped)] = plaintext
+	return key, nil
+}
+
+// openedKey returns the unwrapped data key of an envelope
+func openedKey(resolve ProviderResolver, e *envelope) ([]byte, error) {
+	dataKeysMutex.Lock()
+	defer dataKeysMutex.Unlock()
+
+	if key := openedKeys[string(e.WrappedKey)]; key != nil {
+		return key, nil
+	}
+
+	provider, err := resolve(e.Provider, e.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := provider.UnwrapKey(e.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping data key with %s key %q: %v", e.Provider, e.KeyID, err)
+	}
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("unwrapped data key has unexpected length %d", len(key))
+	}
+
+	openedKeys[string(e.WrappedKey)] = key
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error building cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error building cipher: %v", err)
+	}
+	return gcm, nil
+}
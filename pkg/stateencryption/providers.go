@@ -0,0 +1,284 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stateencryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"golang.org/x/oauth2/google"
+
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+const (
+	// ProviderAWSKMS wraps data keys with an AWS KMS key
+	ProviderAWSKMS = "aws-kms"
+	// ProviderGCPKMS wraps data keys with a GCP KMS key
+	ProviderGCPKMS = "gcp-kms"
+	// ProviderFile wraps data keys with a key read from a local file
+	ProviderFile = "file"
+)
+
+// KeyProvider wraps (encrypts) and unwraps the data keys that encrypt objects in the state store
+type KeyProvider interface {
+	// Name is the name of the kind of key provider, which is recorded with each object
+	Name() string
+	// KeyID identifies the key that wraps the data keys, and is recorded with each object
+	KeyID() string
+	// WrapKey encrypts a data key
+	WrapKey(dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// NewKeyProvider builds the key provider configured by spec
+func NewKeyProvider(spec *kops.StateEncryptionSpec) (KeyProvider, error) {
+	switch {
+	case spec.AWSKMSKeyARN != "":
+		return newAWSKMSProvider(spec.AWSKMSKeyARN)
+	case spec.GCPKMSKeyName != "":
+		return newGCPKMSProvider(spec.GCPKMSKeyName)
+	case spec.KeyFile != "":
+		return NewFileProvider(spec.KeyFile)
+	default:
+		return nil, fmt.Errorf("stateEncryption must set one of awsKMSKeyARN, gcpKMSKeyName or keyFile")
+	}
+}
+
+// providerFor builds the key provider with which to unwrap a data key, from the provider name and key id recorded
+// with an object; spec, which may be nil, supplies the path to the key file
+func providerFor(spec *kops.StateEncryptionSpec, name string, keyID string) (KeyProvider, error) {
+	switch name {
+	case ProviderAWSKMS:
+		return newAWSKMSProvider(keyID)
+	case ProviderGCPKMS:
+		return newGCPKMSProvider(keyID)
+	case ProviderFile:
+		if spec == nil || spec.KeyFile == "" {
+			return nil, fmt.Errorf("object was encrypted with key file %q, but stateEncryption.keyFile is not set", keyID)
+		}
+		provider, err := NewFileProvider(spec.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if provider.KeyID() != keyID {
+			return nil, fmt.Errorf("object was encrypted with key file %q, but %q has id %q", keyID, spec.KeyFile, provider.KeyID())
+		}
+		return provider, nil
+	default:
+		return nil, fmt.Errorf("unknown key provider %q", name)
+	}
+}
+
+// awsKMSProvider wraps data keys with an AWS KMS key; the region is that of the key
+type awsKMSProvider struct {
+	keyARN string
+	kms    *kms.KMS
+}
+
+var _ KeyProvider = &awsKMSProvider{}
+
+func newAWSKMSProvider(keyARN string) (*awsKMSProvider, error) {
+	tokens := strings.SplitN(keyARN, ":", 6)
+	if len(tokens) != 6 || tokens[0] != "arn" || tokens[2] != "kms" || tokens[3] == "" {
+		return nil, fmt.Errorf("invalid AWS KMS key ARN %q", keyARN)
+	}
+
+	sess, err := session.NewSession(aws.NewConfig().WithRegion(tokens[3]))
+	if err != nil {
+		return nil, fmt.Errorf("error starting AWS session: %v", err)
+	}
+	return &awsKMSProvider{keyARN: keyARN, kms: kms.New(sess)}, nil
+}
+
+func (p *awsKMSProvider) Name() string {
+	return ProviderAWSKMS
+}
+
+func (p *awsKMSProvider) KeyID() string {
+	return p.keyARN
+}
+
+func (p *awsKMSProvider) WrapKey(dataKey []byte) ([]byte, error) {
+	response, err := p.kms.Encrypt(&kms.EncryptInput{
+		KeyId:     aws.String(p.keyARN),
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.CiphertextBlob, nil
+}
+
+func (p *awsKMSProvider) UnwrapKey(wrapped []byte) ([]byte, error) {
+	response, err := p.kms.Decrypt(&kms.DecryptInput{
+		CiphertextBlob: wrapped,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.Plaintext, nil
+}
+
+// gcpKMSProvider wraps data keys with a GCP KMS key, using the application default credentials
+type gcpKMSProvider struct {
+	keyName string
+	client  *http.Client
+}
+
+var _ KeyProvider = &gcpKMSProvider{}
+
+const gcpKMSEndpoint = "https://cloudkms.googleapis.com/v1/"
+
+func newGCPKMSProvider(keyName string) (*gcpKMSProvider, error) {
+	client, err := google.DefaultClient(context.Background(), "https://www.googleapis.com/auth/cloudkms")
+	if err != nil {
+		return nil, fmt.Errorf("error building GCP client: %v", err)
+	}
+	return &gcpKMSProvider{keyName: keyName, client: client}, nil
+}
+
+func (p *gcpKMSProvider) Name() string {
+	return ProviderGCPKMS
+}
+
+func (p *gcpKMSProvider) KeyID() string {
+	return p.keyName
+}
+
+func (p *gcpKMSProvider) WrapKey(dataKey []byte) ([]byte, error) {
+	response := struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}{}
+	request := struct {
+		Plaintext []byte `json:"plaintext"`
+	}{dataKey}
+	if err := p.call("encrypt", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Ciphertext, nil
+}
+
+func (p *gcpKMSProvider) UnwrapKey(wrapped []byte) ([]byte, error) {
+	response := struct {
+		Plaintext []byte `json:"plaintext"`
+	}{}
+	request := struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}{wrapped}
+	if err := p.call("decrypt", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Plaintext, nil
+}
+
+// call calls a method of the cloudkms API on the key; []byte fields are base64 encoded, as the API expects
+func (p *gcpKMSProvider) call(method string, request interface{}, response interface{}) error {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	url := gcpKMSEndpoint + p.keyName + ":" + method
+	r, err := p.client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("error calling %s: %v", url, err)
+	}
+	defer r.Body.Close()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response from %s: %v", url, err)
+	}
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("call to %s failed with status %s: %s", url, r.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.Unmarshal(body, response); err != nil {
+		return fmt.Errorf("error parsing response from %s: %v", url, err)
+	}
+	return nil
+}
+
+// FileProvider wraps data keys with a 256 bit key, read base64 encoded from a local file
+type FileProvider struct {
+	key []byte
+}
+
+var _ KeyProvider = &FileProvider{}
+
+// NewFileProvider builds a FileProvider with the key in the file at path
+func NewFileProvider(path string) (*FileProvider, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading key file %q: %v", path, err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("error decoding key file %q: %v", path, err)
+	}
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("key file %q must hold a base64 encoded %d byte key, found %d bytes", path, dataKeySize, len(key))
+	}
+	return &FileProvider{key: key}, nil
+}
+
+func (p *FileProvider) Name() string {
+	return ProviderFile
+}
+
+// KeyID is derived from the key, so that a different key file is detected rather than failing to decrypt
+func (p *FileProvider) KeyID() string {
+	hash := sha256.Sum256(p.key)
+	return hex.EncodeToString(hash[:8])
+}
+
+func (p *FileProvider) WrapKey(dataKey []byte) ([]byte, error) {
+	gcm, err := newGCM(p.key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %v", err)
+	}
+	return gcm.Seal(nonce, nonce, dataKey, nil), nil
+}
+
+func (p *FileProvider) UnwrapKey(wrapped []byte) ([]byte, error) {
+	gcm, err := newGCM(p.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < gcm.NonceSize() {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	return gcm.Open(nil, wrapped[:gcm.NonceSize()], wrapped[gcm.NonceSize():], nil)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stateencryption
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/acls"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// ReencryptTree rewrites every object under basedir as the state encryption of the cluster requires: plaintext
+// objects and objects encrypted with another key are encrypted with the current key, or decrypted if state
+// encryption is not enabled. previousKeyFile, if set, is the key file that objects were encrypted with before
+// the state encryption of the cluster was changed. It returns the paths of the objects that were rewritten;
+// with dryRun they are not written.
+func ReencryptTree(cluster *kops.Cluster, basedir vfs.Path, previousKeyFile string, dryRun bool) ([]vfs.Path, error) {
+	files, err := basedir.ReadTree()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing %q: %v", basedir, err)
+	}
+
+	var provider KeyProvider
+	if spec := specForCluster(cluster); spec != nil {
+		provider, err = NewKeyProvider(spec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	spec := specForCluster(cluster)
+	resolve := func(name string, keyID string) (KeyProvider, error) {
+		if name == ProviderFile && previousKeyFile != "" {
+			previous, err := NewFileProvider(previousKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			if previous.KeyID() == keyID {
+				return previous, nil
+			}
+		}
+		return providerFor(spec, name, keyID)
+	}
+
+	var rewritten []vfs.Path
+	for _, p := range files {
+		data, err := p.ReadFile()
+		if err != nil {
+			return rewritten, fmt.Errorf("error reading %q: %v", p, err)
+		}
+
+		current, err := isCurrent(provider, data)
+		if err != nil {
+			return rewritten, fmt.Errorf("error reading %q: %v", p, err)
+		}
+		if current {
+			continue
+		}
+
+		plaintext := data
+		if IsEncrypted(data) {
+			plaintext, err = Decrypt(resolve, data)
+		}
+		if err != nil {
+			return rewritten, fmt.Errorf("error decrypting %q: %v", p, err)
+		}
+		sealed := plaintext
+		if provider != nil {
+			sealed, err = Encrypt(provider, plaintext)
+			if err != nil {
+				return rewritten, fmt.Errorf("error encrypting %q: %v", p, err)
+			}
+		}
+
+		rewritten = append(rewritten, p)
+		if dryRun {
+			continue
+		}
+
+		acl, err := acls.GetACL(p, cluster)
+		if err != nil {
+			return rewritten, err
+		}
+		if err := p.WriteFile(bytes.NewReader(sealed), acl); err != nil {
+			return rewritten, fmt.Errorf("error writing %q: %v", p, err)
+		}
+		klog.V(2).Infof("re-encrypted %s", p)
+	}
+
+	return rewritten, nil
+}
+
+// isCurrent returns true if data is already stored as required, i.e. encrypted with provider, or plaintext if provider is nil
+func isCurrent(provider KeyProvider, data []byte) (bool, error) {
+	if !IsEncrypted(data) {
+		return provider == nil, nil
+	}
+	if provider == nil {
+		return false, nil
+	}
+	name, keyID, err := EncryptedWith(data)
+	if err != nil {
+		return false, err
+	}
+	return name == provider.Name() && keyID == provider.KeyID(), nil
+}
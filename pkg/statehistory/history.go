@@ -0,0 +1,209 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statehistory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"time"
+
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/acls"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/stateencryption"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// DefaultDepth is the number of versions of each object kept when the cluster does not set SecretHistoryDepth
+const DefaultDepth = 10
+
+// Version is a prior version of an object in the state store
+type Version struct {
+	// ID identifies the version; versions of an object are numbered from 1 upwards
+	ID string `json:"id"`
+	// Timestamp is when the object was replaced by a newer version
+	Timestamp time.Time `json:"timestamp"`
+	// Author is the user that replaced the object
+	Author string `json:"author,omitempty"`
+	// Data is the content of the object
+	Data []byte `json:"data"`
+}
+
+// History keeps the prior versions of the objects in a store, under basedir/<name>/<id>.
+// Versions are encrypted when the cluster has state encryption enabled.
+type History struct {
+	cluster *kops.Cluster
+	basedir vfs.Path
+}
+
+// NewHistory builds a History for versions under basedir
+func NewHistory(cluster *kops.Cluster, basedir vfs.Path) *History {
+	return &History{
+		cluster: cluster,
+		basedir: basedir,
+	}
+}
+
+// Depth returns the number of versions of each object kept for the cluster
+func Depth(cluster *kops.Cluster) int {
+	if cluster == nil || cluster.Spec.SecretHistoryDepth == nil {
+		return DefaultDepth
+	}
+	return int(*cluster.Spec.SecretHistoryDepth)
+}
+
+// Author returns the name of the user running kops, recorded as the author of versions
+func Author() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		name += "@" + hostname
+	}
+	return name
+}
+
+// Record records data as the newest version of the named object, then removes the oldest versions beyond
+// the history depth of the cluster. Nothing is recorded if the depth is 0.
+func (h *History) Record(name string, data []byte) (*Version, error) {
+	depth := Depth(h.cluster)
+	if depth <= 0 {
+		return nil, nil
+	}
+
+	versions, err := h.List(name)
+	if err != nil {
+		return nil, err
+	}
+
+	next := 1
+	if len(versions) != 0 {
+		newest, _ := strconv.Atoi(versions[0].ID)
+		next = newest + 1
+	}
+
+	version := &Version{
+		ID:        strconv.Itoa(next),
+		Timestamp: time.Now().UTC(),
+		Author:    Author(),
+		Data:      data,
+	}
+
+	b, err := json.Marshal(version)
+	if err != nil {
+		return nil, fmt.Errorf("error serializing version of %q: %v", name, err)
+	}
+	b, err = stateencryption.Seal(h.cluster, b)
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting version of %q: %v", name, err)
+	}
+
+	p := h.basedir.Join(name, version.ID)
+	acl, err := acls.GetACL(p, h.cluster)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.WriteFile(bytes.NewReader(b), acl); err != nil {
+		return nil, fmt.Errorf("error writing version of %q to %q: %v", name, p, err)
+	}
+
+	versions = append([]*Version{version}, versions...)
+	for _, old := range versions[min(depth, len(versions)):] {
+		p := h.basedir.Join(name, old.ID)
+		klog.V(2).Infof("pruning version %s of %q", old.ID, name)
+		if err := p.Remove(); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("error removing version %s of %q: %v", old.ID, name, err)
+		}
+	}
+
+	return version, nil
+}
+
+// List returns the recorded versions of the named object, newest first
+func (h *History) List(name string) ([]*Version, error) {
+	files, err := h.basedir.Join(name).ReadDir()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing versions of %q: %v", name, err)
+	}
+
+	var versions []*Version
+	for _, f := range files {
+		if _, err := strconv.Atoi(f.Base()); err != nil {
+			klog.V(2).Infof("ignoring unexpected file in history: %q", f)
+			continue
+		}
+		version, err := h.read(f)
+		if err != nil {
+			return nil, err
+		}
+		if version != nil {
+			versions = append(versions, version)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		a, _ := strconv.Atoi(versions[i].ID)
+		b, _ := strconv.Atoi(versions[j].ID)
+		return a > b
+	})
+	return versions, nil
+}
+
+// Get returns the identified version of the named object, or nil if it is not found
+func (h *History) Get(name string, id string) (*Version, error) {
+	if _, err := strconv.Atoi(id); err != nil {
+		return nil, fmt.Errorf("invalid version %q", id)
+	}
+	return h.read(h.basedir.Join(name, id))
+}
+
+func (h *History) read(p vfs.Path) (*Version, error) {
+	data, err := p.ReadFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading %q: %v", p, err)
+	}
+	data, err = stateencryption.Open(h.cluster, data)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %q: %v", p, err)
+	}
+
+	version := &Version{}
+	if err := json.Unmarshal(data, version); err != nil {
+		return nil, fmt.Errorf("error parsing %q: %v", p, err)
+	}
+	version.ID = p.Base()
+	return version, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statehistory
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func int32p(v int32) *int32 {
+	return &v
+}
+
+func TestRecordAndPrune(t *testing.T) {
+	cluster := &kops.Cluster{}
+	cluster.Spec.SecretHistoryDepth = int32p(3)
+
+	basedir := vfs.NewMemFSPath(vfs.NewMemFSContext(), "memfs://state/cluster.example.com/secrets/.history")
+	h := NewHistory(cluster, basedir)
+
+	for i := 1; i <= 5; i++ {
+		version, err := h.Record("admin", []byte(fmt.Sprintf("v%d", i)))
+		if err != nil {
+			t.Fatalf("unexpected error recording version: %v", err)
+		}
+		if version.ID != fmt.Sprintf("%d", i) {
+			t.Errorf("unexpected version id %q, expected %d", version.ID, i)
+		}
+	}
+
+	versions, err := h.List("admin")
+	if err != nil {
+		t.Fatalf("unexpected error listing versions: %v", err)
+	}
+	var ids []string
+	for _, v := range versions {
+		ids = append(ids, v.ID+"="+string(v.Data))
+		if v.Author == "" || v.Timestamp.IsZero() {
+			t.Errorf("expected version %s to record its author and timestamp", v.ID)
+		}
+	}
+	if fmt.Sprintf("%v", ids) != "[5=v5 4=v4 3=v3]" {
+		t.Errorf("unexpected versions after pruning: %v", ids)
+	}
+
+	version, err := h.Get("admin", "4")
+	if err != nil {
+		t.Fatalf("unexpected error getting version: %v", err)
+	}
+	if version == nil || string(version.Data) != "v4" {
+		t.Errorf("unexpected version %v", version)
+	}
+
+	version, err = h.Get("admin", "1")
+	if err != nil {
+		t.Fatalf("unexpected error getting pruned version: %v", err)
+	}
+	if version != nil {
+		t.Errorf("expected pruned version to be removed, got %v", version)
+	}
+
+	if _, err := h.Get("admin", "../kube"); err == nil {
+		t.Errorf("expected an error for an invalid version")
+	}
+}
+
+func TestRecordDisabled(t *testing.T) {
+	cluster := &kops.Cluster{}
+	cluster.Spec.SecretHistoryDepth = int32p(0)
+
+	basedir := vfs.NewMemFSPath(vfs.NewMemFSContext(), "memfs://state/cluster.example.com/secrets/.history")
+	h := NewHistory(cluster, basedir)
+
+	version, err := h.Record("admin", []byte("v1"))
+	if err != nil {
+		t.Fatalf("unexpected error recording version: %v", err)
+	}
+	if version != nil {
+		t.Errorf("expected no version to be recorded, got %v", version)
+	}
+
+	versions, err := h.List("admin")
+	if err != nil {
+		t.Fatalf("unexpected error listing versions: %v", err)
+	}
+	if len(versions) != 0 {
+		t.Errorf("expected no versions, got %d", len(versions))
+	}
+}
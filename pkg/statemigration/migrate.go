@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statemigration
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/acls"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// PathChange is a path in the cluster spec that refers to the state store
+type PathChange struct {
+	// Field is the field of the cluster spec holding the path
+	Field string
+	// Old is the path before the migration
+	Old string
+	// New is the path after the migration; it is empty if the path is outside the state of the cluster,
+	// and so is not migrated
+	New string
+}
+
+// RewritePaths rewrites the paths in the cluster spec that are under the from path to be under the to path,
+// returning every path that was considered
+func RewritePaths(spec *kops.ClusterSpec, from string, to string) []*PathChange {
+	var changes []*PathChange
+
+	rewrite := func(field string, p *string) {
+		if *p == "" {
+			return
+		}
+		change := &PathChange{Field: field, Old: *p}
+		if rel, ok := relativeTo(from, *p); ok {
+			change.New = joinPath(to, rel)
+			*p = change.New
+		}
+		changes = append(changes, change)
+	}
+
+	rewrite("configBase", &spec.ConfigBase)
+	rewrite("configStore", &spec.ConfigStore)
+	rewrite("keyStore", &spec.KeyStore)
+	rewrite("secretStore", &spec.SecretStore)
+	for _, etcdCluster := range spec.EtcdClusters {
+		if etcdCluster.Backups != nil {
+			rewrite("etcdClusters["+etcdCluster.Name+"].backups.backupStore", &etcdCluster.Backups.BackupStore)
+		}
+	}
+
+	return changes
+}
+
+// relativeTo returns the path of p relative to base, if p is base or under it
+func relativeTo(base string, p string) (string, bool) {
+	base = strings.TrimSuffix(base, "/")
+	p = strings.TrimSuffix(p, "/")
+	if p == base {
+		return "", true
+	}
+	if strings.HasPrefix(p, base+"/") {
+		return strings.TrimPrefix(p, base+"/"), true
+	}
+	return "", false
+}
+
+func joinPath(base string, rel string) string {
+	base = strings.TrimSuffix(base, "/")
+	if rel == "" {
+		return base
+	}
+	return base + "/" + rel
+}
+
+// CopyState copies every file of the cluster's state from one path to another, applying the ACLs the cluster needs
+// at the destination and verifying each copy. Files that have already been copied are skipped, so an interrupted
+// copy can be resumed. It returns the paths of the files copied, relative to the cluster's state.
+func CopyState(cluster *kops.Cluster, from vfs.Path, to vfs.Path) ([]string, error) {
+	srcFiles, err := from.ReadTree()
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s: %v", from, err)
+	}
+
+	var copied []string
+	for _, srcFile := range srcFiles {
+		relativePath, err := vfs.RelativePath(from, srcFile)
+		if err != nil {
+			return nil, err
+		}
+		destFile := to.Join(relativePath)
+
+		if _, err := destFile.ReadFile(); err == nil {
+			if err := vfs.VerifyCopy(srcFile, destFile); err == nil {
+				klog.V(2).Infof("%s has already been copied", relativePath)
+				continue
+			}
+		}
+
+		acl, err := acls.GetACL(destFile, cluster)
+		if err != nil {
+			return nil, err
+		}
+		if err := vfs.CopyFile(srcFile, destFile, acl); err != nil {
+			return nil, err
+		}
+		if err := vfs.VerifyCopy(srcFile, destFile); err != nil {
+			return nil, fmt.Errorf("error verifying copy of %s: %v", relativePath, err)
+		}
+		copied = append(copied, relativePath)
+	}
+
+	return copied, nil
+}
+
+// RewriteCompletedSpec rewrites the paths in the completed cluster spec, which holds the paths nodeup reads
+func RewriteCompletedSpec(cluster *kops.Cluster, configBase vfs.Path, from string, to string) error {
+	p := configBase.Join(registry.PathClusterCompleted)
+
+	completed := &kops.Cluster{}
+	if err := registry.ReadConfigDeprecated(p, completed); err != nil {
+		if os.IsNotExist(err) {
+			// The cluster has not been applied yet
+			return nil
+		}
+		return fmt.Errorf("error reading completed cluster spec: %v", err)
+	}
+
+	RewritePaths(&completed.Spec, from, to)
+
+	if err := registry.WriteConfigDeprecated(cluster, p, completed); err != nil {
+		return fmt.Errorf("error writing completed cluster spec: %v", err)
+	}
+	return nil
+}
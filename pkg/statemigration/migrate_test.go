@@ -0,0 +1,161 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package statemigration
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func TestRewritePaths(t *testing.T) {
+	spec := &kops.ClusterSpec{
+		ConfigBase:  "s3://old/minimal.example.com",
+		ConfigStore: "s3://old/minimal.example.com",
+		KeyStore:    "s3://old/minimal.example.com/pki",
+		SecretStore: "s3://other/secrets",
+		EtcdClusters: []*kops.EtcdClusterSpec{
+			{Name: "main", Backups: &kops.EtcdBackupSpec{BackupStore: "s3://old/minimal.example.com/backups/etcd/main"}},
+			{Name: "events"},
+		},
+	}
+
+	changes := RewritePaths(spec, "s3://old/minimal.example.com", "gs://new/minimal.example.com/")
+
+	expected := []*PathChange{
+		{Field: "configBase", Old: "s3://old/minimal.example.com", New: "gs://new/minimal.example.com"},
+		{Field: "configStore", Old: "s3://old/minimal.example.com", New: "gs://new/minimal.example.com"},
+		{Field: "keyStore", Old: "s3://old/minimal.example.com/pki", New: "gs://new/minimal.example.com/pki"},
+		{Field: "secretStore", Old: "s3://other/secrets", New: ""},
+		{Field: "etcdClusters[main].backups.backupStore", Old: "s3://old/minimal.example.com/backups/etcd/main", New: "gs://new/minimal.example.com/backups/etcd/main"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		for _, c := range changes {
+			t.Logf("%s: %q -> %q", c.Field, c.Old, c.New)
+		}
+		t.Errorf("unexpected changes")
+	}
+	if spec.SecretStore != "s3://other/secrets" {
+		t.Errorf("expected path outside the state of the cluster to be unchanged, got %q", spec.SecretStore)
+	}
+	if spec.EtcdClusters[0].Backups.BackupStore != "gs://new/minimal.example.com/backups/etcd/main" {
+		t.Errorf("unexpected backupStore %q", spec.EtcdClusters[0].Backups.BackupStore)
+	}
+
+	// A path that only shares a prefix is not under the state of the cluster
+	spec = &kops.ClusterSpec{KeyStore: "s3://old/minimal.example.com-pki"}
+	RewritePaths(spec, "s3://old/minimal.example.com", "gs://new/minimal.example.com")
+	if spec.KeyStore != "s3://old/minimal.example.com-pki" {
+		t.Errorf("unexpected keyStore %q", spec.KeyStore)
+	}
+}
+
+func TestCopyState(t *testing.T) {
+	from := vfs.NewMemFSPath(vfs.NewMemFSContext(), "memfs://old/minimal.example.com")
+	files := map[string]string{
+		"config":                    "config",
+		"instancegroup/nodes":       "nodes",
+		"pki/issued/ca/keyset.yaml": "ca",
+		"secrets/admin":             "admin",
+	}
+	for k, v := range files {
+		if err := from.Join(k).WriteFile(bytes.NewReader([]byte(v)), nil); err != nil {
+			t.Fatalf("error writing %s: %v", k, err)
+		}
+	}
+
+	tempDir, err := ioutil.TempDir("", "statemigration")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	to := vfs.NewFSPath(tempDir).Join("minimal.example.com")
+
+	cluster := &kops.Cluster{}
+
+	copied, err := CopyState(cluster, from, to)
+	if err != nil {
+		t.Fatalf("unexpected error copying state: %v", err)
+	}
+	sort.Strings(copied)
+	if !reflect.DeepEqual(copied, []string{"config", "instancegroup/nodes", "pki/issued/ca/keyset.yaml", "secrets/admin"}) {
+		t.Errorf("unexpected files copied: %v", copied)
+	}
+	for k, v := range files {
+		data, err := to.Join(k).ReadFile()
+		if err != nil {
+			t.Errorf("error reading copy of %s: %v", k, err)
+		} else if string(data) != v {
+			t.Errorf("unexpected contents of copy of %s: %q", k, data)
+		}
+	}
+
+	// Resuming only copies files that changed
+	if err := from.Join("secrets/admin").WriteFile(bytes.NewReader([]byte("changed")), nil); err != nil {
+		t.Fatalf("error writing secret: %v", err)
+	}
+	copied, err = CopyState(cluster, from, to)
+	if err != nil {
+		t.Fatalf("unexpected error copying state: %v", err)
+	}
+	if !reflect.DeepEqual(copied, []string{"secrets/admin"}) {
+		t.Errorf("unexpected files copied when resuming: %v", copied)
+	}
+}
+
+func TestRewriteCompletedSpec(t *testing.T) {
+	configBase := vfs.NewMemFSPath(vfs.NewMemFSContext(), "memfs://new/minimal.example.com")
+
+	cluster := &kops.Cluster{}
+	if err := RewriteCompletedSpec(cluster, configBase, "memfs://old/minimal.example.com", "memfs://new/minimal.example.com"); err != nil {
+		t.Fatalf("unexpected error when there is no completed spec: %v", err)
+	}
+
+	completed := `metadata:
+  name: minimal.example.com
+spec:
+  configBase: memfs://old/minimal.example.com
+  keyStore: memfs://old/minimal.example.com/pki
+  secretStore: memfs://old/minimal.example.com/secrets
+`
+	p := configBase.Join(registry.PathClusterCompleted)
+	if err := p.WriteFile(bytes.NewReader([]byte(completed)), nil); err != nil {
+		t.Fatalf("error writing completed spec: %v", err)
+	}
+
+	if err := RewriteCompletedSpec(cluster, configBase, "memfs://old/minimal.example.com", "memfs://new/minimal.example.com"); err != nil {
+		t.Fatalf("unexpected error rewriting completed spec: %v", err)
+	}
+
+	rewritten := &kops.Cluster{}
+	if err := registry.ReadConfigDeprecated(p, rewritten); err != nil {
+		t.Fatalf("error reading completed spec: %v", err)
+	}
+	if rewritten.Name != "minimal.example.com" {
+		t.Errorf("unexpected name %q", rewritten.Name)
+	}
+	if rewritten.Spec.KeyStore != "memfs://new/minimal.example.com/pki" || rewritten.Spec.SecretStore != "memfs://new/minimal.example.com/secrets" {
+		t.Errorf("unexpected paths after rewriting: keyStore=%q secretStore=%q", rewritten.Spec.KeyStore, rewritten.Spec.SecretStore)
+	}
+}
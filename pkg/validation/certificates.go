@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/certificates"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+// DefaultCertificateExpiryThreshold is how long before a certificate expires that we warn about it,
+// unless the cluster spec sets validation.certificateExpiryThreshold
+const DefaultCertificateExpiryThreshold = 30 * 24 * time.Hour
+
+// CertificateExpiryThreshold returns how long before a certificate expires that we warn about it
+func CertificateExpiryThreshold(cluster *kops.Cluster) time.Duration {
+	spec := cluster.Spec.Validation
+	if spec != nil && spec.CertificateExpiryThreshold != nil {
+		return spec.CertificateExpiryThreshold.Duration
+	}
+	return DefaultCertificateExpiryThreshold
+}
+
+// ValidateCertificateExpiry adds a warning for each certificate in the keystore that expires within the threshold
+// configured for the cluster
+func (v *ValidationCluster) ValidateCertificateExpiry(cluster *kops.Cluster, keyStore fi.CAStore) error {
+	threshold := CertificateExpiryThreshold(cluster)
+	if threshold == 0 {
+		return nil
+	}
+
+	certs, err := certificates.ListCertificates(keyStore)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, c := range certificates.Expiring(certs, now, threshold) {
+		message := fmt.Sprintf("Certificate %q (%s) expires on %s, in %d days; rotate it with kops rotate certificates", c.Keyset, c.Subject, c.NotAfter.UTC().Format(time.RFC3339), c.DaysRemaining(now))
+		if c.NotAfter.Before(now) {
+			message = fmt.Sprintf("Certificate %q (%s) expired on %s; rotate it with kops rotate certificates", c.Keyset, c.Subject, c.NotAfter.UTC().Format(time.RFC3339))
+		}
+		v.Warnings = append(v.Warnings, &ValidationError{
+			Kind:    "Certificate",
+			Name:    c.Keyset,
+			Message: message,
+		})
+	}
+	return nil
+}
@@ -38,6 +38,9 @@ import (
 type ValidationCluster struct {
 	Failures []*ValidationError `json:"failures,omitempty"`
 
+	// Warnings are problems that do not fail validation, but need attention
+	Warnings []*ValidationError `json:"warnings,omitempty"`
+
 	Nodes []*ValidationNode `json:"nodes,omitempty"`
 }
 
@@ -152,13 +155,37 @@ func ValidateCluster(cluster *kops.Cluster, instanceGroupList *kops.InstanceGrou
 		return nil, fmt.Errorf("cannot get component status for %q: %v", clusterName, err)
 	}
 
-	if err = v.collectPodFailures(k8sClient); err != nil {
+	if err = v.collectPodFailures(k8sClient, podNamespaces(cluster)); err != nil {
 		return nil, fmt.Errorf("cannot get pod health for %q: %v", clusterName, err)
 	}
 
+	validatorContext := &ValidatorContext{
+		Cluster:        cluster,
+		InstanceGroups: instanceGroups,
+		Nodes:          nodeList.Items,
+		K8sClient:      k8sClient,
+	}
+	if err := v.runValidators(clusterValidators(cluster), validatorContext); err != nil {
+		return nil, err
+	}
+
 	return v, nil
 }
 
+// runValidators adds the failures found by each validator
+func (v *ValidationCluster) runValidators(validators []Validator, ctx *ValidatorContext) error {
+	for _, validator := range validators {
+		failures, err := validator.Validate(ctx)
+		if err != nil {
+			return fmt.Errorf("error running %s validation of %q: %v", validator.Name(), ctx.Cluster.Name, err)
+		}
+		for _, failure := range failures {
+			v.addError(failure)
+		}
+	}
+	return nil
+}
+
 func (v *ValidationCluster) collectComponentFailures(client kubernetes.Interface) error {
 	componentList, err := client.CoreV1().ComponentStatuses().List(metav1.ListOptions{})
 	if err != nil {
@@ -179,37 +206,39 @@ func (v *ValidationCluster) collectComponentFailures(client kubernetes.Interface
 	return nil
 }
 
-func (v *ValidationCluster) collectPodFailures(client kubernetes.Interface) error {
-	pods, err := client.CoreV1().Pods("kube-system").List(metav1.ListOptions{})
-	if err != nil {
-		return fmt.Errorf("error listing Pods: %v", err)
-	}
-
-	for _, pod := range pods.Items {
-		if pod.Status.Phase == v1.PodSucceeded {
-			continue
-		}
-		if pod.Status.Phase == v1.PodPending {
-			v.addError(&ValidationError{
-				Kind:    "Pod",
-				Name:    "kube-system/" + pod.Name,
-				Message: fmt.Sprintf("kube-system pod %q is pending", pod.Name),
-			})
-			continue
+func (v *ValidationCluster) collectPodFailures(client kubernetes.Interface, namespaces []string) error {
+	for _, namespace := range namespaces {
+		pods, err := client.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("error listing Pods in %q: %v", namespace, err)
 		}
-		var notready []string
-		for _, container := range pod.Status.ContainerStatuses {
-			if !container.Ready {
-				notready = append(notready, container.Name)
+
+		for _, pod := range pods.Items {
+			if pod.Status.Phase == v1.PodSucceeded {
+				continue
 			}
-		}
-		if len(notready) != 0 {
-			v.addError(&ValidationError{
-				Kind:    "Pod",
-				Name:    "kube-system/" + pod.Name,
-				Message: fmt.Sprintf("kube-system pod %q is not ready (%s)", pod.Name, strings.Join(notready, ",")),
-			})
+			if pod.Status.Phase == v1.PodPending {
+				v.addError(&ValidationError{
+					Kind:    "Pod",
+					Name:    namespace + "/" + pod.Name,
+					Message: fmt.Sprintf("%s pod %q is pending", namespace, pod.Name),
+				})
+				continue
+			}
+			var notready []string
+			for _, container := range pod.Status.ContainerStatuses {
+				if !container.Ready {
+					notready = append(notready, container.Name)
+				}
+			}
+			if len(notready) != 0 {
+				v.addError(&ValidationError{
+					Kind:    "Pod",
+					Name:    namespace + "/" + pod.Name,
+					Message: fmt.Sprintf("%s pod %q is not ready (%s)", namespace, pod.Name, strings.Join(notready, ",")),
+				})
 
+			}
 		}
 	}
 	return nil
@@ -220,13 +249,21 @@ func (v *ValidationCluster) validateNodes(cloudGroups map[string]*cloudinstances
 		var allMembers []*cloudinstances.CloudInstanceGroupMember
 		allMembers = append(allMembers, cloudGroup.Ready...)
 		allMembers = append(allMembers, cloudGroup.NeedUpdate...)
-		if len(allMembers) < cloudGroup.MinSize {
+
+		// Detached instances are on their way out, so they don't count towards the group's size
+		numMembers := 0
+		for _, member := range allMembers {
+			if !member.Detached {
+				numMembers++
+			}
+		}
+		if numMembers < cloudGroup.MinSize {
 			v.addError(&ValidationError{
 				Kind: "InstanceGroup",
 				Name: cloudGroup.InstanceGroup.Name,
 				Message: fmt.Sprintf("InstanceGroup %q did not have enough nodes %d vs %d",
 					cloudGroup.InstanceGroup.Name,
-					len(allMembers),
+					numMembers,
 					cloudGroup.MinSize),
 			})
 		}
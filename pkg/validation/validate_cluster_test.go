@@ -113,7 +113,7 @@ func Test_ValidateNoPodFailures(t *testing.T) {
 				"phase": string(v1.PodSucceeded),
 			},
 		},
-	))
+	), []string{"kube-system"})
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -135,7 +135,7 @@ func Test_ValidatePodFailure(t *testing.T) {
 				"phase": string(v1.PodRunning),
 			},
 		},
-	))
+	), []string{"kube-system"})
 
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
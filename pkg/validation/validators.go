@@ -0,0 +1,414 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/apis/kops"
+)
+
+const (
+	// defaultDNSCheckImage is the image used to resolve names when the DNS check does not specify one
+	defaultDNSCheckImage = "busybox:1.28"
+	// defaultDNSCheckName is resolved when the DNS check does not specify any names
+	defaultDNSCheckName = "kubernetes.default"
+	// dnsServiceName is the service of the cluster DNS in kube-system, for both CoreDNS and kube-dns
+	dnsServiceName = "kube-dns"
+)
+
+// dnsDeploymentNames are the deployments in kube-system that may serve the cluster DNS
+var dnsDeploymentNames = []string{"coredns", "kube-dns"}
+
+var (
+	// dnsCheckTimeout is the maximum time we wait for the DNS check pod to complete
+	dnsCheckTimeout = 2 * time.Minute
+	// dnsCheckPollInterval is how often we check whether the DNS check pod has completed
+	dnsCheckPollInterval = 2 * time.Second
+)
+
+// ValidatorContext is what a Validator checks
+type ValidatorContext struct {
+	// Cluster is the cluster being validated
+	Cluster *kops.Cluster
+	// InstanceGroups are the instance groups of the cluster
+	InstanceGroups []*kops.InstanceGroup
+	// Nodes are the nodes registered in the cluster
+	Nodes []v1.Node
+	// K8sClient is a client for the cluster
+	K8sClient kubernetes.Interface
+}
+
+// Validator is a check made by ValidateCluster, in addition to the built-in node, component and pod checks
+type Validator interface {
+	// Name identifies the check
+	Name() string
+	// Validate returns the failures found by the check, or an error if the check could not be made
+	Validate(ctx *ValidatorContext) ([]*ValidationError, error)
+}
+
+var validators map[string]Validator
+var validatorsMutex sync.Mutex
+
+// RegisterValidator adds a validator that is run whenever a cluster is validated
+func RegisterValidator(validator Validator) {
+	validatorsMutex.Lock()
+	defer validatorsMutex.Unlock()
+
+	if validators == nil {
+		validators = make(map[string]Validator)
+	}
+
+	validators[validator.Name()] = validator
+}
+
+// clusterValidators returns the registered validators, followed by those configured in the cluster spec
+func clusterValidators(cluster *kops.Cluster) []Validator {
+	var result []Validator
+
+	validatorsMutex.Lock()
+	var names []string
+	for name := range validators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result = append(result, validators[name])
+	}
+	validatorsMutex.Unlock()
+
+	spec := cluster.Spec.Validation
+	if spec == nil {
+		return result
+	}
+	if len(spec.DaemonSets) != 0 {
+		result = append(result, &daemonSetsValidator{daemonSets: spec.DaemonSets})
+	}
+	if len(spec.Deployments) != 0 {
+		result = append(result, &deploymentsValidator{deployments: spec.Deployments})
+	}
+	if len(spec.FailNodeConditions) != 0 {
+		result = append(result, &nodeConditionsValidator{conditions: spec.FailNodeConditions})
+	}
+	if spec.DNS != nil {
+		result = append(result, &dnsValidator{spec: *spec.DNS})
+	}
+	return result
+}
+
+// podNamespaces returns the namespaces whose pods must all be healthy
+func podNamespaces(cluster *kops.Cluster) []string {
+	namespaces := []string{"kube-system"}
+	spec := cluster.Spec.Validation
+	if spec == nil {
+		return namespaces
+	}
+	namespaces = append(namespaces, spec.PodNamespaces...)
+
+	ignored := make(map[string]bool)
+	for _, namespace := range spec.IgnorePodNamespaces {
+		ignored[namespace] = true
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, namespace := range namespaces {
+		if ignored[namespace] || seen[namespace] {
+			continue
+		}
+		seen[namespace] = true
+		result = append(result, namespace)
+	}
+	return result
+}
+
+// daemonSetsValidator checks that DaemonSets are fully rolled out
+type daemonSetsValidator struct {
+	daemonSets []kops.ValidationResource
+}
+
+func (v *daemonSetsValidator) Name() string {
+	return "DaemonSets"
+}
+
+func (v *daemonSetsValidator) Validate(ctx *ValidatorContext) ([]*ValidationError, error) {
+	var failures []*ValidationError
+	for _, ref := range v.daemonSets {
+		name := ref.Namespace + "/" + ref.Name
+		ds, err := ctx.K8sClient.AppsV1().DaemonSets(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				failures = append(failures, &ValidationError{
+					Kind:    "DaemonSet",
+					Name:    name,
+					Message: fmt.Sprintf("daemonset %q was not found", name),
+				})
+				continue
+			}
+			return nil, fmt.Errorf("error getting daemonset %q: %v", name, err)
+		}
+
+		status := ds.Status
+		if status.ObservedGeneration < ds.Generation || status.UpdatedNumberScheduled < status.DesiredNumberScheduled || status.NumberAvailable < status.DesiredNumberScheduled {
+			failures = append(failures, &ValidationError{
+				Kind: "DaemonSet",
+				Name: name,
+				Message: fmt.Sprintf("daemonset %q is not fully rolled out (%d desired, %d updated, %d available)",
+					name, status.DesiredNumberScheduled, status.UpdatedNumberScheduled, status.NumberAvailable),
+			})
+		}
+	}
+	return failures, nil
+}
+
+// deploymentsValidator checks that Deployments have all their replicas updated and available
+type deploymentsValidator struct {
+	deployments []kops.ValidationResource
+}
+
+func (v *deploymentsValidator) Name() string {
+	return "Deployments"
+}
+
+func (v *deploymentsValidator) Validate(ctx *ValidatorContext) ([]*ValidationError, error) {
+	var failures []*ValidationError
+	for _, ref := range v.deployments {
+		name := ref.Namespace + "/" + ref.Name
+		deployment, err := ctx.K8sClient.AppsV1().Deployments(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				failures = append(failures, &ValidationError{
+					Kind:    "Deployment",
+					Name:    name,
+					Message: fmt.Sprintf("deployment %q was not found", name),
+				})
+				continue
+			}
+			return nil, fmt.Errorf("error getting deployment %q: %v", name, err)
+		}
+
+		if failure := deploymentAvailability(deployment, "Deployment"); failure != nil {
+			failures = append(failures, failure)
+		}
+	}
+	return failures, nil
+}
+
+// deploymentAvailability returns a failure of the kind if the deployment does not have all its replicas updated and available
+func deploymentAvailability(deployment *appsv1.Deployment, kind string) *ValidationError {
+	name := deployment.Namespace + "/" + deployment.Name
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	if status.ObservedGeneration < deployment.Generation || status.UpdatedReplicas < replicas || status.AvailableReplicas < replicas {
+		return &ValidationError{
+			Kind: kind,
+			Name: name,
+			Message: fmt.Sprintf("deployment %q is not available (%d desired, %d updated, %d available)",
+				name, replicas, status.UpdatedReplicas, status.AvailableReplicas),
+		}
+	}
+	return nil
+}
+
+// nodeConditionsValidator fails validation for nodes on which any of the conditions are true
+type nodeConditionsValidator struct {
+	conditions []string
+}
+
+func (v *nodeConditionsValidator) Name() string {
+	return "NodeConditions"
+}
+
+func (v *nodeConditionsValidator) Validate(ctx *ValidatorContext) ([]*ValidationError, error) {
+	var failures []*ValidationError
+	for i := range ctx.Nodes {
+		node := &ctx.Nodes[i]
+		var conditions []string
+		for _, conditionType := range v.conditions {
+			condition := findNodeCondition(node, v1.NodeConditionType(conditionType))
+			if condition != nil && condition.Status == v1.ConditionTrue {
+				conditions = append(conditions, conditionType)
+			}
+		}
+		if len(conditions) != 0 {
+			failures = append(failures, &ValidationError{
+				Kind:    "Node",
+				Name:    node.Name,
+				Message: fmt.Sprintf("node %q has condition %s", node.Name, strings.Join(conditions, ",")),
+			})
+		}
+	}
+	return failures, nil
+}
+
+// dnsValidator checks that the cluster DNS is available, and if configured that names resolve from within the cluster
+// by running nslookup in a pod
+type dnsValidator struct {
+	spec kops.ValidationDNSSpec
+}
+
+func (v *dnsValidator) Name() string {
+	return "DNS"
+}
+
+func (v *dnsValidator) Validate(ctx *ValidatorContext) ([]*ValidationError, error) {
+	failures, err := validateDNSService(ctx.K8sClient)
+	if err != nil || len(failures) != 0 {
+		return failures, err
+	}
+
+	if v.spec.ResolveNames == nil || !*v.spec.ResolveNames {
+		return nil, nil
+	}
+	return v.resolveNames(ctx)
+}
+
+// validateDNSService checks that the kube-dns service has ready endpoints, and that the CoreDNS or kube-dns
+// deployment behind it is available
+func validateDNSService(k8sClient kubernetes.Interface) ([]*ValidationError, error) {
+	var failures []*ValidationError
+
+	name := metav1.NamespaceSystem + "/" + dnsServiceName
+	endpoints, err := k8sClient.CoreV1().Endpoints(metav1.NamespaceSystem).Get(dnsServiceName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("error getting endpoints %q: %v", name, err)
+	}
+	ready := 0
+	if err == nil {
+		for _, subset := range endpoints.Subsets {
+			ready += len(subset.Addresses)
+		}
+	}
+	if ready == 0 {
+		failures = append(failures, &ValidationError{
+			Kind:    "DNS",
+			Name:    name,
+			Message: fmt.Sprintf("service %q has no ready endpoints", name),
+		})
+	}
+
+	found := false
+	for _, deploymentName := range dnsDeploymentNames {
+		deployment, err := k8sClient.AppsV1().Deployments(metav1.NamespaceSystem).Get(deploymentName, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("error getting deployment %q: %v", metav1.NamespaceSystem+"/"+deploymentName, err)
+		}
+		found = true
+		if failure := deploymentAvailability(deployment, "DNS"); failure != nil {
+			failures = append(failures, failure)
+		}
+	}
+	if !found {
+		failures = append(failures, &ValidationError{
+			Kind:    "DNS",
+			Name:    metav1.NamespaceSystem,
+			Message: fmt.Sprintf("no DNS deployment (%s) was found in %q", strings.Join(dnsDeploymentNames, " or "), metav1.NamespaceSystem),
+		})
+	}
+
+	return failures, nil
+}
+
+// resolveNames resolves the names from within the cluster, by running nslookup in a pod
+func (v *dnsValidator) resolveNames(ctx *ValidatorContext) ([]*ValidationError, error) {
+	names := v.spec.Names
+	if len(names) == 0 {
+		names = []string{defaultDNSCheckName}
+	}
+	image := v.spec.Image
+	if image == "" {
+		image = defaultDNSCheckImage
+	}
+
+	var commands []string
+	for _, name := range names {
+		commands = append(commands, "nslookup "+name)
+	}
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "kops-validate-dns-",
+			Namespace:    metav1.NamespaceSystem,
+		},
+		Spec: v1.PodSpec{
+			RestartPolicy: v1.RestartPolicyNever,
+			Containers: []v1.Container{
+				{
+					Name:    "nslookup",
+					Image:   image,
+					Command: []string{"sh", "-c", strings.Join(commands, " && ")},
+				},
+			},
+			Tolerations: []v1.Toleration{
+				{Key: "CriticalAddonsOnly", Operator: v1.TolerationOpExists},
+			},
+		},
+	}
+
+	pods := ctx.K8sClient.CoreV1().Pods(metav1.NamespaceSystem)
+	pod, err := pods.Create(pod)
+	if err != nil {
+		return nil, fmt.Errorf("error creating dns check pod: %v", err)
+	}
+	defer func() {
+		if err := pods.Delete(pod.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			klog.Warningf("error deleting dns check pod %q: %v", pod.Name, err)
+		}
+	}()
+
+	failure := &ValidationError{
+		Kind: "DNS",
+		Name: strings.Join(names, ","),
+	}
+
+	deadline := time.Now().Add(dnsCheckTimeout)
+	for {
+		p, err := pods.Get(pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error getting dns check pod %q: %v", pod.Name, err)
+		}
+		switch p.Status.Phase {
+		case v1.PodSucceeded:
+			return nil, nil
+		case v1.PodFailed:
+			failure.Message = fmt.Sprintf("unable to resolve %s from within the cluster", strings.Join(names, ", "))
+			return []*ValidationError{failure}, nil
+		}
+
+		if time.Now().After(deadline) {
+			failure.Message = fmt.Sprintf("dns check pod %q did not complete within %v", pod.Name, dnsCheckTimeout)
+			return []*ValidationError{failure}, nil
+		}
+		time.Sleep(dnsCheckPollInterval)
+	}
+}
@@ -0,0 +1,296 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	kopsapi "k8s.io/kops/pkg/apis/kops"
+)
+
+func init() {
+	dnsCheckPollInterval = time.Millisecond
+}
+
+func failureNames(failures []*ValidationError) []string {
+	var names []string
+	for _, f := range failures {
+		names = append(names, f.Kind+" "+f.Name)
+	}
+	return names
+}
+
+func Test_PodNamespaces(t *testing.T) {
+	grid := []struct {
+		validation *kopsapi.ClusterValidationSpec
+		expected   []string
+	}{
+		{
+			expected: []string{"kube-system"},
+		},
+		{
+			validation: &kopsapi.ClusterValidationSpec{PodNamespaces: []string{"ingress", "kube-system"}},
+			expected:   []string{"kube-system", "ingress"},
+		},
+		{
+			validation: &kopsapi.ClusterValidationSpec{PodNamespaces: []string{"ingress"}, IgnorePodNamespaces: []string{"kube-system"}},
+			expected:   []string{"ingress"},
+		},
+	}
+	for _, g := range grid {
+		cluster := &kopsapi.Cluster{}
+		cluster.Spec.Validation = g.validation
+		if actual := podNamespaces(cluster); !reflect.DeepEqual(actual, g.expected) {
+			t.Errorf("expected pod namespaces %v, got %v", g.expected, actual)
+		}
+	}
+}
+
+func Test_ValidateDaemonSetsAndDeployments(t *testing.T) {
+	replicas := int32(2)
+	k8sClient := fake.NewSimpleClientset(
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "rolled-out", Generation: 2},
+			Status:     appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3},
+		},
+		&appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "rolling", Generation: 2},
+			Status:     appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 1, NumberAvailable: 3},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "available", Generation: 1},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, UpdatedReplicas: 2, AvailableReplicas: 2},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "unavailable", Generation: 1},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, UpdatedReplicas: 2, AvailableReplicas: 1},
+		},
+	)
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Spec.Validation = &kopsapi.ClusterValidationSpec{
+		DaemonSets: []kopsapi.ValidationResource{
+			{Namespace: "kube-system", Name: "rolled-out"},
+			{Namespace: "kube-system", Name: "rolling"},
+			{Namespace: "kube-system", Name: "missing"},
+		},
+		Deployments: []kopsapi.ValidationResource{
+			{Namespace: "default", Name: "available"},
+			{Namespace: "default", Name: "unavailable"},
+		},
+	}
+
+	v := &ValidationCluster{}
+	if err := v.runValidators(clusterValidators(cluster), &ValidatorContext{Cluster: cluster, K8sClient: k8sClient}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"DaemonSet kube-system/rolling", "DaemonSet kube-system/missing", "Deployment default/unavailable"}
+	if actual := failureNames(v.Failures); !reflect.DeepEqual(actual, expected) {
+		printDebug(t, v)
+		t.Errorf("expected failures %v, got %v", expected, actual)
+	}
+}
+
+func Test_ValidateNodeConditions(t *testing.T) {
+	nodes := []v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "healthy"},
+			Status: v1.NodeStatus{
+				Conditions: []v1.NodeCondition{
+					{Type: v1.NodeMemoryPressure, Status: v1.ConditionFalse},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pressured"},
+			Status: v1.NodeStatus{
+				Conditions: []v1.NodeCondition{
+					{Type: v1.NodeMemoryPressure, Status: v1.ConditionTrue},
+					{Type: v1.NodeDiskPressure, Status: v1.ConditionTrue},
+				},
+			},
+		},
+	}
+
+	validator := &nodeConditionsValidator{conditions: []string{"MemoryPressure", "DiskPressure"}}
+	failures, err := validator.Validate(&ValidatorContext{Nodes: nodes})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(failures) != 1 || failures[0].Name != "pressured" || failures[0].Message != `node "pressured" has condition MemoryPressure,DiskPressure` {
+		t.Errorf("expected a single failure for node pressured, got %v", failureNames(failures))
+	}
+}
+
+// dnsObjects returns the kube-dns endpoints and the CoreDNS deployment of a cluster whose DNS is available
+func dnsObjects() []runtime.Object {
+	replicas := int32(2)
+	return []runtime.Object{
+		&v1.Endpoints{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "kube-dns"},
+			Subsets: []v1.EndpointSubset{
+				{Addresses: []v1.EndpointAddress{{IP: "100.96.1.2"}, {IP: "100.96.2.2"}}},
+			},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "coredns"},
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			Status:     appsv1.DeploymentStatus{UpdatedReplicas: 2, AvailableReplicas: 2},
+		},
+	}
+}
+
+func Test_ValidateDNSService(t *testing.T) {
+	replicas := int32(2)
+	grid := []struct {
+		description string
+		objects     []runtime.Object
+		expected    []string
+	}{
+		{
+			description: "available",
+			objects:     dnsObjects(),
+		},
+		{
+			description: "no endpoints or deployment",
+			expected:    []string{"DNS kube-system/kube-dns", "DNS kube-system"},
+		},
+		{
+			description: "no ready endpoints and kube-dns unavailable",
+			objects: []runtime.Object{
+				&v1.Endpoints{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "kube-dns"},
+					Subsets: []v1.EndpointSubset{
+						{NotReadyAddresses: []v1.EndpointAddress{{IP: "100.96.1.2"}}},
+					},
+				},
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "kube-dns"},
+					Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+					Status:     appsv1.DeploymentStatus{UpdatedReplicas: 2, AvailableReplicas: 1},
+				},
+			},
+			expected: []string{"DNS kube-system/kube-dns", "DNS kube-system/kube-dns"},
+		},
+	}
+
+	for _, g := range grid {
+		k8sClient := fake.NewSimpleClientset(g.objects...)
+		validator := &dnsValidator{}
+		failures, err := validator.Validate(&ValidatorContext{K8sClient: k8sClient})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", g.description, err)
+		}
+		if names := failureNames(failures); !reflect.DeepEqual(names, g.expected) {
+			t.Errorf("%s: expected failures %v, got %v", g.description, g.expected, names)
+		}
+
+		// No pod is created unless names are resolved
+		for _, action := range k8sClient.Actions() {
+			if action.GetVerb() == "create" {
+				t.Errorf("%s: unexpected create of %s", g.description, action.GetResource().Resource)
+			}
+		}
+	}
+}
+
+func Test_ValidateDNS(t *testing.T) {
+	for _, phase := range []v1.PodPhase{v1.PodSucceeded, v1.PodFailed} {
+		k8sClient := fake.NewSimpleClientset(dnsObjects()...)
+
+		var command []string
+		k8sClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			pod := action.(k8stesting.CreateAction).GetObject().(*v1.Pod)
+			command = pod.Spec.Containers[0].Command
+			pod.Name = "kops-validate-dns-abcde"
+			return false, nil, nil
+		})
+		k8sClient.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: action.(k8stesting.GetAction).GetName()},
+				Status:     v1.PodStatus{Phase: phase},
+			}
+			return true, pod, nil
+		})
+
+		resolveNames := true
+		validator := &dnsValidator{spec: kopsapi.ValidationDNSSpec{ResolveNames: &resolveNames, Names: []string{"kubernetes.default", "example.com"}}}
+		failures, err := validator.Validate(&ValidatorContext{K8sClient: k8sClient})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if expected := []string{"sh", "-c", "nslookup kubernetes.default && nslookup example.com"}; !reflect.DeepEqual(command, expected) {
+			t.Errorf("expected command %v, got %v", expected, command)
+		}
+		if phase == v1.PodSucceeded && len(failures) != 0 {
+			t.Errorf("expected no failures when names resolve, got %v", failureNames(failures))
+		}
+		if phase == v1.PodFailed && len(failures) != 1 {
+			t.Errorf("expected a failure when names do not resolve, got %v", failureNames(failures))
+		}
+
+		pods, err := k8sClient.CoreV1().Pods("kube-system").List(metav1.ListOptions{})
+		if err != nil {
+			t.Fatalf("error listing pods: %v", err)
+		}
+		if len(pods.Items) != 0 {
+			t.Errorf("expected the dns check pod to be deleted")
+		}
+	}
+}
+
+type testValidator struct{}
+
+func (v *testValidator) Name() string {
+	return "Test"
+}
+
+func (v *testValidator) Validate(ctx *ValidatorContext) ([]*ValidationError, error) {
+	return []*ValidationError{{Kind: "Test", Name: ctx.Cluster.Name, Message: "test failure"}}, nil
+}
+
+func Test_RegisterValidator(t *testing.T) {
+	RegisterValidator(&testValidator{})
+	defer func() {
+		validatorsMutex.Lock()
+		defer validatorsMutex.Unlock()
+		delete(validators, "Test")
+	}()
+
+	cluster := &kopsapi.Cluster{}
+	cluster.Name = "test.k8s.local"
+
+	v := &ValidationCluster{}
+	if err := v.runValidators(clusterValidators(cluster), &ValidatorContext{Cluster: cluster}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"Test test.k8s.local"}; !reflect.DeepEqual(failureNames(v.Failures), expected) {
+		t.Errorf("expected failures %v, got %v", expected, failureNames(v.Failures))
+	}
+}
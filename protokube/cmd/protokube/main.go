@@ -90,6 +90,8 @@ func run() error {
 	flags.StringVar(&dnsProviderID, "dns", "aws-route53", "DNS provider we should use (aws-route53, google-clouddns, coredns, digitalocean)")
 	flags.StringVar(&etcdBackupImage, "etcd-backup-image", "", "Set to override the image for (experimental) etcd backups")
 	flags.StringVar(&etcdBackupStore, "etcd-backup-store", "", "Set to enable (experimental) etcd backups")
+	var etcdBackupRetention []string
+	flags.StringArrayVar(&etcdBackupRetention, "etcd-backup-retention", nil, "Delete etcd backups not kept by the retention policy, as <policy>@<backup store>, e.g. hourly=24,daily=7,weekly=4@s3://bucket/backups/etcd/main; may be repeated")
 	flags.StringVar(&etcdImageSource, "etcd-image", "k8s.gcr.io/etcd:2.2.1", "Etcd Source Container Registry")
 	flags.StringVar(&etcdElectionTimeout, "etcd-election-timeout", etcdElectionTimeout, "time in ms for an election to timeout")
 	flags.StringVar(&etcdHeartbeatInterval, "etcd-heartbeat-interval", etcdHeartbeatInterval, "time in ms of a heartbeat interval")
@@ -386,6 +388,15 @@ func run() error {
 		channels = strings.Split(flagChannels, ",")
 	}
 
+	var etcdBackupRetentions []*protokube.EtcdBackupRetention
+	for _, s := range etcdBackupRetention {
+		r, err := protokube.ParseEtcdBackupRetention(s)
+		if err != nil {
+			return err
+		}
+		etcdBackupRetentions = append(etcdBackupRetentions, r)
+	}
+
 	k := &protokube.KubeBoot{
 		ApplyTaints:           applyTaints,
 		Channels:              channels,
@@ -393,6 +404,7 @@ func run() error {
 		ManageEtcd:            manageEtcd,
 		EtcdBackupImage:       etcdBackupImage,
 		EtcdBackupStore:       etcdBackupStore,
+		EtcdBackupRetention:   etcdBackupRetentions,
 		EtcdImageSource:       etcdImageSource,
 		EtcdElectionTimeout:   etcdElectionTimeout,
 		EtcdHeartbeatInterval: etcdHeartbeatInterval,
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package protokube
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/etcdbackup"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// etcdBackupPruneInterval is how often we delete expired etcd backups
+const etcdBackupPruneInterval = time.Hour
+
+// EtcdBackupRetention is the retention policy for the backups in an etcd backup store
+type EtcdBackupRetention struct {
+	// Store is the backup store
+	Store vfs.Path
+	// Policy decides which backups are kept
+	Policy etcdbackup.RetentionPolicy
+}
+
+// ParseEtcdBackupRetention parses a retention of the form <policy>@<store>, e.g. hourly=24,daily=7@s3://bucket/backups/etcd/main
+func ParseEtcdBackupRetention(s string) (*EtcdBackupRetention, error) {
+	tokens := strings.SplitN(s, "@", 2)
+	if len(tokens) != 2 || tokens[1] == "" {
+		return nil, fmt.Errorf("invalid etcd backup retention %q, expected <policy>@<store>", s)
+	}
+
+	policy, err := etcdbackup.ParseRetentionPolicy(tokens[0])
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := vfs.Context.BuildVfsPath(tokens[1])
+	if err != nil {
+		return nil, fmt.Errorf("error parsing etcd backup store %q: %v", tokens[1], err)
+	}
+
+	return &EtcdBackupRetention{Store: store, Policy: policy}, nil
+}
+
+// pruneEtcdBackups deletes the expired backups from each backup store, at most once per etcdBackupPruneInterval
+func (k *KubeBoot) pruneEtcdBackups() {
+	if len(k.EtcdBackupRetention) == 0 {
+		return
+	}
+	if time.Since(k.lastEtcdBackupPrune) < etcdBackupPruneInterval {
+		return
+	}
+	k.lastEtcdBackupPrune = time.Now()
+
+	for _, r := range k.EtcdBackupRetention {
+		deleted, err := etcdbackup.Prune(r.Store, r.Policy)
+		if err != nil {
+			klog.Warningf("error pruning etcd backups in %s: %v", r.Store, err)
+			continue
+		}
+		klog.V(2).Infof("deleted %d expired etcd backups from %s", len(deleted), r.Store)
+	}
+}
@@ -61,6 +61,8 @@ type KubeBoot struct {
 	EtcdBackupImage string
 	// EtcdBackupStore is the VFS path to which we should backup etcd
 	EtcdBackupStore string
+	// EtcdBackupRetention are the backup stores from which we delete expired etcd backups
+	EtcdBackupRetention []*EtcdBackupRetention
 	// Etcd container registry location.
 	EtcdImageSource string
 	// EtcdElectionTimeout is the leader election timeout
@@ -84,6 +86,9 @@ type KubeBoot struct {
 
 	volumeMounter   *VolumeMountController
 	etcdControllers map[string]*EtcdController
+
+	// lastEtcdBackupPrune is when we last deleted expired etcd backups
+	lastEtcdBackupPrune time.Time
 }
 
 // Init is responsible for initializing the controllers
@@ -133,6 +138,10 @@ func (k *KubeBoot) syncOnce() error {
 		klog.V(4).Infof("protokube management of etcd not enabled; won't scan for volumes")
 	}
 
+	if k.Master {
+		k.pruneEtcdBackups()
+	}
+
 	// Ensure kubelet is running. We avoid doing this automatically so
 	// that when kubelet comes up the first time, all volume mounts
 	// and DNS are available, avoiding the scenario where
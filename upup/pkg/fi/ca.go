@@ -23,6 +23,7 @@ import (
 
 	"k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/pki"
+	"k8s.io/kops/pkg/statehistory"
 	"k8s.io/kops/util/pkg/vfs"
 )
 
@@ -70,6 +71,14 @@ type HasVFSPath interface {
 	VFSPath() vfs.Path
 }
 
+// HasHistory is implemented by keystore & other stores that keep the prior versions of the objects they hold
+type HasHistory interface {
+	// History returns the prior versions of the named object, newest first
+	History(name string) ([]*statehistory.Version, error)
+	// Rollback restores the named object to a prior version; the object as it was before is recorded as a new version
+	Rollback(name string, id string) error
+}
+
 type CAStore interface {
 	Keystore
 
@@ -23,6 +23,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"math/big"
+	"strings"
 	"sync"
 	"time"
 
@@ -318,6 +319,9 @@ func (c *ClientsetCAStore) ListKeysets() ([]*kops.Keyset, error) {
 
 		for i := range list.Items {
 			keyset := &list.Items[i]
+			if strings.HasPrefix(keyset.Name, KeysetHistoryNamePrefix) {
+				continue // The prior versions of keysets and secrets
+			}
 			switch keyset.Spec.Type {
 			case kops.SecretTypeKeypair:
 				items = append(items, &list.Items[i])
@@ -371,12 +375,21 @@ func (c *ClientsetCAStore) IssueCert(signer string, name string, serial *big.Int
 	if caKeyset.primary.certificate == nil {
 		return nil, fmt.Errorf("ca certificate was not found; cannot issue certificates")
 	}
-	if caKeyset.primary.privateKey == nil {
-		return nil, fmt.Errorf("ca privateKey was not found; cannot issue certificates")
-	}
-	cert, err := pki.SignNewCertificate(privateKey, template, caKeyset.primary.certificate.Certificate, caKeyset.primary.privateKey)
-	if err != nil {
-		return nil, err
+
+	var cert *pki.Certificate
+	if UsesExternalCA(c.cluster, signer) {
+		cert, err = IssueExternalCert(c.cluster, caKeyset.primary.certificate, privateKey, template)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if caKeyset.primary.privateKey == nil {
+			return nil, fmt.Errorf("ca privateKey was not found; cannot issue certificates")
+		}
+		cert, err = pki.SignNewCertificate(privateKey, template, caKeyset.primary.certificate.Certificate, caKeyset.primary.privateKey)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if _, err := c.storeAndVerifyKeypair(name, cert, privateKey); err != nil {
@@ -587,8 +600,10 @@ func (c *ClientsetCAStore) storeKeypair(name string, id string, cert *pki.Certif
 	}
 
 	var privateMaterial bytes.Buffer
-	if _, err := privateKey.WriteTo(&privateMaterial); err != nil {
-		return err
+	if privateKey != nil {
+		if _, err := privateKey.WriteTo(&privateMaterial); err != nil {
+			return err
+		}
 	}
 
 	item := &kops.KeysetItem{
@@ -596,6 +611,9 @@ func (c *ClientsetCAStore) storeKeypair(name string, id string, cert *pki.Certif
 		PublicMaterial:  publicMaterial.Bytes(),
 		PrivateMaterial: privateMaterial.Bytes(),
 	}
+	if err := c.recordVersion(name); err != nil {
+		return err
+	}
 	return c.addKey(name, kops.SecretTypeKeypair, item)
 }
 
@@ -642,6 +660,9 @@ func (c *ClientsetCAStore) FindSSHPublicKeys(name string) ([]*kops.SSHCredential
 func (c *ClientsetCAStore) DeleteKeysetItem(item *kops.Keyset, id string) error {
 	switch item.Spec.Type {
 	case kops.SecretTypeKeypair:
+		if err := c.recordVersion(item.Name); err != nil {
+			return err
+		}
 		client := c.clientset.Keysets(c.namespace)
 		return DeleteKeysetItem(client, item.Name, kops.SecretTypeKeypair, id)
 	default:
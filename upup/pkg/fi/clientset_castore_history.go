@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/statehistory"
+)
+
+var _ HasHistory = &ClientsetCAStore{}
+
+// recordVersion records the current items of the keyset in the history, if the keyset exists.
+// The prior versions of a keyset are kept as the items of a keyset named with KeysetHistoryNamePrefix;
+// each version is the serialized spec of the keyset.
+func (c *ClientsetCAStore) recordVersion(name string) error {
+	depth := statehistory.Depth(c.cluster)
+	if depth <= 0 {
+		return nil
+	}
+
+	client := c.clientset.Keysets(c.namespace)
+	o, err := client.Get(name, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("error reading keyset %q: %v", name, err)
+	}
+	data, err := json.Marshal(&o.Spec)
+	if err != nil {
+		return fmt.Errorf("error serializing keyset %q: %v", name, err)
+	}
+
+	if err := RecordKeysetHistory(client, KeysetHistoryNamePrefix+name, kops.SecretTypeKeypair, depth, data); err != nil {
+		return fmt.Errorf("error recording prior version of keyset %q: %v", name, err)
+	}
+	return nil
+}
+
+// History implements HasHistory::History
+func (c *ClientsetCAStore) History(name string) ([]*statehistory.Version, error) {
+	return KeysetHistory(c.clientset.Keysets(c.namespace), KeysetHistoryNamePrefix+name)
+}
+
+// Rollback implements HasHistory::Rollback
+func (c *ClientsetCAStore) Rollback(name string, id string) error {
+	versions, err := c.History(name)
+	if err != nil {
+		return err
+	}
+
+	var version *statehistory.Version
+	for _, v := range versions {
+		if v.ID == id {
+			version = v
+		}
+	}
+	if version == nil {
+		return fmt.Errorf("version %s of keyset %q not found", id, name)
+	}
+
+	spec := kops.KeysetSpec{}
+	if err := json.Unmarshal(version.Data, &spec); err != nil {
+		return fmt.Errorf("error parsing version %s of keyset %q: %v", id, name, err)
+	}
+
+	if err := c.recordVersion(name); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	delete(c.cachedCaKeysets, name)
+	c.mutex.Unlock()
+
+	client := c.clientset.Keysets(c.namespace)
+	o, err := client.Get(name, v1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("error reading keyset %q: %v", name, err)
+		}
+		// The keyset was deleted; it must be created again
+		o = &kops.Keyset{}
+		o.Name = name
+		o.Spec = spec
+		if _, err := client.Create(o); err != nil {
+			return fmt.Errorf("error creating keyset %q: %v", name, err)
+		}
+		return nil
+	}
+
+	o.Spec = spec
+	if _, err := client.Update(o); err != nil {
+		return fmt.Errorf("error updating keyset %q: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/client/clientset_generated/clientset/fake"
+	"k8s.io/kops/pkg/pki"
+)
+
+func TestClientsetCAStoreHistory(t *testing.T) {
+	depth := int32(10)
+	cluster := &kops.Cluster{}
+	cluster.Spec.SecretHistoryDepth = &depth
+
+	clientset := fake.NewSimpleClientset().Kops()
+	store := NewClientsetCAStore(cluster, clientset, "default").(*ClientsetCAStore)
+
+	privateKey, err := pki.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("error generating private key: %v", err)
+	}
+	for _, serial := range []int64{1, 2} {
+		template := &x509.Certificate{
+			Subject:      pkix.Name{CommonName: "kubernetes"},
+			SerialNumber: big.NewInt(serial),
+			IsCA:         true,
+		}
+		cert, err := pki.SignNewCertificate(privateKey, template, nil, nil)
+		if err != nil {
+			t.Fatalf("error signing certificate: %v", err)
+		}
+		if err := store.StoreKeypair("ca", cert, privateKey); err != nil {
+			t.Fatalf("error storing keypair: %v", err)
+		}
+	}
+
+	keyset, err := store.FindCertificateKeyset("ca")
+	if err != nil {
+		t.Fatalf("error reading keyset: %v", err)
+	}
+	if err := store.DeleteKeysetItem(keyset, "1"); err != nil {
+		t.Fatalf("error deleting keyset item: %v", err)
+	}
+
+	versions, err := store.History("ca")
+	if err != nil {
+		t.Fatalf("error listing history: %v", err)
+	}
+	if len(versions) != 2 || versions[0].ID != "2" || versions[1].ID != "1" {
+		t.Fatalf("expected versions 2 and 1 to be recorded, got %v", versions)
+	}
+
+	keysets, err := store.ListKeysets()
+	if err != nil {
+		t.Fatalf("error listing keysets: %v", err)
+	}
+	if len(keysets) != 1 || keysets[0].Name != "ca" {
+		t.Errorf("expected only the ca keyset to be listed, got %v", keysets)
+	}
+
+	// Version 2 was recorded before the first certificate was deleted
+	if err := store.Rollback("ca", "2"); err != nil {
+		t.Fatalf("error rolling back keyset: %v", err)
+	}
+	keyset, err = clientset.Keysets("default").Get("ca", v1.GetOptions{})
+	if err != nil {
+		t.Fatalf("error reading keyset: %v", err)
+	}
+	var ids []string
+	for _, item := range keyset.Spec.Keys {
+		ids = append(ids, item.Id)
+	}
+	if len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+		t.Errorf("expected the keyset to be rolled back to items 1 and 2, got %v", ids)
+	}
+
+	versions, err = store.History("ca")
+	if err != nil {
+		t.Fatalf("error listing history: %v", err)
+	}
+	if len(versions) != 3 || versions[0].ID != "3" {
+		t.Errorf("expected the keyset before the rollback to be recorded as version 3, got %v", versions)
+	}
+}
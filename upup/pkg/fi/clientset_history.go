@@ -0,0 +1,136 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/apis/kops"
+	kopsinternalversion "k8s.io/kops/pkg/client/clientset_generated/clientset/typed/kops/internalversion"
+	"k8s.io/kops/pkg/statehistory"
+)
+
+// KeysetHistoryNamePrefix is the prefix of the keysets holding the prior versions of objects stored as keysets in an API server.
+// The history of the keyset named <name> is held in the keyset named KeysetHistoryNamePrefix + <name>.
+const KeysetHistoryNamePrefix = "history."
+
+// RecordKeysetHistory records data as the newest version in the history keyset historyName, keeping at most depth versions.
+// Each version is held as an item of the history keyset, with the version number as its id.
+func RecordKeysetHistory(client kopsinternalversion.KeysetInterface, historyName string, keysetType kops.KeysetType, depth int, data []byte) error {
+	if depth <= 0 {
+		return nil
+	}
+
+	history, err := client.Get(historyName, v1.GetOptions{})
+	create := false
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("error reading history %q: %v", historyName, err)
+		}
+		create = true
+		history = &kops.Keyset{}
+		history.Name = historyName
+		history.Spec.Type = keysetType
+	}
+
+	versions, err := parseKeysetHistory(history)
+	if err != nil {
+		return err
+	}
+	next := 1
+	if len(versions) != 0 {
+		newest, _ := strconv.Atoi(versions[0].ID)
+		next = newest + 1
+	}
+
+	version := &statehistory.Version{
+		ID:        strconv.Itoa(next),
+		Timestamp: time.Now().UTC(),
+		Author:    statehistory.Author(),
+		Data:      data,
+	}
+	versions = append([]*statehistory.Version{version}, versions...)
+	if len(versions) > depth {
+		versions = versions[:depth]
+	}
+
+	history.Spec.Keys = nil
+	for _, v := range versions {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("error serializing version %s in %q: %v", v.ID, historyName, err)
+		}
+		history.Spec.Keys = append(history.Spec.Keys, kops.KeysetItem{
+			Id:              v.ID,
+			PrivateMaterial: b,
+		})
+	}
+
+	if create {
+		_, err = client.Create(history)
+	} else {
+		_, err = client.Update(history)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing history %q: %v", historyName, err)
+	}
+	return nil
+}
+
+// KeysetHistory returns the versions held in the history keyset historyName, newest first
+func KeysetHistory(client kopsinternalversion.KeysetInterface, historyName string) ([]*statehistory.Version, error) {
+	history, err := client.Get(historyName, v1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading history %q: %v", historyName, err)
+	}
+	return parseKeysetHistory(history)
+}
+
+// parseKeysetHistory returns the versions held in a history keyset, newest first
+func parseKeysetHistory(history *kops.Keyset) ([]*statehistory.Version, error) {
+	var versions []*statehistory.Version
+	for i := range history.Spec.Keys {
+		item := &history.Spec.Keys[i]
+		if _, err := strconv.Atoi(item.Id); err != nil {
+			klog.V(2).Infof("ignoring unexpected item in history %q: %q", history.Name, item.Id)
+			continue
+		}
+		version := &statehistory.Version{}
+		if err := json.Unmarshal(item.PrivateMaterial, version); err != nil {
+			return nil, fmt.Errorf("error parsing version %s in %q: %v", item.Id, history.Name, err)
+		}
+		version.ID = item.Id
+		versions = append(versions, version)
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		a, _ := strconv.Atoi(versions[i].ID)
+		b, _ := strconv.Atoi(versions[j].ID)
+		return a > b
+	})
+	return versions, nil
+}
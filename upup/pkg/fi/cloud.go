@@ -34,6 +34,10 @@ type Cloud interface {
 	// DeleteInstance deletes a cloud instance
 	DeleteInstance(instance *cloudinstances.CloudInstanceGroupMember) error
 
+	// DetachInstance causes a cloud instance to no longer be counted against the group's size limits,
+	// so that the cloud launches a replacement for it
+	DetachInstance(instance *cloudinstances.CloudInstanceGroupMember) error
+
 	// DeleteGroup deletes the cloud resources that make up a CloudInstanceGroup, including the instances
 	DeleteGroup(group *cloudinstances.CloudInstanceGroup) error
 
@@ -18,6 +18,7 @@ package cloudup
 
 import (
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"path"
@@ -30,6 +31,7 @@ import (
 	"k8s.io/klog"
 	kopsbase "k8s.io/kops"
 	"k8s.io/kops/pkg/apis/kops"
+	apimodel "k8s.io/kops/pkg/apis/kops/model"
 	"k8s.io/kops/pkg/apis/kops/registry"
 	"k8s.io/kops/pkg/apis/kops/util"
 	"k8s.io/kops/pkg/apis/kops/validation"
@@ -65,11 +67,13 @@ import (
 	"k8s.io/kops/upup/pkg/fi/cloudup/gcetasks"
 	"k8s.io/kops/upup/pkg/fi/cloudup/openstack"
 	"k8s.io/kops/upup/pkg/fi/cloudup/openstacktasks"
+	"k8s.io/kops/upup/pkg/fi/cloudup/resourcegraph"
 	"k8s.io/kops/upup/pkg/fi/cloudup/spotinsttasks"
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
 	"k8s.io/kops/upup/pkg/fi/cloudup/vsphere"
 	"k8s.io/kops/upup/pkg/fi/cloudup/vspheretasks"
 	"k8s.io/kops/upup/pkg/fi/fitasks"
+	"k8s.io/kops/util/pkg/architectures"
 	"k8s.io/kops/util/pkg/hashing"
 	"k8s.io/kops/util/pkg/vfs"
 )
@@ -100,11 +104,11 @@ type ApplyClusterCmd struct {
 
 	InstanceGroups []*kops.InstanceGroup
 
-	// NodeUpSource is the location from which we download nodeup
-	NodeUpSource string
+	// NodeUpSource is the location from which we download nodeup, for each architecture
+	NodeUpSource map[architectures.Architecture]string
 
-	// NodeUpHash is the sha hash
-	NodeUpHash string
+	// NodeUpHash is the sha hash of nodeup, for each architecture
+	NodeUpHash map[architectures.Architecture]string
 
 	// Models is a list of cloudup models to apply
 	Models []string
@@ -118,17 +122,23 @@ type ApplyClusterCmd struct {
 	// OutDir is a local directory in which we place output, can cache files etc
 	OutDir string
 
-	// Assets is a list of sources for files (primarily when not using everything containerized)
+	// Assets is a list of sources for files (primarily when not using everything containerized), for each architecture
 	// Formats:
 	//  raw url: http://... or https://...
 	//  url with hash: <hex>@http://... or <hex>@https://...
-	Assets []*MirroredAsset
+	Assets map[architectures.Architecture][]*MirroredAsset
+
+	// ContainerdAssets is a list of sources for the containerd files, which are only added to instance groups using containerd
+	ContainerdAssets []*MirroredAsset
 
 	Clientset simple.Clientset
 
 	// DryRun is true if this is only a dry run
 	DryRun bool
 
+	// DryRunOutput is where the dry-run report is written; defaults to stdout
+	DryRunOutput io.Writer
+
 	// RunTasksOptions defines parameters for task execution, e.g. retry interval
 	RunTasksOptions *fi.RunTasksOptions
 
@@ -454,6 +464,10 @@ func (c *ApplyClusterCmd) Run() error {
 			}
 
 			l.TemplateFunctions["MachineTypeInfo"] = awsup.GetMachineTypeInfo
+
+			if err := validateImageArchitectures(awsCloud, cluster, c.InstanceGroups); err != nil {
+				return err
+			}
 		}
 
 	case kops.CloudProviderALI:
@@ -886,8 +900,20 @@ func (c *ApplyClusterCmd) Run() error {
 		// Can cause conflicts with cloudformation management
 		shouldPrecreateDNS = false
 
+	case TargetResourceGraph:
+		checkExisting = false
+		outDir := c.OutDir
+		target = resourcegraph.NewResourceGraphTarget(cloud, region, project, outDir)
+
+		// The graph describes the resources to be created by other tooling
+		shouldPrecreateDNS = false
+
 	case TargetDryRun:
-		target = fi.NewDryRunTarget(assetBuilder, os.Stdout)
+		out := c.DryRunOutput
+		if out == nil {
+			out = os.Stdout
+		}
+		target = fi.NewDryRunTarget(assetBuilder, out)
 		dryRun = true
 
 		// Avoid making changes on a dry-run
@@ -917,6 +943,11 @@ func (c *ApplyClusterCmd) Run() error {
 			if err := vfsMirror.WriteMirror(g); err != nil {
 				return fmt.Errorf("error writing instance group spec to mirror: %v", err)
 			}
+
+			// nodeup reads the published instance group, so that edits only reach the nodes through kops update cluster
+			if err := registry.WriteConfigDeprecated(cluster, registry.InstanceGroupCompletedPath(configBase, g.ObjectMeta.Name), g); err != nil {
+				return fmt.Errorf("error writing completed instance group spec: %v", err)
+			}
 		}
 	}
 
@@ -1115,9 +1146,45 @@ func (c *ApplyClusterCmd) AddFileAssets(assetBuilder *assets.AssetBuilder) error
 		baseURL = "https://storage.googleapis.com/kubernetes-release/release/v" + c.Cluster.Spec.KubernetesVersion
 	}
 
+	archs, err := c.instanceGroupArchitectures()
+	if err != nil {
+		return err
+	}
+
+	c.Assets = make(map[architectures.Architecture][]*MirroredAsset)
+	c.NodeUpSource = make(map[architectures.Architecture]string)
+	c.NodeUpHash = make(map[architectures.Architecture]string)
+	for _, arch := range archs {
+		if err := c.addArchitectureFileAssets(assetBuilder, baseURL, arch); err != nil {
+			return err
+		}
+	}
+
+	if apimodel.UsesContainerd(c.Cluster, c.InstanceGroups) {
+		containerdAsset, containerdAssetHash, err := findContainerdAsset(c.Cluster, assetBuilder)
+		if err != nil {
+			return err
+		}
+
+		c.ContainerdAssets = append(c.ContainerdAssets, BuildMirroredAsset(containerdAsset, containerdAssetHash))
+	}
+
+	// Explicitly add the protokube image,
+	// otherwise when the Target is DryRun this asset is not added
+	// Is there a better way to call this?
+	_, _, err = ProtokubeImageSource(assetBuilder)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// addArchitectureFileAssets adds the file assets for the instance groups with the architecture
+func (c *ApplyClusterCmd) addArchitectureFileAssets(assetBuilder *assets.AssetBuilder, baseURL string, arch architectures.Architecture) error {
 	k8sAssetsNames := []string{
-		"/bin/linux/amd64/kubelet",
-		"/bin/linux/amd64/kubectl",
+		"/bin/linux/" + string(arch) + "/kubelet",
+		"/bin/linux/" + string(arch) + "/kubectl",
 	}
 	if needsMounterAsset(c.Cluster, c.InstanceGroups) {
 		k8sVersion, err := util.ParseKubernetesVersion(c.Cluster.Spec.KubernetesVersion)
@@ -1125,7 +1192,7 @@ func (c *ApplyClusterCmd) AddFileAssets(assetBuilder *assets.AssetBuilder) error
 			return fmt.Errorf("unable to determine kubernetes version from %q", c.Cluster.Spec.KubernetesVersion)
 		} else if util.IsKubernetesGTE("1.9", *k8sVersion) {
 			// Available directly
-			k8sAssetsNames = append(k8sAssetsNames, "/bin/linux/amd64/mounter")
+			k8sAssetsNames = append(k8sAssetsNames, "/bin/linux/"+string(arch)+"/mounter")
 		} else {
 			// Only available in the kubernetes-manifests.tar.gz directory
 			k8sAssetsNames = append(k8sAssetsNames, "/kubernetes-manifests.tar.gz")
@@ -1143,20 +1210,22 @@ func (c *ApplyClusterCmd) AddFileAssets(assetBuilder *assets.AssetBuilder) error
 		if err != nil {
 			return err
 		}
-		c.Assets = append(c.Assets, BuildMirroredAsset(u, hash))
+		c.Assets[arch] = append(c.Assets[arch], BuildMirroredAsset(u, hash))
 	}
 
 	if usesCNI(c.Cluster) {
-		cniAsset, cniAssetHash, err := findCNIAssets(c.Cluster, assetBuilder)
+		cniAsset, cniAssetHash, err := findCNIAssets(c.Cluster, assetBuilder, arch)
 		if err != nil {
 			return err
 		}
 
-		c.Assets = append(c.Assets, BuildMirroredAsset(cniAsset, cniAssetHash))
+		c.Assets[arch] = append(c.Assets[arch], BuildMirroredAsset(cniAsset, cniAssetHash))
 	}
 
-	if c.Cluster.Spec.Networking.LyftVPC != nil {
+	// The lyft-vpc plugin is only distributed for amd64; validation rejects other architectures
+	if c.Cluster.Spec.Networking.LyftVPC != nil && arch == architectures.ArchitectureAmd64 {
 		var hash *hashing.Hash
+		var err error
 
 		urlString := os.Getenv("LYFT_VPC_DOWNLOAD_URL")
 		if urlString == "" {
@@ -1175,7 +1244,7 @@ func (c *ApplyClusterCmd) AddFileAssets(assetBuilder *assets.AssetBuilder) error
 			return fmt.Errorf("unable to parse lyft-vpc URL %q", urlString)
 		}
 
-		c.Assets = append(c.Assets, BuildMirroredAsset(u, hash))
+		c.Assets[arch] = append(c.Assets[arch], BuildMirroredAsset(u, hash))
 	}
 
 	// TODO figure out if we can only do this for CoreOS only and GCE Container OS
@@ -1183,28 +1252,21 @@ func (c *ApplyClusterCmd) AddFileAssets(assetBuilder *assets.AssetBuilder) error
 	// At this time we just copy the socat and conntrack binaries to all distros.
 	// Most distros will have their own socat and conntrack binary.
 	// Container operating systems like CoreOS need to have socat and conntrack added to them.
-	{
+	// The utils are only built for amd64; the distributions we support on arm64 have their own.
+	if arch == architectures.ArchitectureAmd64 {
 		utilsLocation, hash, err := KopsFileUrl("linux/amd64/utils.tar.gz", assetBuilder)
 		if err != nil {
 			return err
 		}
-		c.Assets = append(c.Assets, BuildMirroredAsset(utilsLocation, hash))
+		c.Assets[arch] = append(c.Assets[arch], BuildMirroredAsset(utilsLocation, hash))
 	}
 
-	n, hash, err := NodeUpLocation(assetBuilder)
-	if err != nil {
-		return err
-	}
-	c.NodeUpSource = n.String()
-	c.NodeUpHash = hash.Hex()
-
-	// Explicitly add the protokube image,
-	// otherwise when the Target is DryRun this asset is not added
-	// Is there a better way to call this?
-	_, _, err = ProtokubeImageSource(assetBuilder)
+	n, hash, err := NodeUpLocation(assetBuilder, arch)
 	if err != nil {
 		return err
 	}
+	c.NodeUpSource[arch] = n.String()
+	c.NodeUpHash[arch] = hash.Hex()
 
 	return nil
 }
@@ -1280,9 +1342,18 @@ func (c *ApplyClusterCmd) BuildNodeUpConfig(assetBuilder *assets.AssetBuilder, i
 		config.Tags = append(config.Tags, tag)
 	}
 
-	for _, a := range c.Assets {
+	arch, err := model.InstanceGroupArchitecture(cluster, ig)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range c.Assets[arch] {
 		config.Assets = append(config.Assets, a.CompactString())
 	}
+	if apimodel.ContainerRuntime(cluster, ig) == kops.ContainerRuntimeContainerd {
+		for _, a := range c.ContainerdAssets {
+			config.Assets = append(config.Assets, a.CompactString())
+		}
+	}
 	config.ClusterName = cluster.ObjectMeta.Name
 	config.ConfigBase = fi.String(configBase.Path())
 	config.InstanceGroupName = ig.ObjectMeta.Name
@@ -1302,7 +1373,7 @@ func (c *ApplyClusterCmd) BuildNodeUpConfig(assetBuilder *assets.AssetBuilder, i
 				return nil, err
 			}
 
-			baseURL.Path = path.Join(baseURL.Path, "/bin/linux/amd64/", component+".tar")
+			baseURL.Path = path.Join(baseURL.Path, "/bin/linux/", string(arch), component+".tar")
 
 			u, hash, err := assetBuilder.RemapFileAndSHA(baseURL)
 			if err != nil {
@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudup
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/kops/pkg/apis/kops"
+	apimodel "k8s.io/kops/pkg/apis/kops/model"
+	"k8s.io/kops/pkg/dns"
+	"k8s.io/kops/pkg/model"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/util/pkg/architectures"
+)
+
+// instanceGroupArchitectures returns the architectures of the instance groups that run nodeup, sorted,
+// and checks that the instance groups with an architecture other than amd64 only use what is available for it
+func (c *ApplyClusterCmd) instanceGroupArchitectures() ([]architectures.Architecture, error) {
+	found := make(map[architectures.Architecture]bool)
+	for _, ig := range c.InstanceGroups {
+		if ig.IsBastion() {
+			continue
+		}
+
+		arch, err := model.InstanceGroupArchitecture(c.Cluster, ig)
+		if err != nil {
+			return nil, err
+		}
+		if arch != architectures.ArchitectureAmd64 {
+			if err := validateArchitectureSupported(c.Cluster, ig, arch); err != nil {
+				return nil, err
+			}
+		}
+		found[arch] = true
+	}
+
+	if len(found) == 0 {
+		// Build the assets even when there are no instance groups, so that they are staged
+		found[architectures.ArchitectureAmd64] = true
+	}
+
+	var archs []architectures.Architecture
+	for arch := range found {
+		archs = append(archs, arch)
+	}
+	sort.Slice(archs, func(i, j int) bool { return archs[i] < archs[j] })
+	return archs, nil
+}
+
+// validateArchitectureSupported checks that the instance group does not need anything that is only built for amd64
+func validateArchitectureSupported(cluster *kops.Cluster, ig *kops.InstanceGroup, arch architectures.Architecture) error {
+	// protokube is only built for amd64, and runs on the masters, and on the nodes when using gossip
+	if ig.IsMaster() {
+		return fmt.Errorf("instance group %q is %s, but masters must be amd64", ig.ObjectMeta.Name, arch)
+	}
+	if dns.IsGossipHostname(cluster.Spec.MasterInternalName) {
+		return fmt.Errorf("instance group %q is %s, but gossip clusters only support amd64 instance groups", ig.ObjectMeta.Name, arch)
+	}
+
+	if apimodel.ContainerRuntime(cluster, ig) == kops.ContainerRuntimeContainerd {
+		return fmt.Errorf("instance group %q is %s, but containerd is only supported on amd64", ig.ObjectMeta.Name, arch)
+	}
+	if cluster.Spec.Networking != nil && cluster.Spec.Networking.LyftVPC != nil {
+		return fmt.Errorf("instance group %q is %s, but lyftvpc networking is only supported on amd64", ig.ObjectMeta.Name, arch)
+	}
+
+	return nil
+}
+
+// validateImageArchitectures checks that the image of each instance group has the architecture of its machine type
+func validateImageArchitectures(cloud awsup.AWSCloud, cluster *kops.Cluster, instanceGroups []*kops.InstanceGroup) error {
+	for _, ig := range instanceGroups {
+		if ig.Spec.Image == "" {
+			continue
+		}
+
+		arch, err := model.InstanceGroupArchitecture(cluster, ig)
+		if err != nil {
+			return err
+		}
+
+		image, err := cloud.ResolveImage(ig.Spec.Image)
+		if err != nil {
+			return fmt.Errorf("unable to resolve image %q of instance group %q: %v", ig.Spec.Image, ig.ObjectMeta.Name, err)
+		}
+		if image == nil || image.Architecture == nil {
+			continue
+		}
+
+		imageArch, err := architectures.FromImageArchitecture(*image.Architecture)
+		if err != nil {
+			return fmt.Errorf("image %q of instance group %q: %v", ig.Spec.Image, ig.ObjectMeta.Name, err)
+		}
+		if imageArch != arch {
+			return fmt.Errorf("image %q of instance group %q is %s, but machine type %q is %s", ig.Spec.Image, ig.ObjectMeta.Name, imageArch, ig.Spec.MachineType, arch)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"k8s.io/kops/cloudmock/aws/mockec2"
+	api "k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
+	"k8s.io/kops/util/pkg/architectures"
+)
+
+func TestInstanceGroupArchitectures(t *testing.T) {
+	grid := []struct {
+		Name          string
+		Mutator       func(cluster *api.Cluster, ig *api.InstanceGroup)
+		Expected      []architectures.Architecture
+		ExpectedError string
+	}{
+		{
+			Name:     "amd64 only",
+			Mutator:  func(cluster *api.Cluster, ig *api.InstanceGroup) {},
+			Expected: []architectures.Architecture{architectures.ArchitectureAmd64},
+		},
+		{
+			Name: "arm64 nodes",
+			Mutator: func(cluster *api.Cluster, ig *api.InstanceGroup) {
+				ig.Spec.MachineType = "a1.large"
+			},
+			Expected: []architectures.Architecture{architectures.ArchitectureAmd64, architectures.ArchitectureArm64},
+		},
+		{
+			Name: "arm64 masters",
+			Mutator: func(cluster *api.Cluster, ig *api.InstanceGroup) {
+				ig.Spec.MachineType = "a1.large"
+				ig.Spec.Role = api.InstanceGroupRoleMaster
+			},
+			ExpectedError: "masters must be amd64",
+		},
+		{
+			Name: "arm64 nodes with gossip",
+			Mutator: func(cluster *api.Cluster, ig *api.InstanceGroup) {
+				ig.Spec.MachineType = "a1.large"
+				cluster.Spec.MasterInternalName = "api.internal.testcluster.k8s.local"
+			},
+			ExpectedError: "gossip",
+		},
+		{
+			Name: "arm64 nodes with containerd",
+			Mutator: func(cluster *api.Cluster, ig *api.InstanceGroup) {
+				ig.Spec.MachineType = "a1.large"
+				ig.Spec.ContainerRuntime = api.ContainerRuntimeContainerd
+			},
+			ExpectedError: "containerd",
+		},
+		{
+			Name: "arm64 bastion",
+			Mutator: func(cluster *api.Cluster, ig *api.InstanceGroup) {
+				ig.Spec.MachineType = "a1.large"
+				ig.Spec.Role = api.InstanceGroupRoleBastion
+			},
+			Expected: []architectures.Architecture{architectures.ArchitectureAmd64},
+		},
+	}
+
+	for _, g := range grid {
+		t.Run(g.Name, func(t *testing.T) {
+			cluster := buildMinimalCluster()
+			cluster.Spec.MasterInternalName = "api.internal.testcluster.test.com"
+
+			master := buildMinimalMasterInstanceGroup("subnet-us-mock-1a")
+			master.Spec.MachineType = "m5.large"
+			ig := buildMinimalNodeInstanceGroup("subnet-us-mock-1a")
+			ig.Spec.MachineType = "m5.large"
+			g.Mutator(cluster, ig)
+
+			c := &ApplyClusterCmd{
+				Cluster:        cluster,
+				InstanceGroups: []*api.InstanceGroup{master, ig},
+			}
+			actual, err := c.instanceGroupArchitectures()
+			if g.ExpectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), g.ExpectedError) {
+					t.Fatalf("expected error containing %q, got %v", g.ExpectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(actual) != len(g.Expected) {
+				t.Fatalf("expected architectures %v, got %v", g.Expected, actual)
+			}
+			for i := range actual {
+				if actual[i] != g.Expected[i] {
+					t.Fatalf("expected architectures %v, got %v", g.Expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateImageArchitectures(t *testing.T) {
+	cloud := awsup.BuildMockAWSCloud("us-mock-1", "a")
+	mockEC2 := &mockec2.MockEC2{}
+	cloud.MockEC2 = mockEC2
+
+	mockEC2.Images = append(mockEC2.Images, &ec2.Image{
+		CreationDate: aws.String("2019-10-01T00:00:00.000Z"),
+		ImageId:      aws.String("ami-00000001"),
+		Name:         aws.String("debian-amd64"),
+		Architecture: aws.String("x86_64"),
+	}, &ec2.Image{
+		CreationDate: aws.String("2019-10-01T00:00:00.000Z"),
+		ImageId:      aws.String("ami-00000002"),
+		Name:         aws.String("debian-arm64"),
+		Architecture: aws.String("arm64"),
+	})
+
+	grid := []struct {
+		MachineType string
+		Image       string
+		ExpectError bool
+	}{
+		{MachineType: "m5.large", Image: "debian-amd64"},
+		{MachineType: "a1.large", Image: "debian-arm64"},
+		{MachineType: "a1.large", Image: "debian-amd64", ExpectError: true},
+		{MachineType: "m5.large", Image: "debian-arm64", ExpectError: true},
+	}
+
+	for _, g := range grid {
+		cluster := buildMinimalCluster()
+		ig := buildMinimalNodeInstanceGroup("subnet-us-mock-1a")
+		ig.Spec.MachineType = g.MachineType
+		ig.Spec.Image = g.Image
+
+		err := validateImageArchitectures(cloud, cluster, []*api.InstanceGroup{ig})
+		if g.ExpectError && err == nil {
+			t.Errorf("expected error for machine type %q with image %q", g.MachineType, g.Image)
+		}
+		if !g.ExpectError && err != nil {
+			t.Errorf("unexpected error for machine type %q with image %q: %v", g.MachineType, g.Image, err)
+		}
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 	"k8s.io/kops/upup/pkg/fi/cloudup/cloudformation"
+	"k8s.io/kops/upup/pkg/fi/cloudup/resourcegraph"
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
 	"k8s.io/kops/util/pkg/maps"
 
@@ -883,3 +884,61 @@ func (_ *AutoscalingGroup) RenderCloudformation(t *cloudformation.Cloudformation
 func (e *AutoscalingGroup) CloudformationLink() *cloudformation.Literal {
 	return cloudformation.Ref("AWS::AutoScaling::AutoScalingGroup", fi.StringValue(e.Name))
 }
+
+type resourceGraphAutoscalingGroup struct {
+	MinSize                         *int64            `json:"minSize,omitempty"`
+	MaxSize                         *int64            `json:"maxSize,omitempty"`
+	LaunchConfiguration             string            `json:"launchConfiguration,omitempty"`
+	LaunchTemplate                  string            `json:"launchTemplate,omitempty"`
+	Subnets                         []string          `json:"subnets,omitempty"`
+	Granularity                     *string           `json:"granularity,omitempty"`
+	Metrics                         []string          `json:"metrics,omitempty"`
+	InstanceProtection              *bool             `json:"instanceProtection,omitempty"`
+	MixedInstanceOverrides          []string          `json:"mixedInstanceOverrides,omitempty"`
+	MixedOnDemandAllocationStrategy *string           `json:"mixedOnDemandAllocationStrategy,omitempty"`
+	MixedOnDemandBase               *int64            `json:"mixedOnDemandBase,omitempty"`
+	MixedOnDemandAboveBase          *int64            `json:"mixedOnDemandAboveBase,omitempty"`
+	MixedSpotAllocationStrategy     *string           `json:"mixedSpotAllocationStrategy,omitempty"`
+	MixedSpotInstancePools          *int64            `json:"mixedSpotInstancePools,omitempty"`
+	MixedSpotMaxPrice               *string           `json:"mixedSpotMaxPrice,omitempty"`
+	SuspendProcesses                []string          `json:"suspendProcesses,omitempty"`
+	Tags                            map[string]string `json:"tags,omitempty"`
+}
+
+func (_ *AutoscalingGroup) RenderResourceGraph(t *resourcegraph.ResourceGraphTarget, a, e, changes *AutoscalingGroup) error {
+	rg := &resourceGraphAutoscalingGroup{
+		MinSize:                         e.MinSize,
+		MaxSize:                         e.MaxSize,
+		Granularity:                     e.Granularity,
+		Metrics:                         e.Metrics,
+		InstanceProtection:              e.InstanceProtection,
+		MixedInstanceOverrides:          e.MixedInstanceOverrides,
+		MixedOnDemandAllocationStrategy: e.MixedOnDemandAllocationStrategy,
+		MixedOnDemandBase:               e.MixedOnDemandBase,
+		MixedOnDemandAboveBase:          e.MixedOnDemandAboveBase,
+		MixedSpotAllocationStrategy:     e.MixedSpotAllocationStrategy,
+		MixedSpotInstancePools:          e.MixedSpotInstancePools,
+		MixedSpotMaxPrice:               e.MixedSpotMaxPrice,
+		Tags:                            e.Tags,
+	}
+
+	if e.LaunchConfiguration != nil {
+		rg.LaunchConfiguration = e.LaunchConfiguration.ResourceGraphLink()
+	}
+	if e.LaunchTemplate != nil {
+		rg.LaunchTemplate = e.LaunchTemplate.ResourceGraphLink()
+	}
+	for _, s := range e.Subnets {
+		rg.Subnets = append(rg.Subnets, s.ResourceGraphLink())
+	}
+	if e.SuspendProcesses != nil {
+		rg.SuspendProcesses = *e.SuspendProcesses
+	}
+
+	return t.RenderResource(e, "AWS::AutoScaling::AutoScalingGroup", fi.StringValue(e.Name), rg)
+}
+
+// ResourceGraphLink returns the id of the autoscaling group in the resource graph
+func (e *AutoscalingGroup) ResourceGraphLink() string {
+	return resourcegraph.Ref("AWS::AutoScaling::AutoScalingGroup", fi.StringValue(e.Name))
+}
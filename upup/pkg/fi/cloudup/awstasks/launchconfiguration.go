@@ -28,6 +28,7 @@ import (
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 	"k8s.io/kops/upup/pkg/fi/cloudup/cloudformation"
+	"k8s.io/kops/upup/pkg/fi/cloudup/resourcegraph"
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -708,6 +709,29 @@ func (e *LaunchConfiguration) CloudformationLink() *cloudformation.Literal {
 	return cloudformation.Ref("AWS::AutoScaling::LaunchConfiguration", *e.Name)
 }
 
+func (_ *LaunchConfiguration) RenderResourceGraph(t *resourcegraph.ResourceGraphTarget, a, e, changes *LaunchConfiguration) error {
+	rg, err := buildResourceGraphInstanceSpec(e.IAMInstanceProfile, e.SSHKey, e.SecurityGroups, e.UserData)
+	if err != nil {
+		return err
+	}
+	rg.AssociatePublicIP = e.AssociatePublicIP
+	rg.ImageID = e.ImageID
+	rg.InstanceMonitoring = e.InstanceMonitoring
+	rg.InstanceType = e.InstanceType
+	rg.RootVolumeIops = e.RootVolumeIops
+	rg.RootVolumeOptimization = e.RootVolumeOptimization
+	rg.RootVolumeSize = e.RootVolumeSize
+	rg.RootVolumeType = e.RootVolumeType
+	rg.SpotPrice = e.SpotPrice
+	rg.Tenancy = e.Tenancy
+
+	return t.RenderResource(e, "AWS::AutoScaling::LaunchConfiguration", *e.Name, rg)
+}
+
+func (e *LaunchConfiguration) ResourceGraphLink() string {
+	return resourcegraph.Ref("AWS::AutoScaling::LaunchConfiguration", *e.Name)
+}
+
 // deleteLaunchConfiguration tracks a LaunchConfiguration that we're going to delete
 // It implements fi.Deletion
 type deleteLaunchConfiguration struct {
@@ -0,0 +1,47 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/cloudup/resourcegraph"
+)
+
+// ResourceGraphLink returns the id of the launch template in the resource graph
+func (t *LaunchTemplate) ResourceGraphLink() string {
+	return resourcegraph.Ref("AWS::EC2::LaunchTemplate", fi.StringValue(t.Name))
+}
+
+// RenderResourceGraph is responsible for adding the launch template to the resource graph
+func (t *LaunchTemplate) RenderResourceGraph(target *resourcegraph.ResourceGraphTarget, a, e, changes *LaunchTemplate) error {
+	rg, err := buildResourceGraphInstanceSpec(e.IAMInstanceProfile, e.SSHKey, e.SecurityGroups, e.UserData)
+	if err != nil {
+		return err
+	}
+	rg.AssociatePublicIP = e.AssociatePublicIP
+	rg.ImageID = e.ImageID
+	rg.InstanceMonitoring = e.InstanceMonitoring
+	rg.InstanceType = e.InstanceType
+	rg.RootVolumeIops = e.RootVolumeIops
+	rg.RootVolumeOptimization = e.RootVolumeOptimization
+	rg.RootVolumeSize = e.RootVolumeSize
+	rg.RootVolumeType = e.RootVolumeType
+	rg.SpotPrice = e.SpotPrice
+	rg.Tenancy = e.Tenancy
+
+	return target.RenderResource(e, "AWS::EC2::LaunchTemplate", fi.StringValue(e.Name), rg)
+}
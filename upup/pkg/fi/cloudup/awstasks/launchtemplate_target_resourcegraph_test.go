@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"testing"
+
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+func TestLaunchTemplateResourceGraphRender(t *testing.T) {
+	cases := []*renderTest{
+		{
+			Resource: &LaunchTemplate{
+				Name:              fi.String("test"),
+				AssociatePublicIP: fi.Bool(true),
+				IAMInstanceProfile: &IAMInstanceProfile{
+					Name: fi.String("nodes"),
+				},
+				ID:                     fi.String("test-11"),
+				ImageID:                fi.String("ami-12345"),
+				InstanceMonitoring:     fi.Bool(true),
+				InstanceType:           fi.String("t2.medium"),
+				RootVolumeOptimization: fi.Bool(true),
+				RootVolumeIops:         fi.Int64(100),
+				RootVolumeSize:         fi.Int64(64),
+				SSHKey: &SSHKey{
+					Name: fi.String("mykey"),
+				},
+				SecurityGroups: []*SecurityGroup{
+					{Name: fi.String("nodes-1"), ID: fi.String("1111")},
+					{Name: fi.String("nodes-2"), ID: fi.String("2222")},
+				},
+				Tenancy:  fi.String("dedicated"),
+				UserData: fi.WrapResource(fi.NewStringResource("#!/bin/bash")),
+			},
+			Expected: `{
+  "cloud": "aws",
+  "region": "eu-west-2",
+  "project": "test",
+  "resources": [
+    {
+      "id": "AWS::EC2::LaunchTemplate/test",
+      "type": "AWS::EC2::LaunchTemplate",
+      "name": "test",
+      "properties": {
+        "associatePublicIP": true,
+        "iamInstanceProfile": "nodes",
+        "imageID": "ami-12345",
+        "instanceMonitoring": true,
+        "instanceType": "t2.medium",
+        "rootVolumeIops": 100,
+        "rootVolumeOptimization": true,
+        "rootVolumeSize": 64,
+        "sshKeyName": "mykey",
+        "securityGroups": [
+          "AWS::EC2::SecurityGroup/nodes-1",
+          "AWS::EC2::SecurityGroup/nodes-2"
+        ],
+        "tenancy": "dedicated",
+        "userData": "#!/bin/bash"
+      }
+    }
+  ]
+}
+`,
+		},
+	}
+	doRenderTests(t, "RenderResourceGraph", cases)
+}
@@ -30,6 +30,7 @@ import (
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 	"k8s.io/kops/upup/pkg/fi/cloudup/cloudformation"
+	"k8s.io/kops/upup/pkg/fi/cloudup/resourcegraph"
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
 	"k8s.io/kops/util/pkg/slice"
 )
@@ -918,3 +919,81 @@ func (e *LoadBalancer) CloudformationAttrCanonicalHostedZoneNameID() *cloudforma
 func (e *LoadBalancer) CloudformationAttrDNSName() *cloudformation.Literal {
 	return cloudformation.GetAtt("AWS::ElasticLoadBalancing::LoadBalancer", *e.Name, "DNSName")
 }
+
+type resourceGraphLoadBalancerListener struct {
+	LoadBalancerPort int64  `json:"loadBalancerPort"`
+	InstancePort     int    `json:"instancePort"`
+	SSLCertificateID string `json:"sslCertificateID,omitempty"`
+}
+
+type resourceGraphLoadBalancerHealthCheck struct {
+	Target             *string `json:"target,omitempty"`
+	HealthyThreshold   *int64  `json:"healthyThreshold,omitempty"`
+	UnhealthyThreshold *int64  `json:"unhealthyThreshold,omitempty"`
+	Interval           *int64  `json:"interval,omitempty"`
+	Timeout            *int64  `json:"timeout,omitempty"`
+}
+
+type resourceGraphLoadBalancer struct {
+	LoadBalancerName       *string                               `json:"loadBalancerName,omitempty"`
+	Scheme                 *string                               `json:"scheme,omitempty"`
+	Subnets                []string                              `json:"subnets,omitempty"`
+	SecurityGroups         []string                              `json:"securityGroups,omitempty"`
+	Listeners              []*resourceGraphLoadBalancerListener  `json:"listeners,omitempty"`
+	HealthCheck            *resourceGraphLoadBalancerHealthCheck `json:"healthCheck,omitempty"`
+	CrossZoneLoadBalancing *bool                                 `json:"crossZoneLoadBalancing,omitempty"`
+	IdleTimeout            *int64                                `json:"idleTimeout,omitempty"`
+	Tags                   map[string]string                     `json:"tags,omitempty"`
+}
+
+func (_ *LoadBalancer) RenderResourceGraph(t *resourcegraph.ResourceGraphTarget, a, e, changes *LoadBalancer) error {
+	rg := &resourceGraphLoadBalancer{
+		LoadBalancerName: e.LoadBalancerName,
+		Scheme:           e.Scheme,
+		Tags:             e.Tags,
+	}
+
+	for _, subnet := range e.Subnets {
+		rg.Subnets = append(rg.Subnets, subnet.ResourceGraphLink())
+	}
+	for _, sg := range e.SecurityGroups {
+		rg.SecurityGroups = append(rg.SecurityGroups, sg.ResourceGraphLink())
+	}
+
+	for loadBalancerPort, listener := range e.Listeners {
+		loadBalancerPortInt, err := strconv.ParseInt(loadBalancerPort, 10, 64)
+		if err != nil {
+			return fmt.Errorf("error parsing load balancer listener port: %q", loadBalancerPort)
+		}
+		rg.Listeners = append(rg.Listeners, &resourceGraphLoadBalancerListener{
+			LoadBalancerPort: loadBalancerPortInt,
+			InstancePort:     listener.InstancePort,
+			SSLCertificateID: listener.SSLCertificateID,
+		})
+	}
+	sort.Slice(rg.Listeners, func(i, j int) bool {
+		return rg.Listeners[i].LoadBalancerPort < rg.Listeners[j].LoadBalancerPort
+	})
+
+	if e.HealthCheck != nil {
+		rg.HealthCheck = &resourceGraphLoadBalancerHealthCheck{
+			Target:             e.HealthCheck.Target,
+			HealthyThreshold:   e.HealthCheck.HealthyThreshold,
+			UnhealthyThreshold: e.HealthCheck.UnhealthyThreshold,
+			Interval:           e.HealthCheck.Interval,
+			Timeout:            e.HealthCheck.Timeout,
+		}
+	}
+	if e.CrossZoneLoadBalancing != nil {
+		rg.CrossZoneLoadBalancing = e.CrossZoneLoadBalancing.Enabled
+	}
+	if e.ConnectionSettings != nil {
+		rg.IdleTimeout = e.ConnectionSettings.IdleTimeout
+	}
+
+	return t.RenderResource(e, "AWS::ElasticLoadBalancing::LoadBalancer", *e.Name, rg)
+}
+
+func (e *LoadBalancer) ResourceGraphLink() string {
+	return resourcegraph.Ref("AWS::ElasticLoadBalancing::LoadBalancer", *e.Name)
+}
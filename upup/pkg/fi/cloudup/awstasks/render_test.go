@@ -26,6 +26,7 @@ import (
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 	"k8s.io/kops/upup/pkg/fi/cloudup/cloudformation"
+	"k8s.io/kops/upup/pkg/fi/cloudup/resourcegraph"
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
 )
 
@@ -55,6 +56,9 @@ func doRenderTests(t *testing.T, method string, cases []*renderTest) {
 		case "RenderCloudformation":
 			target = cloudformation.NewCloudformationTarget(cloud, "eu-west-2", "test", outdir)
 			filename = "kubernetes.json"
+		case "RenderResourceGraph":
+			target = resourcegraph.NewResourceGraphTarget(cloud, "eu-west-2", "test", outdir)
+			filename = "resourcegraph.json"
 		default:
 			t.Errorf("unknown render method: %s", method)
 			t.FailNow()
@@ -0,0 +1,61 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package awstasks
+
+import (
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+// resourceGraphInstanceSpec is the resource graph representation of the instances
+// launched by a LaunchConfiguration or LaunchTemplate
+type resourceGraphInstanceSpec struct {
+	AssociatePublicIP      *bool    `json:"associatePublicIP,omitempty"`
+	IAMInstanceProfile     *string  `json:"iamInstanceProfile,omitempty"`
+	ImageID                *string  `json:"imageID,omitempty"`
+	InstanceMonitoring     *bool    `json:"instanceMonitoring,omitempty"`
+	InstanceType           *string  `json:"instanceType,omitempty"`
+	RootVolumeIops         *int64   `json:"rootVolumeIops,omitempty"`
+	RootVolumeOptimization *bool    `json:"rootVolumeOptimization,omitempty"`
+	RootVolumeSize         *int64   `json:"rootVolumeSize,omitempty"`
+	RootVolumeType         *string  `json:"rootVolumeType,omitempty"`
+	SSHKeyName             *string  `json:"sshKeyName,omitempty"`
+	SecurityGroups         []string `json:"securityGroups,omitempty"`
+	SpotPrice              string   `json:"spotPrice,omitempty"`
+	Tenancy                *string  `json:"tenancy,omitempty"`
+	UserData               string   `json:"userData,omitempty"`
+}
+
+func buildResourceGraphInstanceSpec(iamInstanceProfile *IAMInstanceProfile, sshKey *SSHKey, securityGroups []*SecurityGroup, userData *fi.ResourceHolder) (*resourceGraphInstanceSpec, error) {
+	spec := &resourceGraphInstanceSpec{}
+	if iamInstanceProfile != nil {
+		spec.IAMInstanceProfile = iamInstanceProfile.Name
+	}
+	if sshKey != nil {
+		spec.SSHKeyName = sshKey.Name
+	}
+	for _, sg := range securityGroups {
+		spec.SecurityGroups = append(spec.SecurityGroups, sg.ResourceGraphLink())
+	}
+	if userData != nil {
+		s, err := userData.AsString()
+		if err != nil {
+			return nil, err
+		}
+		spec.UserData = s
+	}
+	return spec, nil
+}
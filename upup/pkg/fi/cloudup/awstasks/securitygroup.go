@@ -27,6 +27,7 @@ import (
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 	"k8s.io/kops/upup/pkg/fi/cloudup/cloudformation"
+	"k8s.io/kops/upup/pkg/fi/cloudup/resourcegraph"
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
 )
 
@@ -258,6 +259,30 @@ func (e *SecurityGroup) CloudformationLink() *cloudformation.Literal {
 	return cloudformation.Ref("AWS::EC2::SecurityGroup", *e.Name)
 }
 
+type resourceGraphSecurityGroup struct {
+	ID          *string           `json:"id,omitempty"`
+	VPC         string            `json:"vpc"`
+	Description *string           `json:"description,omitempty"`
+	Shared      bool              `json:"shared,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+func (_ *SecurityGroup) RenderResourceGraph(t *resourcegraph.ResourceGraphTarget, a, e, changes *SecurityGroup) error {
+	rg := &resourceGraphSecurityGroup{
+		ID:          e.ID,
+		VPC:         e.VPC.ResourceGraphLink(),
+		Description: e.Description,
+		Shared:      fi.BoolValue(e.Shared),
+		Tags:        e.Tags,
+	}
+
+	return t.RenderResource(e, "AWS::EC2::SecurityGroup", *e.Name, rg)
+}
+
+func (e *SecurityGroup) ResourceGraphLink() string {
+	return resourcegraph.Ref("AWS::EC2::SecurityGroup", *e.Name)
+}
+
 type deleteSecurityGroupRule struct {
 	groupID    *string
 	permission *ec2.IpPermission
@@ -25,6 +25,7 @@ import (
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 	"k8s.io/kops/upup/pkg/fi/cloudup/cloudformation"
+	"k8s.io/kops/upup/pkg/fi/cloudup/resourcegraph"
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
 	"k8s.io/kops/upup/pkg/fi/utils"
 )
@@ -301,3 +302,29 @@ func (e *Subnet) CloudformationLink() *cloudformation.Literal {
 
 	return cloudformation.Ref("AWS::EC2::Subnet", *e.Name)
 }
+
+type resourceGraphSubnet struct {
+	ID               *string           `json:"id,omitempty"`
+	VPC              string            `json:"vpc"`
+	AvailabilityZone *string           `json:"availabilityZone,omitempty"`
+	CIDR             *string           `json:"cidr,omitempty"`
+	Shared           bool              `json:"shared,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+}
+
+func (_ *Subnet) RenderResourceGraph(t *resourcegraph.ResourceGraphTarget, a, e, changes *Subnet) error {
+	rg := &resourceGraphSubnet{
+		ID:               e.ID,
+		VPC:              e.VPC.ResourceGraphLink(),
+		AvailabilityZone: e.AvailabilityZone,
+		CIDR:             e.CIDR,
+		Shared:           fi.BoolValue(e.Shared),
+		Tags:             e.Tags,
+	}
+
+	return t.RenderResource(e, "AWS::EC2::Subnet", *e.Name, rg)
+}
+
+func (e *Subnet) ResourceGraphLink() string {
+	return resourcegraph.Ref("AWS::EC2::Subnet", *e.Name)
+}
@@ -27,6 +27,7 @@ import (
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/upup/pkg/fi/cloudup/awsup"
 	"k8s.io/kops/upup/pkg/fi/cloudup/cloudformation"
+	"k8s.io/kops/upup/pkg/fi/cloudup/resourcegraph"
 	"k8s.io/kops/upup/pkg/fi/cloudup/terraform"
 )
 
@@ -279,3 +280,29 @@ func (e *VPC) CloudformationLink() *cloudformation.Literal {
 
 	return cloudformation.Ref("AWS::EC2::VPC", *e.Name)
 }
+
+type resourceGraphVPC struct {
+	ID                 *string           `json:"id,omitempty"`
+	CIDR               *string           `json:"cidr,omitempty"`
+	EnableDNSHostnames *bool             `json:"enableDnsHostnames,omitempty"`
+	EnableDNSSupport   *bool             `json:"enableDnsSupport,omitempty"`
+	Shared             bool              `json:"shared,omitempty"`
+	Tags               map[string]string `json:"tags,omitempty"`
+}
+
+func (_ *VPC) RenderResourceGraph(t *resourcegraph.ResourceGraphTarget, a, e, changes *VPC) error {
+	rg := &resourceGraphVPC{
+		ID:                 e.ID,
+		CIDR:               e.CIDR,
+		EnableDNSHostnames: e.EnableDNSHostnames,
+		EnableDNSSupport:   e.EnableDNSSupport,
+		Shared:             fi.BoolValue(e.Shared),
+		Tags:               e.Tags,
+	}
+
+	return t.RenderResource(e, "AWS::EC2::VPC", *e.Name, rg)
+}
+
+func (e *VPC) ResourceGraphLink() string {
+	return resourcegraph.Ref("AWS::EC2::VPC", *e.Name)
+}
@@ -84,6 +84,10 @@ const TagNameKopsRole = "kubernetes.io/kops/role"
 // TagNameClusterOwnershipPrefix is the AWS tag used for ownership
 const TagNameClusterOwnershipPrefix = "kubernetes.io/cluster/"
 
+// TagNameDetachedInstance is the AWS tag set on instances that a rolling update has detached from their ASG;
+// the value is the name of the ASG the instance was detached from
+const TagNameDetachedInstance = "kops.k8s.io/detached-from-asg"
+
 const (
 	WellKnownAccountKopeio             = "383156758163"
 	WellKnownAccountRedhat             = "309956199498"
@@ -415,6 +419,21 @@ func deleteInstance(c AWSCloud, i *cloudinstances.CloudInstanceGroupMember) erro
 		return fmt.Errorf("id was not set on CloudInstanceGroupMember: %v", i)
 	}
 
+	if i.Detached {
+		// The instance is no longer part of the ASG, so we terminate it directly
+		request := &ec2.TerminateInstancesInput{
+			InstanceIds: []*string{aws.String(id)},
+		}
+
+		if _, err := c.EC2().TerminateInstances(request); err != nil {
+			return fmt.Errorf("error deleting detached instance %q: %v", id, err)
+		}
+
+		klog.V(8).Infof("deleted detached aws ec2 instance %q", id)
+
+		return nil
+	}
+
 	request := &autoscaling.TerminateInstanceInAutoScalingGroupInput{
 		InstanceId:                     aws.String(id),
 		ShouldDecrementDesiredCapacity: aws.Bool(false),
@@ -429,6 +448,44 @@ func deleteInstance(c AWSCloud, i *cloudinstances.CloudInstanceGroupMember) erro
 	return nil
 }
 
+// DetachInstance causes an aws instance to no longer be counted against the ASG's size limits.
+func (c *awsCloudImplementation) DetachInstance(i *cloudinstances.CloudInstanceGroupMember) error {
+	if c.spotinst != nil {
+		return spotinst.DetachInstance(c.spotinst, i)
+	}
+
+	return detachInstance(c, i)
+}
+
+func detachInstance(c AWSCloud, i *cloudinstances.CloudInstanceGroupMember) error {
+	id := i.ID
+	if id == "" {
+		return fmt.Errorf("id was not set on CloudInstanceGroupMember: %v", i)
+	}
+
+	asg := i.CloudInstanceGroup.HumanName
+
+	// We tag the instance before detaching it, so that we can still find it if we are interrupted
+	if err := c.CreateTags(id, map[string]string{TagNameDetachedInstance: asg}); err != nil {
+		return fmt.Errorf("error tagging instance %q: %v", id, err)
+	}
+
+	// We don't decrement the desired capacity, so the ASG will launch a replacement
+	request := &autoscaling.DetachInstancesInput{
+		AutoScalingGroupName:           aws.String(asg),
+		InstanceIds:                    []*string{aws.String(id)},
+		ShouldDecrementDesiredCapacity: aws.Bool(false),
+	}
+
+	if _, err := c.Autoscaling().DetachInstances(request); err != nil {
+		return fmt.Errorf("error detaching instance %q: %v", id, err)
+	}
+
+	klog.V(8).Infof("detached aws ec2 instance %q from autoscaling group %q", id, asg)
+
+	return nil
+}
+
 // TODO not used yet, as this requires a major refactor of rolling-update code, slowly but surely
 
 // GetCloudGroups returns a groups of instances that back a kops instance groups
@@ -656,9 +713,50 @@ func awsBuildCloudInstanceGroup(c AWSCloud, ig *kops.InstanceGroup, g *autoscali
 		}
 	}
 
+	detached, err := findDetachedInstances(c, g)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range detached {
+		if err := cg.NewDetachedCloudInstanceGroupMember(id, nodeMap); err != nil {
+			return nil, fmt.Errorf("error creating cloud instance group member: %v", err)
+		}
+	}
+
 	return cg, nil
 }
 
+// findDetachedInstances returns the ids of running instances that were detached from the specified ASG by a rolling update
+func findDetachedInstances(c AWSCloud, g *autoscaling.Group) ([]string, error) {
+	clusterName := c.Tags()[TagClusterName]
+	if clusterName == "" {
+		return nil, nil
+	}
+
+	request := &ec2.DescribeInstancesInput{
+		Filters: []*ec2.Filter{
+			NewEC2Filter("tag:"+TagClusterName, clusterName),
+			NewEC2Filter("tag:"+TagNameDetachedInstance, aws.StringValue(g.AutoScalingGroupName)),
+			NewEC2Filter("instance-state-name", "pending", "running", "stopping", "stopped"),
+		},
+	}
+
+	var ids []string
+	err := c.EC2().DescribeInstancesPages(request, func(p *ec2.DescribeInstancesOutput, lastPage bool) bool {
+		for _, r := range p.Reservations {
+			for _, i := range r.Instances {
+				ids = append(ids, aws.StringValue(i.InstanceId))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing detached instances for autoscaling group %q: %v", aws.StringValue(g.AutoScalingGroupName), err)
+	}
+
+	return ids, nil
+}
+
 func (c *awsCloudImplementation) Tags() map[string]string {
 	// Defensive copy
 	tags := make(map[string]string)
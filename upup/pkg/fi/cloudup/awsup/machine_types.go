@@ -33,6 +33,7 @@ type AWSMachineTypeInfo struct {
 	EphemeralDisks    []int
 	Burstable         bool
 	GPU               bool
+	Arm64             bool
 	MaxPods           int
 	InstanceENIs      int
 	InstanceIPsPerENI int
@@ -92,6 +93,7 @@ var MachineTypes []AWSMachineTypeInfo = []AWSMachineTypeInfo{
 		InstanceENIs:      2,
 		InstanceIPsPerENI: 4,
 		EphemeralDisks:    nil,
+		Arm64:             true,
 	},
 
 	{
@@ -102,6 +104,7 @@ var MachineTypes []AWSMachineTypeInfo = []AWSMachineTypeInfo{
 		InstanceENIs:      3,
 		InstanceIPsPerENI: 10,
 		EphemeralDisks:    nil,
+		Arm64:             true,
 	},
 
 	{
@@ -112,6 +115,7 @@ var MachineTypes []AWSMachineTypeInfo = []AWSMachineTypeInfo{
 		InstanceENIs:      4,
 		InstanceIPsPerENI: 15,
 		EphemeralDisks:    nil,
+		Arm64:             true,
 	},
 
 	{
@@ -122,6 +126,7 @@ var MachineTypes []AWSMachineTypeInfo = []AWSMachineTypeInfo{
 		InstanceENIs:      4,
 		InstanceIPsPerENI: 15,
 		EphemeralDisks:    nil,
+		Arm64:             true,
 	},
 
 	{
@@ -132,6 +137,7 @@ var MachineTypes []AWSMachineTypeInfo = []AWSMachineTypeInfo{
 		InstanceENIs:      8,
 		InstanceIPsPerENI: 30,
 		EphemeralDisks:    nil,
+		Arm64:             true,
 	},
 
 	// c1 family
@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudup
+
+import (
+	"fmt"
+	"net/url"
+
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/assets"
+	"k8s.io/kops/util/pkg/hashing"
+)
+
+const (
+	// defaultContainerdVersion is the version of containerd installed when the cluster does not specify one
+	defaultContainerdVersion = "1.2.10"
+
+	// containerdAssetTemplate is the location of the cri-containerd tarball, which has containerd, runc and crictl
+	containerdAssetTemplate = "https://storage.googleapis.com/cri-containerd-release/cri-containerd-%s.linux-amd64.tar.gz"
+)
+
+// findContainerdAsset returns the location and hash of the containerd tarball for the cluster
+func findContainerdAsset(c *kops.Cluster, assetBuilder *assets.AssetBuilder) (*url.URL, *hashing.Hash, error) {
+	version := defaultContainerdVersion
+	if c.Spec.Containerd != nil && c.Spec.Containerd.Version != nil && *c.Spec.Containerd.Version != "" {
+		version = *c.Spec.Containerd.Version
+	}
+
+	assetURL := fmt.Sprintf(containerdAssetTemplate, version)
+	u, err := url.Parse(assetURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse containerd asset URL %q: %v", assetURL, err)
+	}
+
+	klog.V(2).Infof("Adding containerd asset: %s", assetURL)
+
+	u, hash, err := assetBuilder.RemapFileAndSHA(u)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return u, hash, nil
+}
@@ -53,14 +53,98 @@ func (c *mockGCECloud) DeleteGroup(g *cloudinstances.CloudInstanceGroup) error {
 
 // DeleteInstance deletes a GCE instance
 func (c *gceCloudImplementation) DeleteInstance(i *cloudinstances.CloudInstanceGroupMember) error {
+	if i.Detached {
+		return DeleteInstance(c, i.ID)
+	}
 	return recreateCloudInstanceGroupMember(c, i)
 }
 
 // DeleteInstance deletes a GCE instance
 func (c *mockGCECloud) DeleteInstance(i *cloudinstances.CloudInstanceGroupMember) error {
+	if i.Detached {
+		return DeleteInstance(c, i.ID)
+	}
 	return recreateCloudInstanceGroupMember(c, i)
 }
 
+// DetachInstance removes a GCE instance from its InstanceGroupManager, which then launches a replacement
+func (c *gceCloudImplementation) DetachInstance(i *cloudinstances.CloudInstanceGroupMember) error {
+	return detachCloudInstanceGroupMember(c, i)
+}
+
+// DetachInstance removes a GCE instance from its InstanceGroupManager, which then launches a replacement
+func (c *mockGCECloud) DetachInstance(i *cloudinstances.CloudInstanceGroupMember) error {
+	return detachCloudInstanceGroupMember(c, i)
+}
+
+// detachCloudInstanceGroupMember abandons the specified instance, and restores the target size of its InstanceGroupManager
+func detachCloudInstanceGroupMember(c GCECloud, i *cloudinstances.CloudInstanceGroupMember) error {
+	mig := i.CloudInstanceGroup.Raw.(*compute.InstanceGroupManager)
+
+	klog.V(2).Infof("Abandoning GCE Instance %s in MIG %s", i.ID, mig.Name)
+
+	migURL, err := ParseGoogleCloudURL(mig.SelfLink)
+	if err != nil {
+		return err
+	}
+
+	// Label the instance first, so that it is found again if the rolling update is interrupted after it is abandoned
+	if err := labelInstance(c, i.ID, GceLabelNameDetachedFromMIG, mig.Name); err != nil {
+		return err
+	}
+
+	req := &compute.InstanceGroupManagersAbandonInstancesRequest{
+		Instances: []string{
+			i.ID,
+		},
+	}
+	op, err := c.Compute().InstanceGroupManagers.AbandonInstances(migURL.Project, migURL.Zone, migURL.Name, req).Do()
+	if err != nil {
+		return fmt.Errorf("error abandoning Instance %s: %v", i.ID, err)
+	}
+	if err := c.WaitForOp(op); err != nil {
+		return err
+	}
+
+	// Abandoning an instance reduces the target size, so we restore it to get a replacement
+	op, err = c.Compute().InstanceGroupManagers.Resize(migURL.Project, migURL.Zone, migURL.Name, mig.TargetSize).Do()
+	if err != nil {
+		return fmt.Errorf("error resizing InstanceGroupManager %s: %v", mig.Name, err)
+	}
+
+	return c.WaitForOp(op)
+}
+
+// labelInstance sets a label on the specified instance (by URL), keeping its existing labels
+func labelInstance(c GCECloud, instanceSelfLink string, key string, value string) error {
+	u, err := ParseGoogleCloudURL(instanceSelfLink)
+	if err != nil {
+		return err
+	}
+
+	instance, err := c.Compute().Instances.Get(u.Project, u.Zone, u.Name).Do()
+	if err != nil {
+		return fmt.Errorf("error getting Instance %s: %v", instanceSelfLink, err)
+	}
+
+	labels := make(map[string]string)
+	for k, v := range instance.Labels {
+		labels[k] = v
+	}
+	labels[key] = value
+
+	req := &compute.InstancesSetLabelsRequest{
+		Labels:           labels,
+		LabelFingerprint: instance.LabelFingerprint,
+	}
+	op, err := c.Compute().Instances.SetLabels(u.Project, u.Zone, u.Name, req).Do()
+	if err != nil {
+		return fmt.Errorf("error labelling Instance %s: %v", instanceSelfLink, err)
+	}
+
+	return c.WaitForOp(op)
+}
+
 // recreateCloudInstanceGroupMember recreates the specified instances, managed by an InstanceGroupManager
 func recreateCloudInstanceGroupMember(c GCECloud, i *cloudinstances.CloudInstanceGroupMember) error {
 	mig := i.CloudInstanceGroup.Raw.(*compute.InstanceGroupManager)
@@ -194,6 +278,28 @@ func getCloudGroups(c GCECloud, cluster *kops.Cluster, instancegroups []*kops.In
 					}
 				}
 
+				detached, err := findDetachedInstances(c, zoneName, mig)
+				if err != nil {
+					return err
+				}
+				for _, i := range detached {
+					cm := &cloudinstances.CloudInstanceGroupMember{
+						ID:                 i.SelfLink,
+						CloudInstanceGroup: g,
+						Detached:           true,
+					}
+
+					providerID := "gce://" + project + "/" + zoneName + "/" + i.Name
+					if node := nodesByProviderID[providerID]; node != nil {
+						cm.Node = node
+					} else {
+						klog.V(8).Infof("unable to find node for instance: %s", i.SelfLink)
+					}
+
+					// Detached instances always need to be replaced
+					g.NeedUpdate = append(g.NeedUpdate, cm)
+				}
+
 			}
 			return nil
 		})
@@ -206,6 +312,24 @@ func getCloudGroups(c GCECloud, cluster *kops.Cluster, instancegroups []*kops.In
 	return groups, nil
 }
 
+// findDetachedInstances returns the instances that were detached from the specified MIG by a rolling update
+func findDetachedInstances(c GCECloud, zoneName string, mig *compute.InstanceGroupManager) ([]*compute.Instance, error) {
+	ctx := context.Background()
+
+	filter := fmt.Sprintf("labels.%s = %s", GceLabelNameDetachedFromMIG, mig.Name)
+
+	var instances []*compute.Instance
+	err := c.Compute().Instances.List(c.Project(), zoneName).Filter(filter).Pages(ctx, func(page *compute.InstanceList) error {
+		instances = append(instances, page.Items...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing instances detached from MIG %q: %v", mig.Name, err)
+	}
+
+	return instances, nil
+}
+
 // NameForInstanceGroupManager builds a name for an InstanceGroupManager in the specified zone
 func NameForInstanceGroupManager(c *kops.Cluster, ig *kops.InstanceGroup, zone string) string {
 	shortZone := zone
@@ -29,6 +29,10 @@ const (
 
 	GceLabelNameRolePrefix        = "k8s-io-role-"
 	GceLabelNameEtcdClusterPrefix = "k8s-io-etcd-"
+
+	// GceLabelNameDetachedFromMIG is the label set on instances that a rolling update has detached from their MIG;
+	// its value is the name of the MIG, so that the instances can be found again if the rolling update is resumed
+	GceLabelNameDetachedFromMIG = "kops-k8s-io-detached-from-mig"
 )
 
 // EncodeGCELabel encodes a string into an RFC1035 compatible value, suitable for use as GCE label key or value
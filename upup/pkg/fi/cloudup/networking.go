@@ -25,6 +25,7 @@ import (
 	api "k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/apis/kops/util"
 	"k8s.io/kops/pkg/assets"
+	"k8s.io/kops/util/pkg/architectures"
 	"k8s.io/kops/util/pkg/hashing"
 )
 
@@ -127,24 +128,35 @@ const (
 	defaultCNIAssetK8s1_11           = "https://storage.googleapis.com/kubernetes-release/network-plugins/cni-plugins-amd64-v0.7.5.tgz"
 	defaultCNIAssetHashStringK8s1_11 = "52e9d2de8a5f927307d9397308735658ee44ab8d"
 
+	// defaultCNIAssetArm64K8s1_11 is the arm64 CNI tarball for k8s >= 1.11; its hash is read from the .sha1 file
+	defaultCNIAssetArm64K8s1_11 = "https://storage.googleapis.com/kubernetes-release/network-plugins/cni-plugins-arm64-v0.7.5.tgz"
+
 	// Environment variable for overriding CNI url
 	ENV_VAR_CNI_VERSION_URL       = "CNI_VERSION_URL"
 	ENV_VAR_CNI_ASSET_HASH_STRING = "CNI_ASSET_HASH_STRING"
+
+	// Environment variable for overriding the arm64 CNI url
+	ENV_VAR_CNI_VERSION_URL_ARM64       = "CNI_VERSION_URL_ARM64"
+	ENV_VAR_CNI_ASSET_HASH_STRING_ARM64 = "CNI_ASSET_HASH_STRING_ARM64"
 )
 
-func findCNIAssets(c *api.Cluster, assetBuilder *assets.AssetBuilder) (*url.URL, *hashing.Hash, error) {
+func findCNIAssets(c *api.Cluster, assetBuilder *assets.AssetBuilder, arch architectures.Architecture) (*url.URL, *hashing.Hash, error) {
+	envVersionURL, envAssetHashString := ENV_VAR_CNI_VERSION_URL, ENV_VAR_CNI_ASSET_HASH_STRING
+	if arch == architectures.ArchitectureArm64 {
+		envVersionURL, envAssetHashString = ENV_VAR_CNI_VERSION_URL_ARM64, ENV_VAR_CNI_ASSET_HASH_STRING_ARM64
+	}
 
-	if cniVersionURL := os.Getenv(ENV_VAR_CNI_VERSION_URL); cniVersionURL != "" {
+	if cniVersionURL := os.Getenv(envVersionURL); cniVersionURL != "" {
 		u, err := url.Parse(cniVersionURL)
 		if err != nil {
 			return nil, nil, fmt.Errorf("unable to parse %q as a URL: %v", cniVersionURL, err)
 		}
 
-		klog.Infof("Using CNI asset version %q, as set in %s", cniVersionURL, ENV_VAR_CNI_VERSION_URL)
+		klog.Infof("Using CNI asset version %q, as set in %s", cniVersionURL, envVersionURL)
 
-		if cniAssetHashString := os.Getenv(ENV_VAR_CNI_ASSET_HASH_STRING); cniAssetHashString != "" {
+		if cniAssetHashString := os.Getenv(envAssetHashString); cniAssetHashString != "" {
 
-			klog.Infof("Using CNI asset hash %q, as set in %s", cniAssetHashString, ENV_VAR_CNI_ASSET_HASH_STRING)
+			klog.Infof("Using CNI asset hash %q, as set in %s", cniAssetHashString, envAssetHashString)
 
 			hash, err := hashing.FromString(cniAssetHashString)
 			if err != nil {
@@ -161,6 +173,20 @@ func findCNIAssets(c *api.Cluster, assetBuilder *assets.AssetBuilder) (*url.URL,
 		return nil, nil, fmt.Errorf("failed to lookup kubernetes version: %v", err)
 	}
 
+	if arch == architectures.ArchitectureArm64 {
+		if !util.IsKubernetesGTE("1.11", *sv) {
+			return nil, nil, fmt.Errorf("arm64 instance groups require kubernetes 1.11 or later")
+		}
+
+		u, err := url.Parse(defaultCNIAssetArm64K8s1_11)
+		if err != nil {
+			return nil, nil, err
+		}
+		klog.V(2).Infof("Adding default arm64 CNI asset for k8s >= 1.11: %s", defaultCNIAssetArm64K8s1_11)
+
+		return assetBuilder.RemapFileAndSHA(u)
+	}
+
 	var cniAsset, cniAssetHash string
 	if util.IsKubernetesGTE("1.11", *sv) {
 		cniAsset = defaultCNIAssetK8s1_11
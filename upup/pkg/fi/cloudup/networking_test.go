@@ -22,6 +22,7 @@ import (
 
 	api "k8s.io/kops/pkg/apis/kops"
 	"k8s.io/kops/pkg/assets"
+	"k8s.io/kops/util/pkg/architectures"
 )
 
 func Test_FindCNIAssetFromEnvironmentVariable(t *testing.T) {
@@ -36,7 +37,7 @@ func Test_FindCNIAssetFromEnvironmentVariable(t *testing.T) {
 	cluster.Spec.KubernetesVersion = "v1.9.0"
 
 	assetBuilder := assets.NewAssetBuilder(cluster, "")
-	cniAsset, cniAssetHash, err := findCNIAssets(cluster, assetBuilder)
+	cniAsset, cniAssetHash, err := findCNIAssets(cluster, assetBuilder, architectures.ArchitectureAmd64)
 
 	if err != nil {
 		t.Errorf("Unable to parse k8s version %s", err)
@@ -56,7 +57,7 @@ func Test_FindCNIAssetDefaultValue1_6(t *testing.T) {
 	cluster := &api.Cluster{}
 	cluster.Spec.KubernetesVersion = "v1.7.0"
 	assetBuilder := assets.NewAssetBuilder(cluster, "")
-	cniAsset, cniAssetHash, err := findCNIAssets(cluster, assetBuilder)
+	cniAsset, cniAssetHash, err := findCNIAssets(cluster, assetBuilder, architectures.ArchitectureAmd64)
 
 	if err != nil {
 		t.Errorf("Unable to parse k8s version %s", err)
@@ -77,7 +78,7 @@ func Test_FindCNIAssetDefaultValue1_5(t *testing.T) {
 	cluster := &api.Cluster{}
 	cluster.Spec.KubernetesVersion = "v1.5.12"
 	assetBuilder := assets.NewAssetBuilder(cluster, "")
-	cniAsset, cniAssetHash, err := findCNIAssets(cluster, assetBuilder)
+	cniAsset, cniAssetHash, err := findCNIAssets(cluster, assetBuilder, architectures.ArchitectureAmd64)
 
 	if err != nil {
 		t.Errorf("Unable to parse k8s version %s", err)
@@ -92,3 +93,40 @@ func Test_FindCNIAssetDefaultValue1_5(t *testing.T) {
 	}
 
 }
+
+func Test_FindCNIAssetArm64FromEnvironmentVariable(t *testing.T) {
+
+	desiredCNIVersion := "https://storage.googleapis.com/kubernetes-release/network-plugins/cni-plugins-arm64-TEST-VERSION.tgz"
+	os.Setenv(ENV_VAR_CNI_VERSION_URL, "https://storage.googleapis.com/kubernetes-release/network-plugins/cni-plugins-amd64-TEST-VERSION.tgz")
+	os.Setenv(ENV_VAR_CNI_VERSION_URL_ARM64, desiredCNIVersion)
+	defer func() {
+		os.Unsetenv(ENV_VAR_CNI_VERSION_URL)
+		os.Unsetenv(ENV_VAR_CNI_VERSION_URL_ARM64)
+	}()
+
+	cluster := &api.Cluster{}
+	cluster.Spec.KubernetesVersion = "v1.15.0"
+
+	assetBuilder := assets.NewAssetBuilder(cluster, "")
+	cniAsset, _, err := findCNIAssets(cluster, assetBuilder, architectures.ArchitectureArm64)
+
+	if err != nil {
+		t.Errorf("Unable to find arm64 CNI asset: %v", err)
+	}
+
+	if cniAsset.String() != desiredCNIVersion {
+		t.Errorf("Expected arm64 CNI version from Environment variable %q, but got %q instead", desiredCNIVersion, cniAsset)
+	}
+}
+
+func Test_FindCNIAssetArm64RequiresK8s1_11(t *testing.T) {
+
+	cluster := &api.Cluster{}
+	cluster.Spec.KubernetesVersion = "v1.10.0"
+	assetBuilder := assets.NewAssetBuilder(cluster, "")
+	_, _, err := findCNIAssets(cluster, assetBuilder, architectures.ArchitectureArm64)
+
+	if err == nil {
+		t.Errorf("Expected an error finding the arm64 CNI asset for k8s 1.10")
+	}
+}
@@ -99,6 +99,12 @@ func (c *openstackCloud) DeleteInstance(i *cloudinstances.CloudInstanceGroupMemb
 	return c.DeleteInstanceWithID(i.ID)
 }
 
+// DetachInstance is not supported on OpenStack: servers are only ever created by kops update cluster,
+// so there is nothing that would launch a replacement for the detached server. Surging is rejected by validation.
+func (c *openstackCloud) DetachInstance(i *cloudinstances.CloudInstanceGroupMember) error {
+	return fmt.Errorf("openstack cloud provider does not support surging")
+}
+
 func (c *openstackCloud) DeleteInstanceWithID(instanceID string) error {
 	return servers.Delete(c.novaClient, instanceID).ExtractErr()
 }
@@ -0,0 +1,220 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcegraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/klog"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+// ResourceGraphTarget renders the cloud resources of a cluster as a declarative graph,
+// with an edge from each resource to the resources it depends on.
+// Only tasks that define a RenderResourceGraph method are rendered; other tasks are skipped.
+type ResourceGraphTarget struct {
+	Cloud   fi.Cloud
+	Region  string
+	Project string
+
+	outDir string
+
+	// mutex protects the following items (resources)
+	mutex     sync.Mutex
+	resources map[fi.Task]*Resource
+}
+
+// Graph is the output of the ResourceGraphTarget
+type Graph struct {
+	// Cloud is the cloud provider of the resources
+	Cloud string `json:"cloud"`
+	// Region is the region in which the resources are created
+	Region string `json:"region,omitempty"`
+	// Project is the project in which the resources are created, for clouds that have projects
+	Project string `json:"project,omitempty"`
+	// Resources are the nodes of the graph, ordered by id
+	Resources []*Resource `json:"resources"`
+}
+
+// Resource is a node in the resource graph
+type Resource struct {
+	// ID uniquely identifies the resource in the graph; it is built by Ref
+	ID string `json:"id"`
+	// Type is the type of the resource, e.g. AWS::EC2::VPC
+	Type string `json:"type"`
+	// Name is the name of the resource
+	Name string `json:"name"`
+	// Properties are the desired settings of the resource
+	Properties interface{} `json:"properties,omitempty"`
+	// DependsOn are the ids of the resources that must exist before this resource is created
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+func NewResourceGraphTarget(cloud fi.Cloud, region, project string, outDir string) *ResourceGraphTarget {
+	return &ResourceGraphTarget{
+		Cloud:     cloud,
+		Region:    region,
+		Project:   project,
+		outDir:    outDir,
+		resources: make(map[fi.Task]*Resource),
+	}
+}
+
+var _ fi.OptInTarget = &ResourceGraphTarget{}
+
+// Ref returns the id of a resource, for use in the properties of resources that refer to it
+func Ref(resourceType, resourceName string) string {
+	return resourceType + "/" + resourceName
+}
+
+func (t *ResourceGraphTarget) ProcessDeletions() bool {
+	// The graph is declarative; consumers remove resources that are no longer in it
+	return false
+}
+
+// SkipTask implements fi.OptInTarget
+func (t *ResourceGraphTarget) SkipTask(e fi.Task) {
+	klog.V(2).Infof("task %T does not support the resource graph target; skipping", e)
+}
+
+// RenderResource adds the resource managed by task e to the graph
+func (t *ResourceGraphTarget) RenderResource(e fi.Task, resourceType string, resourceName string, properties interface{}) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.resources[e] != nil {
+		return fmt.Errorf("task %T rendered more than one resource", e)
+	}
+	t.resources[e] = &Resource{
+		ID:         Ref(resourceType, resourceName),
+		Type:       resourceType,
+		Name:       resourceName,
+		Properties: properties,
+	}
+
+	return nil
+}
+
+// buildGraph returns the rendered resources, with the dependencies between them found from the task graph.
+// A dependency on a task that was not rendered is replaced by that task's own rendered dependencies.
+func (t *ResourceGraphTarget) buildGraph(taskMap map[string]fi.Task) (*Graph, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	graph := &Graph{
+		Region:    t.Region,
+		Project:   t.Project,
+		Resources: []*Resource{},
+	}
+	if t.Cloud != nil {
+		graph.Cloud = string(t.Cloud.ProviderID())
+	}
+
+	ids := make(map[string]bool)
+	for _, resource := range t.resources {
+		if ids[resource.ID] {
+			return nil, fmt.Errorf("resource %q was rendered by more than one task", resource.ID)
+		}
+		ids[resource.ID] = true
+		graph.Resources = append(graph.Resources, resource)
+	}
+	sort.Slice(graph.Resources, func(i, j int) bool {
+		return graph.Resources[i].ID < graph.Resources[j].ID
+	})
+
+	rendered := make(map[string]*Resource)
+	for key, task := range taskMap {
+		if resource := t.resources[task]; resource != nil {
+			rendered[key] = resource
+		}
+	}
+
+	edges := fi.FindTaskDependencies(taskMap)
+
+	for key, resource := range rendered {
+		dependsOn := make(map[string]bool)
+		visited := make(map[string]bool)
+		queue := append([]string{}, edges[key]...)
+		for len(queue) != 0 {
+			dep := queue[0]
+			queue = queue[1:]
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+
+			if r := rendered[dep]; r != nil {
+				dependsOn[r.ID] = true
+			} else {
+				queue = append(queue, edges[dep]...)
+			}
+		}
+
+		resource.DependsOn = nil
+		for id := range dependsOn {
+			resource.DependsOn = append(resource.DependsOn, id)
+		}
+		sort.Strings(resource.DependsOn)
+	}
+
+	return graph, nil
+}
+
+func (t *ResourceGraphTarget) Finish(taskMap map[string]fi.Task) error {
+	graph, err := t.buildGraph(taskMap)
+	if err != nil {
+		return err
+	}
+
+	jsonBytes, err := json.MarshalIndent(graph, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling resource graph to json: %v", err)
+	}
+	yamlBytes, err := yaml.JSONToYAML(jsonBytes)
+	if err != nil {
+		return fmt.Errorf("error converting resource graph to yaml: %v", err)
+	}
+
+	files := make(map[string][]byte)
+	files["resourcegraph.json"] = append(jsonBytes, '\n')
+	files["resourcegraph.yaml"] = yamlBytes
+
+	for relativePath, contents := range files {
+		p := path.Join(t.outDir, relativePath)
+
+		err = os.MkdirAll(path.Dir(p), os.FileMode(0755))
+		if err != nil {
+			return fmt.Errorf("error creating output directory %q: %v", path.Dir(p), err)
+		}
+
+		err = ioutil.WriteFile(p, contents, os.FileMode(0644))
+		if err != nil {
+			return fmt.Errorf("error writing resource graph to output file %q: %v", p, err)
+		}
+	}
+
+	klog.Infof("Resource graph output is in %s", t.outDir)
+
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcegraph
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+type testTask struct {
+	Name   *string
+	Parent *testTask
+}
+
+func (t *testTask) Run(c *fi.Context) error {
+	return nil
+}
+
+func TestResourceGraphDependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resourcegraph")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	vpc := &testTask{Name: fi.String("vpc")}
+	// The security group is not rendered, so the launch template depends on the vpc through it
+	sg := &testTask{Name: fi.String("sg"), Parent: vpc}
+	lt := &testTask{Name: fi.String("lt"), Parent: sg}
+	asg := &testTask{Name: fi.String("asg"), Parent: lt}
+	taskMap := map[string]fi.Task{
+		"VPC/vpc":                vpc,
+		"SecurityGroup/sg":       sg,
+		"LaunchTemplate/lt":      lt,
+		"AutoscalingGroup/asg":   asg,
+		"AutoscalingGroup/other": &testTask{Name: fi.String("other")},
+	}
+
+	target := NewResourceGraphTarget(nil, "us-test-1", "", dir)
+	for _, task := range []*testTask{vpc, lt, asg} {
+		if err := target.RenderResource(task, "Test", *task.Name, map[string]string{"name": *task.Name}); err != nil {
+			t.Fatalf("error rendering %s: %v", *task.Name, err)
+		}
+	}
+	target.SkipTask(sg)
+
+	if err := target.Finish(taskMap); err != nil {
+		t.Fatalf("error writing resource graph: %v", err)
+	}
+
+	expected := &Graph{
+		Region: "us-test-1",
+		Resources: []*Resource{
+			{ID: "Test/asg", Type: "Test", Name: "asg", Properties: map[string]interface{}{"name": "asg"}, DependsOn: []string{"Test/lt"}},
+			{ID: "Test/lt", Type: "Test", Name: "lt", Properties: map[string]interface{}{"name": "lt"}, DependsOn: []string{"Test/vpc"}},
+			{ID: "Test/vpc", Type: "Test", Name: "vpc", Properties: map[string]interface{}{"name": "vpc"}},
+		},
+	}
+
+	for _, f := range []string{"resourcegraph.json", "resourcegraph.yaml"} {
+		b, err := ioutil.ReadFile(path.Join(dir, f))
+		if err != nil {
+			t.Fatalf("error reading %s: %v", f, err)
+		}
+
+		actual := &Graph{}
+		if path.Ext(f) == ".json" {
+			err = json.Unmarshal(b, actual)
+		} else {
+			err = yaml.Unmarshal(b, actual)
+		}
+		if err != nil {
+			t.Fatalf("error parsing %s: %v", f, err)
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("unexpected %s:\n%s", f, string(b))
+		}
+	}
+}
+
+func TestResourceGraphDuplicateRender(t *testing.T) {
+	target := NewResourceGraphTarget(nil, "", "", "")
+	task := &testTask{Name: fi.String("vpc")}
+	if err := target.RenderResource(task, "Test", "vpc", nil); err != nil {
+		t.Fatalf("error rendering: %v", err)
+	}
+	if err := target.RenderResource(task, "Test", "vpc", nil); err == nil {
+		t.Errorf("expected an error rendering a task twice")
+	}
+}
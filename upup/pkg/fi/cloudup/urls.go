@@ -26,6 +26,7 @@ import (
 	"k8s.io/klog"
 	"k8s.io/kops"
 	"k8s.io/kops/pkg/assets"
+	"k8s.io/kops/util/pkg/architectures"
 	"k8s.io/kops/util/pkg/hashing"
 )
 
@@ -55,11 +56,11 @@ var defaultKopsMirrors = []mirror{
 
 var kopsBaseUrl *url.URL
 
-// nodeUpLocation caches the nodeUpLocation url
-var nodeUpLocation *url.URL
+// nodeUpLocations caches the nodeup url for each architecture
+var nodeUpLocations = make(map[architectures.Architecture]*url.URL)
 
-// nodeUpHash caches the hash for nodeup
-var nodeUpHash *hashing.Hash
+// nodeUpHashes caches the hash of nodeup for each architecture
+var nodeUpHashes = make(map[architectures.Architecture]*hashing.Hash)
 
 // protokubeLocation caches the protokubeLocation url
 var protokubeLocation *url.URL
@@ -119,36 +120,46 @@ func SetKopsAssetsLocations(assetsBuilder *assets.AssetBuilder) error {
 	return nil
 }
 
-// NodeUpLocation returns the URL where nodeup should be downloaded
-func NodeUpLocation(assetsBuilder *assets.AssetBuilder) (*url.URL, *hashing.Hash, error) {
+// NodeUpLocation returns the URL where nodeup for the architecture should be downloaded
+func NodeUpLocation(assetsBuilder *assets.AssetBuilder, arch architectures.Architecture) (*url.URL, *hashing.Hash, error) {
 	// Avoid repeated logging
-	if nodeUpLocation != nil && nodeUpHash != nil {
+	if nodeUpLocations[arch] != nil && nodeUpHashes[arch] != nil {
 		// Avoid repeated logging
-		klog.V(8).Infof("Using cached nodeup location: %q", nodeUpLocation.String())
-		return nodeUpLocation, nodeUpHash, nil
+		klog.V(8).Infof("Using cached nodeup location for %s: %q", arch, nodeUpLocations[arch].String())
+		return nodeUpLocations[arch], nodeUpHashes[arch], nil
 	}
-	env := os.Getenv("NODEUP_URL")
+
+	envVar := "NODEUP_URL"
+	if arch != architectures.ArchitectureAmd64 {
+		envVar = "NODEUP_URL_" + strings.ToUpper(string(arch))
+	}
+
+	env := os.Getenv(envVar)
+	var location *url.URL
+	var hash *hashing.Hash
 	var err error
 	if env == "" {
-		nodeUpLocation, nodeUpHash, err = KopsFileUrl("linux/amd64/nodeup", assetsBuilder)
+		location, hash, err = KopsFileUrl(path.Join("linux", string(arch), "nodeup"), assetsBuilder)
 		if err != nil {
 			return nil, nil, err
 		}
-		klog.V(8).Infof("Using default nodeup location: %q", nodeUpLocation.String())
+		klog.V(8).Infof("Using default nodeup location for %s: %q", arch, location.String())
 	} else {
-		nodeUpLocation, err = url.Parse(env)
+		location, err = url.Parse(env)
 		if err != nil {
-			return nil, nil, fmt.Errorf("unable to parse env var NODEUP_URL %q as a url: %v", env, err)
+			return nil, nil, fmt.Errorf("unable to parse env var %s %q as a url: %v", envVar, env, err)
 		}
 
-		nodeUpLocation, nodeUpHash, err = assetsBuilder.RemapFileAndSHA(nodeUpLocation)
+		location, hash, err = assetsBuilder.RemapFileAndSHA(location)
 		if err != nil {
 			return nil, nil, err
 		}
-		klog.Warningf("Using nodeup location from NODEUP_URL env var: %q", nodeUpLocation.String())
+		klog.Warningf("Using nodeup location from %s env var: %q", envVar, location.String())
 	}
 
-	return nodeUpLocation, nodeUpHash, nil
+	nodeUpLocations[arch] = location
+	nodeUpHashes[arch] = hash
+	return location, hash, nil
 }
 
 // TODO make this a container when hosted assets
@@ -213,6 +213,10 @@ func (c *Context) Render(a, e, changes Task) error {
 
 	}
 	if renderer == nil {
+		if t, ok := c.Target.(OptInTarget); ok {
+			t.SkipTask(e)
+			return nil
+		}
 		return fmt.Errorf("Could not find Render method on type %T (target %T)", e, c.Target)
 	}
 	rendererArgs = append(rendererArgs, reflect.ValueOf(a))
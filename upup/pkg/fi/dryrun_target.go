@@ -25,6 +25,7 @@ import (
 	"strings"
 	"sync"
 
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/klog"
 	"k8s.io/kops/pkg/assets"
 	"k8s.io/kops/pkg/diff"
@@ -45,6 +46,9 @@ type DryRunTarget struct {
 
 	// assetBuilder records all assets used
 	assetBuilder *assets.AssetBuilder
+
+	// Tags are the tags of the machine, when the dry-run is against a node rather than a cloud
+	Tags sets.String
 }
 
 type render struct {
@@ -81,6 +85,12 @@ func NewDryRunTarget(assetBuilder *assets.AssetBuilder, out io.Writer) *DryRunTa
 	return t
 }
 
+// HasTag returns true if the machine being checked has the specified tag
+func (t *DryRunTarget) HasTag(tag string) bool {
+	_, found := t.Tags[tag]
+	return found
+}
+
 func (t *DryRunTarget) ProcessDeletions() bool {
 	// We display deletions
 	return true
@@ -151,56 +161,8 @@ func (t *DryRunTarget) PrintReport(taskMap map[string]Task, out io.Writer) error
 				taskName := getTaskName(r.changes)
 				fmt.Fprintf(b, "  %s/%s\n", taskName, idForTask(taskMap, r.e))
 
-				changes := reflect.ValueOf(r.changes)
-				if changes.Kind() == reflect.Ptr && !changes.IsNil() {
-					changes = changes.Elem()
-				}
-
-				if changes.Kind() == reflect.Struct {
-					for i := 0; i < changes.NumField(); i++ {
-
-						field := changes.Field(i)
-
-						fieldName := changes.Type().Field(i).Name
-						if changes.Type().Field(i).PkgPath != "" {
-							// Not exported
-							continue
-						}
-
-						fieldValue := reflectutils.ValueAsString(field)
-
-						shouldPrint := true
-						if fieldName == "Name" {
-							// The field name is already printed above, no need to repeat it.
-							shouldPrint = false
-						}
-						if fieldName == "Lifecycle" {
-							// Lifecycle is a "system" field; no need to show it
-							shouldPrint = false
-						}
-						if fieldValue == "<nil>" || fieldValue == "<resource>" {
-							// Uninformative
-							shouldPrint = false
-						}
-						if fieldValue == "id:<nil>" {
-							// Uninformative, but we can often print the name instead
-							name := ""
-							if field.CanInterface() {
-								hasName, ok := field.Interface().(HasName)
-								if ok {
-									name = StringValue(hasName.GetName())
-								}
-							}
-							if name != "" {
-								fieldValue = "name:" + name
-							} else {
-								shouldPrint = false
-							}
-						}
-						if shouldPrint {
-							fmt.Fprintf(b, "  \t%-20s\t%s\n", fieldName, fieldValue)
-						}
-					}
+				for _, change := range buildCreateList(r.changes, false) {
+					fmt.Fprintf(b, "  \t%-20s\t%s\n", change.FieldName, change.Description)
 				}
 
 				fmt.Fprintf(b, "\n")
@@ -276,6 +238,77 @@ func (t *DryRunTarget) PrintReport(taskMap map[string]Task, out io.Writer) error
 type change struct {
 	FieldName   string
 	Description string
+
+	// Before is the actual value of the field, and After the expected value
+	Before string
+	After  string
+}
+
+// buildCreateList returns the fields that are set on a task that is to be created.
+// The contents of resources are only included if includeResources is true, as rendering them can be expensive.
+func buildCreateList(changes Task, includeResources bool) []change {
+	var changeList []change
+
+	valC := reflect.ValueOf(changes)
+	if valC.Kind() == reflect.Ptr && !valC.IsNil() {
+		valC = valC.Elem()
+	}
+	if valC.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < valC.NumField(); i++ {
+		field := valC.Field(i)
+
+		fieldName := valC.Type().Field(i).Name
+		if valC.Type().Field(i).PkgPath != "" {
+			// Not exported
+			continue
+		}
+
+		if fieldName == "Name" {
+			// The field name is already printed above, no need to repeat it.
+			continue
+		}
+		if fieldName == "Lifecycle" {
+			// Lifecycle is a "system" field; no need to show it
+			continue
+		}
+
+		fieldValue := reflectutils.ValueAsString(field)
+		if fieldValue == "<nil>" {
+			// Uninformative
+			continue
+		}
+		if fieldValue == "<resource>" {
+			if !includeResources {
+				// Uninformative
+				continue
+			}
+			if s, ok := tryResourceAsString(field); ok {
+				changeList = append(changeList, change{FieldName: fieldName, Description: fieldValue, After: s})
+			}
+			continue
+		}
+		if fieldValue == "id:<nil>" {
+			// Uninformative, but we can often print the name instead
+			name := ""
+			if field.CanInterface() {
+				hasName, ok := field.Interface().(HasName)
+				if ok {
+					name = StringValue(hasName.GetName())
+				}
+			}
+			if name == "" {
+				continue
+			}
+			fieldValue = "name:" + name
+		}
+
+		changeList = append(changeList, change{FieldName: fieldName, Description: fieldValue, After: fieldValue})
+	}
+
+	return changeList
 }
 
 func buildChangeList(a, e, changes Task) ([]change, error) {
@@ -322,6 +355,8 @@ func buildChangeList(a, e, changes Task) ([]change, error) {
 			fieldValE := valE.Field(i)
 
 			description := ""
+			before := ""
+			after := ""
 			ignored := false
 			if fieldValE.CanInterface() {
 				fieldValA := valA.Field(i)
@@ -334,17 +369,21 @@ func buildChangeList(a, e, changes Task) ([]change, error) {
 					resE, okE := tryResourceAsString(fieldValE)
 					if okA && okE {
 						description = diff.FormatDiff(resA, resE)
+						before = resA
+						after = resE
 					}
 				}
 
 				if !ignored && description == "" {
-					description = fmt.Sprintf(" %v -> %v", reflectutils.ValueAsString(fieldValA), reflectutils.ValueAsString(fieldValE))
+					before = reflectutils.ValueAsString(fieldValA)
+					after = reflectutils.ValueAsString(fieldValE)
+					description = fmt.Sprintf(" %v -> %v", before, after)
 				}
 			}
 			if ignored {
 				continue
 			}
-			changeList = append(changeList, change{FieldName: valC.Type().Field(i).Name, Description: description})
+			changeList = append(changeList, change{FieldName: valC.Type().Field(i).Name, Description: description, Before: before, After: after})
 		}
 	} else {
 		return nil, fmt.Errorf("unhandled change type: %v", valC.Type())
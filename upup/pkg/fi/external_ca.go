@@ -0,0 +1,40 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"crypto/x509"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/pki"
+	"k8s.io/kops/pkg/pki/signer"
+)
+
+// UsesExternalCA returns true if certificates for the signer keyset are issued by an external CA,
+// in which case the keystore holds only the certificate chain of the CA, not its private key
+func UsesExternalCA(cluster *kops.Cluster, signerName string) bool {
+	return signerName == CertificateId_CA && cluster != nil && cluster.Spec.ExternalCA != nil
+}
+
+// IssueExternalCert issues a certificate through the external CA configured for the cluster
+func IssueExternalCert(cluster *kops.Cluster, caCertificate *pki.Certificate, privateKey *pki.PrivateKey, template *x509.Certificate) (*pki.Certificate, error) {
+	s, err := signer.New(cluster.Spec.ExternalCA, caCertificate)
+	if err != nil {
+		return nil, err
+	}
+	return signer.Issue(s, caCertificate, template, privateKey)
+}
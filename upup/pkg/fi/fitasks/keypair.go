@@ -83,7 +83,8 @@ func (e *Keypair) Find(c *fi.Context) (*Keypair, error) {
 	if cert == nil {
 		return nil, nil
 	}
-	if key == nil {
+	externalCA := fi.UsesExternalCA(c.Cluster, name)
+	if key == nil && !externalCA {
 		return nil, fmt.Errorf("found cert in store, but did not find private key: %q", name)
 	}
 
@@ -105,6 +106,14 @@ func (e *Keypair) Find(c *fi.Context) (*Keypair, error) {
 
 	actual.Signer = &Keypair{Subject: pkixNameToString(&cert.Certificate.Issuer)}
 
+	if externalCA {
+		// An external CA is imported rather than created by kops, so we only check that it exists
+		actual.Subject = e.Subject
+		actual.Type = e.Type
+		actual.AlternateNames = e.AlternateNames
+		actual.Signer = e.Signer
+	}
+
 	// Avoid spurious changes
 	actual.Lifecycle = e.Lifecycle
 
@@ -23,7 +23,9 @@ import (
 	"io"
 	"io/ioutil"
 	"net"
+	"os"
 	"os/exec"
+	"path"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +33,7 @@ import (
 	"k8s.io/kops/nodeup/pkg/distros"
 	"k8s.io/kops/nodeup/pkg/model"
 	api "k8s.io/kops/pkg/apis/kops"
+	apimodel "k8s.io/kops/pkg/apis/kops/model"
 	"k8s.io/kops/pkg/apis/kops/registry"
 	"k8s.io/kops/pkg/apis/nodeup"
 	"k8s.io/kops/pkg/assets"
@@ -40,6 +43,7 @@ import (
 	"k8s.io/kops/upup/pkg/fi/nodeup/nodetasks"
 	"k8s.io/kops/upup/pkg/fi/secrets"
 	"k8s.io/kops/upup/pkg/fi/utils"
+	"k8s.io/kops/util/pkg/architectures"
 	"k8s.io/kops/util/pkg/vfs"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -54,11 +58,27 @@ import (
 // MaxTaskDuration is the amount of time to keep trying for; we retry for a long time - there is not really any great fallback
 const MaxTaskDuration = 365 * 24 * time.Hour
 
+const (
+	// ModeApply configures the node
+	ModeApply = "apply"
+	// ModeCheck reports where the node has drifted from its configuration, without changing the node
+	ModeCheck = "check"
+	// ModeReconcile reports drift, and re-applies the drifted tasks that are safe to re-apply on a running node
+	ModeReconcile = "reconcile"
+	// ModeUpdate applies a changed in-place configuration to a running node, if the configuration has changed since it was last applied
+	ModeUpdate = "update"
+)
+
+// ErrDriftFound is returned by a check when the node has drifted from its configuration
+var ErrDriftFound = errors.New("node has drifted from its configuration")
+
 // NodeUpCommand is the configuration for nodeup
 type NodeUpCommand struct {
 	CacheDir       string
 	ConfigLocation string
 	FSRoot         string
+	MetricsFile    string
+	Mode           string
 	ModelDir       vfs.Path
 	Target         string
 	cluster        *api.Cluster
@@ -72,6 +92,27 @@ func (c *NodeUpCommand) Run(out io.Writer) error {
 		return fmt.Errorf("FSRoot is required")
 	}
 
+	switch c.Mode {
+	case "", ModeApply:
+	case ModeCheck, ModeReconcile, ModeUpdate:
+		if c.Target != "direct" {
+			return fmt.Errorf("mode %q can only be used with the direct target", c.Mode)
+		}
+	default:
+		return fmt.Errorf("unsupported mode %q", c.Mode)
+	}
+
+	if c.Target == "direct" {
+		if c.CacheDir == "" {
+			return fmt.Errorf("CacheDir is required")
+		}
+		lock, err := c.lockNode()
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
+	}
+
 	if c.ConfigLocation != "" {
 		config, err := vfs.Context.ReadFile(c.ConfigLocation)
 		if err != nil {
@@ -147,19 +188,46 @@ func (c *NodeUpCommand) Run(out io.Writer) error {
 	}
 
 	if c.config.InstanceGroupName != "" {
-		instanceGroupLocation := configBase.Join("instancegroup", c.config.InstanceGroupName)
+		var err error
+		c.instanceGroup, err = readInstanceGroup(configBase, c.config.InstanceGroupName)
+		if err != nil {
+			return err
+		}
+	} else {
+		klog.Warningf("No instance group defined in nodeup config")
+	}
 
-		c.instanceGroup = &api.InstanceGroup{}
-		b, err := instanceGroupLocation.ReadFile()
+	// The hash is computed before the spec is evaluated, so that it matches the hash computed by kops from the state store
+	var configHash string
+	if apimodel.InPlaceUpdates(c.cluster) && c.instanceGroup != nil {
+		var err error
+		configHash, err = apimodel.InPlaceConfigHash(c.cluster, c.instanceGroup)
 		if err != nil {
-			return fmt.Errorf("error loading InstanceGroup %q: %v", instanceGroupLocation, err)
+			return err
 		}
+	}
 
-		if err = utils.YamlUnmarshal(b, c.instanceGroup); err != nil {
-			return fmt.Errorf("error parsing InstanceGroup %q: %v", instanceGroupLocation, err)
+	if c.Mode == ModeUpdate {
+		if configHash == "" {
+			klog.Infof("In-place updates are not enabled for this node; nothing to do")
+			return nil
 		}
-	} else {
-		klog.Warningf("No instance group defined in nodeup config")
+
+		state, err := readConfigState(path.Join(c.CacheDir, configStateFile))
+		if err != nil {
+			return err
+		}
+		if state.Hash == configHash {
+			klog.Infof("Configuration %s is already applied", configHash)
+			if state.Complete && !state.Reported {
+				if err := evaluateSpec(c.cluster); err != nil {
+					return err
+				}
+				return c.recordConfigState(state)
+			}
+			return nil
+		}
+		klog.Infof("Configuration has changed from %q to %q; applying in place", state.Hash, configHash)
 	}
 
 	err := evaluateSpec(c.cluster)
@@ -172,6 +240,11 @@ func (c *NodeUpCommand) Run(out io.Writer) error {
 		return fmt.Errorf("error determining OS distribution: %v", err)
 	}
 
+	architecture, err := architectures.FindArchitecture()
+	if err != nil {
+		return fmt.Errorf("error determining architecture: %v", err)
+	}
+
 	osTags := distribution.BuildTags()
 
 	nodeTags := sets.NewString()
@@ -182,10 +255,11 @@ func (c *NodeUpCommand) Run(out io.Writer) error {
 	klog.Infof("OS tags: %v", osTags)
 
 	modelContext := &model.NodeupModelContext{
-		Architecture:  model.ArchitectureAmd64,
+		Architecture:  model.Architecture(architecture),
 		Assets:        assetStore,
 		Cluster:       c.cluster,
 		Distribution:  distribution,
+		FSRoot:        c.FSRoot,
 		InstanceGroup: c.instanceGroup,
 		NodeupConfig:  c.config,
 	}
@@ -218,8 +292,20 @@ func (c *NodeUpCommand) Run(out io.Writer) error {
 		return err
 	}
 
-	if err := loadKernelModules(modelContext); err != nil {
-		return err
+	// The hooks and file assets are recorded with the applied configuration, so that those removed from the spec
+	// can be removed from the node when a later configuration is applied
+	var units, files []string
+	if configHash != "" {
+		units, files, err = inPlaceAssets(modelContext)
+		if err != nil {
+			return fmt.Errorf("error building in-place assets: %v", err)
+		}
+	}
+
+	if c.Mode != ModeCheck {
+		if err := loadKernelModules(modelContext); err != nil {
+			return err
+		}
 	}
 
 	loader := NewLoader(c.config, c.cluster, assetStore, nodeTags)
@@ -227,6 +313,7 @@ func (c *NodeUpCommand) Run(out io.Writer) error {
 	loader.Builders = append(loader.Builders, &model.UpdateServiceBuilder{NodeupModelContext: modelContext})
 	loader.Builders = append(loader.Builders, &model.VolumesBuilder{NodeupModelContext: modelContext})
 	loader.Builders = append(loader.Builders, &model.DockerBuilder{NodeupModelContext: modelContext})
+	loader.Builders = append(loader.Builders, &model.ContainerdBuilder{NodeupModelContext: modelContext})
 	loader.Builders = append(loader.Builders, &model.ProtokubeBuilder{NodeupModelContext: modelContext})
 	loader.Builders = append(loader.Builders, &model.CloudConfigBuilder{NodeupModelContext: modelContext})
 	loader.Builders = append(loader.Builders, &model.FileAssetsBuilder{NodeupModelContext: modelContext})
@@ -236,6 +323,17 @@ func (c *NodeUpCommand) Run(out io.Writer) error {
 	loader.Builders = append(loader.Builders, &model.KubectlBuilder{NodeupModelContext: modelContext})
 	loader.Builders = append(loader.Builders, &model.EtcdBuilder{NodeupModelContext: modelContext})
 	loader.Builders = append(loader.Builders, &model.LogrotateBuilder{NodeupModelContext: modelContext})
+	if c.cluster.Spec.NodeReconciliation != nil {
+		nodeupPath, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("error finding the nodeup binary: %v", err)
+		}
+		loader.Builders = append(loader.Builders, &model.NodeReconciliationBuilder{
+			NodeupModelContext: modelContext,
+			Nodeup:             nodeupPath,
+			ConfigLocation:     c.ConfigLocation,
+		})
+	}
 	loader.Builders = append(loader.Builders, &model.ManifestsBuilder{NodeupModelContext: modelContext})
 	loader.Builders = append(loader.Builders, &model.PackagesBuilder{NodeupModelContext: modelContext})
 	loader.Builders = append(loader.Builders, &model.SecretBuilder{NodeupModelContext: modelContext})
@@ -295,13 +393,46 @@ func (c *NodeUpCommand) Run(out io.Writer) error {
 		taskMap["LoadImage."+strconv.Itoa(i)] = &nodetasks.LoadImageTask{
 			Sources: image.Sources,
 			Hash:    image.Hash,
+			Runtime: modelContext.ContainerRuntime(),
 		}
 	}
 	if c.config.ProtokubeImage != nil {
 		taskMap["LoadImage.protokube"] = &nodetasks.LoadImageTask{
 			Sources: c.config.ProtokubeImage.Sources,
 			Hash:    c.config.ProtokubeImage.Hash,
+			Runtime: modelContext.ContainerRuntime(),
+		}
+	}
+
+	switch c.Mode {
+	case ModeCheck, ModeReconcile:
+		report, err := c.checkDrift(out, taskMap, nodeTags, configBase)
+		if err != nil {
+			return err
+		}
+		if c.Mode == ModeCheck && len(report.Drifted) != 0 {
+			return ErrDriftFound
+		}
+		return nil
+
+	case ModeUpdate:
+		report, err := c.checkDrift(out, taskMap, nodeTags, configBase)
+		if err != nil {
+			return err
+		}
+		complete := len(report.Drifted) == len(report.Reconciled)
+		if !complete {
+			klog.Warningf("%d changes cannot be applied in place; the instance must be replaced to apply them", len(report.Drifted)-len(report.Reconciled))
 		}
+		if err := c.removeStaleAssets(nodeTags, units, files); err != nil {
+			return err
+		}
+		return c.recordConfigState(&configState{
+			Hash:     configHash,
+			Complete: complete,
+			Units:    units,
+			Files:    files,
+		})
 	}
 
 	var cloud fi.Cloud
@@ -318,7 +449,9 @@ func (c *NodeUpCommand) Run(out io.Writer) error {
 		}
 	case "dryrun":
 		assetBuilder := assets.NewAssetBuilder(c.cluster, "")
-		target = fi.NewDryRunTarget(assetBuilder, out)
+		dryRunTarget := fi.NewDryRunTarget(assetBuilder, out)
+		dryRunTarget.Tags = nodeTags
+		target = dryRunTarget
 	case "cloudinit":
 		checkExisting = false
 		target = cloudinit.NewCloudInitTarget(out, nodeTags)
@@ -345,9 +478,45 @@ func (c *NodeUpCommand) Run(out io.Writer) error {
 		klog.Exitf("error closing target: %v", err)
 	}
 
+	if c.Target == "direct" && configHash != "" {
+		if err := c.removeStaleAssets(nodeTags, units, files); err != nil {
+			return err
+		}
+		state := &configState{
+			Hash:     configHash,
+			Complete: true,
+			Units:    units,
+			Files:    files,
+		}
+		if err := c.recordConfigState(state); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// readInstanceGroup reads the instance group as published by kops update cluster, so that edits are not applied to
+// the node before then. The instance group as edited is only read if it has not been published by this version of kops.
+func readInstanceGroup(configBase vfs.Path, name string) (*api.InstanceGroup, error) {
+	p := registry.InstanceGroupCompletedPath(configBase, name)
+	b, err := p.ReadFile()
+	if err != nil && os.IsNotExist(err) {
+		klog.Warningf("InstanceGroup %q has not been published by kops update cluster; reading it from the registry", name)
+		p = configBase.Join("instancegroup", name)
+		b, err = p.ReadFile()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading InstanceGroup %q: %v", p, err)
+	}
+
+	ig := &api.InstanceGroup{}
+	if err := utils.YamlUnmarshal(b, ig); err != nil {
+		return nil, fmt.Errorf("error parsing InstanceGroup %q: %v", p, err)
+	}
+	return ig, nil
+}
+
 func evaluateSpec(c *api.Cluster) error {
 	var err error
 
@@ -0,0 +1,336 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeup
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/assets"
+	"k8s.io/kops/pkg/diff"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/nodeup/local"
+	"k8s.io/kops/upup/pkg/fi/nodeup/nodetasks"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+// driftReport describes how the node differs from the configuration nodeup would apply
+type driftReport struct {
+	// Checked is the number of tasks that were checked
+	Checked int
+	// Drifted are the changes that applying the configuration would make
+	Drifted []*fi.PlanChange
+	// Reconciled are the keys of the drifted tasks that were re-applied
+	Reconciled []string
+}
+
+// isDriftCheckable returns true if the task can find the current state of the node;
+// tasks that cannot would always be reported as drifted, so they are not checked
+func isDriftCheckable(task fi.Task) bool {
+	switch task.(type) {
+	case *nodetasks.Chattr, *nodetasks.LoadImageTask, *nodetasks.UpdatePackages:
+		return false
+	default:
+		return true
+	}
+}
+
+// isReconcilable returns true if the task is safe to re-apply on a running node.
+// Restoring packages, archives, images or disks could disrupt running workloads, so drift in them is only reported.
+func isReconcilable(task fi.Task) bool {
+	switch task.(type) {
+	case *nodetasks.File, *nodetasks.Service, *nodetasks.GroupTask, *nodetasks.UserTask:
+		return true
+	default:
+		return false
+	}
+}
+
+// isSteadyState returns true if the change to a service is only the state it settles in after nodeup has run, rather than drift:
+// a oneshot service is inactive once it has exited unless it sets RemainAfterExit, and a unit without an [Install] section
+// cannot be enabled
+func isSteadyState(change *fi.PlanChange) bool {
+	service, ok := change.Task.(*nodetasks.Service)
+	if !ok || change.Action != fi.PlanActionUpdate {
+		return false
+	}
+
+	settings := make(map[string]string)
+	for _, line := range strings.Split(fi.StringValue(service.Definition), "\n") {
+		tokens := strings.SplitN(line, "=", 2)
+		if len(tokens) == 2 {
+			settings[strings.TrimSpace(tokens[0])] = strings.TrimSpace(tokens[1])
+		}
+	}
+
+	for _, field := range change.Fields {
+		switch field.Name {
+		case "Running":
+			if settings["Type"] != "oneshot" || settings["RemainAfterExit"] == "yes" {
+				return false
+			}
+		case "Enabled":
+			if settings["WantedBy"] != "" || settings["RequiredBy"] != "" {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// checkDrift finds where the node has drifted from the task graph, without changing the node.
+// In reconcile and update modes, the drifted tasks that are safe to re-apply are then applied.
+func (c *NodeUpCommand) checkDrift(out io.Writer, taskMap map[string]fi.Task, nodeTags sets.String, configBase vfs.Path) (*driftReport, error) {
+	checkMap := make(map[string]fi.Task)
+	for k, task := range taskMap {
+		if isDriftCheckable(task) {
+			checkMap[k] = task
+		}
+	}
+
+	target := fi.NewDryRunTarget(assets.NewAssetBuilder(c.cluster, ""), out)
+	target.Tags = nodeTags
+	if err := runTasks(target, checkMap, configBase); err != nil {
+		return nil, fmt.Errorf("error checking tasks: %v", err)
+	}
+
+	plan, err := target.Plan(checkMap)
+	if err != nil {
+		return nil, fmt.Errorf("error building drift report: %v", err)
+	}
+	report := &driftReport{
+		Checked: len(plan.Tasks),
+	}
+	for _, change := range plan.Changes {
+		if !isSteadyState(change) {
+			report.Drifted = append(report.Drifted, change)
+		}
+	}
+
+	if c.Mode == ModeReconcile || c.Mode == ModeUpdate {
+		report.Reconciled, err = c.reconcile(checkMap, report.Drifted, nodeTags, configBase)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report.Print(out)
+
+	if c.MetricsFile != "" {
+		if err := writeMetricsFile(c.MetricsFile, report, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// reconcile re-applies the drifted tasks that are safe to re-apply, returning their keys.
+// Running services that depend on a restored file are restarted, as SmartRestart would do on boot.
+func (c *NodeUpCommand) reconcile(taskMap map[string]fi.Task, drifted []*fi.PlanChange, nodeTags sets.String, configBase vfs.Path) ([]string, error) {
+	applyMap := make(map[string]fi.Task)
+	var reconciled []string
+	var files []string
+	for _, change := range drifted {
+		if change.Task == nil || !isReconcilable(change.Task) {
+			klog.Warningf("not re-applying %s; it is not safe to re-apply on a running node", change.Key)
+			continue
+		}
+		for k, task := range taskMap {
+			if task == change.Task {
+				applyMap[k] = task
+			}
+		}
+		if file, ok := change.Task.(*nodetasks.File); ok {
+			files = append(files, file.Path)
+		}
+		reconciled = append(reconciled, change.Key)
+	}
+	if len(applyMap) == 0 {
+		return nil, nil
+	}
+
+	target := &local.LocalTarget{
+		CacheDir: c.CacheDir,
+		Tags:     nodeTags,
+	}
+	if err := runTasks(target, applyMap, configBase); err != nil {
+		return nil, fmt.Errorf("error re-applying tasks: %v", err)
+	}
+
+	for k, task := range taskMap {
+		service, ok := task.(*nodetasks.Service)
+		if !ok || applyMap[k] != nil {
+			continue
+		}
+		if !fi.BoolValue(service.ManageState) || !fi.BoolValue(service.SmartRestart) || !fi.BoolValue(service.Running) {
+			continue
+		}
+		restart, err := service.DependsOn(files)
+		if err != nil {
+			return nil, err
+		}
+		if !restart {
+			continue
+		}
+		klog.Infof("Restarting service %q because a restored file is a dependency", service.Name)
+		cmd := exec.Command("systemctl", "restart", service.Name)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return nil, fmt.Errorf("error doing systemd restart %s: %v\nOutput: %s", service.Name, err, output)
+		}
+	}
+
+	return reconciled, nil
+}
+
+// runTasks runs the tasks against the target, finding the existing state of each one
+func runTasks(target fi.Target, taskMap map[string]fi.Task, configBase vfs.Path) error {
+	context, err := fi.NewContext(target, nil, nil, nil, nil, configBase, true, taskMap)
+	if err != nil {
+		return fmt.Errorf("error building context: %v", err)
+	}
+	defer context.Close()
+
+	var options fi.RunTasksOptions
+	options.InitDefaults()
+
+	return context.RunTasks(options)
+}
+
+// Print writes a human-readable description of the drift
+func (r *driftReport) Print(out io.Writer) {
+	b := &bytes.Buffer{}
+
+	if len(r.Drifted) == 0 {
+		fmt.Fprintf(b, "Checked %d tasks; no drift found\n", r.Checked)
+	} else {
+		fmt.Fprintf(b, "Checked %d tasks; %d have drifted from the node configuration:\n", r.Checked, len(r.Drifted))
+		for _, change := range r.Drifted {
+			fmt.Fprintf(b, "  %s\t%s\n", change.Action, change.Key)
+			if change.Action != fi.PlanActionUpdate {
+				continue
+			}
+			for _, field := range change.Fields {
+				if strings.Contains(field.Before, "\n") || strings.Contains(field.After, "\n") {
+					fmt.Fprintf(b, "  \t%s\n", field.Name)
+					for _, line := range strings.Split(strings.TrimSuffix(diff.FormatDiff(field.Before, field.After), "\n"), "\n") {
+						fmt.Fprintf(b, "  \t\t%s\n", line)
+					}
+				} else {
+					fmt.Fprintf(b, "  \t%s\t%s -> %s\n", field.Name, field.Before, field.After)
+				}
+			}
+		}
+	}
+
+	if len(r.Reconciled) != 0 {
+		fmt.Fprintf(b, "Re-applied %d tasks:\n", len(r.Reconciled))
+		for _, key := range r.Reconciled {
+			fmt.Fprintf(b, "  %s\n", key)
+		}
+	}
+
+	b.WriteTo(out)
+}
+
+// WriteMetrics writes the drift in the Prometheus text exposition format
+func (r *driftReport) WriteMetrics(out io.Writer, now time.Time) {
+	b := &bytes.Buffer{}
+
+	fmt.Fprintf(b, "# HELP kops_nodeup_drift_check_timestamp_seconds Time of the last nodeup drift check.\n")
+	fmt.Fprintf(b, "# TYPE kops_nodeup_drift_check_timestamp_seconds gauge\n")
+	fmt.Fprintf(b, "kops_nodeup_drift_check_timestamp_seconds %d\n", now.Unix())
+
+	fmt.Fprintf(b, "# HELP kops_nodeup_checked_tasks Number of nodeup tasks checked for drift.\n")
+	fmt.Fprintf(b, "# TYPE kops_nodeup_checked_tasks gauge\n")
+	fmt.Fprintf(b, "kops_nodeup_checked_tasks %d\n", r.Checked)
+
+	fmt.Fprintf(b, "# HELP kops_nodeup_drifted_tasks Number of nodeup tasks whose configuration differs from the node.\n")
+	fmt.Fprintf(b, "# TYPE kops_nodeup_drifted_tasks gauge\n")
+	fmt.Fprintf(b, "kops_nodeup_drifted_tasks %d\n", len(r.Drifted))
+
+	fmt.Fprintf(b, "# HELP kops_nodeup_reconciled_tasks Number of drifted nodeup tasks that were re-applied.\n")
+	fmt.Fprintf(b, "# TYPE kops_nodeup_reconciled_tasks gauge\n")
+	fmt.Fprintf(b, "kops_nodeup_reconciled_tasks %d\n", len(r.Reconciled))
+
+	if len(r.Drifted) != 0 {
+		reconciled := sets.NewString(r.Reconciled...)
+
+		drifted := make([]*fi.PlanChange, len(r.Drifted))
+		copy(drifted, r.Drifted)
+		sort.SliceStable(drifted, func(i, j int) bool {
+			return drifted[i].Key < drifted[j].Key
+		})
+
+		fmt.Fprintf(b, "# HELP kops_nodeup_task_drift Nodeup tasks whose configuration differs from the node; reconciled is true if the task was re-applied.\n")
+		fmt.Fprintf(b, "# TYPE kops_nodeup_task_drift gauge\n")
+		for _, change := range drifted {
+			fmt.Fprintf(b, "kops_nodeup_task_drift{task=%q,type=%q,action=%q,reconciled=\"%t\"} 1\n", change.Key, change.Type, string(change.Action), reconciled.Has(change.Key))
+		}
+	}
+
+	b.WriteTo(out)
+}
+
+// writeMetricsFile writes the metrics to a file, replacing it atomically so that a collector never reads a partial file
+func writeMetricsFile(p string, r *driftReport, now time.Time) error {
+	var b bytes.Buffer
+	r.WriteMetrics(&b, now)
+	return writeFileAtomic(p, b.Bytes(), 0644)
+}
+
+// writeFileAtomic writes data to a temp file and renames it over p, so readers never see a partial file
+func writeFileAtomic(p string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating directory %q: %v", dir, err)
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(p))
+	if err != nil {
+		return fmt.Errorf("error creating temp file in %q: %v", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing file %q: %v", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing file %q: %v", tmp.Name(), err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("error setting permissions on %q: %v", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("error writing file %q: %v", p, err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeup
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/nodeup/nodetasks"
+)
+
+func TestIsSteadyState(t *testing.T) {
+	oneshot := &nodetasks.Service{
+		Name:       "logrotate.service",
+		Definition: fi.String("[Service]\nType=oneshot\nExecStart=/usr/sbin/logrotate /etc/logrotate.conf\n"),
+	}
+	remainAfterExit := &nodetasks.Service{
+		Name:       "kubernetes-iptables-setup.service",
+		Definition: fi.String("[Service]\nType=oneshot\nRemainAfterExit=yes\nExecStart=/home/kubernetes/bin/iptables-setup\n\n[Install]\nWantedBy=basic.target\n"),
+	}
+	daemon := &nodetasks.Service{
+		Name:       "kubelet.service",
+		Definition: fi.String("[Service]\nExecStart=/usr/local/bin/kubelet\n\n[Install]\nWantedBy=multi-user.target\n"),
+	}
+
+	grid := []struct {
+		Change   *fi.PlanChange
+		Expected bool
+	}{
+		{
+			Change:   &fi.PlanChange{Action: fi.PlanActionUpdate, Task: oneshot, Fields: []*fi.PlanFieldChange{{Name: "Running"}, {Name: "Enabled"}}},
+			Expected: true,
+		},
+		{
+			Change:   &fi.PlanChange{Action: fi.PlanActionUpdate, Task: oneshot, Fields: []*fi.PlanFieldChange{{Name: "Definition"}}},
+			Expected: false,
+		},
+		{
+			Change:   &fi.PlanChange{Action: fi.PlanActionCreate, Task: oneshot},
+			Expected: false,
+		},
+		{
+			Change:   &fi.PlanChange{Action: fi.PlanActionUpdate, Task: remainAfterExit, Fields: []*fi.PlanFieldChange{{Name: "Running"}}},
+			Expected: false,
+		},
+		{
+			Change:   &fi.PlanChange{Action: fi.PlanActionUpdate, Task: daemon, Fields: []*fi.PlanFieldChange{{Name: "Running"}}},
+			Expected: false,
+		},
+		{
+			Change:   &fi.PlanChange{Action: fi.PlanActionUpdate, Task: daemon, Fields: []*fi.PlanFieldChange{{Name: "Enabled"}}},
+			Expected: false,
+		},
+		{
+			Change:   &fi.PlanChange{Action: fi.PlanActionUpdate, Task: &nodetasks.File{Path: "/etc/sysconfig/kubelet"}, Fields: []*fi.PlanFieldChange{{Name: "Contents"}}},
+			Expected: false,
+		},
+	}
+	for _, g := range grid {
+		actual := isSteadyState(g.Change)
+		if actual != g.Expected {
+			t.Errorf("isSteadyState(%s %v): expected %v, got %v", g.Change.Action, g.Change.Task, g.Expected, actual)
+		}
+	}
+}
+
+func testDriftReport() *driftReport {
+	return &driftReport{
+		Checked: 42,
+		Drifted: []*fi.PlanChange{
+			{
+				Key:    "File/etc/sysconfig/kubelet",
+				Type:   "File",
+				Name:   "etc/sysconfig/kubelet",
+				Action: fi.PlanActionUpdate,
+				Fields: []*fi.PlanFieldChange{
+					{Name: "Contents", Before: "DAEMON_ARGS=\"--v=4\"\n", After: "DAEMON_ARGS=\"--v=2\"\n"},
+					{Name: "Mode", Before: "0600", After: "0644"},
+				},
+			},
+			{
+				Key:    "File/etc/kubernetes/kubelet.conf",
+				Type:   "File",
+				Name:   "etc/kubernetes/kubelet.conf",
+				Action: fi.PlanActionCreate,
+			},
+			{
+				Key:    "Package/docker-ce",
+				Type:   "Package",
+				Name:   "docker-ce",
+				Action: fi.PlanActionUpdate,
+				Fields: []*fi.PlanFieldChange{
+					{Name: "Version", Before: "18.06.2", After: "18.06.3"},
+				},
+			},
+		},
+		Reconciled: []string{"File/etc/kubernetes/kubelet.conf", "File/etc/sysconfig/kubelet"},
+	}
+}
+
+func TestDriftReport_Print(t *testing.T) {
+	var b bytes.Buffer
+	(&driftReport{Checked: 42}).Print(&b)
+	if b.String() != "Checked 42 tasks; no drift found\n" {
+		t.Errorf("unexpected report:\n%s", b.String())
+	}
+
+	b.Reset()
+	testDriftReport().Print(&b)
+	expected := `Checked 42 tasks; 3 have drifted from the node configuration:
+  update	File/etc/sysconfig/kubelet
+  	Contents
+  		+ DAEMON_ARGS="--v=2"
+  		- DAEMON_ARGS="--v=4"
+  	Mode	0600 -> 0644
+  create	File/etc/kubernetes/kubelet.conf
+  update	Package/docker-ce
+  	Version	18.06.2 -> 18.06.3
+Re-applied 2 tasks:
+  File/etc/kubernetes/kubelet.conf
+  File/etc/sysconfig/kubelet
+`
+	if b.String() != expected {
+		t.Errorf("unexpected report; expected:\n%s\nactual:\n%s", expected, b.String())
+	}
+}
+
+func TestDriftReport_WriteMetrics(t *testing.T) {
+	var b bytes.Buffer
+	testDriftReport().WriteMetrics(&b, time.Unix(1571400000, 0))
+
+	expected := `# HELP kops_nodeup_drift_check_timestamp_seconds Time of the last nodeup drift check.
+# TYPE kops_nodeup_drift_check_timestamp_seconds gauge
+kops_nodeup_drift_check_timestamp_seconds 1571400000
+# HELP kops_nodeup_checked_tasks Number of nodeup tasks checked for drift.
+# TYPE kops_nodeup_checked_tasks gauge
+kops_nodeup_checked_tasks 42
+# HELP kops_nodeup_drifted_tasks Number of nodeup tasks whose configuration differs from the node.
+# TYPE kops_nodeup_drifted_tasks gauge
+kops_nodeup_drifted_tasks 3
+# HELP kops_nodeup_reconciled_tasks Number of drifted nodeup tasks that were re-applied.
+# TYPE kops_nodeup_reconciled_tasks gauge
+kops_nodeup_reconciled_tasks 2
+# HELP kops_nodeup_task_drift Nodeup tasks whose configuration differs from the node; reconciled is true if the task was re-applied.
+# TYPE kops_nodeup_task_drift gauge
+kops_nodeup_task_drift{task="File/etc/kubernetes/kubelet.conf",type="File",action="create",reconciled="true"} 1
+kops_nodeup_task_drift{task="File/etc/sysconfig/kubelet",type="File",action="update",reconciled="true"} 1
+kops_nodeup_task_drift{task="Package/docker-ce",type="Package",action="update",reconciled="false"} 1
+`
+	if b.String() != expected {
+		t.Errorf("unexpected metrics; expected:\n%s\nactual:\n%s", expected, b.String())
+	}
+}
+
+func TestWriteMetricsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "metrics")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, "textfile", "kops_nodeup.prom")
+	for i := 0; i < 2; i++ {
+		if err := writeMetricsFile(p, &driftReport{Checked: 42}, time.Unix(1571400000, 0)); err != nil {
+			t.Fatalf("error writing metrics file: %v", err)
+		}
+	}
+
+	files, err := ioutil.ReadDir(filepath.Dir(p))
+	if err != nil {
+		t.Fatalf("error reading directory: %v", err)
+	}
+	if len(files) != 1 || files[0].Name() != "kops_nodeup.prom" {
+		t.Errorf("expected only the metrics file to be written, found %v", files)
+	}
+	if files[0].Mode().Perm() != 0644 {
+		t.Errorf("expected metrics file to have mode 0644, got %v", files[0].Mode().Perm())
+	}
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog"
+	"k8s.io/kops/nodeup/pkg/model"
+	apimodel "k8s.io/kops/pkg/apis/kops/model"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/upup/pkg/fi/nodeup/local"
+	"k8s.io/kops/upup/pkg/fi/nodeup/nodetasks"
+)
+
+const (
+	// configStateFile is the file in the cache directory in which nodeup records the in-place configuration it has applied
+	configStateFile = "inplace-config.json"
+	// lockFile is the file in the cache directory that is locked while nodeup runs against the node,
+	// so that nodeup is never run concurrently by the boot service and the timers
+	lockFile = "nodeup.lock"
+	// kubeletKubeConfig is the kubeconfig with which nodeup updates the Node object
+	kubeletKubeConfig = "/var/lib/kubelet/kubeconfig"
+)
+
+// configState is the in-place configuration that has been applied to the node
+type configState struct {
+	// Hash is the hash of the in-place configuration, as computed by InPlaceConfigHash
+	Hash string `json:"hash,omitempty"`
+	// Complete is true if all the changes were applied; if false the instance must be replaced to apply the rest
+	Complete bool `json:"complete,omitempty"`
+	// Reported is true once the hash has been set on the Node object
+	Reported bool `json:"reported,omitempty"`
+	// Units are the units of the hooks installed on the node
+	Units []string `json:"units,omitempty"`
+	// Files are the paths of the file assets installed on the node
+	Files []string `json:"files,omitempty"`
+}
+
+// systemctl runs systemctl with the arguments; it is replaced in tests
+var systemctl = func(args ...string) error {
+	output, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error running systemctl %s: %v\nOutput: %s", strings.Join(args, " "), err, output)
+	}
+	return nil
+}
+
+// lockNode takes an exclusive lock for running nodeup against the node, waiting for any other run to finish.
+// The lock is released when the returned file is closed.
+func (c *NodeUpCommand) lockNode() (*os.File, error) {
+	if err := os.MkdirAll(c.CacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating directory %q: %v", c.CacheDir, err)
+	}
+
+	p := path.Join(c.CacheDir, lockFile)
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file %q: %v", p, err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error locking %q: %v", p, err)
+	}
+	return f, nil
+}
+
+// readConfigState returns the in-place configuration applied to the node, or an empty state if none has been recorded
+func readConfigState(p string) (*configState, error) {
+	state := &configState{}
+
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("error reading %q: %v", p, err)
+	}
+
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("error parsing %q: %v", p, err)
+	}
+	return state, nil
+}
+
+// writeConfigState records the in-place configuration applied to the node
+func writeConfigState(p string, state *configState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error serializing configuration state: %v", err)
+	}
+	return writeFileAtomic(p, b, 0644)
+}
+
+// recordConfigState records the in-place configuration that has been applied, and if it was applied completely,
+// reports its hash on the Node object. Failing to report is not an error; it is retried on the next update check.
+func (c *NodeUpCommand) recordConfigState(state *configState) error {
+	state.Reported = false
+	if state.Complete {
+		if err := c.reportConfigHash(state.Hash); err != nil {
+			klog.Warningf("unable to report the configuration hash on the node (will retry): %v", err)
+		} else {
+			state.Reported = true
+		}
+	}
+	return writeConfigState(path.Join(c.CacheDir, configStateFile), state)
+}
+
+// inPlaceAssets returns the units of the hooks and the paths of the file assets that nodeup installs on the node.
+// They are recorded in the configuration state, so that those removed from the spec can be removed from the node.
+func inPlaceAssets(modelContext *model.NodeupModelContext) ([]string, []string, error) {
+	c := &fi.ModelBuilderContext{
+		Tasks: make(map[string]fi.Task),
+	}
+	builders := []fi.ModelBuilder{
+		&model.HookBuilder{NodeupModelContext: modelContext},
+		&model.FileAssetsBuilder{NodeupModelContext: modelContext},
+	}
+	for _, builder := range builders {
+		if err := builder.Build(c); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var units, files []string
+	for _, task := range c.Tasks {
+		switch task := task.(type) {
+		case *nodetasks.Service:
+			units = append(units, task.Name)
+		case *nodetasks.File:
+			if task.Type == nodetasks.FileType_File {
+				files = append(files, task.Path)
+			}
+		}
+	}
+	sort.Strings(units)
+	sort.Strings(files)
+	return units, files, nil
+}
+
+// removeStaleAssets removes the hooks and file assets of the previously applied configuration that are not in units and files
+func (c *NodeUpCommand) removeStaleAssets(nodeTags sets.String, units []string, files []string) error {
+	previous, err := readConfigState(path.Join(c.CacheDir, configStateFile))
+	if err != nil {
+		return err
+	}
+	systemdSystemPath, err := nodetasks.SystemdSystemPath(&local.LocalTarget{Tags: nodeTags})
+	if err != nil {
+		return err
+	}
+	return deleteStaleAssets(systemdSystemPath, previous, units, files)
+}
+
+// deleteStaleAssets stops and removes the units of the hooks, and removes the file assets, that were installed by
+// the previously applied configuration but are not part of the configuration being applied
+func deleteStaleAssets(systemdSystemPath string, previous *configState, units []string, files []string) error {
+	staleUnits := sets.NewString(previous.Units...).Difference(sets.NewString(units...)).List()
+	for _, unit := range staleUnits {
+		klog.Infof("Removing unit %q; its hook has been removed", unit)
+		if err := systemctl("disable", "--now", unit); err != nil {
+			klog.Warningf("unable to stop unit %q: %v", unit, err)
+		}
+		p := path.Join(systemdSystemPath, unit)
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing unit %q: %v", p, err)
+		}
+	}
+	if len(staleUnits) != 0 {
+		if err := systemctl("daemon-reload"); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range sets.NewString(previous.Files...).Difference(sets.NewString(files...)).List() {
+		klog.Infof("Removing file %q; its file asset has been removed", file)
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing file %q: %v", file, err)
+		}
+	}
+
+	return nil
+}
+
+// reportConfigHash sets the configuration hash annotation on the Node object, using the credentials of the kubelet
+func (c *NodeUpCommand) reportConfigHash(hash string) error {
+	nodeName, err := c.nodeName()
+	if err != nil {
+		return err
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeletKubeConfig)
+	if err != nil {
+		return fmt.Errorf("error loading kubeconfig %q: %v", kubeletKubeConfig, err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("error building kubernetes client: %v", err)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				apimodel.NodeConfigHashAnnotation: hash,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error building node patch: %v", err)
+	}
+
+	klog.V(2).Infof("sending patch for node %q: %q", nodeName, string(patch))
+	if _, err := client.CoreV1().Nodes().Patch(nodeName, types.StrategicMergePatchType, patch); err != nil {
+		return fmt.Errorf("error patching node %q: %v", nodeName, err)
+	}
+	klog.Infof("Reported configuration hash %s on node %q", hash, nodeName)
+	return nil
+}
+
+// nodeName returns the name with which the kubelet registers the node
+func (c *NodeUpCommand) nodeName() (string, error) {
+	kubelet := c.cluster.Spec.Kubelet
+	if c.instanceGroup != nil && c.instanceGroup.IsMaster() {
+		kubelet = c.cluster.Spec.MasterKubelet
+	}
+	if kubelet != nil && kubelet.HostnameOverride != "" {
+		return kubelet.HostnameOverride, nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("error getting hostname: %v", err)
+	}
+	return strings.ToLower(hostname), nil
+}
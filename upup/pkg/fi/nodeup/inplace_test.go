@@ -0,0 +1,203 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"k8s.io/kops/nodeup/pkg/model"
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/apis/kops/registry"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func TestConfigStateRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inplace")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, configStateFile)
+
+	state, err := readConfigState(p)
+	if err != nil {
+		t.Fatalf("unexpected error reading missing state: %v", err)
+	}
+	if !reflect.DeepEqual(state, &configState{}) {
+		t.Errorf("expected empty state when none has been recorded, got %+v", state)
+	}
+
+	expected := &configState{Hash: "abc123", Complete: true}
+	if err := writeConfigState(p, expected); err != nil {
+		t.Fatalf("error writing state: %v", err)
+	}
+
+	state, err = readConfigState(p)
+	if err != nil {
+		t.Fatalf("error reading state: %v", err)
+	}
+	if !reflect.DeepEqual(state, expected) {
+		t.Errorf("expected %+v, got %+v", expected, state)
+	}
+
+	if err := ioutil.WriteFile(p, []byte("not json"), 0644); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+	if _, err := readConfigState(p); err == nil {
+		t.Errorf("expected error reading corrupt state")
+	}
+}
+
+func TestInPlaceAssets(t *testing.T) {
+	modelContext := &model.NodeupModelContext{
+		Cluster: &kops.Cluster{
+			Spec: kops.ClusterSpec{
+				Hooks: []kops.HookSpec{
+					{Name: "cluster-hook.service", Manifest: "Type=oneshot"},
+					{Name: "master-hook.service", Manifest: "Type=oneshot", Roles: []kops.InstanceGroupRole{kops.InstanceGroupRoleMaster}},
+				},
+				FileAssets: []kops.FileAssetSpec{
+					{Name: "cluster-asset", Path: "/etc/cluster-asset", Content: "cluster"},
+				},
+			},
+		},
+		InstanceGroup: &kops.InstanceGroup{
+			Spec: kops.InstanceGroupSpec{
+				Role: kops.InstanceGroupRoleNode,
+				Hooks: []kops.HookSpec{
+					{Manifest: "Type=oneshot"},
+				},
+				FileAssets: []kops.FileAssetSpec{
+					{Name: "ig-asset", Content: "ig"},
+				},
+			},
+		},
+	}
+
+	units, files, err := inPlaceAssets(modelContext)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"cluster-hook.service", "kops-hook-0-ig.service"}; !reflect.DeepEqual(units, expected) {
+		t.Errorf("expected units %v, got %v", expected, units)
+	}
+	if expected := []string{"/etc/cluster-asset", "/srv/kubernetes/assets/ig-asset"}; !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected files %v, got %v", expected, files)
+	}
+}
+
+func TestDeleteStaleAssets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inplace")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var commands []string
+	defer func(f func(args ...string) error) { systemctl = f }(systemctl)
+	systemctl = func(args ...string) error {
+		commands = append(commands, strings.Join(args, " "))
+		return nil
+	}
+
+	unitDir := filepath.Join(dir, "system")
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		t.Fatalf("error creating dir: %v", err)
+	}
+	for _, p := range []string{
+		filepath.Join(unitDir, "kept.service"),
+		filepath.Join(unitDir, "removed.service"),
+		filepath.Join(dir, "kept-asset"),
+		filepath.Join(dir, "removed-asset"),
+	} {
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatalf("error writing file: %v", err)
+		}
+	}
+
+	previous := &configState{
+		Hash:  "abc123",
+		Units: []string{"kept.service", "removed.service"},
+		Files: []string{filepath.Join(dir, "kept-asset"), filepath.Join(dir, "removed-asset"), filepath.Join(dir, "missing-asset")},
+	}
+	if err := deleteStaleAssets(unitDir, previous, []string{"kept.service", "new.service"}, []string{filepath.Join(dir, "kept-asset")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for p, exists := range map[string]bool{
+		filepath.Join(unitDir, "kept.service"):    true,
+		filepath.Join(unitDir, "removed.service"): false,
+		filepath.Join(dir, "kept-asset"):          true,
+		filepath.Join(dir, "removed-asset"):       false,
+	} {
+		_, err := os.Stat(p)
+		if exists && err != nil {
+			t.Errorf("expected %q to be kept: %v", p, err)
+		}
+		if !exists && !os.IsNotExist(err) {
+			t.Errorf("expected %q to be removed", p)
+		}
+	}
+
+	if expected := []string{"disable --now removed.service", "daemon-reload"}; !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected systemctl commands %v, got %v", expected, commands)
+	}
+}
+
+func TestReadInstanceGroup(t *testing.T) {
+	configBase := vfs.NewMemFSPath(vfs.NewMemFSContext(), "memfs://state/cluster.example.com")
+
+	write := func(p vfs.Path, machineType string) {
+		ig := &kops.InstanceGroup{}
+		ig.ObjectMeta.Name = "nodes"
+		ig.Spec.MachineType = machineType
+		if err := registry.WriteConfigDeprecated(nil, p, ig); err != nil {
+			t.Fatalf("error writing instance group: %v", err)
+		}
+	}
+
+	// An instance group that has not been published is read from the registry
+	write(configBase.Join("instancegroup", "nodes"), "t2.medium")
+	ig, err := readInstanceGroup(configBase, "nodes")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ig.Spec.MachineType != "t2.medium" {
+		t.Errorf("expected the instance group to be read from the registry, got %q", ig.Spec.MachineType)
+	}
+
+	// Once published, edits in the registry are not read until they are published again
+	write(registry.InstanceGroupCompletedPath(configBase, "nodes"), "t2.medium")
+	write(configBase.Join("instancegroup", "nodes"), "t2.large")
+	ig, err = readInstanceGroup(configBase, "nodes")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ig.Spec.MachineType != "t2.medium" {
+		t.Errorf("expected the published instance group to be read, got %q", ig.Spec.MachineType)
+	}
+
+	if _, err := readInstanceGroup(configBase, "missing"); err == nil {
+		t.Errorf("expected error reading a missing instance group")
+	}
+}
@@ -31,24 +31,37 @@ import (
 	"k8s.io/kops/util/pkg/hashing"
 )
 
-const dockerService = "docker.service"
+const (
+	dockerService     = "docker.service"
+	containerdService = "containerd.service"
+
+	// ContainerdRuntime is the Runtime of a LoadImageTask that loads the image into containerd
+	ContainerdRuntime = "containerd"
+)
 
 // LoadImageTask is responsible for downloading a docker image
 type LoadImageTask struct {
 	Sources []string
 	Hash    string
+	// Runtime is the container runtime the image is loaded into; docker if not set
+	Runtime string
 }
 
 var _ fi.Task = &LoadImageTask{}
 var _ fi.HasDependencies = &LoadImageTask{}
 
 func (t *LoadImageTask) GetDependencies(tasks map[string]fi.Task) []fi.Task {
-	// LoadImageTask depends on the docker service to ensure we
-	// sideload images after docker is completely updated and
+	// LoadImageTask depends on the container runtime service to ensure we
+	// sideload images after the runtime is completely updated and
 	// configured.
+	serviceName := dockerService
+	if t.Runtime == ContainerdRuntime {
+		serviceName = containerdService
+	}
+
 	var deps []fi.Task
 	for _, v := range tasks {
-		if svc, ok := v.(*Service); ok && svc.Name == dockerService {
+		if svc, ok := v.(*Service); ok && svc.Name == serviceName {
 			deps = append(deps, v)
 		}
 	}
@@ -102,15 +115,19 @@ func (_ *LoadImageTask) RenderLocal(t *local.LocalTarget, a, e, changes *LoadIma
 		return err
 	}
 
-	// Load the image into docker
+	// Load the image into the container runtime
 	args := []string{"docker", "load", "-i", localFile}
+	if e.Runtime == ContainerdRuntime {
+		// The kubelet only sees images in the k8s.io namespace
+		args = []string{"/usr/local/bin/ctr", "--namespace", "k8s.io", "images", "import", localFile}
+	}
 	human := strings.Join(args, " ")
 
 	klog.Infof("running command %s", human)
 	cmd := exec.Command(args[0], args[1:]...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("error loading docker image with '%s': %v: %s", human, err, string(output))
+		return fmt.Errorf("error loading image with '%s': %v: %s", human, err, string(output))
 	}
 
 	return nil
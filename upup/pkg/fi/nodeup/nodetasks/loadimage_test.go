@@ -39,3 +39,18 @@ func TestLoadImageTask_Deps(t *testing.T) {
 	}
 
 }
+
+func TestLoadImageTask_ContainerdDeps(t *testing.T) {
+	l := &LoadImageTask{Runtime: ContainerdRuntime}
+
+	tasks := make(map[string]fi.Task)
+	tasks["FileTask1"] = &File{}
+	tasks["ServiceDocker"] = &Service{Name: "docker.service"}
+	tasks["ServiceContainerd"] = &Service{Name: "containerd.service"}
+
+	deps := l.GetDependencies(tasks)
+	expected := []fi.Task{tasks["ServiceContainerd"]}
+	if !reflect.DeepEqual(expected, deps) {
+		t.Fatalf("unexpected deps.  expected=%v, actual=%v", expected, deps)
+	}
+}
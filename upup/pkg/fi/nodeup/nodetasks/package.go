@@ -122,7 +122,7 @@ func NewPackage(name string, contents string, meta string) (fi.Task, error) {
 }
 
 func (e *Package) Find(c *fi.Context) (*Package, error) {
-	target := c.Target.(*local.LocalTarget)
+	target := c.Target.(tags.HasTags)
 
 	if target.HasTag(tags.TagOSFamilyDebian) {
 		return e.findDpkg(c)
@@ -142,6 +142,11 @@ func getSystemdStatus(name string) (map[string]string, error) {
 }
 
 func (e *Service) systemdSystemPath(target tags.HasTags) (string, error) {
+	return SystemdSystemPath(target)
+}
+
+// SystemdSystemPath returns the directory in which unit files are installed on the OS of the target
+func SystemdSystemPath(target tags.HasTags) (string, error) {
 	if target.HasTag(tags.TagOSFamilyDebian) {
 		return debianSystemdSystemPath, nil
 	} else if target.HasTag(tags.TagOSFamilyRHEL) {
@@ -220,6 +225,23 @@ func (e *Service) Find(c *fi.Context) (*Service, error) {
 	return actual, nil
 }
 
+// DependsOn returns true if the service definition has an obvious dependency on any of the files,
+// in which case a running service should be restarted when they change
+func (e *Service) DependsOn(files []string) (bool, error) {
+	dependencies, err := getSystemdDependencies(e.Name, fi.StringValue(e.Definition))
+	if err != nil {
+		return false, err
+	}
+	for _, dependency := range dependencies {
+		for _, f := range files {
+			if dependency == f {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 // Parse the systemd unit file to extract obvious dependencies
 func getSystemdDependencies(serviceName string, definition string) ([]string, error) {
 	var dependencies []string
@@ -55,3 +55,32 @@ func TestServiceTask_UnknownTypes(t *testing.T) {
 		t.Fatalf("unexpected deps.  expected=%v, actual=%v", expected, deps)
 	}
 }
+
+func TestServiceTask_DependsOn(t *testing.T) {
+	s := &Service{
+		Name: "kubelet.service",
+		Definition: fi.String(`[Service]
+EnvironmentFile=/etc/sysconfig/kubelet
+ExecStart=/usr/local/bin/kubelet "$DAEMON_ARGS"
+`),
+	}
+
+	grid := []struct {
+		Files    []string
+		Expected bool
+	}{
+		{Files: []string{"/etc/sysconfig/kubelet"}, Expected: true},
+		{Files: []string{"/etc/hosts", "/usr/local/bin/kubelet"}, Expected: true},
+		{Files: []string{"/etc/hosts"}, Expected: false},
+		{Files: nil, Expected: false},
+	}
+	for _, g := range grid {
+		actual, err := s.DependsOn(g.Files)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actual != g.Expected {
+			t.Errorf("DependsOn(%v): expected %v, got %v", g.Files, g.Expected, actual)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PlanAction is what applying a plan does to a resource
+type PlanAction string
+
+const (
+	PlanActionCreate PlanAction = "create"
+	PlanActionUpdate PlanAction = "update"
+	PlanActionDelete PlanAction = "delete"
+)
+
+// Plan is a machine-readable description of the changes found by a DryRunTarget
+type Plan struct {
+	// Tasks are the keys of all the tasks that were run, whether or not they found changes
+	Tasks []string `json:"tasks,omitempty"`
+	// Changes are the resources that would be created, updated or deleted
+	Changes []*PlanChange `json:"changes"`
+}
+
+// PlanChange describes the change to a single resource
+type PlanChange struct {
+	// Key identifies the resource, as type/name
+	Key string `json:"key"`
+	// Type is the type of the task managing the resource, or for deletions the type of the deleted item
+	Type string `json:"type"`
+	// Name is the name of the resource
+	Name string `json:"name"`
+	// Action is what would be done to the resource
+	Action PlanAction `json:"action"`
+	// Fields are the fields that would be changed; for creations only the values after the change are set
+	Fields []*PlanFieldChange `json:"fields,omitempty"`
+	// Task is the task that would make the change; it is not set for deletions
+	Task Task `json:"-"`
+}
+
+// PlanFieldChange describes the change to a single field of a resource
+type PlanFieldChange struct {
+	// Name is the name of the field
+	Name string `json:"name"`
+	// Before is the current value of the field
+	Before string `json:"before,omitempty"`
+	// After is the value of the field after the change
+	After string `json:"after,omitempty"`
+}
+
+// Plan returns a machine-readable description of the changes that would be made.
+// Creations come first, followed by updates and then deletions, each ordered by key.
+func (t *DryRunTarget) Plan(taskMap map[string]Task) (*Plan, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	plan := &Plan{
+		Changes: []*PlanChange{},
+	}
+
+	for key := range taskMap {
+		plan.Tasks = append(plan.Tasks, key)
+	}
+	sort.Strings(plan.Tasks)
+
+	var creates, updates, deletes []*PlanChange
+	for _, r := range t.changes {
+		taskName := getTaskName(r.changes)
+		name := idForTask(taskMap, r.e)
+		c := &PlanChange{
+			Key:  taskName + "/" + name,
+			Type: taskName,
+			Name: name,
+			Task: r.e,
+		}
+
+		var changeList []change
+		if r.aIsNil {
+			c.Action = PlanActionCreate
+			changeList = buildCreateList(r.changes, true)
+			creates = append(creates, c)
+		} else {
+			c.Action = PlanActionUpdate
+			var err error
+			changeList, err = buildChangeList(r.a, r.e, r.changes)
+			if err != nil {
+				return nil, err
+			}
+			updates = append(updates, c)
+		}
+
+		for _, fc := range changeList {
+			c.Fields = append(c.Fields, &PlanFieldChange{
+				Name:   fc.FieldName,
+				Before: fc.Before,
+				After:  fc.After,
+			})
+		}
+	}
+
+	for _, d := range t.deletions {
+		deletes = append(deletes, &PlanChange{
+			Key:    d.TaskName() + "/" + d.Item(),
+			Type:   d.TaskName(),
+			Name:   d.Item(),
+			Action: PlanActionDelete,
+		})
+	}
+
+	for _, changes := range [][]*PlanChange{creates, updates, deletes} {
+		sort.SliceStable(changes, func(i, j int) bool {
+			return changes[i].Key < changes[j].Key
+		})
+		plan.Changes = append(plan.Changes, changes...)
+	}
+
+	return plan, nil
+}
+
+// Diff compares the plan with live, a plan computed later for the same cluster.
+// It returns a description of each difference; if there are none, applying live makes the changes that were planned.
+func (p *Plan) Diff(live *Plan) []string {
+	var diffs []string
+
+	liveTasks := make(map[string]bool)
+	for _, key := range live.Tasks {
+		liveTasks[key] = true
+	}
+	plannedTasks := make(map[string]bool)
+	for _, key := range p.Tasks {
+		plannedTasks[key] = true
+		if !liveTasks[key] {
+			diffs = append(diffs, fmt.Sprintf("planned task %s is no longer present", key))
+		}
+	}
+	for _, key := range live.Tasks {
+		if !plannedTasks[key] {
+			diffs = append(diffs, fmt.Sprintf("task %s was not in the plan", key))
+		}
+	}
+
+	planned := make(map[string]*PlanChange)
+	for _, c := range p.Changes {
+		planned[c.Key] = c
+	}
+	found := make(map[string]bool)
+	for _, c := range live.Changes {
+		found[c.Key] = true
+		e := planned[c.Key]
+		if e == nil {
+			diffs = append(diffs, fmt.Sprintf("unplanned %s of %s", c.Action, c.Key))
+			continue
+		}
+		if e.Action != c.Action {
+			diffs = append(diffs, fmt.Sprintf("%s: planned %s, but would now %s", c.Key, e.Action, c.Action))
+			continue
+		}
+		for _, name := range diffFields(e.Fields, c.Fields) {
+			diffs = append(diffs, fmt.Sprintf("%s of %s: field %s differs from the plan", c.Action, c.Key, name))
+		}
+	}
+	for _, e := range p.Changes {
+		if !found[e.Key] {
+			diffs = append(diffs, fmt.Sprintf("planned %s of %s is no longer needed", e.Action, e.Key))
+		}
+	}
+
+	return diffs
+}
+
+// diffFields returns the sorted names of the fields whose changes are not the same in planned and live
+func diffFields(planned, live []*PlanFieldChange) []string {
+	fields := make(map[string]*PlanFieldChange)
+	for _, f := range planned {
+		fields[f.Name] = f
+	}
+
+	var names []string
+	for _, f := range live {
+		e := fields[f.Name]
+		delete(fields, f.Name)
+		if e == nil || *e != *f {
+			names = append(names, f.Name)
+		}
+	}
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testPlanTask struct {
+	Name      *string
+	Lifecycle *Lifecycle
+	Size      *int64
+	Data      Resource
+}
+
+func (t *testPlanTask) Run(*Context) error {
+	return nil
+}
+
+type testPlanDeletion struct {
+	item string
+}
+
+func (d *testPlanDeletion) Delete(target Target) error {
+	return nil
+}
+
+func (d *testPlanDeletion) TaskName() string {
+	return "Instance"
+}
+
+func (d *testPlanDeletion) Item() string {
+	return d.item
+}
+
+func Test_DryRunTargetPlan(t *testing.T) {
+	createE := &testPlanTask{Name: String("new"), Size: Int64(10), Data: NewStringResource("hello")}
+	updateA := &testPlanTask{Name: String("existing"), Size: Int64(10)}
+	updateE := &testPlanTask{Name: String("existing"), Size: Int64(20)}
+	taskMap := map[string]Task{
+		"testPlanTask/new":      createE,
+		"testPlanTask/existing": updateE,
+	}
+
+	target := &DryRunTarget{}
+	if err := target.Render(updateA, updateE, &testPlanTask{Size: Int64(20)}); err != nil {
+		t.Fatalf("error rendering: %v", err)
+	}
+	var nilTask *testPlanTask
+	if err := target.Render(nilTask, createE, createE); err != nil {
+		t.Fatalf("error rendering: %v", err)
+	}
+	if err := target.Delete(&testPlanDeletion{item: "i-1234"}); err != nil {
+		t.Fatalf("error deleting: %v", err)
+	}
+
+	plan, err := target.Plan(taskMap)
+	if err != nil {
+		t.Fatalf("error building plan: %v", err)
+	}
+
+	expected := &Plan{
+		Tasks: []string{"testPlanTask/existing", "testPlanTask/new"},
+		Changes: []*PlanChange{
+			{
+				Key:    "testPlanTask/new",
+				Type:   "testPlanTask",
+				Name:   "new",
+				Action: PlanActionCreate,
+				Fields: []*PlanFieldChange{
+					{Name: "Size", After: "10"},
+					{Name: "Data", After: "hello"},
+				},
+				Task: createE,
+			},
+			{
+				Key:    "testPlanTask/existing",
+				Type:   "testPlanTask",
+				Name:   "existing",
+				Action: PlanActionUpdate,
+				Fields: []*PlanFieldChange{
+					{Name: "Size", Before: "10", After: "20"},
+				},
+				Task: updateE,
+			},
+			{
+				Key:    "Instance/i-1234",
+				Type:   "Instance",
+				Name:   "i-1234",
+				Action: PlanActionDelete,
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(plan, expected) {
+		for _, c := range plan.Changes {
+			t.Logf("change: %+v", c)
+			for _, f := range c.Fields {
+				t.Logf("  field: %+v", f)
+			}
+		}
+		t.Errorf("unexpected plan")
+	}
+}
+
+func Test_PlanDiff(t *testing.T) {
+	planned := &Plan{
+		Tasks: []string{"Instance/a", "Instance/b", "Instance/c"},
+		Changes: []*PlanChange{
+			{Key: "Instance/a", Action: PlanActionCreate, Fields: []*PlanFieldChange{{Name: "Size", After: "10"}}},
+			{Key: "Instance/b", Action: PlanActionUpdate, Fields: []*PlanFieldChange{{Name: "Size", Before: "10", After: "20"}}},
+			{Key: "Volume/d", Action: PlanActionDelete},
+		},
+	}
+
+	grid := []struct {
+		live     *Plan
+		expected []string
+	}{
+		{
+			live:     planned,
+			expected: nil,
+		},
+		{
+			live: &Plan{
+				Tasks: []string{"Instance/a", "Instance/b", "Instance/c"},
+				Changes: []*PlanChange{
+					{Key: "Instance/a", Action: PlanActionCreate, Fields: []*PlanFieldChange{{Name: "Size", After: "10"}}},
+					{Key: "Instance/b", Action: PlanActionUpdate, Fields: []*PlanFieldChange{{Name: "Size", Before: "15", After: "20"}}},
+				},
+			},
+			expected: []string{
+				"update of Instance/b: field Size differs from the plan",
+				"planned delete of Volume/d is no longer needed",
+			},
+		},
+		{
+			live: &Plan{
+				Tasks: []string{"Instance/a", "Instance/b", "Instance/e"},
+				Changes: []*PlanChange{
+					{Key: "Instance/a", Action: PlanActionUpdate, Fields: []*PlanFieldChange{{Name: "Size", Before: "5", After: "10"}}},
+					{Key: "Instance/b", Action: PlanActionUpdate, Fields: []*PlanFieldChange{{Name: "Size", Before: "10", After: "20"}, {Name: "Type", Before: "t2.micro", After: "t2.large"}}},
+					{Key: "Volume/d", Action: PlanActionDelete},
+					{Key: "Instance/f", Action: PlanActionDelete},
+				},
+			},
+			expected: []string{
+				"planned task Instance/c is no longer present",
+				"task Instance/e was not in the plan",
+				"Instance/a: planned create, but would now update",
+				"update of Instance/b: field Type differs from the plan",
+				"unplanned delete of Instance/f",
+			},
+		},
+	}
+	for i, g := range grid {
+		actual := planned.Diff(g.live)
+		if !reflect.DeepEqual(actual, g.expected) {
+			t.Errorf("case %d: expected differences:\n%s\ngot:\n%s", i, strings.Join(g.expected, "\n"), strings.Join(actual, "\n"))
+		}
+	}
+}
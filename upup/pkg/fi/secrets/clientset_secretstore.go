@@ -30,6 +30,7 @@ import (
 	"k8s.io/kops/pkg/apis/kops"
 	kopsinternalversion "k8s.io/kops/pkg/client/clientset_generated/clientset/typed/kops/internalversion"
 	"k8s.io/kops/pkg/pki"
+	"k8s.io/kops/pkg/statehistory"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/util/pkg/vfs"
 )
@@ -37,6 +38,9 @@ import (
 // NamePrefix is a prefix we use to avoid collisions with other keysets
 const NamePrefix = "token-"
 
+// HistoryNamePrefix is the prefix of the keysets holding the prior versions of secrets
+const HistoryNamePrefix = fi.KeysetHistoryNamePrefix + NamePrefix
+
 // ClientsetSecretStore is a SecretStore backed by Keyset objects in an API server
 type ClientsetSecretStore struct {
 	cluster   *kops.Cluster
@@ -45,6 +49,7 @@ type ClientsetSecretStore struct {
 }
 
 var _ fi.SecretStore = &ClientsetSecretStore{}
+var _ fi.HasHistory = &ClientsetSecretStore{}
 
 // NewClientsetSecretStore is the constructor for ClientsetSecretStore
 func NewClientsetSecretStore(cluster *kops.Cluster, clientset kopsinternalversion.KopsInterface, namespace string) fi.SecretStore {
@@ -65,7 +70,7 @@ func (c *ClientsetSecretStore) MirrorTo(basedir vfs.Path) error {
 	for i := range list.Items {
 		keyset := &list.Items[i]
 
-		if keyset.Spec.Type != kops.SecretTypeSecret {
+		if keyset.Spec.Type != kops.SecretTypeSecret || strings.HasPrefix(keyset.Name, HistoryNamePrefix) {
 			continue
 		}
 
@@ -118,6 +123,11 @@ func (c *ClientsetSecretStore) ListSecrets() ([]string, error) {
 	for i := range list.Items {
 		keyset := &list.Items[i]
 
+		if strings.HasPrefix(keyset.Name, HistoryNamePrefix) {
+			// Skip the prior versions of secrets
+			continue
+		}
+
 		switch keyset.Spec.Type {
 		case kops.SecretTypeSecret:
 			name := strings.TrimPrefix(keyset.Name, NamePrefix)
@@ -144,7 +154,7 @@ func (c *ClientsetSecretStore) Secret(name string) (*fi.Secret, error) {
 func (c *ClientsetSecretStore) DeleteSecret(name string) error {
 	client := c.clientset.Keysets(c.namespace)
 
-	keyset, err := client.Get(name, v1.GetOptions{})
+	keyset, err := client.Get(NamePrefix+name, v1.GetOptions{})
 	if err != nil {
 		if errors.IsNotFound(err) {
 			return nil
@@ -157,7 +167,11 @@ func (c *ClientsetSecretStore) DeleteSecret(name string) error {
 		return fmt.Errorf("mismatch on Keyset type on %q", name)
 	}
 
-	if err := client.Delete(name, &v1.DeleteOptions{}); err != nil {
+	if err := c.recordVersion(name); err != nil {
+		return err
+	}
+
+	if err := client.Delete(NamePrefix+name, &v1.DeleteOptions{}); err != nil {
 		return fmt.Errorf("error deleting Keyset %q: %v", name, err)
 	}
 
@@ -202,6 +216,10 @@ func (c *ClientsetSecretStore) GetOrCreateSecret(name string, secret *fi.Secret)
 
 // ReplaceSecret implements fi.SecretStore::ReplaceSecret
 func (c *ClientsetSecretStore) ReplaceSecret(name string, secret *fi.Secret) (*fi.Secret, error) {
+	if err := c.recordVersion(name); err != nil {
+		return nil, err
+	}
+
 	_, err := c.createSecret(secret, name, true)
 	if err != nil {
 		return nil, fmt.Errorf("unable to write secret: %v", err)
@@ -215,6 +233,73 @@ func (c *ClientsetSecretStore) ReplaceSecret(name string, secret *fi.Secret) (*f
 	return s, nil
 }
 
+// recordVersion records the current value of the secret in the history, if the secret exists.
+// The prior versions of a secret are kept as the items of a keyset named with HistoryNamePrefix.
+func (c *ClientsetSecretStore) recordVersion(name string) error {
+	depth := statehistory.Depth(c.cluster)
+	if depth <= 0 {
+		return nil
+	}
+
+	secret, err := c.loadSecret(name)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return nil
+	}
+	data, err := json.Marshal(secret)
+	if err != nil {
+		return fmt.Errorf("error serializing secret %q: %v", name, err)
+	}
+
+	client := c.clientset.Keysets(c.namespace)
+	if err := fi.RecordKeysetHistory(client, HistoryNamePrefix+name, kops.SecretTypeSecret, depth, data); err != nil {
+		return fmt.Errorf("error recording prior version of secret %q: %v", name, err)
+	}
+	return nil
+}
+
+// History implements fi.HasHistory History
+func (c *ClientsetSecretStore) History(name string) ([]*statehistory.Version, error) {
+	return fi.KeysetHistory(c.clientset.Keysets(c.namespace), HistoryNamePrefix+name)
+}
+
+// Rollback implements fi.HasHistory Rollback
+func (c *ClientsetSecretStore) Rollback(name string, id string) error {
+	versions, err := c.History(name)
+	if err != nil {
+		return err
+	}
+
+	var version *statehistory.Version
+	for _, v := range versions {
+		if v.ID == id {
+			version = v
+		}
+	}
+	if version == nil {
+		return fmt.Errorf("version %s of secret %q not found", id, name)
+	}
+
+	secret := &fi.Secret{}
+	if err := json.Unmarshal(version.Data, secret); err != nil {
+		return fmt.Errorf("error parsing version %s of secret %q: %v", id, name, err)
+	}
+
+	existing, err := c.FindSecret(name)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		// The secret was deleted; the keyset must be created again
+		_, err = c.createSecret(secret, name, false)
+		return err
+	}
+	_, err = c.ReplaceSecret(name, secret)
+	return err
+}
+
 // loadSecret returns the named secret, if it exists, otherwise returns nil
 func (c *ClientsetSecretStore) loadSecret(name string) (*fi.Secret, error) {
 	name = NamePrefix + name
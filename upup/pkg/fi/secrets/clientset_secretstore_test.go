@@ -0,0 +1,84 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/client/clientset_generated/clientset/fake"
+	"k8s.io/kops/upup/pkg/fi"
+)
+
+func TestClientsetSecretStoreHistory(t *testing.T) {
+	depth := int32(2)
+	cluster := &kops.Cluster{}
+	cluster.Spec.SecretHistoryDepth = &depth
+
+	store := NewClientsetSecretStore(cluster, fake.NewSimpleClientset().Kops(), "default").(*ClientsetSecretStore)
+
+	if _, _, err := store.GetOrCreateSecret("admin", &fi.Secret{Data: []byte("one")}); err != nil {
+		t.Fatalf("unexpected error creating secret: %v", err)
+	}
+	for _, data := range []string{"two", "three", "four"} {
+		if _, err := store.ReplaceSecret("admin", &fi.Secret{Data: []byte(data)}); err != nil {
+			t.Fatalf("unexpected error replacing secret: %v", err)
+		}
+	}
+
+	versions, err := store.History("admin")
+	if err != nil {
+		t.Fatalf("unexpected error listing history: %v", err)
+	}
+	if len(versions) != 2 || versions[0].ID != "3" || versions[1].ID != "2" {
+		t.Fatalf("expected versions 3 and 2 to be kept, got %v", versions)
+	}
+
+	names, err := store.ListSecrets()
+	if err != nil {
+		t.Fatalf("unexpected error listing secrets: %v", err)
+	}
+	if len(names) != 1 || names[0] != "admin" {
+		t.Errorf("expected only the admin secret to be listed, got %v", names)
+	}
+
+	if err := store.DeleteSecret("admin"); err != nil {
+		t.Fatalf("unexpected error deleting secret: %v", err)
+	}
+	if s, err := store.FindSecret("admin"); err != nil || s != nil {
+		t.Fatalf("expected secret to be deleted, got %v, %v", s, err)
+	}
+
+	versions, err = store.History("admin")
+	if err != nil {
+		t.Fatalf("unexpected error listing history: %v", err)
+	}
+	if len(versions) != 2 || versions[0].ID != "4" {
+		t.Fatalf("expected the deleted secret to be recorded as version 4, got %v", versions)
+	}
+
+	if err := store.Rollback("admin", "3"); err != nil {
+		t.Fatalf("unexpected error rolling back secret: %v", err)
+	}
+	s, err := store.FindSecret("admin")
+	if err != nil {
+		t.Fatalf("unexpected error reading secret: %v", err)
+	}
+	if s == nil || string(s.Data) != "three" {
+		t.Errorf("expected secret to be rolled back to \"three\", got %v", s)
+	}
+}
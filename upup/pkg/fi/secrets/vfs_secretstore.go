@@ -21,10 +21,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"k8s.io/klog"
 	"k8s.io/kops/pkg/acls"
 	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/stateencryption"
+	"k8s.io/kops/pkg/statehistory"
 	"k8s.io/kops/upup/pkg/fi"
 	"k8s.io/kops/util/pkg/vfs"
 )
@@ -35,6 +38,7 @@ type VFSSecretStore struct {
 }
 
 var _ fi.SecretStore = &VFSSecretStore{}
+var _ fi.HasHistory = &VFSSecretStore{}
 
 func NewVFSSecretStore(cluster *kops.Cluster, basedir vfs.Path) fi.SecretStore {
 	c := &VFSSecretStore{
@@ -79,7 +83,7 @@ func (c *VFSSecretStore) MirrorTo(basedir vfs.Path) error {
 
 		klog.Infof("mirroring secret %s -> %s", name, p)
 
-		err = createSecret(secret, p, acl, true)
+		err = createSecret(c.cluster, secret, p, acl, true)
 		if err != nil {
 			return fmt.Errorf("error writing secret %q for mirror: %v", name, err)
 		}
@@ -96,6 +100,55 @@ func (c *VFSSecretStore) buildSecretPath(name string) vfs.Path {
 	return BuildVfsSecretPath(c.basedir, name)
 }
 
+// history returns the prior versions of secrets, which are kept under .history in the secrets directory
+func (c *VFSSecretStore) history() *statehistory.History {
+	return statehistory.NewHistory(c.cluster, c.basedir.Join(".history"))
+}
+
+// recordVersion records the current value of the secret in the history, if the secret exists
+func (c *VFSSecretStore) recordVersion(name string) error {
+	p := c.buildSecretPath(name)
+	data, err := p.ReadFile()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error reading secret %q: %v", name, err)
+	}
+	data, err = stateencryption.Open(c.cluster, data)
+	if err != nil {
+		return fmt.Errorf("error decrypting secret from %q: %v", p, err)
+	}
+	if _, err := c.history().Record(name, data); err != nil {
+		return fmt.Errorf("error recording prior version of secret %q: %v", name, err)
+	}
+	return nil
+}
+
+// History implements fi.HasHistory History
+func (c *VFSSecretStore) History(name string) ([]*statehistory.Version, error) {
+	return c.history().List(name)
+}
+
+// Rollback implements fi.HasHistory Rollback
+func (c *VFSSecretStore) Rollback(name string, id string) error {
+	version, err := c.history().Get(name, id)
+	if err != nil {
+		return err
+	}
+	if version == nil {
+		return fmt.Errorf("version %s of secret %q not found", id, name)
+	}
+
+	secret := &fi.Secret{}
+	if err := json.Unmarshal(version.Data, secret); err != nil {
+		return fmt.Errorf("error parsing version %s of secret %q: %v", id, name, err)
+	}
+
+	_, err = c.ReplaceSecret(name, secret)
+	return err
+}
+
 func (c *VFSSecretStore) FindSecret(id string) (*fi.Secret, error) {
 	p := c.buildSecretPath(id)
 	s, err := c.loadSecret(p)
@@ -107,6 +160,9 @@ func (c *VFSSecretStore) FindSecret(id string) (*fi.Secret, error) {
 
 // DeleteSecret implements fi.SecretStore DeleteSecret
 func (c *VFSSecretStore) DeleteSecret(name string) error {
+	if err := c.recordVersion(name); err != nil {
+		return err
+	}
 	p := c.buildSecretPath(name)
 	return p.Remove()
 }
@@ -119,6 +175,10 @@ func (c *VFSSecretStore) ListSecrets() ([]string, error) {
 	var ids []string
 	for _, f := range files {
 		id := f.Base()
+		if strings.HasPrefix(id, ".") {
+			// Skip the history directory
+			continue
+		}
 		ids = append(ids, id)
 	}
 	return ids, nil
@@ -153,7 +213,7 @@ func (c *VFSSecretStore) GetOrCreateSecret(id string, secret *fi.Secret) (*fi.Se
 			return nil, false, err
 		}
 
-		err = createSecret(secret, p, acl, false)
+		err = createSecret(c.cluster, secret, p, acl, false)
 		if err != nil {
 			if os.IsExist(err) && i == 0 {
 				klog.Infof("Got already-exists error when writing secret; likely due to concurrent creation.  Will retry")
@@ -185,7 +245,11 @@ func (c *VFSSecretStore) ReplaceSecret(id string, secret *fi.Secret) (*fi.Secret
 		return nil, err
 	}
 
-	err = createSecret(secret, p, acl, true)
+	if err := c.recordVersion(id); err != nil {
+		return nil, err
+	}
+
+	err = createSecret(c.cluster, secret, p, acl, true)
 	if err != nil {
 		return nil, fmt.Errorf("unable to write secret: %v", err)
 	}
@@ -205,6 +269,10 @@ func (c *VFSSecretStore) loadSecret(p vfs.Path) (*fi.Secret, error) {
 			return nil, nil
 		}
 	}
+	data, err = stateencryption.Open(c.cluster, data)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting secret from %q: %v", p, err)
+	}
 	s := &fi.Secret{}
 	err = json.Unmarshal(data, s)
 	if err != nil {
@@ -213,13 +281,19 @@ func (c *VFSSecretStore) loadSecret(p vfs.Path) (*fi.Secret, error) {
 	return s, nil
 }
 
-// createSecret will create the Secret, overwriting an existing secret if replace is true
-func createSecret(s *fi.Secret, p vfs.Path, acl vfs.ACL, replace bool) error {
+// createSecret will create the Secret, overwriting an existing secret if replace is true.
+// The secret is encrypted if the cluster has state encryption enabled.
+func createSecret(cluster *kops.Cluster, s *fi.Secret, p vfs.Path, acl vfs.ACL, replace bool) error {
 	data, err := json.Marshal(s)
 	if err != nil {
 		return fmt.Errorf("error serializing secret: %v", err)
 	}
 
+	data, err = stateencryption.Seal(cluster, data)
+	if err != nil {
+		return fmt.Errorf("error encrypting secret: %v", err)
+	}
+
 	rs := bytes.NewReader(data)
 	if replace {
 		return p.WriteFile(rs, acl)
@@ -0,0 +1,162 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/kops/pkg/apis/kops"
+	"k8s.io/kops/pkg/stateencryption"
+	"k8s.io/kops/upup/pkg/fi"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+func TestVFSSecretStoreEncryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "state.key")
+	if err := ioutil.WriteFile(keyFile, []byte("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=\n"), 0600); err != nil {
+		t.Fatalf("error writing key file: %v", err)
+	}
+
+	cluster := &kops.Cluster{}
+	cluster.Spec.StateEncryption = &kops.StateEncryptionSpec{KeyFile: keyFile}
+
+	basedir := vfs.NewMemFSPath(vfs.NewMemFSContext(), "memfs://state/cluster.example.com/secrets")
+	store := NewVFSSecretStore(cluster, basedir)
+
+	if _, err := store.ReplaceSecret("admin", &fi.Secret{Data: []byte("password")}); err != nil {
+		t.Fatalf("unexpected error writing secret: %v", err)
+	}
+
+	data, err := basedir.Join("admin").ReadFile()
+	if err != nil {
+		t.Fatalf("error reading secret: %v", err)
+	}
+	if !stateencryption.IsEncrypted(data) || bytes.Contains(data, []byte("cGFzc3dvcmQ")) {
+		t.Errorf("expected the secret to be encrypted in the state store: %q", data)
+	}
+
+	secret, err := store.FindSecret("admin")
+	if err != nil {
+		t.Fatalf("unexpected error reading secret: %v", err)
+	}
+	if secret == nil || string(secret.Data) != "password" {
+		t.Errorf("unexpected secret %v", secret)
+	}
+
+	// Secrets written before encryption was enabled are still read
+	if err := basedir.Join("legacy").WriteFile(bytes.NewReader([]byte(`{"Data":"bGVnYWN5"}`)), nil); err != nil {
+		t.Fatalf("error writing secret: %v", err)
+	}
+	secret, err = store.FindSecret("legacy")
+	if err != nil {
+		t.Fatalf("unexpected error reading plaintext secret: %v", err)
+	}
+	if secret == nil || string(secret.Data) != "legacy" {
+		t.Errorf("unexpected secret %v", secret)
+	}
+}
+
+func TestVFSSecretStoreHistory(t *testing.T) {
+	cluster := &kops.Cluster{}
+	basedir := vfs.NewMemFSPath(vfs.NewMemFSContext(), "memfs://state/cluster.example.com/secrets")
+	store := NewVFSSecretStore(cluster, basedir).(*VFSSecretStore)
+
+	for _, value := range []string{"first", "second", "third"} {
+		if _, err := store.ReplaceSecret("admin", &fi.Secret{Data: []byte(value)}); err != nil {
+			t.Fatalf("unexpected error writing secret: %v", err)
+		}
+	}
+
+	versions, err := store.History("admin")
+	if err != nil {
+		t.Fatalf("unexpected error listing history: %v", err)
+	}
+	if len(versions) != 2 || versions[0].ID != "2" || versions[1].ID != "1" {
+		t.Fatalf("unexpected history %v", versions)
+	}
+
+	names, err := store.ListSecrets()
+	if err != nil {
+		t.Fatalf("unexpected error listing secrets: %v", err)
+	}
+	if len(names) != 1 || names[0] != "admin" {
+		t.Errorf("expected the history to be excluded from the secrets, got %v", names)
+	}
+
+	if err := store.Rollback("admin", "1"); err != nil {
+		t.Fatalf("unexpected error rolling back secret: %v", err)
+	}
+	secret, err := store.FindSecret("admin")
+	if err != nil {
+		t.Fatalf("unexpected error reading secret: %v", err)
+	}
+	if secret == nil || string(secret.Data) != "first" {
+		t.Errorf("unexpected secret after rollback %v", secret)
+	}
+
+	// The value replaced by the rollback is itself kept, so the rollback can be undone
+	versions, err = store.History("admin")
+	if err != nil {
+		t.Fatalf("unexpected error listing history: %v", err)
+	}
+	if len(versions) != 3 || versions[0].ID != "3" {
+		t.Fatalf("unexpected history after rollback %v", versions)
+	}
+	if err := store.Rollback("admin", "3"); err != nil {
+		t.Fatalf("unexpected error undoing rollback: %v", err)
+	}
+	secret, err = store.FindSecret("admin")
+	if err != nil {
+		t.Fatalf("unexpected error reading secret: %v", err)
+	}
+	if secret == nil || string(secret.Data) != "third" {
+		t.Errorf("unexpected secret after undoing rollback %v", secret)
+	}
+
+	// Deleted secrets can be restored
+	if err := store.DeleteSecret("admin"); err != nil {
+		t.Fatalf("unexpected error deleting secret: %v", err)
+	}
+	versions, err = store.History("admin")
+	if err != nil {
+		t.Fatalf("unexpected error listing history: %v", err)
+	}
+	if err := store.Rollback("admin", versions[0].ID); err != nil {
+		t.Fatalf("unexpected error restoring deleted secret: %v", err)
+	}
+	secret, err = store.FindSecret("admin")
+	if err != nil {
+		t.Fatalf("unexpected error reading secret: %v", err)
+	}
+	if secret == nil || string(secret.Data) != "third" {
+		t.Errorf("unexpected secret after restoring %v", secret)
+	}
+
+	if err := store.Rollback("admin", "99"); err == nil {
+		t.Errorf("expected an error rolling back to a missing version")
+	}
+}
@@ -24,3 +24,12 @@ type Target interface {
 	// Some providers (e.g. Terraform) actively keep state, and will delete resources automatically
 	ProcessDeletions() bool
 }
+
+// OptInTarget is a Target that renders only the tasks that define a Render method for it.
+// Other tasks are skipped, rather than failing the run.
+type OptInTarget interface {
+	Target
+
+	// SkipTask is called for each task that does not define a Render method for the target
+	SkipTask(e Task)
+}
@@ -37,6 +37,7 @@ import (
 	"k8s.io/kops/pkg/kopscodecs"
 	"k8s.io/kops/pkg/pki"
 	"k8s.io/kops/pkg/sshcredentials"
+	"k8s.io/kops/pkg/stateencryption"
 	"k8s.io/kops/util/pkg/vfs"
 )
 
@@ -116,7 +117,7 @@ func (s *VFSCAStore) readCAKeypairs(id string) (*keyset, *keyset, error) {
 			return nil, nil, err
 		}
 
-		if caPrivateKeys == nil {
+		if caPrivateKeys == nil && !UsesExternalCA(s.cluster, id) {
 			klog.Warningf("CA private key was not found")
 			//return nil, fmt.Errorf("error loading CA private key - key not found")
 		}
@@ -249,6 +250,11 @@ func (c *VFSCAStore) loadKeysetBundle(p vfs.Path) (*keyset, error) {
 		}
 	}
 
+	data, err = stateencryption.Open(c.cluster, data)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting bundle %q: %v", p, err)
+	}
+
 	o, format, err := c.parseKeysetYaml(data)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing bundle %q: %v", p, err)
@@ -309,6 +315,13 @@ func (c *VFSCAStore) writeKeysetBundle(p vfs.Path, name string, keyset *keyset,
 		return err
 	}
 
+	if includePrivateKeyMaterial {
+		objectData, err = stateencryption.Seal(c.cluster, objectData)
+		if err != nil {
+			return fmt.Errorf("error encrypting bundle %q: %v", p, err)
+		}
+	}
+
 	acl, err := acls.GetACL(p, c.cluster)
 	if err != nil {
 		return err
@@ -686,6 +699,10 @@ func mirrorKeyset(cluster *kops.Cluster, basedir vfs.Path, keyset *kops.Keyset)
 				return err
 			}
 			p := basedir.Join("private", keyset.Name, "keyset.yaml")
+			data, err = stateencryption.Seal(cluster, data)
+			if err != nil {
+				return fmt.Errorf("error encrypting %q: %v", p, err)
+			}
 			acl, err := acls.GetACL(p, cluster)
 			if err != nil {
 				return err
@@ -743,15 +760,22 @@ func (c *VFSCAStore) IssueCert(signer string, id string, serial *big.Int, privat
 			return nil, err
 		}
 
-		if caPrivateKeys == nil || caPrivateKeys.primary == nil || caPrivateKeys.primary.privateKey == nil {
-			return nil, fmt.Errorf("ca key for %q was not found; cannot issue certificates", signer)
-		}
 		if caCertificates == nil || caCertificates.primary == nil || caCertificates.primary.certificate == nil {
 			return nil, fmt.Errorf("ca certificate for %q was not found; cannot issue certificates", signer)
 		}
-		cert, err = pki.SignNewCertificate(privateKey, template, caCertificates.primary.certificate.Certificate, caPrivateKeys.primary.privateKey)
-		if err != nil {
-			return nil, err
+		if UsesExternalCA(c.cluster, signer) {
+			cert, err = IssueExternalCert(c.cluster, caCertificates.primary.certificate, privateKey, template)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			if caPrivateKeys == nil || caPrivateKeys.primary == nil || caPrivateKeys.primary.privateKey == nil {
+				return nil, fmt.Errorf("ca key for %q was not found; cannot issue certificates", signer)
+			}
+			cert, err = pki.SignNewCertificate(privateKey, template, caCertificates.primary.certificate.Certificate, caPrivateKeys.primary.privateKey)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -766,6 +790,10 @@ func (c *VFSCAStore) IssueCert(signer string, id string, serial *big.Int, privat
 }
 
 func (c *VFSCAStore) StoreKeypair(name string, cert *pki.Certificate, privateKey *pki.PrivateKey) error {
+	if err := c.recordVersion(name); err != nil {
+		return err
+	}
+
 	serial := cert.Certificate.SerialNumber.String()
 
 	ki := &keysetItem{
@@ -774,7 +802,8 @@ func (c *VFSCAStore) StoreKeypair(name string, cert *pki.Certificate, privateKey
 		privateKey:  privateKey,
 	}
 
-	{
+	// The private key of an external CA is not held in the keystore
+	if privateKey != nil {
 		err := c.storePrivateKey(name, ki)
 		if err != nil {
 			return err
@@ -804,6 +833,9 @@ func (c *VFSCAStore) AddCert(name string, cert *pki.Certificate) error {
 		id:          serial,
 		certificate: cert,
 	}
+	if err := c.recordVersion(name); err != nil {
+		return err
+	}
 	err := c.storeCertificate(name, ki)
 	if err != nil {
 		return err
@@ -881,6 +913,10 @@ func (c *VFSCAStore) loadOnePrivateKey(p vfs.Path) (*pki.PrivateKey, error) {
 		}
 		return nil, err
 	}
+	data, err = stateencryption.Open(c.cluster, data)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting private key from %q: %v", p, err)
+	}
 	k, err := pki.ParsePEMPrivateKey(data)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing private key from %q: %v", p, err)
@@ -980,11 +1016,15 @@ func (c *VFSCAStore) storePrivateKey(name string, ki *keysetItem) error {
 		}
 
 		p := c.buildPrivateKeyPath(name, ki.id)
+		sealed, err := stateencryption.Seal(c.cluster, data.Bytes())
+		if err != nil {
+			return fmt.Errorf("error encrypting %q: %v", p, err)
+		}
 		acl, err := acls.GetACL(p, c.cluster)
 		if err != nil {
 			return err
 		}
-		return p.WriteFile(bytes.NewReader(data.Bytes()), acl)
+		return p.WriteFile(bytes.NewReader(sealed), acl)
 	}
 }
 
@@ -1063,7 +1103,7 @@ func (c *VFSCAStore) deletePrivateKey(name string, id string) (bool, error) {
 func (c *VFSCAStore) deleteCertificate(name string, id string) (bool, error) {
 	// Update the bundle
 	{
-		p := c.buildPrivateKeyPoolPath(name)
+		p := c.buildCertificatePoolPath(name)
 		ks, err := c.loadCertificates(p, false)
 		if err != nil {
 			return false, err
@@ -1152,6 +1192,10 @@ func (c *VFSCAStore) loadData(p vfs.Path) (*pki.PrivateKey, error) {
 		}
 		return nil, err
 	}
+	data, err = stateencryption.Open(c.cluster, data)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting private key from %q: %v", p, err)
+	}
 	k, err := pki.ParsePEMPrivateKey(data)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing private key from %q: %v", p, err)
@@ -1167,6 +1211,9 @@ func (c *VFSCAStore) DeleteKeysetItem(item *kops.Keyset, id string) error {
 		if !ok {
 			return fmt.Errorf("keypair had non-integer version: %q", id)
 		}
+		if err := c.recordVersion(item.Name); err != nil {
+			return err
+		}
 		removed, err := c.deleteCertificate(item.Name, id)
 		if err != nil {
 			return fmt.Errorf("error deleting certificate: %v", err)
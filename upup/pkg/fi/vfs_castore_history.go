@@ -0,0 +1,208 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fi
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/klog"
+	"k8s.io/kops/pkg/acls"
+	"k8s.io/kops/pkg/stateencryption"
+	"k8s.io/kops/pkg/statehistory"
+	"k8s.io/kops/util/pkg/vfs"
+)
+
+var _ HasHistory = &VFSCAStore{}
+
+// history returns the prior versions of keysets, which are kept under .history in the keystore.
+// Each version is a keyset bundle holding both the certificates and the private keys.
+func (c *VFSCAStore) history() *statehistory.History {
+	return statehistory.NewHistory(c.cluster, c.basedir.Join(".history"))
+}
+
+// recordVersion records the current certificates and private keys of the keyset in the history, if the keyset exists
+func (c *VFSCAStore) recordVersion(name string) error {
+	certificates, err := c.loadCertificates(c.buildCertificatePoolPath(name), true)
+	if err != nil {
+		return fmt.Errorf("error reading keyset %q: %v", name, err)
+	}
+	privateKeys, err := c.loadPrivateKeys(c.buildPrivateKeyPoolPath(name), true)
+	if err != nil {
+		return fmt.Errorf("error reading keyset %q: %v", name, err)
+	}
+	if certificates == nil && privateKeys == nil {
+		return nil
+	}
+
+	merged := &keyset{
+		items: make(map[string]*keysetItem),
+	}
+	if certificates != nil {
+		for id, ki := range certificates.items {
+			merged.items[id] = &keysetItem{id: id, certificate: ki.certificate}
+		}
+	}
+	if privateKeys != nil {
+		for id, ki := range privateKeys.items {
+			if merged.items[id] == nil {
+				merged.items[id] = &keysetItem{id: id}
+			}
+			merged.items[id].privateKey = ki.privateKey
+		}
+	}
+
+	o, err := merged.ToAPIObject(name, true)
+	if err != nil {
+		return err
+	}
+	data, err := serializeKeysetBundle(o)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.history().Record(name, data); err != nil {
+		return fmt.Errorf("error recording prior version of keyset %q: %v", name, err)
+	}
+	return nil
+}
+
+// History implements HasHistory::History
+func (c *VFSCAStore) History(name string) ([]*statehistory.Version, error) {
+	return c.history().List(name)
+}
+
+// Rollback implements HasHistory::Rollback
+func (c *VFSCAStore) Rollback(name string, id string) error {
+	version, err := c.history().Get(name, id)
+	if err != nil {
+		return err
+	}
+	if version == nil {
+		return fmt.Errorf("version %s of keyset %q not found", id, name)
+	}
+
+	o, _, err := c.parseKeysetYaml(version.Data)
+	if err != nil {
+		return fmt.Errorf("error parsing version %s of keyset %q: %v", id, name, err)
+	}
+	restored, err := parseKeyset(o)
+	if err != nil {
+		return fmt.Errorf("error parsing version %s of keyset %q: %v", id, name, err)
+	}
+
+	if err := c.recordVersion(name); err != nil {
+		return err
+	}
+
+	certificates := &keyset{items: make(map[string]*keysetItem)}
+	privateKeys := &keyset{items: make(map[string]*keysetItem)}
+	for id, ki := range restored.items {
+		if ki.certificate != nil {
+			certificates.items[id] = ki
+		}
+		if ki.privateKey != nil {
+			privateKeys.items[id] = ki
+		}
+	}
+
+	// The individual files are read when items are added to the keyset, so they must match the bundles
+	if err := c.removeItemsNotIn(c.buildCertificatePoolPath(name), certificates, ".crt"); err != nil {
+		return err
+	}
+	if err := c.removeItemsNotIn(c.buildPrivateKeyPoolPath(name), privateKeys, ".key"); err != nil {
+		return err
+	}
+
+	for _, ki := range certificates.items {
+		var data bytes.Buffer
+		if _, err := ki.certificate.WriteTo(&data); err != nil {
+			return err
+		}
+		if err := c.writeItem(c.buildCertificatePath(name, ki.id), data.Bytes()); err != nil {
+			return err
+		}
+	}
+	for _, ki := range privateKeys.items {
+		var data bytes.Buffer
+		if _, err := ki.privateKey.WriteTo(&data); err != nil {
+			return err
+		}
+		sealed, err := stateencryption.Seal(c.cluster, data.Bytes())
+		if err != nil {
+			return fmt.Errorf("error encrypting private key %s of keyset %q: %v", ki.id, name, err)
+		}
+		if err := c.writeItem(c.buildPrivateKeyPath(name, ki.id), sealed); err != nil {
+			return err
+		}
+	}
+
+	if err := c.writeKeysetBundle(c.buildCertificatePoolPath(name), name, certificates, false); err != nil {
+		return fmt.Errorf("error writing bundle: %v", err)
+	}
+	if len(privateKeys.items) != 0 {
+		if err := c.writeKeysetBundle(c.buildPrivateKeyPoolPath(name), name, privateKeys, true); err != nil {
+			return fmt.Errorf("error writing bundle: %v", err)
+		}
+	} else {
+		p := c.buildPrivateKeyPoolPath(name).Join("keyset.yaml")
+		if err := p.Remove(); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing %q: %v", p, err)
+		}
+	}
+
+	c.mutex.Lock()
+	delete(c.cachedCAs, name)
+	c.mutex.Unlock()
+
+	return nil
+}
+
+// removeItemsNotIn removes the individual files of items in the pool that are not in the keyset
+func (c *VFSCAStore) removeItemsNotIn(pool vfs.Path, keep *keyset, suffix string) error {
+	files, err := pool.ReadDir()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("error listing %q: %v", pool, err)
+	}
+	for _, f := range files {
+		id := f.Base()
+		if !strings.HasSuffix(id, suffix) {
+			continue
+		}
+		if keep.items[strings.TrimSuffix(id, suffix)] != nil {
+			continue
+		}
+		klog.V(2).Infof("removing %q", f)
+		if err := f.Remove(); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing %q: %v", f, err)
+		}
+	}
+	return nil
+}
+
+func (c *VFSCAStore) writeItem(p vfs.Path, data []byte) error {
+	acl, err := acls.GetACL(p, c.cluster)
+	if err != nil {
+		return err
+	}
+	return p.WriteFile(bytes.NewReader(data), acl)
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package architectures
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Architecture is a CPU architecture, as named by the go toolchain and the kubernetes release
+type Architecture string
+
+const (
+	// ArchitectureAmd64 is the x86-64 architecture
+	ArchitectureAmd64 Architecture = "amd64"
+	// ArchitectureArm64 is the 64-bit ARM architecture, e.g. AWS Graviton
+	ArchitectureArm64 Architecture = "arm64"
+)
+
+// FindArchitecture returns the architecture of the running binary
+func FindArchitecture() (Architecture, error) {
+	switch runtime.GOARCH {
+	case "amd64":
+		return ArchitectureAmd64, nil
+	case "arm64":
+		return ArchitectureArm64, nil
+	default:
+		return "", fmt.Errorf("unsupported architecture %q", runtime.GOARCH)
+	}
+}
+
+// FromImageArchitecture returns the architecture of an image, as named by EC2 (x86_64 or arm64)
+func FromImageArchitecture(imageArchitecture string) (Architecture, error) {
+	switch imageArchitecture {
+	case "x86_64":
+		return ArchitectureAmd64, nil
+	case "arm64":
+		return ArchitectureArm64, nil
+	default:
+		return "", fmt.Errorf("unsupported image architecture %q", imageArchitecture)
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package architectures
+
+import (
+	"testing"
+)
+
+func TestFromImageArchitecture(t *testing.T) {
+	grid := map[string]Architecture{
+		"x86_64": ArchitectureAmd64,
+		"arm64":  ArchitectureArm64,
+	}
+	for imageArchitecture, expected := range grid {
+		actual, err := FromImageArchitecture(imageArchitecture)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", imageArchitecture, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("unexpected architecture for %q: expected %q, got %q", imageArchitecture, expected, actual)
+		}
+	}
+
+	if _, err := FromImageArchitecture("i386"); err == nil {
+		t.Errorf("expected error for i386")
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package vfs
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -219,7 +220,7 @@ func hashesMatch(src, dest Path) (bool, error) {
 		if err != nil {
 			klog.Warningf("error getting hash of dest file %s: %v", src, err)
 		} else if destHash != nil {
-			srcHash, err := dh.Hash(destHash.Algorithm)
+			srcHash, err := sh.Hash(destHash.Algorithm)
 			if err != nil {
 				klog.Warningf("error comparing hash of src file %s: %v", dest, err)
 			} else if srcHash != nil {
@@ -232,6 +233,39 @@ func hashesMatch(src, dest Path) (bool, error) {
 	return false, nil
 }
 
+// VerifyCopy checks that dest has the same contents as src.  It compares the hashes of the files where both support
+// hashing with a common algorithm, and otherwise reads both files and compares their hashes.
+func VerifyCopy(src, dest Path) error {
+	match, err := hashesMatch(src, dest)
+	if err != nil {
+		return err
+	}
+	if match {
+		return nil
+	}
+
+	srcHash, err := hashContents(src)
+	if err != nil {
+		return err
+	}
+	destHash, err := hashContents(dest)
+	if err != nil {
+		return err
+	}
+	if !srcHash.Equal(destHash) {
+		return fmt.Errorf("contents of %s do not match %s: hash %s, expected %s", dest, src, destHash, srcHash)
+	}
+	return nil
+}
+
+func hashContents(p Path) (*hashing.Hash, error) {
+	data, err := p.ReadFile()
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", p, err)
+	}
+	return hashing.HashAlgorithmSHA256.Hash(bytes.NewReader(data))
+}
+
 // CopyTree copies all files in src to dest.  It copies the whole recursive subtree of files.
 func CopyTree(src Path, dest Path, aclOracle ACLOracle) error {
 	srcFiles, err := src.ReadTree()